@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// healthcheckTimeout bounds the request of -healthcheck.
+const healthcheckTimeout = 5 * time.Second
+
+// registerAdminHandlers adds the operational endpoints to mux: metrics,
+// health and, when credentials are configured, the dashboard under both
+// /dashboard and /admin.
+func registerAdminHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc("/healthz", healthzHandler)
+	if dashboardAuth != "" {
+		mux.HandleFunc("/dashboard", dashboardHandler)
+		mux.HandleFunc("/admin", dashboardHandler)
+	}
+}
+
+// serveAdmin runs the operational endpoints on their own listener, so the
+// MCP endpoint can be public while they stay on an internal interface.
+func serveAdmin(addr string) {
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux)
+	log.Printf("Admin server listening on %s: /metrics, /healthz", addr)
+	if dashboardAuth != "" {
+		log.Printf("Dashboard available at http://%s/admin", addr)
+	}
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Fatalf("Admin server error: %v", err)
+	}
+}
+
+// healthzHandler reports that the process is up. It does not contact the
+// SearXNG instance, so a slow instance never fails liveness checks; use the
+// searxng_instance_status tool or the dashboard for that.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "ok",
+		"uptime_seconds": int64(time.Since(metrics.started).Seconds()),
+	})
+}
+
+// healthcheck requests /healthz of the server running at addr. It backs
+// -healthcheck, so container images need no curl or wget for their
+// HEALTHCHECK.
+func healthcheck(addr string) error {
+	return checkHealth(&http.Client{Timeout: healthcheckTimeout}, "http://"+addr+"/healthz")
+}
+
+// healthcheckServer requests /healthz of the sse server listening on
+// addrs: on the first TCP address, or else on the unix socket.
+func healthcheckServer(addrs []listenAddr) error {
+	if host, port, ok := firstTCPAddr(addrs); ok {
+		return healthcheck(localAddr(host, port))
+	}
+	return healthcheckUnix(addrs[0].address)
+}
+
+// healthcheckUnix requests /healthz of the server listening on the unix
+// socket at path.
+func healthcheckUnix(path string) error {
+	client := &http.Client{
+		Timeout: healthcheckTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	return checkHealth(client, "http://unix/healthz")
+}
+
+func checkHealth(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("/healthz returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// localAddr is the address reaching a listener on host:port from the same
+// host; listeners on every interface are reached on loopback.
+func localAddr(host, port string) string {
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
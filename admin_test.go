@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	previous := dashboardAuth
+	dashboardAuth = "admin:secret"
+	t.Cleanup(func() { dashboardAuth = previous })
+
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/healthz", http.StatusOK},
+		{"/metrics", http.StatusOK},
+		{"/admin", http.StatusUnauthorized},
+		{"/dashboard", http.StatusUnauthorized},
+		{"/sse", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestHealthcheck(t *testing.T) {
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux)
+	healthy := httptest.NewServer(mux)
+	defer healthy.Close()
+	if err := healthcheck(strings.TrimPrefix(healthy.URL, "http://")); err != nil {
+		t.Errorf("healthy server: %v", err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if err := healthcheck(strings.TrimPrefix(failing.URL, "http://")); err == nil {
+		t.Error("want an error for a 503 /healthz")
+	}
+
+	failing.Close()
+	if err := healthcheck(strings.TrimPrefix(failing.URL, "http://")); err == nil {
+		t.Error("want an error for a stopped server")
+	}
+}
+
+func TestLocalAddr(t *testing.T) {
+	for _, tt := range []struct{ host, want string }{
+		{"0.0.0.0", "127.0.0.1:8892"},
+		{"", "127.0.0.1:8892"},
+		{"10.0.0.5", "10.0.0.5:8892"},
+		{"::1", "[::1]:8892"},
+	} {
+		if got := localAddr(tt.host, "8892"); got != tt.want {
+			t.Errorf("localAddr(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
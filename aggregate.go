@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Instance modes of -instance-mode.
+const (
+	// instanceFailover searches the first instance whose circuit is closed.
+	instanceFailover = "failover"
+	// instanceAggregate searches every instance and merges the results.
+	instanceAggregate = "aggregate"
+)
+
+// instanceMode is how the instances of -searxng and -searxng-fallback are
+// used by general searches.
+var instanceMode = instanceFailover
+
+// rankFusionK damps the reciprocal rank fusion of aggregated results: a
+// result scores the sum of 1/(rankFusionK+rank) over the instances that
+// returned it, so hits found by several instances rise.
+const rankFusionK = 60
+
+// aggregateSearch searches every instance whose circuit is closed in
+// parallel and merges the responses: results are deduplicated by URL and
+// ranked by reciprocal rank fusion, and each lists the instances that
+// returned it. Instances that failed are returned with their error; the
+// search fails only when all of them did.
+func aggregateSearch(ctx context.Context, params searxng.SearchParams) (*searxng.SearchResponse, []string, error) {
+	var instances []*searxng.Client
+	for _, client := range allInstances(ctx) {
+		if !circuitOpen(client) {
+			instances = append(instances, client)
+		}
+	}
+	if len(instances) == 0 {
+		// Every circuit is open; the primary fails fast with the reason.
+		instances = []*searxng.Client{stateOf(ctx).client}
+	}
+
+	responses := make([]*searxng.SearchResponse, len(instances))
+	errs := make([]error, len(instances))
+	var wg sync.WaitGroup
+	for i, client := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = client.Search(ctx, params)
+		}()
+	}
+	wg.Wait()
+
+	var answered []*searxng.SearchResponse
+	var names []string
+	var failed []string
+	for i, client := range instances {
+		if errs[i] != nil {
+			log.Printf("Aggregated search on %s failed: %v", client.BaseURL, errs[i])
+			failed = append(failed, fmt.Sprintf("%s: %v", client.BaseURL, errs[i]))
+			continue
+		}
+		answered = append(answered, responses[i])
+		names = append(names, client.BaseURL)
+	}
+	if len(answered) == 0 {
+		return nil, failed, errs[0]
+	}
+	return mergeResponses(answered, names), failed, nil
+}
+
+// mergeResponses merges the responses of the named instances.
+func mergeResponses(responses []*searxng.SearchResponse, instances []string) *searxng.SearchResponse {
+	merged := &searxng.SearchResponse{Query: responses[0].Query, Results: []searxng.SearchResult{}}
+	scores := make(map[string]float64)
+	index := make(map[string]int)
+	seenAnswers := make(map[string]bool)
+	seenEngines := make(map[searxng.UnresponsiveEngine]bool)
+
+	for i, response := range responses {
+		merged.NumberOfResults = max(merged.NumberOfResults, response.NumberOfResults)
+		for rank, r := range response.Results {
+			key := aggregateKey(r.URL)
+			scores[key] += 1 / float64(rankFusionK+rank+1)
+			if j, ok := index[key]; ok {
+				merged.Results[j].Instances = append(merged.Results[j].Instances, instances[i])
+				continue
+			}
+			index[key] = len(merged.Results)
+			r.Instances = []string{instances[i]}
+			merged.Results = append(merged.Results, r)
+		}
+		for _, a := range response.Answers {
+			if !seenAnswers[a.Answer] {
+				seenAnswers[a.Answer] = true
+				merged.Answers = append(merged.Answers, a)
+			}
+		}
+		merged.Corrections = appendNew(merged.Corrections, response.Corrections)
+		merged.Suggestions = appendNew(merged.Suggestions, response.Suggestions)
+		if len(merged.Infoboxes) == 0 {
+			merged.Infoboxes = response.Infoboxes
+		}
+		for _, e := range response.UnresponsiveEngines {
+			if !seenEngines[e] {
+				seenEngines[e] = true
+				merged.UnresponsiveEngines = append(merged.UnresponsiveEngines, e)
+			}
+		}
+	}
+
+	sort.SliceStable(merged.Results, func(i, j int) bool {
+		return scores[aggregateKey(merged.Results[i].URL)] > scores[aggregateKey(merged.Results[j].URL)]
+	})
+	return merged
+}
+
+// aggregatedInstances lists the instances that returned results of an
+// aggregated response, in order of first appearance.
+func aggregatedInstances(response *searxng.SearchResponse) []string {
+	var instances []string
+	for _, r := range response.Results {
+		instances = appendNew(instances, r.Instances)
+	}
+	return instances
+}
+
+// aggregateKey identifies a URL across instances, which may report it with
+// another scheme, a www. prefix or a trailing slash.
+func aggregateKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// pageKey identifies the page of a result URL, whatever its tracking
+// parameters, www prefix or trailing slash.
+func pageKey(rawURL string) string {
+	return aggregateKey(normalizePageURL(rawURL))
+}
+
+// appendNew appends the values not in list yet.
+func appendNew(list, values []string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Statuses of answerCheck.
+const (
+	answerConfirmed = "confirmed"
+	// answerUnsupported means the source page no longer states the
+	// answer, e.g. because the answerer data is stale.
+	answerUnsupported = "unsupported"
+	// answerUnverifiable means the answer names no source page.
+	answerUnverifiable = "unverifiable"
+	answerUnreachable  = "unreachable"
+)
+
+const (
+	// minAnswerSupport is the share of the answer terms a block of the
+	// source page must contain to confirm the answer.
+	minAnswerSupport  = 0.8
+	answerSnippetSize = 300
+)
+
+// answerCheck is an instant answer checked against its source page.
+type answerCheck struct {
+	Answer string `json:"answer"`
+	URL    string `json:"url,omitempty"`
+	Engine string `json:"engine,omitempty"`
+	Status string `json:"status"`
+	// Snippet is the passage of the source page confirming the answer,
+	// or the closest one when it is unsupported.
+	Snippet string `json:"snippet,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func verifyAnswersOption() mcp.ToolOption {
+	return mcp.WithBoolean("verify_answers",
+		mcp.Description("Fetch the source page of each instant answer and report whether it still supports the answer, with a confirming snippet"),
+	)
+}
+
+// answerTexts returns the texts of answers, as tools list them.
+func answerTexts(answers []searxng.Answer) []string {
+	if len(answers) == 0 {
+		return nil
+	}
+	texts := make([]string, len(answers))
+	for i, a := range answers {
+		texts[i] = a.Answer
+	}
+	return texts
+}
+
+// verifyAnswers fetches the source pages of answers concurrently and
+// checks whether they state the answers.
+func verifyAnswers(ctx context.Context, answers []searxng.Answer) []answerCheck {
+	checks := make([]answerCheck, len(answers))
+	var wg sync.WaitGroup
+	for i, a := range answers {
+		checks[i] = answerCheck{Answer: a.Answer, URL: a.URL, Engine: a.Engine}
+		if a.URL == "" {
+			checks[i].Status = answerUnverifiable
+			continue
+		}
+		wg.Add(1)
+		go func(check *answerCheck) {
+			defer wg.Done()
+			page, err := fetchPage(ctx, check.URL)
+			if err != nil {
+				check.Status = answerUnreachable
+				check.Error = err.Error()
+				return
+			}
+			check.Status, check.Snippet = checkAnswer(check.Answer, page)
+		}(&checks[i])
+	}
+	wg.Wait()
+	return checks
+}
+
+// checkAnswer looks for the block of page that best supports answer. The
+// answer is confirmed when a block contains it verbatim or nearly all of
+// its terms.
+func checkAnswer(answer string, page *Page) (string, string) {
+	terms := answerTerms(answer)
+	if len(terms) == 0 {
+		return answerUnsupported, ""
+	}
+	needle := strings.Join(terms, " ")
+	best, bestSupport := "", 0.0
+	for _, block := range page.Blocks {
+		words := answerTerms(block)
+		if strings.Contains(" "+strings.Join(words, " ")+" ", " "+needle+" ") {
+			return answerConfirmed, snippet(block)
+		}
+		present := make(map[string]bool, len(words))
+		for _, w := range words {
+			present[w] = true
+		}
+		found := 0
+		for _, term := range terms {
+			if present[term] {
+				found++
+			}
+		}
+		if support := float64(found) / float64(len(terms)); support > bestSupport {
+			best, bestSupport = block, support
+		}
+	}
+	if bestSupport >= minAnswerSupport && len(terms) > 2 {
+		return answerConfirmed, snippet(best)
+	}
+	return answerUnsupported, snippet(best)
+}
+
+// answerTerms splits text into lower case words and numbers.
+func answerTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func snippet(block string) string {
+	s, _ := excerpt(collapse(block), answerSnippetSize)
+	return s
+}
+
+// answerNote flags the i-th answer in the markdown and compact formats
+// when it was verified and its source does not support it.
+func answerNote(checks []answerCheck, i int) string {
+	if i >= len(checks) {
+		return ""
+	}
+	switch checks[i].Status {
+	case answerUnsupported:
+		return " (not supported by its source " + checks[i].URL + ")"
+	case answerUnreachable:
+		return " (source unreachable)"
+	}
+	return ""
+}
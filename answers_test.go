@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAnswers(t *testing.T) {
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+<p>Paris is the capital and largest city of France.</p>
+<p>The city has a population of 2,102,650 residents as of 2023.</p>
+</body></html>`))
+	}))
+	defer pages.Close()
+
+	useEvidencePool(t)
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{},
+		"answers": []interface{}{
+			map[string]interface{}{"answer": "Capital of France: Paris", "url": pages.URL + "/paris", "engine": "wikidata"},
+			map[string]interface{}{"answer": "Population 2,200,000", "url": pages.URL + "/paris", "engine": "wikidata"},
+			map[string]interface{}{"answer": "Mayor: Anne Hidalgo", "url": pages.URL + "/gone"},
+			"1 EUR = 1.08 USD",
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "paris", "verify_answers": true})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Answers) != 4 || response.Answers[3] != "1 EUR = 1.08 USD" {
+		t.Errorf("answers = %q", response.Answers)
+	}
+	var statuses []string
+	for _, check := range response.AnswerChecks {
+		statuses = append(statuses, check.Status)
+	}
+	if strings.Join(statuses, ",") != "confirmed,unsupported,unreachable,unverifiable" {
+		t.Errorf("statuses = %v", statuses)
+	}
+	if check := response.AnswerChecks[0]; check.Snippet != "Paris is the capital and largest city of France." || check.Engine != "wikidata" {
+		t.Errorf("confirmed check = %+v", check)
+	}
+
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "paris"})
+	var unverified searchV2Response
+	decodeResult(t, result, &unverified)
+	if unverified.AnswerChecks != nil {
+		t.Errorf("answers were verified without verify_answers: %+v", unverified.AnswerChecks)
+	}
+}
+
+func TestCheckAnswer(t *testing.T) {
+	page := &Page{Blocks: []string{"Go 1.22 was released in February 2024.", "The latest release is Go 1.23."}}
+	for answer, want := range map[string]string{
+		"Go 1.23":                        answerConfirmed,
+		"Go 1.22 released February 2024": answerConfirmed,
+		"Go 1.24":                        answerUnsupported,
+		"":                               answerUnsupported,
+	} {
+		if got, _ := checkAnswer(answer, page); got != want {
+			t.Errorf("checkAnswer(%q) = %s, want %s", answer, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// timeRanges are the time_range values SearXNG accepts.
+var timeRanges = []string{"day", "week", "month", "year"}
+
+// searchCategories are the categories of the SearXNG search tabs, checked
+// against when the config of the instance is not available.
+var searchCategories = []string{"general", "images", "videos", "news", "map", "music", "it", "science", "files", "social media"}
+
+// Bounds of safe_search: 0 disabled, 1 moderate, 2 strict.
+const (
+	minSafeSearch = 0
+	maxSafeSearch = 2
+)
+
+// strictArguments accepts only JSON numbers and booleans for numeric and
+// boolean arguments. By default the string encodings some MCP clients
+// send ("2", "true", "1") are accepted too.
+var strictArguments bool
+
+// intArgument reads an integer argument. It reports whether the argument
+// was given, and an error when its value is not an integer.
+func intArgument(arguments map[string]interface{}, name string) (int, bool, error) {
+	value, ok := arguments[name]
+	if !ok || value == nil {
+		return 0, false, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return int(v), true, nil
+		}
+	case int:
+		return v, true, nil
+	case string:
+		if s := strings.TrimSpace(v); s == "" {
+			return 0, false, nil
+		} else if n, err := strconv.Atoi(s); err == nil && !strictArguments {
+			return n, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("%s must be an integer, got %s", name, describeArgument(value))
+}
+
+// floatArgument reads a number argument. It reports whether the argument
+// was given, and an error when its value is not a number.
+func floatArgument(arguments map[string]interface{}, name string) (float64, bool, error) {
+	value, ok := arguments[name]
+	if !ok || value == nil {
+		return 0, false, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return v, true, nil
+		}
+	case int:
+		return float64(v), true, nil
+	case string:
+		if s := strings.TrimSpace(v); s == "" {
+			return 0, false, nil
+		} else if f, err := strconv.ParseFloat(s, 64); err == nil && !strictArguments && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("%s must be a number, got %s", name, describeArgument(value))
+}
+
+// boolArgument reads a boolean argument. Besides true and false, the
+// strings "true", "false", "1", "0", "yes", "no" and the numbers 1 and 0
+// are accepted unless arguments are strict.
+func boolArgument(arguments map[string]interface{}, name string) (bool, bool, error) {
+	value, ok := arguments[name]
+	if !ok || value == nil {
+		return false, false, nil
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, true, nil
+	case float64:
+		if !strictArguments && (v == 0 || v == 1) {
+			return v == 1, true, nil
+		}
+	case string:
+		if strictArguments {
+			break
+		}
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "":
+			return false, false, nil
+		case "true", "1", "yes":
+			return true, true, nil
+		case "false", "0", "no":
+			return false, true, nil
+		}
+	}
+	return false, false, fmt.Errorf("%s must be a boolean, got %s", name, describeArgument(value))
+}
+
+// listArgument reads a list argument, given as a comma separated string
+// ("google, bing") or a JSON array of strings. Items are trimmed and empty
+// ones dropped; it reports whether any item was given.
+func listArgument(arguments map[string]interface{}, name string) ([]string, bool, error) {
+	value, ok := arguments[name]
+	if !ok || value == nil {
+		return nil, false, nil
+	}
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []string:
+		items = v
+	case []interface{}:
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("%s must be a list of strings, item %d is %s", name, i, describeArgument(item))
+			}
+			items = append(items, s)
+		}
+	default:
+		return nil, false, fmt.Errorf("%s must be a comma separated string or an array of strings, got %s", name, describeArgument(value))
+	}
+	var list []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, len(list) > 0, nil
+}
+
+// enumArgument reads a string argument that must be one of values, compared
+// case insensitively. It reports whether the argument was given, and an
+// error listing the valid values otherwise.
+func enumArgument(arguments map[string]interface{}, name string, values []string) (string, bool, error) {
+	value, ok := arguments[name]
+	if !ok || value == nil {
+		return "", false, nil
+	}
+	if s, ok := value.(string); ok {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			return "", false, nil
+		}
+		for _, v := range values {
+			if s == v {
+				return v, true, nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("%s must be one of %s, got %s", name, strings.Join(values, ", "), describeArgument(value))
+}
+
+// checkCategories returns an error naming the first of categories that the
+// instance does not have, with the valid ones: the categories of its
+// /config, or searchCategories without it.
+func checkCategories(ctx context.Context, categories []string) error {
+	known := searchCategories
+	if catalog, err := instanceConfigs.get(ctx); err == nil && len(catalog.categories) > 0 {
+		known = slices.Sorted(maps.Keys(catalog.categories))
+	}
+	for _, c := range categories {
+		if !containsString(known, strings.ToLower(c)) {
+			return fmt.Errorf("unknown category %q, categories are %s", c, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+func describeArgument(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("%v", value)
+}
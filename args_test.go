@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestIntArgument(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    int
+		wantOK  bool
+		wantErr bool
+	}{
+		{value: float64(2), want: 2, wantOK: true},
+		{value: "2", want: 2, wantOK: true},
+		{value: " 10 ", want: 10, wantOK: true},
+		{value: "", wantOK: false},
+		{value: nil, wantOK: false},
+		{value: float64(2.5), wantErr: true},
+		{value: "two", wantErr: true},
+		{value: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := intArgument(map[string]interface{}{"page": tt.value}, "page")
+		if (err != nil) != tt.wantErr || ok != tt.wantOK || got != tt.want {
+			t.Errorf("intArgument(%#v) = %d, %v, %v", tt.value, got, ok, err)
+		}
+	}
+}
+
+func TestBoolArgument(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    bool
+		wantErr bool
+	}{
+		{value: true, want: true},
+		{value: "true", want: true},
+		{value: "1", want: true},
+		{value: "Yes", want: true},
+		{value: float64(1), want: true},
+		{value: "false"},
+		{value: float64(0)},
+		{value: "maybe", wantErr: true},
+		{value: float64(2), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, _, err := boolArgument(map[string]interface{}{"dry_run": tt.value}, "dry_run")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("boolArgument(%#v) = %v, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestStrictArguments(t *testing.T) {
+	strictArguments = true
+	t.Cleanup(func() { strictArguments = false })
+
+	if _, _, err := intArgument(map[string]interface{}{"page": "2"}, "page"); err == nil {
+		t.Error("want an error for a string page in strict mode")
+	}
+	if _, _, err := boolArgument(map[string]interface{}{"dry_run": "true"}, "dry_run"); err == nil {
+		t.Error("want an error for a string dry_run in strict mode")
+	}
+	if n, ok, err := intArgument(map[string]interface{}{"page": float64(2)}, "page"); err != nil || !ok || n != 2 {
+		t.Errorf("intArgument = %d, %v, %v", n, ok, err)
+	}
+}
+
+func TestListArgument(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    []string
+		wantErr bool
+	}{
+		{value: "google, bing ,", want: []string{"google", "bing"}},
+		{value: []interface{}{"google", " bing", ""}, want: []string{"google", "bing"}},
+		{value: []string{"google images"}, want: []string{"google images"}},
+		{value: ""},
+		{value: []interface{}{}},
+		{value: nil},
+		{value: []interface{}{"google", float64(1)}, wantErr: true},
+		{value: float64(1), wantErr: true},
+		{value: map[string]interface{}{"google": true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := listArgument(map[string]interface{}{"engines": tt.value}, "engines")
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) || ok != (len(tt.want) > 0) {
+			t.Errorf("listArgument(%#v) = %q, %v, %v", tt.value, got, ok, err)
+		}
+	}
+}
+
+func TestEnumArgument(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{value: "week", want: "week"},
+		{value: " Month ", want: "month"},
+		{value: ""},
+		{value: nil},
+		{value: "last_week", wantErr: true},
+		{value: float64(7), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, _, err := enumArgument(map[string]interface{}{"time_range": tt.value}, "time_range", timeRanges)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("enumArgument(%#v) = %q, %v", tt.value, got, err)
+		}
+	}
+
+	_, _, err := enumArgument(map[string]interface{}{"time_range": "last_week"}, "time_range", timeRanges)
+	if want := `time_range must be one of day, week, month, year, got "last_week"`; err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %s", err, want)
+	}
+}
+
+func TestCheckCategories(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetConfig(map[string]interface{}{
+		"categories": []interface{}{"general"},
+		"engines": []interface{}{
+			map[string]interface{}{"name": "github", "enabled": true, "categories": []interface{}{"it", "repos"}},
+		},
+	})
+	ctx := context.Background()
+	if err := checkCategories(ctx, []string{"General", "repos"}); err != nil {
+		t.Errorf("categories of the instance refused: %v", err)
+	}
+	if err := checkCategories(ctx, []string{"news"}); err == nil {
+		t.Error("category the instance lacks accepted")
+	}
+
+	// Without the instance config, the SearXNG tabs are accepted.
+	fake.Handle("/config", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	instanceConfigs = &instanceConfigCache{}
+	if err := checkCategories(ctx, []string{"news"}); err != nil {
+		t.Errorf("tab category refused without the instance config: %v", err)
+	}
+	if err := checkCategories(ctx, []string{"repos"}); err == nil {
+		t.Error("unknown category accepted without the instance config")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// bangShortcut is a bang of the instance: "!gh" selects the github engine,
+// "!images" the images category.
+type bangShortcut struct {
+	Bang string `json:"bang"`
+	// Engine is set for engine bangs, Category for category bangs.
+	Engine   string `json:"engine,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Categories are those of the engine.
+	Categories []string `json:"categories,omitempty"`
+	Enabled    bool     `json:"enabled"`
+}
+
+type bangCatalogResponse struct {
+	Bangs []bangShortcut `json:"bangs"`
+	Count int            `json:"count"`
+}
+
+// instanceBangs returns the bangs of the active instance, read from its
+// cached /config: category bangs first, then engine bangs by name.
+func instanceBangs(ctx context.Context) ([]bangShortcut, error) {
+	catalog, err := instanceConfigs.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var bangs []bangShortcut
+	for _, category := range catalog.config.Categories {
+		bangs = append(bangs, bangShortcut{Bang: "!" + strings.ReplaceAll(category, " ", "_"), Category: category, Enabled: true})
+	}
+	var engines []bangShortcut
+	for _, engine := range catalog.config.Engines {
+		if engine.Shortcut == "" {
+			continue
+		}
+		engines = append(engines, bangShortcut{
+			Bang:       "!" + engine.Shortcut,
+			Engine:     engine.Name,
+			Categories: engine.Categories,
+			Enabled:    engine.Enabled,
+		})
+	}
+	sort.Slice(engines, func(i, j int) bool { return engines[i].Engine < engines[j].Engine })
+	return append(bangs, engines...), nil
+}
+
+// resolveBang finds the bang named name: a shortcut with or without its
+// "!" ("gh", "!gh"), a category or an engine name ("github"), ignoring
+// case.
+func resolveBang(bangs []bangShortcut, name string) (bangShortcut, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	bang := "!" + strings.TrimLeft(name, "!")
+	for _, b := range bangs {
+		if strings.ToLower(b.Bang) == bang || strings.ToLower(b.Engine) == name || strings.ToLower(b.Category) == name {
+			if !b.Enabled {
+				return bangShortcut{}, fmt.Errorf("bang %s selects engine %q, which is disabled on the instance", b.Bang, b.Engine)
+			}
+			return b, nil
+		}
+	}
+	known := make(map[string]bool, len(bangs))
+	for _, b := range bangs {
+		known[strings.ToLower(b.Bang)] = b.Enabled
+	}
+	message := fmt.Sprintf("unknown bang %q", bang)
+	if suggestions := similarEngines(bang, known); len(suggestions) > 0 {
+		message += fmt.Sprintf(", did you mean %s?", quoteAll(suggestions))
+	} else {
+		message += ", list_bangs lists the bangs of the instance"
+	}
+	return bangShortcut{}, errors.New(message)
+}
+
+func listBangsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	category, _ := arguments["category"].(string)
+	category = strings.ToLower(strings.TrimSpace(category))
+	includeDisabled, _, err := boolArgument(arguments, "include_disabled")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	bangs, err := instanceBangs(ctx)
+	if err != nil {
+		return upstreamErrorResult("listing bangs", err), nil
+	}
+	response := bangCatalogResponse{Bangs: []bangShortcut{}}
+	for _, b := range bangs {
+		if !b.Enabled && !includeDisabled {
+			continue
+		}
+		if category != "" && b.Category != category && !slices.Contains(b.Categories, category) {
+			continue
+		}
+		response.Bangs = append(response.Bangs, b)
+	}
+	response.Count = len(response.Bangs)
+	return structuredResult(response)
+}
+
+// bangSearchHandler searches with a bang of the instance, checked against
+// its catalog first: SearXNG searches an unknown bang as a word. The bang
+// is applied as the engines or categories of the search rather than
+// written into the query, so the search goes through the same checks
+// (denylist, engine policies, quotas) as one naming them.
+func bangSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	name, _ := arguments["bang"].(string)
+	if strings.Trim(name, "! ") == "" {
+		return invalidArgumentsResult(errors.New("bang must be a non-empty string, e.g. gh or !gh")), nil
+	}
+	query, _ := arguments["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return invalidArgumentsResult(errors.New("query must be a non-empty string")), nil
+	}
+	bangs, err := instanceBangs(ctx)
+	if err != nil {
+		return upstreamErrorResult("listing bangs", err), nil
+	}
+	bang, err := resolveBang(bangs, name)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	// The bang replaces the categories and engines of the search.
+	searchArguments := make(map[string]interface{}, len(arguments))
+	for key, value := range arguments {
+		if key != "bang" && key != "categories" && key != "engines" {
+			searchArguments[key] = value
+		}
+	}
+	if bang.Engine != "" {
+		searchArguments["engines"] = bang.Engine
+	} else {
+		searchArguments["categories"] = bang.Category
+	}
+	request.Params.Arguments = searchArguments
+	return searxngSearchV2Handler(ctx, request)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+func useBangInstance(t *testing.T) *searxngtest.Server {
+	t.Helper()
+	fake := useFakeInstance(t)
+	fake.SetConfig(map[string]interface{}{
+		"categories": []interface{}{"general", "social media"},
+		"engines": []interface{}{
+			map[string]interface{}{"name": "github", "shortcut": "gh", "categories": []interface{}{"it", "repos"}, "enabled": true},
+			map[string]interface{}{"name": "youtube", "shortcut": "yt", "categories": []interface{}{"videos"}, "enabled": true},
+			map[string]interface{}{"name": "yahoo", "shortcut": "yh", "categories": []interface{}{"general"}, "enabled": false},
+			map[string]interface{}{"name": "no shortcut", "enabled": true},
+		},
+	})
+	return fake
+}
+
+func TestListBangs(t *testing.T) {
+	useBangInstance(t)
+
+	result, err := callTool(t, listBangsHandler, map[string]interface{}{})
+	if err != nil || result.IsError {
+		t.Fatalf("list_bangs: %+v, %v", result, err)
+	}
+	var response bangCatalogResponse
+	decodeResult(t, result, &response)
+	var bangs []string
+	for _, b := range response.Bangs {
+		bangs = append(bangs, b.Bang)
+	}
+	if got := strings.Join(bangs, " "); got != "!general !social_media !gh !yt" {
+		t.Errorf("bangs = %s", got)
+	}
+
+	result, _ = callTool(t, listBangsHandler, map[string]interface{}{"category": "it"})
+	response = bangCatalogResponse{}
+	decodeResult(t, result, &response)
+	if response.Count != 1 || response.Bangs[0].Engine != "github" {
+		t.Errorf("it bangs = %+v", response.Bangs)
+	}
+}
+
+func TestBangSearch(t *testing.T) {
+	fake := useBangInstance(t)
+
+	for _, bang := range []string{"gh", "!GH", "github"} {
+		result, err := callTool(t, bangSearchHandler, map[string]interface{}{"bang": bang, "query": "mcp server", "engines": "google"})
+		if err != nil || result.IsError {
+			t.Fatalf("bang_search %s: %+v, %v", bang, result, err)
+		}
+		request, _ := fake.LastRequest("/search")
+		if q := request.Query.Get("q"); q != "mcp server" || request.Query.Get("engines") != "github" {
+			t.Errorf("bang %s searched q=%q engines=%q", bang, q, request.Query.Get("engines"))
+		}
+	}
+
+	tests := []struct {
+		bang, wantErr string
+	}{
+		{"gj", `did you mean "!gh"`},
+		{"yh", "disabled"},
+		{"!", "bang must be"},
+	}
+	for _, tt := range tests {
+		result, _ := callTool(t, bangSearchHandler, map[string]interface{}{"bang": tt.bang, "query": "q"})
+		text, _ := mcp.AsTextContent(result.Content[0])
+		if errorCode(result) != errorInvalidParams || text == nil || !strings.Contains(text.Text, tt.wantErr) {
+			t.Errorf("bang %q: got %+v, want an error containing %q", tt.bang, result.Content, tt.wantErr)
+		}
+	}
+}
+
+func TestBangSearchChecks(t *testing.T) {
+	fake := useBangInstance(t)
+
+	result, err := callTool(t, bangSearchHandler, map[string]interface{}{"bang": "general", "query": "mcp"})
+	if err != nil || result.IsError {
+		t.Fatalf("category bang: %+v, %v", result, err)
+	}
+	request, _ := fake.LastRequest("/search")
+	if request.Query.Get("categories") != "general" || request.Query.Get("q") != "mcp" {
+		t.Errorf("category bang searched %v", request.Query)
+	}
+
+	useState(t, func(s *serverState) { s.denylist = newDenylist(&DenyConfig{Categories: []string{"repos"}}) })
+	result, _ = callTool(t, bangSearchHandler, map[string]interface{}{"bang": "gh", "query": "mcp"})
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if !result.IsError || text == nil || !strings.Contains(text.Text, "policy forbids") {
+		t.Errorf("denied bang: %+v", result.Content)
+	}
+}
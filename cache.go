@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// errNotCached is returned in offline mode for searches without a cached
+// response.
+var errNotCached = errors.New("no cached response for this search (offline mode)")
+
+// responseStore keeps raw JSON responses by key with the time they were
+// stored.
+type responseStore interface {
+	get(key string) ([]byte, time.Time, bool)
+	put(key string, value []byte, stored time.Time) error
+	len() int
+	close() error
+}
+
+// responseCache caches search responses keyed by a hash of the upstream
+// request, so identical searches are answered without the instance.
+type responseCache struct {
+	store   responseStore
+	backend string
+	ttl     time.Duration
+	// offline serves only cached responses, whatever their age.
+	offline bool
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// searchCache is nil when caching is disabled.
+var searchCache *responseCache
+
+// offlineMode reports whether the server must not contact the instance.
+func offlineMode() bool {
+	return searchCache != nil && searchCache.offline
+}
+
+// newResponseCache returns a cache persisted in dir, or held in memory when
+// dir is empty.
+func newResponseCache(dir string, ttl time.Duration, offline bool) (*responseCache, error) {
+	c := &responseCache{ttl: ttl, offline: offline}
+	if dir == "" {
+		c.store = &memoryStore{entries: make(map[string]memoryEntry)}
+		c.backend = "memory"
+		return c, nil
+	}
+	store, err := openBoltStore(filepath.Join(dir, "cache.db"))
+	if err != nil {
+		return nil, err
+	}
+	c.store = store
+	c.backend = "disk " + dir
+	return c, nil
+}
+
+// cachedSearch answers the search from the cache when possible. kind
+// separates response types sharing a request, e.g. general and image
+// searches. It reports when the returned response was stored. Searches
+// sent to the instance count against the engine quotas.
+func cachedSearch[T any](ctx context.Context, kind string, params searxng.SearchParams, upstream func() (*T, error)) (*T, time.Time, error) {
+	return searchThroughCache(ctx, kind, params, upstream, true)
+}
+
+// freshSearch sends the search to the instance whatever the cache holds,
+// for callers comparing results over time, and caches the response for
+// the searches that follow.
+func freshSearch[T any](ctx context.Context, kind string, params searxng.SearchParams, upstream func() (*T, error)) (*T, error) {
+	result, _, err := searchThroughCache(ctx, kind, params, upstream, false)
+	return result, err
+}
+
+func searchThroughCache[T any](ctx context.Context, kind string, params searxng.SearchParams, upstream func() (*T, error), useCached bool) (*T, time.Time, error) {
+	if err := stateOf(ctx).denylist.check(ctx, params); err != nil {
+		log.Printf("Refused %s search: %v", kind, err)
+		return nil, time.Time{}, err
+	}
+	echo := echoSearch(ctx, kind, params)
+	search := func() (*T, error) {
+		stateOf(ctx).policies.count(ctx, params, time.Now())
+		return upstream()
+	}
+	if searchCache == nil {
+		result, err := search()
+		return result, time.Time{}, err
+	}
+
+	key, err := searchCacheKey(ctx, kind, params)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if data, stored, ok := searchCache.store.get(key); ok && (useCached || searchCache.offline) && searchCache.fresh(stored) {
+		var result T
+		if err := json.Unmarshal(data, &result); err == nil {
+			searchCache.hits.Add(1)
+			echo.cached(stored)
+			return &result, stored, nil
+		}
+	}
+	searchCache.misses.Add(1)
+	if searchCache.offline {
+		return nil, time.Time{}, errNotCached
+	}
+
+	result, err := search()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if data, err := json.Marshal(result); err == nil {
+		searchCache.store.put(key, data, time.Now())
+	}
+	return result, time.Time{}, nil
+}
+
+// fresh reports whether an entry stored at the given time may be served.
+// A zero TTL never expires entries.
+func (c *responseCache) fresh(stored time.Time) bool {
+	return c.offline || c.ttl == 0 || time.Since(stored) < c.ttl
+}
+
+// searchCacheKey hashes the upstream request of params: two searches share
+// an entry exactly when they would send the same request.
+func searchCacheKey(ctx context.Context, kind string, params searxng.SearchParams) (string, error) {
+	req, err := stateOf(ctx).client.NewSearchRequest(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("error building cache key: %w", err)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", kind, req.Method, req.URL)
+	if req.Body != nil {
+		io.Copy(h, req.Body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// describe summarizes the cache for the dashboard.
+func (c *responseCache) describe() string {
+	if c == nil {
+		return "No response cache configured"
+	}
+	mode := fmt.Sprintf("TTL %s", c.ttl)
+	switch {
+	case c.offline:
+		mode = "offline, serving cached responses only"
+	case c.ttl == 0:
+		mode = "entries never expire"
+	}
+	return fmt.Sprintf("%s cache, %s: %d entries, %d hits, %d misses",
+		c.backend, mode, c.store.len(), c.hits.Load(), c.misses.Load())
+}
+
+// maxMemoryEntries bounds the in-memory cache; the oldest entry is evicted
+// first.
+const maxMemoryEntries = 1000
+
+type memoryEntry struct {
+	value  []byte
+	stored time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	// limit is the number of entries kept, maxMemoryEntries when 0.
+	limit int
+}
+
+func (s *memoryStore) get(key string) ([]byte, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	return e.value, e.stored, ok
+}
+
+func (s *memoryStore) put(key string, value []byte, stored time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limit := s.limit
+	if limit == 0 {
+		limit = maxMemoryEntries
+	}
+	if _, ok := s.entries[key]; !ok && len(s.entries) >= limit {
+		oldest := ""
+		for k, e := range s.entries {
+			if oldest == "" || e.stored.Before(s.entries[oldest].stored) {
+				oldest = k
+			}
+		}
+		delete(s.entries, oldest)
+	}
+	s.entries[key] = memoryEntry{value: value, stored: stored}
+	return nil
+}
+
+func (s *memoryStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *memoryStore) close() error { return nil }
+
+var responsesBucket = []byte("responses")
+
+// boltStore persists responses in a BoltDB file. Values are prefixed with
+// the store time as Unix nanoseconds.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func openBoltStore(path string) (*boltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening cache %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(responsesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing cache: %w", err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) get(key string) ([]byte, time.Time, bool) {
+	var value []byte
+	var stored time.Time
+	s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(responsesBucket).Get([]byte(key))
+		if len(data) < 8 {
+			return nil
+		}
+		stored = time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+		value = append([]byte(nil), data[8:]...)
+		return nil
+	})
+	return value, stored, value != nil
+}
+
+func (s *boltStore) put(key string, value []byte, stored time.Time) error {
+	data := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(data, uint64(stored.UnixNano()))
+	copy(data[8:], value)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(responsesBucket).Put([]byte(key), data)
+	})
+}
+
+func (s *boltStore) len() int {
+	n := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(responsesBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+func (s *boltStore) close() error {
+	return s.db.Close()
+}
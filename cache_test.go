@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// useCache enables a search cache for the duration of the test.
+func useCache(t *testing.T, dir string, ttl time.Duration, offline bool) *responseCache {
+	t.Helper()
+	cache, err := newResponseCache(dir, ttl, offline)
+	if err != nil {
+		t.Fatalf("newResponseCache: %v", err)
+	}
+	previous := searchCache
+	searchCache = cache
+	t.Cleanup(func() {
+		searchCache = previous
+		cache.store.close()
+	})
+	return cache
+}
+
+func TestSearchCacheAndOfflineReplay(t *testing.T) {
+	fake := useFakeInstance(t)
+	dir := t.TempDir()
+	useCache(t, dir, time.Hour, false)
+
+	arguments := map[string]interface{}{"query": "golang", "engines": "duckduckgo"}
+	for i := 0; i < 2; i++ {
+		if _, err := callTool(t, searxngSearchV2Handler, arguments); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("instance received %d requests, want 1 with the second search cached", n)
+	}
+	searchCache.store.close()
+
+	// Replay from disk with the instance gone.
+	fake.Close()
+	cache := useCache(t, dir, time.Hour, true)
+	result, err := callTool(t, searxngSearchV2Handler, arguments)
+	if err != nil || result.IsError {
+		t.Fatalf("offline replay: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if response.Meta.CachedAt == "" || len(response.Results) == 0 {
+		t.Errorf("meta = %+v, want a cached response", response.Meta)
+	}
+
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "uncached"})
+	if !result.IsError {
+		t.Error("want an error result for an uncached offline search")
+	}
+	if cache.hits.Load() != 1 || cache.misses.Load() != 1 {
+		t.Errorf("hits %d, misses %d", cache.hits.Load(), cache.misses.Load())
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := &responseCache{ttl: time.Minute}
+	if !cache.fresh(time.Now().Add(-30*time.Second)) || cache.fresh(time.Now().Add(-2*time.Minute)) {
+		t.Error("entries must expire after the TTL")
+	}
+	cache.offline = true
+	if !cache.fresh(time.Now().Add(-24 * time.Hour)) {
+		t.Error("offline mode must serve expired entries")
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	store := &memoryStore{entries: make(map[string]memoryEntry)}
+	start := time.Now()
+	for i := 0; i <= maxMemoryEntries; i++ {
+		store.put(string(rune(i)), nil, start.Add(time.Duration(i)*time.Second))
+	}
+	if store.len() != maxMemoryEntries {
+		t.Errorf("len = %d, want %d", store.len(), maxMemoryEntries)
+	}
+	if _, _, ok := store.get(string(rune(0))); ok {
+		t.Error("the oldest entry was not evicted")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	// canaryTimeout bounds a mirrored search; it runs detached from the
+	// tool call.
+	canaryTimeout = 30 * time.Second
+	// canaryOverlapDepth is the number of top production results looked
+	// up among the canary results.
+	canaryOverlapDepth = 10
+)
+
+// CanaryConfig mirrors a share of the searches to a secondary instance.
+type CanaryConfig struct {
+	URL string `json:"url"`
+	// Percent is the share of searches mirrored, from 0 to 100.
+	Percent float64 `json:"percent"`
+	// SearchMethod is the HTTP method for its searches, default the one
+	// of the production instance.
+	SearchMethod string `json:"search_method,omitempty"`
+}
+
+// canaryStats compares the mirrored searches with their production
+// counterparts.
+type canaryStats struct {
+	Searches        int64
+	Errors          int64
+	Duration        time.Duration
+	PrimaryDuration time.Duration
+	Results         int64
+	PrimaryResults  int64
+	// OverlapSum adds up, per successful search, the share of the top
+	// production result URLs the canary also returned.
+	OverlapSum float64
+	LastError  string
+}
+
+// canaryMirror sends a sample of searches to a canary instance, discards
+// its results and records its latency and quality, so operators can
+// validate a new instance before cutting over.
+type canaryMirror struct {
+	client  *searxng.Client
+	percent float64
+
+	mu    sync.Mutex
+	stats canaryStats
+	// wg tracks the mirrored searches in flight.
+	wg sync.WaitGroup
+}
+
+// canary is nil when no canary is configured.
+var canary *canaryMirror
+
+func newCanaryMirror(cfg CanaryConfig, opts ...searxng.Option) *canaryMirror {
+	return &canaryMirror{client: searxng.New(cfg.URL, opts...), percent: cfg.Percent}
+}
+
+// mirror sends params to the canary with the configured probability.
+// primary is the production response, found in primaryDuration.
+func (m *canaryMirror) mirror(params searxng.SearchParams, primary *searxng.SearchResponse, primaryDuration time.Duration) {
+	if m == nil || rand.Float64()*100 >= m.percent {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), canaryTimeout)
+		defer cancel()
+
+		start := time.Now()
+		result, err := m.client.Search(ctx, params)
+		m.record(primary, primaryDuration, result, time.Since(start), err)
+	}()
+}
+
+func (m *canaryMirror) record(primary *searxng.SearchResponse, primaryDuration time.Duration, result *searxng.SearchResponse, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Searches++
+	if err != nil {
+		m.stats.Errors++
+		m.stats.LastError = err.Error()
+		return
+	}
+	m.stats.Duration += duration
+	m.stats.PrimaryDuration += primaryDuration
+	m.stats.Results += int64(len(result.Results))
+	m.stats.PrimaryResults += int64(len(primary.Results))
+	m.stats.OverlapSum += resultOverlap(primary.Results, result.Results)
+}
+
+// resultOverlap is the share of the top production result URLs found among
+// the canary results; 1 when production found nothing.
+func resultOverlap(primary, canary []searxng.SearchResult) float64 {
+	if len(primary) > canaryOverlapDepth {
+		primary = primary[:canaryOverlapDepth]
+	}
+	if len(primary) == 0 {
+		return 1
+	}
+	urls := make(map[string]bool, len(canary))
+	for _, r := range canary {
+		urls[r.URL] = true
+	}
+	found := 0
+	for _, r := range primary {
+		if urls[r.URL] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(primary))
+}
+
+func (m *canaryMirror) snapshot() canaryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// describe summarizes the canary for the dashboard.
+func (m *canaryMirror) describe() string {
+	if m == nil {
+		return "No canary instance configured"
+	}
+	s := m.snapshot()
+	summary := fmt.Sprintf("%s, %g%% of searches mirrored: %d searches, %d errors", m.client.BaseURL, m.percent, s.Searches, s.Errors)
+	if ok := s.Searches - s.Errors; ok > 0 {
+		summary += fmt.Sprintf("; avg latency %s (production %s), avg results %.1f (production %.1f), top %d overlap %.0f%%",
+			(s.Duration / time.Duration(ok)).Round(time.Millisecond),
+			(s.PrimaryDuration / time.Duration(ok)).Round(time.Millisecond),
+			float64(s.Results)/float64(ok), float64(s.PrimaryResults)/float64(ok),
+			canaryOverlapDepth, 100*s.OverlapSum/float64(ok))
+	}
+	if s.LastError != "" {
+		summary += "; last error: " + s.LastError
+	}
+	return summary
+}
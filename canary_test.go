@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+func TestCanaryMirror(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "A", "url": "https://a.example"},
+			{"title": "B", "url": "https://b.example"},
+		},
+	})
+	secondary := searxngtest.NewServer()
+	defer secondary.Close()
+	secondary.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "B", "url": "https://b.example"},
+			{"title": "C", "url": "https://c.example"},
+			{"title": "D", "url": "https://d.example"},
+		},
+	})
+
+	mirror := newCanaryMirror(CanaryConfig{URL: secondary.URL, Percent: 100})
+	canary = mirror
+	t.Cleanup(func() { canary = nil })
+
+	for _, query := range []string{"first", "second"} {
+		if _, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": query}); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+	}
+	mirror.wg.Wait()
+
+	req, ok := secondary.LastRequest("/search")
+	if !ok || req.Query.Get("q") != "second" {
+		t.Errorf("canary request = %+v, %v", req, ok)
+	}
+	s := mirror.snapshot()
+	if s.Searches != 2 || s.Errors != 0 || s.Results != 6 || s.PrimaryResults != 4 || s.OverlapSum != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	if summary := mirror.describe(); !strings.Contains(summary, "top 10 overlap 50%") {
+		t.Errorf("describe = %q", summary)
+	}
+
+	mirror.percent = 0
+	callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "third"})
+	mirror.wg.Wait()
+	if s := mirror.snapshot(); s.Searches != 2 {
+		t.Errorf("searches = %d with percent 0, want 2", s.Searches)
+	}
+}
+
+func TestResultOverlap(t *testing.T) {
+	results := func(urls ...string) []searxng.SearchResult {
+		var r []searxng.SearchResult
+		for _, u := range urls {
+			r = append(r, searxng.SearchResult{URL: u})
+		}
+		return r
+	}
+	tests := []struct {
+		primary, canary []searxng.SearchResult
+		want            float64
+	}{
+		{results("a", "b", "c", "d"), results("d", "a"), 0.5},
+		{results("a"), nil, 0},
+		{nil, results("a"), 1},
+	}
+	for _, tt := range tests {
+		if got := resultOverlap(tt.primary, tt.canary); got != tt.want {
+			t.Errorf("resultOverlap = %g, want %g", got, tt.want)
+		}
+	}
+}
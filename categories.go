@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// categoryDescriptions describe the categories of a default SearXNG
+// install. Instances may define others, listed without a description.
+var categoryDescriptions = map[string]string{
+	"general":                 "Web pages from general purpose engines (google, bing, duckduckgo, ...), plus wikipedia and instant answers",
+	"images":                  "Images with their thumbnail, resolution and source page",
+	"videos":                  "Videos from youtube and other video sites, with duration and thumbnail",
+	"news":                    "News articles with their publication date; works best with time_range",
+	"map":                     "Places and addresses from OpenStreetMap and photon, with coordinates",
+	"music":                   "Tracks, albums and lyrics from bandcamp, soundcloud, genius, ...",
+	"it":                      "Developer resources: code repositories, Q&A sites, package registries and documentation",
+	"science":                 "Scientific papers from arxiv, google scholar, pubmed, semantic scholar, ...",
+	"files":                   "Downloads and torrents",
+	"social media":            "Posts from social networks and forums such as reddit and mastodon",
+	"web":                     "Web pages, the general search engines",
+	"wikimedia":               "Wikipedia, Wikidata and the other Wikimedia projects",
+	"dictionaries":            "Definitions and translations of words",
+	"translate":               "Machine translation of the query",
+	"weather":                 "Weather reports and forecasts",
+	"currency":                "Currency conversion",
+	"apps":                    "Mobile and desktop applications from app stores",
+	"books":                   "Books from library catalogs and shadow libraries",
+	"movies":                  "Movies and series",
+	"lyrics":                  "Song lyrics",
+	"radio":                   "Internet radio stations",
+	"packages":                "Software packages from language and distribution registries",
+	"repos":                   "Code repositories from github, gitlab, codeberg, ...",
+	"q&a":                     "Questions and answers from stackoverflow and other Q&A sites",
+	"software wikis":          "Wikis of software projects such as the Arch wiki",
+	"scientific publications": "Papers and their citations",
+	"shopping":                "Products and prices from online shops",
+}
+
+// categoryInfo is a category of the instance with the engines searching
+// it.
+type categoryInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Bang selects the category in a query, e.g. "!images".
+	Bang string `json:"bang"`
+	// Tab is set for the categories the instance shows as result tabs;
+	// the others group engines within a tab.
+	Tab             bool     `json:"tab"`
+	EnabledEngines  []string `json:"enabled_engines"`
+	DisabledEngines []string `json:"disabled_engines,omitempty"`
+}
+
+type categoriesResponse struct {
+	Categories []categoryInfo `json:"categories"`
+	Count      int            `json:"count"`
+}
+
+// instanceCategories maps the categories of an instance config to their
+// engines: the tabs in the order of the instance, then the other
+// categories by name.
+func instanceCategories(instanceConfig *searxng.InstanceConfig) []categoryInfo {
+	byName := make(map[string]*categoryInfo)
+	var names []string
+	category := func(name string) *categoryInfo {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if c, ok := byName[key]; ok {
+			return c
+		}
+		c := &categoryInfo{
+			Name:           key,
+			Description:    categoryDescriptions[key],
+			Bang:           "!" + strings.ReplaceAll(key, " ", "_"),
+			EnabledEngines: []string{},
+		}
+		byName[key] = c
+		names = append(names, key)
+		return c
+	}
+	for _, name := range instanceConfig.Categories {
+		category(name).Tab = true
+	}
+	tabs := len(names)
+	for _, engine := range instanceConfig.Engines {
+		for _, name := range engine.Categories {
+			c := category(name)
+			if engine.Enabled {
+				c.EnabledEngines = append(c.EnabledEngines, engine.Name)
+			} else {
+				c.DisabledEngines = append(c.DisabledEngines, engine.Name)
+			}
+		}
+	}
+	sort.Strings(names[tabs:])
+
+	categories := make([]categoryInfo, 0, len(names))
+	for _, name := range names {
+		c := byName[name]
+		sort.Strings(c.EnabledEngines)
+		sort.Strings(c.DisabledEngines)
+		categories = append(categories, *c)
+	}
+	return categories
+}
+
+func searxngCategoriesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	name, _ := arguments["category"].(string)
+	name = strings.ToLower(strings.TrimSpace(name))
+	includeDisabled := true
+	if value, ok, err := boolArgument(arguments, "include_disabled"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		includeDisabled = value
+	}
+
+	instanceConfig, err := activeInstance(ctx).GetInstanceConfig(ctx)
+	if err != nil {
+		return upstreamErrorResult("listing categories", err), nil
+	}
+	categories := instanceCategories(instanceConfig)
+	response := categoriesResponse{Categories: []categoryInfo{}}
+	for _, c := range categories {
+		if name != "" && c.Name != name {
+			continue
+		}
+		if !includeDisabled {
+			c.DisabledEngines = nil
+		}
+		response.Categories = append(response.Categories, c)
+	}
+	if name != "" && len(response.Categories) == 0 {
+		known := make(map[string]bool, len(categories))
+		for _, c := range categories {
+			known[c.Name] = true
+		}
+		message := fmt.Sprintf("unknown category %q", name)
+		if suggestions := similarEngines(name, known); len(suggestions) > 0 {
+			message += fmt.Sprintf(", did you mean %s?", quoteAll(suggestions))
+		}
+		return invalidArgumentsResult(errors.New(message)), nil
+	}
+	response.Count = len(response.Categories)
+	return structuredResult(response)
+}
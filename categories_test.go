@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearxngCategories(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetConfig(map[string]interface{}{
+		"categories": []interface{}{"general", "it"},
+		"engines": []interface{}{
+			map[string]interface{}{"name": "google", "enabled": true, "categories": []interface{}{"general", "web"}},
+			map[string]interface{}{"name": "bing", "enabled": false, "categories": []interface{}{"general", "web"}},
+			map[string]interface{}{"name": "github", "enabled": true, "categories": []interface{}{"it", "repos"}},
+			map[string]interface{}{"name": "reddit", "enabled": true, "categories": []interface{}{"social media"}},
+		},
+	})
+
+	result, err := callTool(t, searxngCategoriesHandler, map[string]interface{}{})
+	if err != nil || result.IsError {
+		t.Fatalf("categories: %+v, %v", result, err)
+	}
+	var response categoriesResponse
+	decodeResult(t, result, &response)
+	var names []string
+	for _, c := range response.Categories {
+		names = append(names, c.Name)
+	}
+	if want := []string{"general", "it", "repos", "social media", "web"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("categories = %q, want %q", names, want)
+	}
+	general := response.Categories[0]
+	if !general.Tab || general.Bang != "!general" || general.Description == "" ||
+		!reflect.DeepEqual(general.EnabledEngines, []string{"google"}) || !reflect.DeepEqual(general.DisabledEngines, []string{"bing"}) {
+		t.Errorf("general = %+v", general)
+	}
+	if social := response.Categories[3]; social.Tab || social.Bang != "!social_media" {
+		t.Errorf("social media = %+v", social)
+	}
+
+	result, err = callTool(t, searxngCategoriesHandler, map[string]interface{}{"category": "Web", "include_disabled": false})
+	if err != nil || result.IsError {
+		t.Fatalf("web category: %+v, %v", result, err)
+	}
+	var web categoriesResponse
+	decodeResult(t, result, &web)
+	if len(web.Categories) != 1 || web.Categories[0].Name != "web" || web.Categories[0].DisabledEngines != nil {
+		t.Errorf("web = %+v", web.Categories)
+	}
+
+	result, err = callTool(t, searxngCategoriesHandler, map[string]interface{}{"category": "genral"})
+	if err != nil || errorCode(result) != errorInvalidParams {
+		t.Errorf("unknown category: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// autoEngines is the engines value asking the server to pick the engines
+// from the query.
+const autoEngines = "auto"
+
+// QueryClass maps queries matching its patterns to a category and a set of
+// engines. Classes of the config file are tried before the builtin ones:
+//
+//	{"name": "go", "patterns": ["\\bgolang\\b", "\\bgo (mod|vet|test)\\b"], "engines": ["pkg.go.dev", "github"]}
+type QueryClass struct {
+	Name string `json:"name"`
+	// Patterns are case-insensitive regular expressions; the class with
+	// the most matching patterns wins.
+	Patterns   []string `json:"patterns"`
+	Categories []string `json:"categories,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+	// TimeRange is applied when the call gives none, e.g. week for news.
+	TimeRange string `json:"time_range,omitempty"`
+}
+
+// builtinQueryClasses are tried in order; on a tie the first wins.
+var builtinQueryClasses = []QueryClass{
+	{
+		Name: "code",
+		Patterns: []string{
+			`\b(error|exception|stack ?trace|traceback|segfault|panic|undefined reference|null ?pointer|typeerror|syntaxerror|compile[rd]?)\b`,
+			`\b(golang|python|javascript|typescript|rust|java|kotlin|npm|pip|docker|kubernetes|git|sql|regex|bash)\b`,
+			// \b cannot end c++ and c#, which end in non-word characters.
+			`\bc(\+\+|#)(\W|$)`,
+			`\b(function|method|library|package|module|api|sdk|cli)\b`,
+			`\w+(\.\w+)+\(|\w+::\w+|\w+Error\b|\w+Exception\b`,
+		},
+		Categories: []string{"it"},
+		Engines:    []string{"stackoverflow", "github", "duckduckgo"},
+	},
+	{
+		Name: "news",
+		Patterns: []string{
+			`\b(latest|breaking|today|yesterday|this week|right now|currently)\b`,
+			`\b(news|headlines|announce[ds]?|announcement|election|press release)\b`,
+		},
+		Categories: []string{"news"},
+		Engines:    []string{"google news", "bing news", "yahoo news"},
+		TimeRange:  "week",
+	},
+	{
+		Name: "science",
+		Patterns: []string{
+			`\b(paper|papers|study|studies|research|arxiv|doi|journal|preprint|peer.reviewed|meta.analysis)\b`,
+			`\b(clinical trial|hypothesis|theorem|dataset)\b`,
+		},
+		Categories: []string{"science"},
+		Engines:    []string{"arxiv", "google scholar", "pubmed", "semantic scholar"},
+	},
+	{
+		Name: "map",
+		Patterns: []string{
+			`\b(near me|directions to|address of|map of|where is|located in|how far)\b`,
+		},
+		Categories: []string{"map"},
+		Engines:    []string{"openstreetmap", "photon"},
+	},
+	{
+		Name: "videos",
+		Patterns: []string{
+			`\b(video|videos|youtube|trailer|watch|clip|livestream)\b`,
+		},
+		Categories: []string{"videos"},
+		Engines:    []string{"youtube", "vimeo", "dailymotion"},
+	},
+	{
+		Name: "images",
+		Patterns: []string{
+			`\b(image|images|picture|pictures|photo|photos|wallpaper|logo|icon)\b`,
+		},
+		Categories: []string{"images"},
+		Engines:    []string{"google images", "bing images"},
+	},
+	{
+		Name: "music",
+		Patterns: []string{
+			`\b(lyrics|song|songs|album|chords|discography|playlist)\b`,
+		},
+		Categories: []string{"music"},
+		Engines:    []string{"genius", "bandcamp", "soundcloud"},
+	},
+}
+
+// builtinClasses are builtinQueryClasses compiled.
+var builtinClasses = mustCompileQueryClasses(builtinQueryClasses)
+
+// generalQueryClass is the class of queries no rule matches: the general
+// category, with the default engines of the instance.
+var generalQueryClass = QueryClass{
+	Name:       "general",
+	Categories: []string{"general"},
+}
+
+// compiledQueryClass is a QueryClass with its patterns compiled.
+type compiledQueryClass struct {
+	QueryClass
+	patterns []*regexp.Regexp
+}
+
+// queryClassification is the class picked for a query.
+type queryClassification struct {
+	Query      string   `json:"query"`
+	Class      string   `json:"class"`
+	Categories []string `json:"categories,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+	TimeRange  string   `json:"time_range,omitempty"`
+	// Signals are the parts of the query the class patterns matched,
+	// empty for the general class.
+	Signals []string `json:"signals,omitempty"`
+	// AvoidedEngines are engines of the class left out because they have
+	// been failing in the recent searches of the server.
+	AvoidedEngines []string `json:"avoided_engines,omitempty"`
+	// MissingEngines are engines of the class left out because the
+	// instance does not have them enabled.
+	MissingEngines []string `json:"missing_engines,omitempty"`
+}
+
+// checkQueryClass checks a configured class and compiles its patterns.
+func checkQueryClass(class QueryClass) ([]*regexp.Regexp, error) {
+	if class.Name == "" {
+		return nil, errors.New("query class needs a name")
+	}
+	if len(class.Patterns) == 0 {
+		return nil, fmt.Errorf("query class %q needs patterns", class.Name)
+	}
+	if len(class.Categories) == 0 && len(class.Engines) == 0 {
+		return nil, fmt.Errorf("query class %q needs categories or engines", class.Name)
+	}
+	if class.TimeRange != "" && !slices.Contains(timeRanges, class.TimeRange) {
+		return nil, fmt.Errorf("query class %q: time_range must be one of %s", class.Name, strings.Join(timeRanges, ", "))
+	}
+	patterns := make([]*regexp.Regexp, 0, len(class.Patterns))
+	for _, pattern := range class.Patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("query class %q pattern %q: %w", class.Name, pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// compileQueryClasses checks and compiles classes.
+func compileQueryClasses(classes []QueryClass) ([]compiledQueryClass, error) {
+	compiled := make([]compiledQueryClass, 0, len(classes))
+	for _, class := range classes {
+		patterns, err := checkQueryClass(class)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, compiledQueryClass{QueryClass: class, patterns: patterns})
+	}
+	return compiled, nil
+}
+
+func mustCompileQueryClasses(classes []QueryClass) []compiledQueryClass {
+	compiled, err := compileQueryClasses(classes)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// classifyQuery picks the class of query with the most matching patterns,
+// the configured classes first, or the general class. Engines of the class
+// the instance does not have enabled are left out; without any left, the
+// instance picks the engines of the categories.
+func classifyQuery(ctx context.Context, query string) queryClassification {
+	best := queryClassification{
+		Query:      query,
+		Class:      generalQueryClass.Name,
+		Categories: generalQueryClass.Categories,
+		Engines:    generalQueryClass.Engines,
+	}
+	for _, class := range slices.Concat(stateOf(ctx).queryClasses, builtinClasses) {
+		var signals []string
+		for _, re := range class.patterns {
+			if match := re.FindString(query); match != "" {
+				signals = append(signals, strings.TrimSpace(match))
+			}
+		}
+		if len(signals) > len(best.Signals) {
+			best = queryClassification{
+				Query:      query,
+				Class:      class.Name,
+				Categories: class.Categories,
+				Engines:    class.Engines,
+				TimeRange:  class.TimeRange,
+				Signals:    signals,
+			}
+		}
+	}
+	best.Engines, best.MissingEngines = instanceEngines(ctx, best.Engines)
+	best.Engines, best.AvoidedEngines = avoidBrokenEngines(best.Engines)
+	return best
+}
+
+// instanceEngines leaves out the engines the instance does not have
+// enabled, according to its /config. Without the config all are kept.
+func instanceEngines(ctx context.Context, engines []string) (kept, missing []string) {
+	if len(engines) == 0 {
+		return nil, nil
+	}
+	catalog, err := instanceConfigs.get(ctx)
+	if err != nil || len(catalog.engines) == 0 {
+		return engines, nil
+	}
+	for _, engine := range engines {
+		if catalog.enabled[strings.ToLower(engine)] {
+			kept = append(kept, engine)
+		} else {
+			missing = append(missing, engine)
+		}
+	}
+	return kept, missing
+}
+
+// avoidBrokenEngines leaves out the engines that have been failing
+// chronically, unless all of them have.
+func avoidBrokenEngines(engines []string) (kept, avoided []string) {
+	for _, engine := range engines {
+		if engineOutcomes.broken(engine) {
+			avoided = append(avoided, engine)
+		} else {
+			kept = append(kept, engine)
+		}
+	}
+	if len(kept) == 0 {
+		return engines, nil
+	}
+	return kept, avoided
+}
+
+// isAutoEngines reports whether engines asks for engines=auto.
+func isAutoEngines(engines []string) bool {
+	return len(engines) == 1 && strings.EqualFold(engines[0], autoEngines)
+}
+
+// applyTo sets the engines of params, and its categories and time range
+// unless the call chose them.
+func (c queryClassification) applyTo(params *searxng.SearchParams) {
+	params.Engines = append([]string(nil), c.Engines...)
+	if params.Categories == nil && c.Categories != nil {
+		params.Categories = append([]string(nil), c.Categories...)
+	}
+	if params.TimeRange == "" {
+		params.TimeRange = c.TimeRange
+	}
+}
+
+// autoClassification returns the classification engines=auto applied to a
+// search, nil when the call named its engines. Bangs select the engines
+// instead.
+func autoClassification(ctx context.Context, arguments map[string]interface{}) *queryClassification {
+	engines, _, _ := listArgument(arguments, "engines")
+	query, _ := arguments["query"].(string)
+	if !isAutoEngines(engines) || len(queryBangs(query)) > 0 {
+		return nil
+	}
+	classification := classifyQuery(ctx, query)
+	return &classification
+}
+
+func searxngClassifyQueryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.GetArguments()["query"].(string)
+	if query = strings.TrimSpace(query); query == "" {
+		return invalidArgumentsResult(errors.New("query must be a non-empty string")), nil
+	}
+	return structuredResult(classifyQuery(ctx, query))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyQuery(t *testing.T) {
+	useFakeInstance(t)
+	useConfig(t, &Config{QueryClasses: []QueryClass{
+		{Name: "go", Patterns: []string{`\bgolang\b`}, Engines: []string{"pkg.go.dev"}},
+	}})
+
+	tests := []struct {
+		query     string
+		class     string
+		timeRange string
+	}{
+		{"TypeError: undefined is not a function stack trace", "code", ""},
+		{"latest news on the election", "news", "week"},
+		{"arxiv paper on diffusion models", "science", ""},
+		{"golang", "go", ""},
+		{"golang panic: runtime error", "code", ""},
+		{"c++ templates", "code", ""},
+		{"c# generics", "code", ""},
+		{"chocolate cake recipe", "general", ""},
+	}
+	for _, tt := range tests {
+		got := classifyQuery(context.Background(), tt.query)
+		if got.Class != tt.class || got.TimeRange != tt.timeRange {
+			t.Errorf("classifyQuery(context.Background(), %q) = %+v, want class %s and time range %q", tt.query, got, tt.class, tt.timeRange)
+		}
+	}
+}
+
+func TestCheckQueryClass(t *testing.T) {
+	for _, class := range []QueryClass{
+		{Patterns: []string{"x"}, Engines: []string{"google"}},
+		{Name: "x", Engines: []string{"google"}},
+		{Name: "x", Patterns: []string{"x"}},
+		{Name: "x", Patterns: []string{"("}, Engines: []string{"google"}},
+		{Name: "x", Patterns: []string{"x"}, Engines: []string{"google"}, TimeRange: "decade"},
+	} {
+		if _, err := checkQueryClass(class); err == nil {
+			t.Errorf("checkQueryClass(%+v) accepted an invalid class", class)
+		}
+	}
+}
+
+func TestSearchAutoEngines(t *testing.T) {
+	useFakeInstance(t)
+	params, err := searchParamsFromArguments(context.Background(), map[string]interface{}{"query": "breaking news today", "engines": "auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params.Categories, []string{"news"}) || params.Engines[0] != "google news" || params.TimeRange != "week" {
+		t.Errorf("params = %+v, want the news class", params)
+	}
+
+	// General queries go to the instance's default engines.
+	params, err = searchParamsFromArguments(context.Background(), map[string]interface{}{"query": "chocolate cake recipe", "engines": "auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params.Categories, []string{"general"}) || params.Engines != nil {
+		t.Errorf("params = %+v, want the general category without engines", params)
+	}
+
+	// The call's categories and time range are kept.
+	params, err = searchParamsFromArguments(context.Background(), map[string]interface{}{"query": "breaking news today", "engines": "auto", "categories": "general", "time_range": "day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params.Categories, []string{"general"}) || params.TimeRange != "day" {
+		t.Errorf("params = %+v, want the given categories and time range", params)
+	}
+
+	// Bangs pick the engines instead.
+	params, err = searchParamsFromArguments(context.Background(), map[string]interface{}{"query": "!wp latest news", "engines": "auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Engines != nil || params.Categories != nil {
+		t.Errorf("params = %+v, want no engines and categories with bangs", params)
+	}
+}
+
+func TestSearchV2AutoEnginesMeta(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetConfig(map[string]interface{}{
+		"engines": []interface{}{
+			map[string]interface{}{"name": "stackoverflow", "enabled": true, "categories": []interface{}{"it"}},
+			map[string]interface{}{"name": "github", "enabled": false, "categories": []interface{}{"it"}},
+			map[string]interface{}{"name": "duckduckgo", "enabled": true, "categories": []interface{}{"general"}},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "python traceback KeyError", "engines": "auto"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if c := response.Meta.QueryClass; c == nil || c.Class != "code" || len(c.Signals) == 0 || !reflect.DeepEqual(c.MissingEngines, []string{"github"}) {
+		t.Errorf("query class = %+v, want code without github", c)
+	}
+	if last, _ := fake.LastRequest("/search"); last.Query.Get("engines") != "stackoverflow,duckduckgo" {
+		t.Errorf("engines = %q", last.Query.Get("engines"))
+	}
+}
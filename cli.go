@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Build information, set with -ldflags "-X main.version=... -X
+// main.commit=... -X main.buildDate=..." (see the Makefile). Builds without
+// them fall back to the VCS stamp of the Go toolchain.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// Subcommands of the binary. The first argument names one; without it, or
+// when the arguments start with a flag, the server is run.
+const (
+	commandServe      = "serve"
+	commandVersion    = "version"
+	commandCheck      = "check"
+	commandCompletion = "completion"
+	commandSearch     = "search"
+)
+
+var commands = []string{commandServe, commandVersion, commandCheck, commandSearch, commandCompletion}
+
+// completionShells are the shells completion scripts are generated for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// splitCommand splits the command line arguments after the program name
+// into the subcommand and its arguments.
+func splitCommand(args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commandServe, args, nil
+	}
+	for _, command := range commands {
+		if args[0] == command {
+			return command, args[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown command %q, want one of %s", args[0], strings.Join(commands, ", "))
+}
+
+// usage prints the subcommands and flags of the binary.
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintf(w, `Usage: %[1]s [command] [flags]
+
+Commands:
+  serve                     Run the MCP server (default)
+  version                   Print the version, commit and build date
+  check                     Validate the flags and config and check the SearXNG instances, exit 1 when one is unusable
+  search QUERY              Run one search as searxng_search_v2 does and print the results; takes -engines,
+                            -categories, -language, -page, -time-range, -safe-search, -max-results and -format
+  completion bash|zsh|fish  Print a shell completion script
+
+Flags:
+`, filepath.Base(flags.Name()))
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+// versionInfo returns the version line of the version command.
+func versionInfo() string {
+	revision, date, modified := commit, buildDate, false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if revision == "" {
+					revision = setting.Value
+				}
+			case "vcs.time":
+				if date == "" {
+					date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision == "" {
+		revision = "unknown"
+	} else if modified && commit == "" {
+		revision += "-dirty"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("go_mcp_server_searxng %s (commit %s, built %s, %s %s/%s)", version, revision, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// runCheck checks each instance as searxng_instance_status does and prints
+// the outcome. It reports whether every instance can serve searches.
+func runCheck(ctx context.Context, w io.Writer, instances []*searxng.Client) bool {
+	ok := true
+	for _, client := range instances {
+		status := checkInstance(ctx, client)
+		usable := status.Reachable && (status.JSONFormat || status.JSONFormatStatus == http.StatusForbidden && client.HTMLFallback)
+		result := "ok"
+		if !usable {
+			result = "FAILED"
+			ok = false
+		}
+		fmt.Fprintf(w, "%s: %s", status.URL, result)
+		if status.Reachable {
+			fmt.Fprintf(w, " (%d ms", status.LatencyMS)
+			if status.Version != "" {
+				fmt.Fprintf(w, ", SearXNG %s", status.Version)
+			}
+			if status.EnabledEngines > 0 {
+				fmt.Fprintf(w, ", %d engines enabled", status.EnabledEngines)
+			}
+			fmt.Fprint(w, ")")
+		}
+		fmt.Fprintln(w)
+		for _, problem := range status.Problems {
+			fmt.Fprintf(w, "  - %s\n", problem)
+		}
+		engines := make([]string, 0, len(status.EngineErrors))
+		for engine := range status.EngineErrors {
+			engines = append(engines, engine)
+		}
+		sort.Strings(engines)
+		for _, engine := range engines {
+			fmt.Fprintf(w, "  - engine %s: %s\n", engine, status.EngineErrors[engine][0])
+		}
+	}
+	return ok
+}
+
+// cliSearch holds the flags of the search command, named after the
+// arguments of searxng_search_v2.
+type cliSearch struct {
+	engines    string
+	categories string
+	language   string
+	timeRange  string
+	format     string
+	page       int
+	maxResults int
+	safeSearch int
+}
+
+// addSearchFlags registers the flags of the search command on flags.
+func addSearchFlags(flags *flag.FlagSet) *cliSearch {
+	s := &cliSearch{}
+	flags.StringVar(&s.engines, "engines", "", "Engines of the search, comma separated")
+	flags.StringVar(&s.categories, "categories", "", "Categories of the search, comma separated")
+	flags.StringVar(&s.language, "language", "", "Language of the search (default -default-language)")
+	flags.StringVar(&s.timeRange, "time-range", "", "Time range of the search: day, week, month or year")
+	flags.StringVar(&s.format, "format", formatMarkdown, "Output format: json, markdown (md) or compact")
+	flags.IntVar(&s.page, "page", 0, "Page of results")
+	flags.IntVar(&s.maxResults, "max-results", 0, "Maximum number of results, 0 for all of the page")
+	flags.IntVar(&s.safeSearch, "safe-search", -1, "Safe search level: 0 off, 1 moderate, 2 strict (default the instance setting)")
+	return s
+}
+
+// arguments returns the searxng_search_v2 arguments of the search.
+func (s *cliSearch) arguments(query string) map[string]interface{} {
+	format := s.format
+	if format == "md" {
+		format = formatMarkdown
+	}
+	arguments := map[string]interface{}{"query": query, "format": format}
+	for name, value := range map[string]string{
+		"engines":    s.engines,
+		"categories": s.categories,
+		"language":   s.language,
+		"time_range": s.timeRange,
+	} {
+		if value != "" {
+			arguments[name] = value
+		}
+	}
+	if s.page > 0 {
+		arguments["page"] = float64(s.page)
+	}
+	if s.maxResults > 0 {
+		arguments["max_results"] = float64(s.maxResults)
+	}
+	if s.safeSearch >= 0 {
+		arguments["safe_search"] = float64(s.safeSearch)
+	}
+	return arguments
+}
+
+// parseInterspersed parses flags that may come after positional arguments,
+// as in search "golang generics" -engines duckduckgo, and returns the
+// positional arguments. Everything after "--" is positional.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// runSearchCommand runs a search through the searxng_search_v2 handler, so
+// the command line takes the code path of the tool, and prints the result.
+func runSearchCommand(ctx context.Context, w io.Writer, arguments map[string]interface{}) error {
+	var request mcp.CallToolRequest
+	request.Params.Name = "searxng_search_v2"
+	request.Params.Arguments = arguments
+	result, err := searxngSearchV2Handler(ctx, request)
+	if err != nil {
+		return err
+	}
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	if e, ok := resultError(result); ok {
+		return errors.New(e.Message)
+	} else if result.IsError {
+		return errors.New(strings.Join(texts, "\n"))
+	}
+	for _, text := range texts {
+		fmt.Fprintln(w, text)
+	}
+	return nil
+}
+
+// completionScript returns the completion script for shell, completing
+// the subcommands and the flags of flags.
+func completionScript(shell string, flags *flag.FlagSet) (string, error) {
+	name := filepath.Base(flags.Name())
+	var names []string
+	flags.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+	commandList := strings.Join(commands, " ")
+
+	var b strings.Builder
+	switch shell {
+	case "bash":
+		function := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+		fmt.Fprintf(&b, "# bash completion for %s\n", name)
+		fmt.Fprintf(&b, "%s() {\n", function)
+		b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+		b.WriteString("\tif [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then\n")
+		fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", commandList)
+		b.WriteString("\telif [[ ${COMP_WORDS[1]} == completion && $COMP_CWORD -eq 2 ]]; then\n")
+		fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(completionShells, " "))
+		b.WriteString("\telif [[ $cur == -* ]]; then\n")
+		fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+		b.WriteString("\telse\n")
+		b.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		b.WriteString("\tfi\n")
+		b.WriteString("}\n")
+		fmt.Fprintf(&b, "complete -F %s %s\n", function, name)
+	case "zsh":
+		fmt.Fprintf(&b, "#compdef %s\n", name)
+		b.WriteString("_arguments \\\n")
+		fmt.Fprintf(&b, "\t'1:command:(%s)' \\\n", commandList)
+		flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&b, "\t'-%s[%s]' \\\n", f.Name, zshEscape(firstSentence(f.Usage)))
+		})
+		b.WriteString("\t'*:file:_files'\n")
+	case "fish":
+		fmt.Fprintf(&b, "# fish completion for %s\n", name)
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -f -a %q\n", name, commandList)
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from completion' -f -a %q\n", name, strings.Join(completionShells, " "))
+		flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&b, "complete -c %s -o %s -d %q\n", name, f.Name, firstSentence(f.Usage))
+		})
+	default:
+		return "", fmt.Errorf("unsupported shell %q, want one of %s", shell, strings.Join(completionShells, ", "))
+	}
+	return b.String(), nil
+}
+
+// firstSentence shortens a flag usage for completion menus.
+func firstSentence(usage string) string {
+	for _, sep := range []string{", ", " ("} {
+		if i := strings.Index(usage, sep); i > 0 {
+			usage = usage[:i]
+		}
+	}
+	return usage
+}
+
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
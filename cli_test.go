@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		args    []string
+		command string
+		rest    []string
+	}{
+		{nil, commandServe, nil},
+		{[]string{"-t", "stdio"}, commandServe, []string{"-t", "stdio"}},
+		{[]string{"serve", "-t", "stdio"}, commandServe, []string{"-t", "stdio"}},
+		{[]string{"check", "-searxng", "http://x"}, commandCheck, []string{"-searxng", "http://x"}},
+		{[]string{"completion", "zsh"}, commandCompletion, []string{"zsh"}},
+	}
+	for _, tt := range tests {
+		command, rest, err := splitCommand(tt.args)
+		if err != nil || command != tt.command || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("splitCommand(%q) = %q, %q, %v", tt.args, command, rest, err)
+		}
+	}
+	if _, _, err := splitCommand([]string{"sevre"}); err == nil {
+		t.Error("want an error for an unknown command")
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	flags := flag.NewFlagSet("/usr/bin/go_mcp_server_searxng", flag.ContinueOnError)
+	flags.String("searxng", "", "SearXNG instance URL")
+	flags.Bool("offline", false, "Serve only cached search responses, never contacting the instance")
+
+	for _, shell := range completionShells {
+		script, err := completionScript(shell, flags)
+		if err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		for _, want := range []string{"go_mcp_server_searxng", "searxng", "offline", "check"} {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s script lacks %q:\n%s", shell, want, script)
+			}
+		}
+		if strings.Contains(script, "/usr/bin") {
+			t.Errorf("%s script uses the program path", shell)
+		}
+	}
+	if _, err := completionScript("tcsh", flags); err == nil {
+		t.Error("want an error for an unsupported shell")
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	fake := useFakeInstance(t)
+	var out strings.Builder
+	if !runCheck(context.Background(), &out, []*searxng.Client{currentState().client}) {
+		t.Errorf("check failed on a healthy instance:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), currentState().client.BaseURL+": ok") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	fake.Close()
+	out.Reset()
+	if runCheck(context.Background(), &out, []*searxng.Client{currentState().client}) {
+		t.Error("check passed on an instance that is down")
+	}
+	if !strings.Contains(out.String(), "FAILED") || !strings.Contains(out.String(), "unreachable") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	search := addSearchFlags(flags)
+	words, err := parseInterspersed(flags, []string{"golang", "generics", "--engines", "duckduckgo", "-format", "md", "--", "-v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"golang", "generics", "-v"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("words = %q, want %q", words, want)
+	}
+	want := map[string]interface{}{"query": "golang generics", "format": formatMarkdown, "engines": "duckduckgo"}
+	if got := search.arguments("golang generics"); !reflect.DeepEqual(got, want) {
+		t.Errorf("arguments = %v, want %v", got, want)
+	}
+}
+
+func TestRunSearchCommand(t *testing.T) {
+	fake := useFakeInstance(t)
+	var out strings.Builder
+	if err := runSearchCommand(context.Background(), &out, map[string]interface{}{"query": "golang", "format": formatCompact}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "https://example.com/first") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	fake.Close()
+	if err := runSearchCommand(context.Background(), &out, map[string]interface{}{"query": "golang"}); err == nil {
+		t.Error("want an error when the instance is down")
+	}
+}
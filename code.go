@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// defaultCodeEngines are the developer engines searched by
+// searxng_code_search unless engines are given.
+var defaultCodeEngines = []string{"github", "gitlab", "stackoverflow", "docker hub"}
+
+// repoHosts are the code hosting sites whose result URLs name a
+// repository as /owner/name.
+var repoHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"codeberg.org":  true,
+	"bitbucket.org": true,
+}
+
+// codeResult is a result of searxng_code_search with the package and
+// repository fields engines provide, empty when they do not.
+type codeResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+	Engine  string `json:"engine"`
+	// Repo is "owner/name" for results on code hosting sites.
+	Repo string `json:"repo,omitempty"`
+	// Stars is the star count of repositories.
+	Stars *int64 `json:"stars,omitempty"`
+	// Popularity is the download or pull count of packages.
+	Popularity    *int64   `json:"popularity,omitempty"`
+	Package       string   `json:"package,omitempty"`
+	Version       string   `json:"version,omitempty"`
+	License       string   `json:"license,omitempty"`
+	LicenseURL    string   `json:"license_url,omitempty"`
+	Homepage      string   `json:"homepage,omitempty"`
+	SourceCodeURL string   `json:"source_code_url,omitempty"`
+	Maintainer    string   `json:"maintainer,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	PublishedDate string   `json:"published_date,omitempty"`
+}
+
+type codeSearchResponse struct {
+	Query               string                       `json:"query"`
+	NumberOfResults     int                          `json:"number_of_results"`
+	Results             []codeResult                 `json:"results"`
+	Suggestions         []string                     `json:"suggestions,omitempty"`
+	UnresponsiveEngines []searxng.UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+func searxngCodeSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, ok := request.GetArguments()["query"].(string)
+	if !ok {
+		return invalidArgumentsResult(errors.New("query must be a string")), nil
+	}
+
+	params := searxng.SearchParams{
+		Query:      query,
+		Categories: []string{"it"},
+		Engines:    append([]string(nil), defaultCodeEngines...),
+		Language:   stateOf(ctx).language,
+	}
+
+	if engines, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.Engines = engines
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	if page, ok, err := intArgument(request.GetArguments(), "page"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.PageNo = page
+	}
+
+	dryRun, err := isDryRun(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	meta := prepareSearch(ctx, &params)
+	if dryRun {
+		return dryRunResult(ctx, params, meta, request.GetArguments(), "engines", "page")
+	}
+
+	result, cachedAt, err := cachedSearch(ctx, "code", params, func() (*searxng.CodeSearchResponse, error) {
+		return activeInstance(ctx).SearchCode(ctx, params)
+	})
+	if err != nil {
+		return upstreamErrorResult("code search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
+	}
+
+	response := codeSearchResponse{
+		Query:               result.Query,
+		NumberOfResults:     result.NumberOfResults,
+		Results:             make([]codeResult, len(result.Results)),
+		Suggestions:         result.Suggestions,
+		UnresponsiveEngines: result.UnresponsiveEngines,
+	}
+	for i, r := range result.Results {
+		response.Results[i] = newCodeResult(r)
+	}
+
+	return structuredResult(response)
+}
+
+func newCodeResult(r searxng.CodeResult) codeResult {
+	result := codeResult{
+		Title:         r.Title,
+		URL:           r.URL,
+		Content:       r.Content,
+		Engine:        r.Engine,
+		Repo:          repoName(r.URL),
+		Package:       r.PackageName,
+		Version:       r.Version,
+		License:       r.LicenseName,
+		LicenseURL:    r.LicenseURL,
+		Homepage:      r.Homepage,
+		SourceCodeURL: r.SourceCodeURL,
+		Maintainer:    r.Maintainer,
+		Tags:          r.Tags,
+		PublishedDate: r.PublishedDate,
+	}
+	if r.Popularity > 0 {
+		count := int64(r.Popularity)
+		if result.Repo != "" {
+			result.Stars = &count
+		} else {
+			result.Popularity = &count
+		}
+	}
+	return result
+}
+
+// repoName returns "owner/name" for repository URLs of code hosting sites.
+func repoName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !repoHosts[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")] {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + strings.TrimSuffix(parts[1], ".git")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeSearch(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "golang/go", "url": "https://github.com/golang/go", "engine": "github", "package_name": "go",
+				"popularity": 120000, "license_name": "BSD-3-Clause"},
+			{"title": "golang", "url": "https://hub.docker.com/_/golang", "engine": "docker hub", "popularity": 1000},
+			{"title": "How do generics work?", "url": "https://stackoverflow.com/questions/1", "engine": "stackoverflow"},
+		},
+	})
+
+	result, err := callTool(t, searxngCodeSearchHandler, map[string]interface{}{"query": "golang"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("categories") != "it" || req.Query.Get("engines") != "github,gitlab,stackoverflow,docker hub" {
+		t.Errorf("request = %v", req.Query)
+	}
+
+	var response codeSearchResponse
+	decodeResult(t, result, &response)
+	repo, image, question := response.Results[0], response.Results[1], response.Results[2]
+	if repo.Repo != "golang/go" || repo.Stars == nil || *repo.Stars != 120000 || repo.Popularity != nil || repo.License != "BSD-3-Clause" {
+		t.Errorf("repository = %+v", repo)
+	}
+	if image.Repo != "" || image.Stars != nil || image.Popularity == nil || *image.Popularity != 1000 {
+		t.Errorf("image = %+v", image)
+	}
+	if !reflect.DeepEqual(question, codeResult{Title: "How do generics work?", URL: "https://stackoverflow.com/questions/1", Engine: "stackoverflow"}) {
+		t.Errorf("question = %+v", question)
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://github.com/golang/go":                "golang/go",
+		"https://github.com/golang/go/issues/1":       "golang/go",
+		"https://gitlab.com/group/project.git":        "group/project",
+		"https://www.github.com/owner/repo":           "owner/repo",
+		"https://github.com/golang":                   "",
+		"https://stackoverflow.com/questions/1/title": "",
+	} {
+		if got := repoName(rawURL); got != want {
+			t.Errorf("repoName(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
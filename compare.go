@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	defaultCompareSources = 3
+	maxCompareSources     = 5
+	maxCompareItems       = 6
+	maxCompareAttributes  = 30
+)
+
+// compareCell is one value of the comparison matrix together with the page
+// it was taken from.
+type compareCell struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+type compareRow struct {
+	Attribute string                 `json:"attribute"`
+	Values    map[string]compareCell `json:"values"`
+}
+
+type compareResponse struct {
+	Items      []string            `json:"items"`
+	Attributes []string            `json:"attributes"`
+	Matrix     []compareRow        `json:"matrix"`
+	Sources    map[string][]string `json:"sources"`
+	Errors     map[string]string   `json:"errors,omitempty"`
+}
+
+func searxngCompareHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	items, _, err := listArgument(request.GetArguments(), "items")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if len(items) < 2 {
+		return invalidArgumentsResult(errors.New("at least two items are required")), nil
+	}
+	if len(items) > maxCompareItems {
+		return invalidArgumentsResult(fmt.Errorf("at most %d items can be compared", maxCompareItems)), nil
+	}
+
+	aspect := "specifications"
+	if a, ok := request.GetArguments()["aspect"].(string); ok && a != "" {
+		aspect = a
+	}
+
+	sources := defaultCompareSources
+	if n, ok, err := intArgument(request.GetArguments(), "sources"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > 0 {
+		sources = min(n, maxCompareSources)
+	}
+
+	attributes, _, err := listArgument(request.GetArguments(), "attributes")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	var wanted []string
+	for _, attr := range attributes {
+		if attr = normalizeAttribute(attr); attr != "" {
+			wanted = append(wanted, attr)
+		}
+	}
+
+	response := compareResponse{
+		Items:   items,
+		Sources: make(map[string][]string),
+		Errors:  make(map[string]string),
+	}
+	// values[item][attribute]
+	values := make(map[string]map[string]compareCell)
+
+	progress := newProgressReporter(ctx, request, len(items)*(1+sources))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		go func(item string) {
+			defer wg.Done()
+			cells, urls, err := collectItemAttributes(ctx, item, aspect, sources, progress)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				response.Errors[item] = describeUpstreamError(err)
+			}
+			response.Sources[item] = urls
+			values[item] = cells
+		}(item)
+	}
+	wg.Wait()
+
+	response.Attributes = rankAttributes(values, wanted)
+	for _, attr := range response.Attributes {
+		row := compareRow{Attribute: attr, Values: make(map[string]compareCell)}
+		for _, item := range items {
+			if cell, ok := values[item][attr]; ok {
+				row.Values[item] = cell
+			}
+		}
+		response.Matrix = append(response.Matrix, row)
+	}
+
+	return structuredResult(response)
+}
+
+// collectItemAttributes searches for the item and extracts attribute/value
+// pairs from its top result pages. Earlier (better ranked) pages win when
+// several pages define the same attribute. progress expects 1+sources
+// steps.
+func collectItemAttributes(ctx context.Context, item, aspect string, sources int, progress *progressReporter) (map[string]compareCell, []string, error) {
+	params := searxng.SearchParams{
+		Query:      item + " " + aspect,
+		Categories: []string{"general"},
+		Language:   stateOf(ctx).language,
+	}
+	prepareSearch(ctx, &params)
+	result, _, err := cachedSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	progress.step(fmt.Sprintf("Searched %q", params.Query))
+	if err != nil {
+		progress.addTotal(-sources)
+		return nil, nil, fmt.Errorf("search error: %w", err)
+	}
+
+	var urls []string
+	for _, r := range result.Results {
+		if len(urls) == sources {
+			break
+		}
+		if r.URL != "" {
+			urls = append(urls, r.URL)
+		}
+	}
+
+	progress.addTotal(len(urls) - sources)
+
+	pages := make([]*Page, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			defer progress.step("Read " + u)
+			if page, err := fetchPage(ctx, u); err == nil {
+				pages[i] = page
+			}
+		}(i, u)
+	}
+	wg.Wait()
+
+	cells := make(map[string]compareCell)
+	for _, page := range pages {
+		if page == nil {
+			continue
+		}
+		for _, pair := range extractAttributes(page) {
+			if _, seen := cells[pair[0]]; !seen {
+				cells[pair[0]] = compareCell{Value: pair[1], Source: page.URL}
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return cells, urls, errors.New("no attributes found in fetched sources")
+	}
+	return cells, urls, nil
+}
+
+var labelValueRe = regexp.MustCompile(`^([^:]{2,40}):\s+(.{1,120})$`)
+
+// extractAttributes returns normalized attribute/value pairs from spec
+// tables, definition lists and "Label: value" lines of a page.
+func extractAttributes(page *Page) [][2]string {
+	var pairs [][2]string
+	add := func(label, value string) {
+		label = normalizeAttribute(label)
+		value = collapse(value)
+		if label == "" || value == "" || len(value) > 120 || len(strings.Fields(label)) > 5 {
+			return
+		}
+		pairs = append(pairs, [2]string{label, value})
+	}
+	for _, pair := range page.Pairs {
+		add(pair[0], pair[1])
+	}
+	for _, block := range page.Blocks {
+		if m := labelValueRe.FindStringSubmatch(block); m != nil {
+			add(m[1], m[2])
+		}
+	}
+	return pairs
+}
+
+func normalizeAttribute(label string) string {
+	label = strings.ToLower(collapse(label))
+	label = strings.TrimRight(label, ":")
+	if len(label) < 2 || len(label) > 40 {
+		return ""
+	}
+	return label
+}
+
+// rankAttributes orders attributes by how many items define them, so the
+// most comparable rows come first. When wanted is set only those
+// attributes are returned, in the requested order.
+func rankAttributes(values map[string]map[string]compareCell, wanted []string) []string {
+	if len(wanted) > 0 {
+		return wanted
+	}
+
+	coverage := make(map[string]int)
+	for _, cells := range values {
+		for attr := range cells {
+			coverage[attr]++
+		}
+	}
+
+	var attributes []string
+	for attr, count := range coverage {
+		if count > 1 || len(values) == 1 {
+			attributes = append(attributes, attr)
+		}
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		if coverage[attributes[i]] != coverage[attributes[j]] {
+			return coverage[attributes[i]] > coverage[attributes[j]]
+		}
+		return attributes[i] < attributes[j]
+	})
+	if len(attributes) > maxCompareAttributes {
+		attributes = attributes[:maxCompareAttributes]
+	}
+	return attributes
+}
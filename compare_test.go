@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractAttributes(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head><title>Phone X</title></head><body>
+<nav>Home: menu</nav>
+<p>Weight: 180 g</p>
+<p>This long sentence clearly has a colon: so it is prose, not an attribute</p>
+<table><tr><th>Battery</th><td>5000 mAh</td></tr><tr><td>a</td><td>b</td><td>c</td></tr></table>
+<dl><dt>Screen size</dt><dd>6.1"</dd></dl>
+</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &Page{URL: "https://example.com"}
+	extractPage(doc, page)
+
+	if page.Title != "Phone X" {
+		t.Errorf("title = %q", page.Title)
+	}
+	want := [][2]string{
+		{"battery", "5000 mAh"},
+		{"screen size", `6.1"`},
+		{"weight", "180 g"},
+	}
+	if got := extractAttributes(page); !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes = %q, want %q", got, want)
+	}
+}
+
+func TestRankAttributes(t *testing.T) {
+	values := map[string]map[string]compareCell{
+		"a": {"weight": {}, "battery": {}, "color": {}},
+		"b": {"weight": {}, "battery": {}},
+		"c": {"weight": {}},
+	}
+	want := []string{"weight", "battery"}
+	if got := rankAttributes(values, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("rankAttributes = %q, want %q", got, want)
+	}
+	if got := rankAttributes(values, []string{"color"}); !reflect.DeepEqual(got, []string{"color"}) {
+		t.Errorf("wanted attributes = %q", got)
+	}
+}
+
+func TestCollectItemAttributesLanguage(t *testing.T) {
+	fake := useFakeInstance(t)
+	useState(t, func(s *serverState) { s.language = "de" })
+	fake.SetSearchResponse(map[string]interface{}{"results": []interface{}{}})
+
+	collectItemAttributes(context.Background(), "Phone X", "battery", 0, nil)
+	if req, _ := fake.LastRequest("/search"); req.Query.Get("language") != "de" {
+		t.Errorf("language = %q, want the default language de", req.Query.Get("language"))
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Config holds the settings that are too structured for flags. It is read
+// from the JSON file given with -config.
+type Config struct {
+	// Instances are the URLs of the SearXNG instances, the first one
+	// searched and the others its fallbacks in order, used unless
+	// -searxng or -searxng-fallback is given.
+	Instances []string `json:"instances,omitempty"`
+	// SyntheticEngines are pseudo-engines selectable by name in the
+	// engines argument of search tools.
+	SyntheticEngines map[string]SyntheticEngine `json:"synthetic_engines"`
+	// BlockedImageDomains are domains whose images are removed from image
+	// search results, subdomains included.
+	BlockedImageDomains []string `json:"blocked_image_domains"`
+	// Profiles are engine profiles selectable with the profile argument
+	// of search tools.
+	Profiles map[string]EngineProfile `json:"profiles"`
+	// EnginePolicies replace engines on a schedule or once their quota is
+	// used up.
+	EnginePolicies []EnginePolicy `json:"engine_policies"`
+	// DefaultLanguage is the language of searches not given one, as
+	// -default-language.
+	DefaultLanguage string `json:"default_language,omitempty"`
+	// Canary is a secondary instance receiving a copy of some searches.
+	Canary *CanaryConfig `json:"canary,omitempty"`
+	// Deny forbids searching categories and engines.
+	Deny *DenyConfig `json:"deny,omitempty"`
+	// RedactPatterns are regular expressions masked in queries, next to
+	// the builtin ones, with -log-redact-queries.
+	RedactPatterns []string `json:"redact_patterns,omitempty"`
+	// Fetch restricts the URLs and content the fetching tools read.
+	Fetch *FetchConfig `json:"fetch,omitempty"`
+	// QueryClasses map queries to engines with engines=auto, before the
+	// builtin classes.
+	QueryClasses []QueryClass `json:"query_classes,omitempty"`
+	// ResultPipeline post-processes the results of general searches, in
+	// order.
+	ResultPipeline []ProcessorConfig `json:"result_pipeline,omitempty"`
+	// RewriteRules rewrite queries before they are searched, in order.
+	RewriteRules []RewriteRule `json:"rewrite_rules,omitempty"`
+}
+
+// SyntheticEngine expands into query operators and a set of real engines.
+// In the config file it is either an object or just the query string:
+//
+//	"company_docs": "site:docs.mycompany.com"
+//	"py_docs": {"query": "site:docs.python.org", "engines": ["duckduckgo"]}
+type SyntheticEngine struct {
+	// Query is appended to the search query, e.g. "site:docs.example.com".
+	Query string `json:"query"`
+	// Engines are the real engines used; empty means the instance defaults.
+	Engines []string `json:"engines,omitempty"`
+}
+
+func (e *SyntheticEngine) UnmarshalJSON(data []byte) error {
+	var query string
+	if err := json.Unmarshal(data, &query); err == nil {
+		*e = SyntheticEngine{Query: query}
+		return nil
+	}
+	type plain SyntheticEngine
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %w", path, err)
+	}
+
+	for i, instanceURL := range cfg.Instances {
+		if !isHTTPURL(instanceURL) {
+			return nil, fmt.Errorf("instance %q must be an http or https URL", instanceURL)
+		}
+		if slices.Contains(cfg.Instances[:i], instanceURL) {
+			return nil, fmt.Errorf("instance %q is listed twice", instanceURL)
+		}
+	}
+
+	for name, engine := range cfg.SyntheticEngines {
+		if engine.Query == "" && len(engine.Engines) == 0 {
+			return nil, fmt.Errorf("synthetic engine %q needs a query or engines", name)
+		}
+	}
+
+	for name, profile := range cfg.Profiles {
+		if len(profile.Categories) == 0 && len(profile.Engines) == 0 && profile.Language == "" {
+			return nil, fmt.Errorf("profile %q is empty", name)
+		}
+	}
+
+	for _, class := range cfg.QueryClasses {
+		if _, err := checkQueryClass(class); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := newResultPipeline(cfg.ResultPipeline); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range cfg.RewriteRules {
+		if _, err := checkRewriteRule(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	if c := cfg.Canary; c != nil {
+		if c.URL == "" {
+			return nil, fmt.Errorf("canary needs a url")
+		}
+		if c.Percent < 0 || c.Percent > 100 {
+			return nil, fmt.Errorf("canary percent must be between 0 and 100, got %g", c.Percent)
+		}
+		if c.SearchMethod != "" && c.SearchMethod != "get" && c.SearchMethod != "post" {
+			return nil, fmt.Errorf("canary search_method must be get or post, got %q", c.SearchMethod)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// imageBlocklist holds the blocked_image_domains, subdomains included. As
+// a ResultProcessor it drops the image results of general searches on
+// them.
+type imageBlocklist siteFilter
+
+func newImageBlocklist(domains []string) imageBlocklist {
+	trimmed := make([]string, len(domains))
+	for i, domain := range domains {
+		trimmed[i] = strings.TrimPrefix(strings.TrimSpace(domain), ".")
+	}
+	return imageBlocklist(domainFilter(trimmed))
+}
+
+// blocks reports whether rawURL is on a blocked domain.
+func (b imageBlocklist) blocks(rawURL string) bool {
+	return len(b) > 0 && siteFilter(b).allows(rawURL)
+}
+
+func (b imageBlocklist) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	kept := make([]searxng.SearchResult, 0, len(results))
+	for _, r := range results {
+		if r.Category != "images" || !b.blocks(r.URL) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// processImages drops the image results whose page or image is on a
+// blocked domain.
+func (b imageBlocklist) processImages(results []searxng.ImageResult) []searxng.ImageResult {
+	kept := make([]searxng.ImageResult, 0, len(results))
+	for _, image := range results {
+		if !b.blocks(image.URL) && !b.blocks(image.ImgSrc) {
+			kept = append(kept, image)
+		}
+	}
+	return kept
+}
+
+// syntheticEngineNames returns the configured synthetic engine names sorted.
+func (c *Config) syntheticEngineNames() []string {
+	names := make([]string, 0, len(c.SyntheticEngines))
+	for name := range c.SyntheticEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// convertEngines are searched by searxng_convert and searxng_calculate:
+// the currency engine answers currency conversions, and the unit converter
+// and calculator plugins of the instance answer whatever the engines.
+var convertEngines = []string{"currency"}
+
+// parsedAnswer is an instant answer reduced to its value.
+type parsedAnswer struct {
+	Query string `json:"query"`
+	// Value is the number of the answer, Unit what follows it, e.g.
+	// "Euro (EUR)" or "mi".
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	Answer string  `json:"answer"`
+	// Source is the page or engine the answer came from.
+	Source string `json:"source,omitempty"`
+}
+
+// answerValuePattern matches the number of an answer, e.g. "92.31 Euro
+// (EUR)" or "1,234.5".
+var answerValuePattern = regexp.MustCompile(`^([-+]?\d[\d,]*(?:\.\d+)?(?:[eE][-+]?\d+)?)\s*(.*)$`)
+
+// parseAnswer reads the value of an answer such as "100 US Dollar (USD) =
+// 92.31 Euro (EUR)", "2+2 = 4" or "0.621 mi": the part after the last "="
+// is the result.
+func parseAnswer(answer searxng.Answer) (parsedAnswer, bool) {
+	text := collapse(answer.Answer)
+	result := text
+	if i := strings.LastIndex(text, "="); i >= 0 {
+		result = strings.TrimSpace(text[i+1:])
+	}
+	m := answerValuePattern.FindStringSubmatch(result)
+	if m == nil {
+		return parsedAnswer{}, false
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil {
+		return parsedAnswer{}, false
+	}
+	parsed := parsedAnswer{Value: value, Unit: strings.TrimSpace(m[2]), Answer: text, Source: answer.URL}
+	if parsed.Source == "" {
+		parsed.Source = answer.Engine
+	}
+	return parsed, true
+}
+
+// answerSearch runs query on engines and returns the first answer with a
+// value. noAnswer explains what to check when there is none.
+func answerSearch(ctx context.Context, query string, engines []string, noAnswer string) (*mcp.CallToolResult, error) {
+	params := searxng.SearchParams{
+		Query:    query,
+		Engines:  engines,
+		Language: stateOf(ctx).language,
+	}
+	prepareSearch(ctx, &params)
+	result, cachedAt, err := cachedSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	if err != nil {
+		return upstreamErrorResult("answer search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
+	}
+
+	for _, answer := range result.Answers {
+		if parsed, ok := parseAnswer(answer); ok {
+			parsed.Query = query
+			return structuredResult(parsed)
+		}
+	}
+	return engineErrorResult(fmt.Sprintf("No answer to %q: %s", query, noAnswer)), nil
+}
+
+func searxngConvertHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	from, _ := arguments["from"].(string)
+	to, _ := arguments["to"].(string)
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if from == "" || to == "" {
+		return invalidArgumentsResult(errors.New("from and to must name currencies or units, e.g. usd and eur")), nil
+	}
+	amount, ok, err := floatArgument(arguments, "amount")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if !ok {
+		amount = 1
+	}
+
+	query := fmt.Sprintf("%s %s to %s", strconv.FormatFloat(amount, 'f', -1, 64), from, to)
+	return answerSearch(ctx, query, convertEngines,
+		"use ISO currency codes (usd, eur) or common unit symbols (km, mi, kg, lb); the instance needs the currency engine and the unit converter plugin enabled")
+}
+
+func searxngCalculateHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	expression, _ := request.GetArguments()["expression"].(string)
+	if expression = strings.TrimSpace(expression); expression == "" {
+		return invalidArgumentsResult(errors.New("expression must be an arithmetic expression, e.g. (12.5 * 4) / 3")), nil
+	}
+	return answerSearch(ctx, expression, convertEngines,
+		"the instance needs the calculator plugin enabled, and the expression may only use numbers, operators and parentheses")
+}
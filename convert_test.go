@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestParseAnswer(t *testing.T) {
+	tests := []struct {
+		answer    string
+		wantValue float64
+		wantUnit  string
+		wantOK    bool
+	}{
+		{"100 US Dollar (USD) = 92.31 Euro (EUR)", 92.31, "Euro (EUR)", true},
+		{"2+2 = 4", 4, "", true},
+		{"0.621371 mi", 0.621371, "mi", true},
+		{"1 BTC = 61,234.5 USD", 61234.5, "USD", true},
+		{"(12.5 * 4) / 3 = 16.666666666666668", 16.666666666666668, "", true},
+		{"-3 = -3", -3, "", true},
+		{"Berlin is the capital of Germany", 0, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseAnswer(searxng.Answer{Answer: tt.answer, Engine: "currency"})
+		if ok != tt.wantOK {
+			t.Errorf("parseAnswer(%q) ok = %v, want %v", tt.answer, ok, tt.wantOK)
+			continue
+		}
+		if ok && (got.Value != tt.wantValue || got.Unit != tt.wantUnit || got.Source != "currency") {
+			t.Errorf("parseAnswer(%q) = %+v, want %v %q", tt.answer, got, tt.wantValue, tt.wantUnit)
+		}
+	}
+}
+
+func TestConvertTool(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"answers": []interface{}{
+			map[string]interface{}{"answer": "250 US Dollar (USD) = 230.75 Euro (EUR)", "url": "https://duckduckgo.com/?q=250+usd+to+eur", "engine": "currency"},
+		},
+	})
+
+	result, err := callTool(t, searxngConvertHandler, map[string]interface{}{"amount": 250.0, "from": "usd", "to": "eur"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("q") != "250 usd to eur" {
+		t.Errorf("q = %q", req.Query.Get("q"))
+	}
+
+	var response parsedAnswer
+	decodeResult(t, result, &response)
+	if response.Value != 230.75 || response.Unit != "Euro (EUR)" || response.Query != "250 usd to eur" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestConvertToolNoAnswer(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{"results": []interface{}{}})
+
+	result, err := callTool(t, searxngCalculateHandler, map[string]interface{}{"expression": "2+2"})
+	if err != nil || !result.IsError {
+		t.Fatalf("handler: %+v, %v, want a tool error", result, err)
+	}
+
+	result, err = callTool(t, searxngConvertHandler, map[string]interface{}{"from": "usd"})
+	if err != nil || !result.IsError {
+		t.Fatalf("handler without to: %+v, %v, want a tool error", result, err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const instanceStatusTTL = 30 * time.Second
+
+// dashboardAuth holds the "user:password" pair protecting /dashboard.
+var dashboardAuth string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="10">
+<title>SearXNG MCP Server</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f0f0f0; }
+.bad { color: #b00; }
+.ok { color: #070; }
+</style>
+</head>
+<body>
+<h1>SearXNG MCP Server</h1>
+<p>Up since {{.Started.Format "2006-01-02 15:04:05"}} ({{.Uptime}})</p>
+
+<h2>Instance</h2>
+<table>
+<tr><th>URL</th><td>{{.Instance.URL}}</td></tr>
+<tr><th>Reachable</th><td class="{{if .Instance.Reachable}}ok{{else}}bad{{end}}">{{.Instance.Reachable}}</td></tr>
+<tr><th>Latency</th><td>{{.Instance.LatencyMS}} ms</td></tr>
+<tr><th>JSON format</th><td class="{{if .Instance.JSONFormat}}ok{{else}}bad{{end}}">{{.Instance.JSONFormat}}</td></tr>
+<tr><th>Version</th><td>{{.Instance.Version}}</td></tr>
+<tr><th>Enabled engines</th><td>{{.Instance.EnabledEngines}}</td></tr>
+{{range .Instance.SuspendedEngines}}<tr><th>Suspended</th><td class="bad">{{.Engine}}: {{.Reason}}, avoided until {{.Until.Format "15:04:05"}}</td></tr>{{end}}
+{{range .Instance.Problems}}<tr><th>Problem</th><td class="bad">{{.}}</td></tr>{{end}}
+</table>
+
+<h2>Cache</h2>
+<p>{{.Cache}}</p>
+<p>{{.PageCache}}</p>
+
+<h2>Engine policies</h2>
+<p>{{.Policies}}</p>
+
+<h2>Circuit breakers</h2>
+<p>{{.Circuits}}</p>
+
+<h2>Upstream connections</h2>
+<p>{{.Conns}}</p>
+
+<h2>Canary</h2>
+<p>{{.Canary}}</p>
+
+<h2>Tools</h2>
+<table>
+<tr><th>Tool</th><th>Calls</th><th>Errors</th><th>Avg duration</th><th>Avg response</th><th>Largest response</th><th>Truncated</th><th>Last call</th><th>Last error</th></tr>
+{{range .Tools}}<tr><td>{{.Name}}</td><td>{{.Calls}}</td><td>{{.Errors}}</td><td>{{.AvgDuration}}</td><td>{{.AvgResponseBytes}} B</td><td>{{.MaxResponseBytes}} B</td><td>{{.Truncated}}</td><td>{{.LastCall.Format "15:04:05"}}</td><td class="bad">{{.LastError}}</td></tr>
+{{else}}<tr><td colspan="9">No calls yet</td></tr>{{end}}
+</table>
+
+<h2>Recent searches</h2>
+<table>
+<tr><th>Time</th><th>Tool</th><th>Query</th><th>Duration</th><th>Error</th></tr>
+{{range .Recent}}<tr><td>{{.Time.Format "15:04:05"}}</td><td>{{.Tool}}</td><td>{{if $.Private}}<i>hidden</i>{{else}}{{.Query}}{{end}}</td><td>{{.Duration}}</td><td class="bad">{{.Error}}</td></tr>
+{{else}}<tr><td colspan="5">No searches yet</td></tr>{{end}}
+</table>
+</body>
+</html>
+`))
+
+type dashboardTool struct {
+	Name string
+	toolStats
+}
+
+type dashboardData struct {
+	Started   time.Time
+	Uptime    time.Duration
+	Instance  *instanceStatus
+	Cache     string
+	PageCache string
+	Canary    string
+	Policies  string
+	Circuits  string
+	Conns     string
+	Tools     []dashboardTool
+	Recent    []recentSearch
+	Private   bool
+}
+
+// cachedStatus keeps the last instance check so reloading the dashboard
+// does not hammer the instance.
+var cachedStatus struct {
+	sync.Mutex
+	status  *instanceStatus
+	checked time.Time
+}
+
+func currentInstanceStatus(ctx context.Context) *instanceStatus {
+	cachedStatus.Lock()
+	defer cachedStatus.Unlock()
+
+	if cachedStatus.status == nil || time.Since(cachedStatus.checked) > instanceStatusTTL {
+		cachedStatus.status = checkInstance(ctx, stateOf(ctx).client)
+		cachedStatus.checked = time.Now()
+	}
+	return cachedStatus.status
+}
+
+func dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if !checkBasicAuth(r, dashboardAuth) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="dashboard"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	names, stats := metrics.snapshot()
+	data := dashboardData{
+		Started:   metrics.started,
+		Uptime:    time.Since(metrics.started).Round(time.Second),
+		Instance:  currentInstanceStatus(r.Context()),
+		Cache:     searchCache.describe(),
+		PageCache: pageCache.describe(),
+		Canary:    canary.describe(),
+		Policies:  currentState().policies.describe(),
+		Circuits:  describeCircuits(),
+		Conns:     describeConnections(),
+		Recent:    recentSearches.list(),
+		Private:   recentSearches.private,
+	}
+	for _, name := range names {
+		data.Tools = append(data.Tools, dashboardTool{Name: name, toolStats: stats[name]})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, data); err != nil {
+		log.Printf("Dashboard error: %v", err)
+	}
+}
+
+// checkBasicAuth compares the request credentials with a "user:password"
+// pair in constant time.
+func checkBasicAuth(r *http.Request, credentials string) bool {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(user+":"+password), []byte(credentials)) == 1
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// publishedDateLayouts are the publishedDate formats SearXNG engines emit,
+// tried in order.
+var publishedDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+}
+
+// parsePublishedDate parses a publishedDate value; dates without a zone are
+// taken as UTC.
+func parsePublishedDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range publishedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// publishedRange filters results by publishedDate: After is inclusive,
+// Before exclusive. Zero bounds are open.
+type publishedRange struct {
+	After  time.Time
+	Before time.Time
+}
+
+func dateRangeOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("published_after",
+			mcp.Description("Only return results published on or after this date (YYYY-MM-DD or RFC 3339). Results without a published date are dropped"),
+		),
+		mcp.WithString("published_before",
+			mcp.Description("Only return results published before this date (YYYY-MM-DD or RFC 3339). Results without a published date are dropped"),
+		),
+	}
+}
+
+func publishedRangeFromArguments(arguments map[string]interface{}) (publishedRange, error) {
+	var r publishedRange
+	for name, bound := range map[string]*time.Time{"published_after": &r.After, "published_before": &r.Before} {
+		value, _ := arguments[name].(string)
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, value); err != nil {
+				return publishedRange{}, fmt.Errorf("%s must be a date like 2024-06-01 or an RFC 3339 time, got %q", name, value)
+			}
+		}
+		*bound = t
+	}
+	if !r.After.IsZero() && !r.Before.IsZero() && !r.After.Before(r.Before) {
+		return publishedRange{}, fmt.Errorf("published_after must be earlier than published_before")
+	}
+	return r, nil
+}
+
+func (r publishedRange) isSet() bool {
+	return !r.After.IsZero() || !r.Before.IsZero()
+}
+
+// narrowTimeRange sets the narrowest SearXNG time_range covering the range,
+// so the instance drops old results before they are filtered here. An
+// explicit time_range is kept.
+func (r publishedRange) narrowTimeRange(params *searxng.SearchParams) {
+	if params.TimeRange != "" || r.After.IsZero() {
+		return
+	}
+	age := time.Since(r.After)
+	for _, tr := range []struct {
+		name string
+		span time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"year", 365 * 24 * time.Hour},
+	} {
+		if age <= tr.span {
+			params.TimeRange = tr.name
+			return
+		}
+	}
+}
+
+// filter returns the results published within the range, the number
+// removed and how many of those had no readable date.
+func (r publishedRange) filter(results []searxng.SearchResult) ([]searxng.SearchResult, int, int) {
+	if !r.isSet() {
+		return results, 0, 0
+	}
+	kept := make([]searxng.SearchResult, 0, len(results))
+	undated := 0
+	for _, result := range results {
+		published, ok := parsePublishedDate(result.PublishedDate)
+		switch {
+		case !ok:
+			undated++
+		case !r.After.IsZero() && published.Before(r.After):
+		case !r.Before.IsZero() && !published.Before(r.Before):
+		default:
+			kept = append(kept, result)
+		}
+	}
+	return kept, len(results) - len(kept), undated
+}
+
+// Process keeps the results published within the range.
+func (r publishedRange) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	kept, _, _ := r.filter(results)
+	return kept
+}
+
+// applyTo filters the results of a search and records the filter in meta.
+func (r publishedRange) applyTo(result *searxng.SearchResponse, meta *searchMeta) {
+	if !r.isSet() {
+		return
+	}
+	if !r.After.IsZero() {
+		meta.PublishedAfter = r.After.Format(time.RFC3339)
+	}
+	if !r.Before.IsZero() {
+		meta.PublishedBefore = r.Before.Format(time.RFC3339)
+	}
+	result.Results, meta.DateFilteredResults, meta.UndatedResults = r.filter(result.Results)
+	meta.ReturnedResults = len(result.Results)
+}
+
+// parseMaxAge reads a max_age argument: a Go duration such as 6h or 90m,
+// or a number of days or weeks such as 3d or 2w.
+func parseMaxAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(value); n > 1 && unit[value[n-1]] > 0 {
+		if count, err := strconv.Atoi(value[:n-1]); err == nil && count > 0 {
+			return time.Duration(count) * unit[value[n-1]], nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("max_age must be a positive duration like 6h, 3d or 2w, got %q", value)
+}
+
+// formatAge describes how long before now published is, the way people
+// do: "3 hours ago", "2 days ago".
+func formatAge(published, now time.Time) string {
+	age := now.Sub(published)
+	days := int(age.Hours() / 24)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return agoString(int(age.Minutes()), "minute")
+	case age < 24*time.Hour:
+		return agoString(int(age.Hours()), "hour")
+	case days < 14:
+		return agoString(days, "day")
+	case days < 60:
+		return agoString(days/7, "week")
+	case days < 365:
+		return agoString(days/30, "month")
+	default:
+		return agoString(days/365, "year")
+	}
+}
+
+func agoString(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit + " ago"
+	}
+	return strconv.Itoa(n) + " " + unit + "s ago"
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestParsePublishedDate(t *testing.T) {
+	want := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	for _, value := range []string{
+		"2024-06-01T12:30:00",
+		"2024-06-01T12:30:00Z",
+		"2024-06-01T14:30:00+02:00",
+		"2024-06-01 12:30:00",
+		"2024-06-01 12:30:00.000000",
+		"Sat, 01 Jun 2024 12:30:00 +0000",
+		"Sat, 01 Jun 2024 12:30:00 GMT",
+	} {
+		got, ok := parsePublishedDate(value)
+		if !ok || !got.Equal(want) {
+			t.Errorf("parsePublishedDate(%q) = %v, %v", value, got, ok)
+		}
+	}
+	if got, ok := parsePublishedDate("Jun 1, 2024"); !ok || !got.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parsePublishedDate(Jun 1, 2024) = %v, %v", got, ok)
+	}
+	for _, value := range []string{"", "yesterday", "null"} {
+		if _, ok := parsePublishedDate(value); ok {
+			t.Errorf("parsePublishedDate(%q) succeeded", value)
+		}
+	}
+}
+
+func TestSearchV2PublishedRange(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "May", "url": "https://a.example/may", "publishedDate": "2024-05-31T23:59:59"},
+			{"title": "June", "url": "https://a.example/june", "publishedDate": "2024-06-01T00:00:00"},
+			{"title": "Mid June", "url": "https://a.example/mid", "publishedDate": "2024-06-15 08:00:00"},
+			{"title": "July", "url": "https://a.example/july", "publishedDate": "2024-07-01T00:00:00"},
+			{"title": "Undated", "url": "https://a.example/undated"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{
+		"query":            "news",
+		"published_after":  "2024-06-01",
+		"published_before": "2024-07-01",
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	var titles []string
+	for _, r := range response.Results {
+		titles = append(titles, r.Title)
+	}
+	if len(titles) != 2 || titles[0] != "June" || titles[1] != "Mid June" {
+		t.Errorf("results = %v, want June and Mid June", titles)
+	}
+	if response.Meta.DateFilteredResults != 3 || response.Meta.UndatedResults != 1 || response.Meta.PublishedAfter != "2024-06-01T00:00:00Z" {
+		t.Errorf("meta = %+v", response.Meta)
+	}
+
+	for _, arguments := range []map[string]interface{}{
+		{"query": "news", "published_after": "June"},
+		{"query": "news", "published_after": "2024-07-01", "published_before": "2024-06-01"},
+	} {
+		if result, _ := callTool(t, searxngSearchV2Handler, arguments); !result.IsError {
+			t.Errorf("want an error result for %v", arguments)
+		}
+	}
+}
+
+func TestNarrowTimeRange(t *testing.T) {
+	tests := []struct {
+		after time.Time
+		given string
+		want  string
+	}{
+		{time.Now().Add(-time.Hour), "", "day"},
+		{time.Now().Add(-3 * 24 * time.Hour), "", "week"},
+		{time.Now().Add(-20 * 24 * time.Hour), "", "month"},
+		{time.Now().Add(-200 * 24 * time.Hour), "", "year"},
+		{time.Now().Add(-800 * 24 * time.Hour), "", ""},
+		{time.Now().Add(-time.Hour), "year", "year"},
+	}
+	for _, tt := range tests {
+		params := searxng.SearchParams{TimeRange: tt.given}
+		publishedRange{After: tt.after}.narrowTimeRange(&params)
+		if params.TimeRange != tt.want {
+			t.Errorf("after %v with %q: time_range = %q, want %q", tt.after, tt.given, params.TimeRange, tt.want)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		age  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{3 * time.Hour, "3 hours ago"},
+		{26 * time.Hour, "1 day ago"},
+		{20 * 24 * time.Hour, "2 weeks ago"},
+		{100 * 24 * time.Hour, "3 months ago"},
+		{800 * 24 * time.Hour, "2 years ago"},
+	} {
+		if got := formatAge(now.Add(-tt.age), now); got != tt.want {
+			t.Errorf("formatAge(%v) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	for value, want := range map[string]time.Duration{"6h": 6 * time.Hour, "90m": 90 * time.Minute, "3d": 72 * time.Hour, "2W": 14 * 24 * time.Hour} {
+		if got, err := parseMaxAge(value); err != nil || got != want {
+			t.Errorf("parseMaxAge(%q) = %v, %v", value, got, err)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "0h", "soon"} {
+		if _, err := parseMaxAge(value); err == nil {
+			t.Errorf("parseMaxAge(%q) succeeded", value)
+		}
+	}
+}
+
+func TestNewsSearchMaxAge(t *testing.T) {
+	fake := useFakeInstance(t)
+	now := time.Now().UTC()
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Fresh", "url": "https://a.example/fresh", "publishedDate": now.Add(-3 * time.Hour).Format(time.RFC3339)},
+			{"title": "Old", "url": "https://a.example/old", "publishedDate": now.Add(-5 * 24 * time.Hour).Format(time.RFC3339)},
+			{"title": "Undated", "url": "https://a.example/undated"},
+		},
+	})
+
+	result, err := callTool(t, searxngNewsSearchHandler, map[string]interface{}{"query": "news", "time_range": "month", "max_age": "2d"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var response newsSearchResponse
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 || response.Results[0].Age != "3 hours ago" || response.DateFilteredResults != 2 {
+		t.Errorf("results = %+v, %d filtered", response.Results, response.DateFilteredResults)
+	}
+	if request, _ := fake.LastRequest("/search"); request.Query.Get("time_range") != "month" {
+		t.Errorf("time_range = %q, want the explicit month kept", request.Query.Get("time_range"))
+	}
+
+	result, _ = callTool(t, searxngNewsSearchHandler, map[string]interface{}{"query": "news"})
+	response = newsSearchResponse{}
+	decodeResult(t, result, &response)
+	if len(response.Results) != 3 || response.Results[1].Age != "5 days ago" || response.Results[2].Age != "" {
+		t.Errorf("results without max_age = %+v", response.Results)
+	}
+}
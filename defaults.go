@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const setDefaultsToolName = "set_search_defaults"
+
+// searchDefaults are the arguments a session's tool calls inherit unless
+// they give them.
+type searchDefaults struct {
+	Language   string   `json:"language,omitempty"`
+	SafeSearch *int     `json:"safe_search,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+	MaxResults int      `json:"max_results,omitempty"`
+}
+
+// arguments returns the defaults as tool arguments.
+func (d searchDefaults) arguments() map[string]interface{} {
+	arguments := make(map[string]interface{})
+	if d.Language != "" {
+		arguments["language"] = d.Language
+	}
+	if d.SafeSearch != nil {
+		arguments["safe_search"] = float64(*d.SafeSearch)
+	}
+	if len(d.Engines) > 0 {
+		arguments["engines"] = strings.Join(d.Engines, ",")
+	}
+	if d.MaxResults > 0 {
+		arguments["max_results"] = float64(d.MaxResults)
+	}
+	return arguments
+}
+
+type sessionDefaults struct {
+	defaults searchDefaults
+	lastUsed time.Time
+}
+
+// defaultsStore holds the search defaults per MCP session.
+type defaultsStore struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionDefaults
+}
+
+var searchDefaultsStore = &defaultsStore{sessions: make(map[string]*sessionDefaults)}
+
+// get returns the defaults of the session, dropping those of sessions idle
+// for evidenceSessionIdle.
+func (s *defaultsStore) get(ctx context.Context) searchDefaults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, session := range s.sessions {
+		if now.Sub(session.lastUsed) > evidenceSessionIdle {
+			delete(s.sessions, id)
+		}
+	}
+	session, ok := s.sessions[sessionID(ctx)]
+	if !ok {
+		return searchDefaults{}
+	}
+	session.lastUsed = now
+	return session.defaults
+}
+
+func (s *defaultsStore) set(ctx context.Context, defaults searchDefaults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID(ctx)] = &sessionDefaults{defaults: defaults, lastUsed: time.Now()}
+}
+
+// toolArguments holds the argument names each registered tool declares, so
+// defaults are only passed to tools that take them.
+var toolArguments = make(map[string]map[string]bool)
+
+func registerToolArguments(tool mcp.Tool) {
+	names := make(map[string]bool, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		names[name] = true
+	}
+	toolArguments[tool.Name] = names
+}
+
+// applySearchDefaults is the tool middleware adding the session defaults
+// to tool calls that take those arguments and do not give them. Calls with
+// raw_query get none, and calls with a profile keep its engines and
+// language.
+func applySearchDefaults(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		defaults := searchDefaultsStore.get(ctx).arguments()
+		declared := toolArguments[request.Params.Name]
+		// set_search_defaults declares the arguments it sets: filling them
+		// in would undo clear and partial updates.
+		if len(defaults) == 0 || len(declared) == 0 || request.Params.Name == setDefaultsToolName {
+			return next(ctx, request)
+		}
+		given := request.GetArguments()
+		if raw, _, _ := boolArgument(given, "raw_query"); raw {
+			return next(ctx, request)
+		}
+
+		arguments := make(map[string]interface{}, len(given)+len(defaults))
+		for name, value := range given {
+			arguments[name] = value
+		}
+		for name, value := range defaults {
+			if _, ok := given[name]; ok || !declared[name] {
+				continue
+			}
+			if profile, _ := given["profile"].(string); profile != "" && (name == "engines" || name == "language") {
+				continue
+			}
+			// languages replaces language.
+			if _, ok := given["languages"]; ok && name == "language" {
+				continue
+			}
+			arguments[name] = value
+		}
+		request.Params.Arguments = arguments
+		return next(ctx, request)
+	}
+}
+
+func setSearchDefaultsOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithDescription("Set defaults for the search tool calls of this session: language, safe_search, engines and max_results are added to every later call that takes them and does not give them. Given arguments replace the current defaults, an empty string or -1 removes one. Returns the defaults in effect"),
+		outputSchema[searchDefaults](),
+		mcp.WithString("language",
+			mcp.Description("Default search language (ru, en, de, fr, etc.), "+languageHint()),
+		),
+		mcp.WithNumber("safe_search",
+			mcp.Description("Default safe search (0 - disabled, 1 - moderate, 2 - strict), -1 to remove"),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Default search engines, separated by comma"+syntheticEnginesHint()),
+		),
+		mcp.WithNumber("max_results",
+			mcp.Description("Default maximum number of results, 0 to remove"),
+		),
+		mcp.WithBoolean("clear",
+			mcp.Description("Remove all defaults before applying the given arguments"),
+		),
+	}
+}
+
+func setSearchDefaultsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	defaults := searchDefaultsStore.get(ctx)
+	if clear, _, err := boolArgument(arguments, "clear"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if clear {
+		defaults = searchDefaults{}
+	}
+
+	if language, ok := arguments["language"].(string); ok {
+		language = strings.TrimSpace(language)
+		if language != "" && !languagePattern.MatchString(language) {
+			return invalidArgumentsResult(fmt.Errorf("language must be auto, all or a language code such as en or pt-BR, got %q", language)), nil
+		}
+		defaults.Language = language
+	}
+
+	if level, ok, err := intArgument(arguments, "safe_search"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		switch {
+		case level == -1:
+			defaults.SafeSearch = nil
+		case level < minSafeSearch || level > maxSafeSearch:
+			return invalidArgumentsResult(fmt.Errorf("safe_search must be 0, 1 or 2, or -1 to remove it, got %d", level)), nil
+		default:
+			defaults.SafeSearch = &level
+		}
+	}
+
+	if engines, ok := arguments["engines"].(string); ok && strings.TrimSpace(engines) == "" {
+		defaults.Engines = nil
+	} else if engines, ok, err := listArgument(arguments, "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		if err := engineNames.check(ctx, engines); err != nil {
+			return invalidArgumentsResult(err), nil
+		}
+		defaults.Engines = engines
+	}
+
+	if n, ok, err := intArgument(arguments, "max_results"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		if n < 0 {
+			return invalidArgumentsResult(fmt.Errorf("max_results must not be negative, got %d", n)), nil
+		}
+		defaults.MaxResults = n
+	}
+
+	searchDefaultsStore.set(ctx, defaults)
+	return structuredResult(defaults)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchDefaults(t *testing.T) {
+	fake := useFakeInstance(t)
+	t.Cleanup(func() { searchDefaultsStore = &defaultsStore{sessions: make(map[string]*sessionDefaults)} })
+	registerToolArguments(mcp.NewTool("searxng_search_v2", append(searchArgumentOptions(), mcp.WithNumber("max_results"))...))
+	registerToolArguments(mcp.NewTool("searxng_image_search", mcp.WithString("query"), mcp.WithString("engines")))
+
+	result, err := callTool(t, setSearchDefaultsHandler, map[string]interface{}{
+		"language": "de", "engines": "duckduckgo,bing", "safe_search": float64(2), "max_results": float64(1),
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("set_search_defaults: %v %+v", err, result)
+	}
+
+	search := applySearchDefaults(searxngSearchV2Handler)
+	call := func(name string, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), arguments map[string]interface{}) {
+		t.Helper()
+		var request mcp.CallToolRequest
+		request.Params.Name = name
+		request.Params.Arguments = arguments
+		if _, err := handler(context.Background(), request); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	call("searxng_search_v2", search, map[string]interface{}{"query": "golang"})
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("language") != "de" || req.Query.Get("engines") != "duckduckgo,bing" || req.Query.Get("safesearch") != "2" {
+		t.Errorf("search request %v does not inherit the defaults", req.Query)
+	}
+
+	call("searxng_search_v2", search, map[string]interface{}{"query": "golang", "language": "fr", "engines": "brave"})
+	req, _ = fake.LastRequest("/search")
+	if req.Query.Get("language") != "fr" || req.Query.Get("engines") != "brave" || req.Query.Get("safesearch") != "2" {
+		t.Errorf("given arguments must override the defaults, got %v", req.Query)
+	}
+
+	call("searxng_search_v2", search, map[string]interface{}{"query": "golang", "raw_query": true})
+	req, _ = fake.LastRequest("/search")
+	if req.Query.Get("language") == "de" || req.Query.Get("engines") != "" {
+		t.Errorf("raw_query must get no defaults, got %v", req.Query)
+	}
+
+	var got map[string]interface{}
+	call("searxng_image_search", applySearchDefaults(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		got = request.GetArguments()
+		return mcp.NewToolResultText(""), nil
+	}), map[string]interface{}{"query": "cats"})
+	if got["engines"] != "duckduckgo,bing" || got["language"] != nil || got["max_results"] != nil {
+		t.Errorf("image search arguments = %v, want only the declared engines default", got)
+	}
+
+	result, err = callTool(t, setSearchDefaultsHandler, map[string]interface{}{"engines": "", "safe_search": float64(-1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var defaults searchDefaults
+	decodeResult(t, result, &defaults)
+	if defaults.Engines != nil || defaults.SafeSearch != nil || defaults.Language != "de" || defaults.MaxResults != 1 {
+		t.Errorf("defaults = %+v, want engines and safe_search removed", defaults)
+	}
+
+	result, _ = callTool(t, setSearchDefaultsHandler, map[string]interface{}{"safe_search": float64(3)})
+	if !result.IsError {
+		t.Error("want an error for safe_search 3")
+	}
+
+	registerToolArguments(mcp.NewTool(setDefaultsToolName, setSearchDefaultsOptions()...))
+	var request mcp.CallToolRequest
+	request.Params.Name = setDefaultsToolName
+	request.Params.Arguments = map[string]interface{}{"clear": true}
+	result, err = applySearchDefaults(setSearchDefaultsHandler)(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults = searchDefaults{}
+	decodeResult(t, result, &defaults)
+	if defaults.Language != "" || defaults.MaxResults != 0 {
+		t.Errorf("defaults after clear = %+v, want none", defaults)
+	}
+}
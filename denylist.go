@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// DenyConfig forbids searching categories and engines, e.g. the files
+// category or torrent engines in a corporate deployment:
+//
+//	"deny": {"categories": ["files"], "engines": ["piratebay", "1337x"]}
+//
+// Tool calls whose search would use them are refused, whether the engines
+// were requested directly or came from a profile, a synthetic engine, an
+// engine policy or a bang. Shortcut bangs and engines are resolved through
+// the /config of the instance, so denying a category denies its engines
+// and denying an engine its shortcut. Engines the instance picks by default
+// are not known here; disable those on the instance.
+type DenyConfig struct {
+	Categories []string `json:"categories,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+}
+
+// policyError is a search the deny config forbids.
+type policyError struct {
+	Kind  string
+	Names []string
+}
+
+func (e *policyError) Error() string {
+	kind := e.Kind
+	if len(e.Names) > 1 {
+		kind += "s"
+	}
+	return fmt.Sprintf("the server policy forbids searching the %s %s", kind, strings.Join(e.Names, ", "))
+}
+
+// denylist is the compiled deny config, with lowercase names.
+type denylist struct {
+	categories map[string]bool
+	engines    map[string]bool
+}
+
+func newDenylist(c *DenyConfig) *denylist {
+	if c == nil || len(c.Categories) == 0 && len(c.Engines) == 0 {
+		return nil
+	}
+	d := &denylist{categories: make(map[string]bool), engines: make(map[string]bool)}
+	for _, category := range c.Categories {
+		d.categories[denyKey(category)] = true
+	}
+	for _, engine := range c.Engines {
+		d.engines[denyKey(engine)] = true
+	}
+	return d
+}
+
+// denyKey normalizes a category or engine name; bangs write the spaces of
+// names as underscores ("!social_media").
+func denyKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", " ")
+}
+
+// check returns a *policyError when params search a denied category or
+// engine, by argument or by a bang of the query. Shortcut bangs ("!tpb")
+// are resolved to their engine, and engines to their categories, through
+// the /config of the instance; while it is unavailable only the names as
+// given are checked.
+func (d *denylist) check(ctx context.Context, params searxng.SearchParams) error {
+	if d == nil {
+		return nil
+	}
+	catalog, _ := instanceConfigs.get(ctx)
+	var categories, engines []string
+	deny := func(names *[]string, name string) {
+		if !slices.Contains(*names, name) {
+			*names = append(*names, name)
+		}
+	}
+	// checkEngine checks engine and its categories; given is how the
+	// search named it, the engine or a bang.
+	checkEngine := func(engine, given string) {
+		if d.engines[denyKey(engine)] {
+			if given != engine {
+				given = fmt.Sprintf("%s (%s)", given, engine)
+			}
+			deny(&engines, given)
+			return
+		}
+		if catalog == nil {
+			return
+		}
+		for _, category := range catalog.engineCategories(engine) {
+			if d.categories[denyKey(category)] {
+				deny(&categories, fmt.Sprintf("%s (%s)", category, given))
+			}
+		}
+	}
+	for _, category := range params.Categories {
+		if d.categories[denyKey(category)] {
+			deny(&categories, category)
+		}
+	}
+	for _, engine := range params.Engines {
+		checkEngine(engine, engine)
+	}
+	for _, bang := range queryBangs(params.Query) {
+		name := denyKey(strings.TrimLeft(bang, "!"))
+		switch {
+		case d.categories[name]:
+			deny(&categories, bang)
+		case d.engines[name]:
+			deny(&engines, bang)
+		case catalog != nil && catalog.shortcuts[strings.ToLower(strings.TrimLeft(bang, "!"))] != "":
+			checkEngine(catalog.shortcuts[strings.ToLower(strings.TrimLeft(bang, "!"))], bang)
+		default:
+			checkEngine(name, bang)
+		}
+	}
+	if len(categories) > 0 {
+		return &policyError{Kind: "category", Names: categories}
+	}
+	if len(engines) > 0 {
+		return &policyError{Kind: "engine", Names: engines}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestDenylistCheck(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetConfig(map[string]interface{}{
+		"engines": []interface{}{
+			map[string]interface{}{"name": "piratebay", "shortcut": "tpb", "categories": []interface{}{"files"}},
+			map[string]interface{}{"name": "solidtorrents", "shortcut": "solid", "categories": []interface{}{"files"}},
+			map[string]interface{}{"name": "kickass", "shortcut": "kc", "categories": []interface{}{"videos"}},
+			map[string]interface{}{"name": "github", "shortcut": "gh", "categories": []interface{}{"it"}},
+		},
+	})
+	deny := newDenylist(&DenyConfig{Categories: []string{"files", "social media"}, Engines: []string{"PirateBay", "kickass"}})
+	tests := []struct {
+		name   string
+		params searxng.SearchParams
+		denied string
+	}{
+		{"allowed", searxng.SearchParams{Query: "golang", Categories: []string{"general"}, Engines: []string{"duckduckgo"}}, ""},
+		{"category", searxng.SearchParams{Query: "ubuntu iso", Categories: []string{"general", "files"}}, "category files"},
+		{"engine", searxng.SearchParams{Query: "ubuntu iso", Engines: []string{"piratebay"}}, "engine piratebay"},
+		{"category bang", searxng.SearchParams{Query: "!social_media golang"}, "category !social_media"},
+		{"engine name bang", searxng.SearchParams{Query: "ubuntu !kickass"}, "engine !kickass"},
+		{"shortcut bang", searxng.SearchParams{Query: "ubuntu !kc"}, "engine !kc (kickass)"},
+		{"engine of a denied category", searxng.SearchParams{Query: "ubuntu iso", Engines: []string{"solidtorrents"}}, "category files (solidtorrents)"},
+		{"shortcut bang of a denied category", searxng.SearchParams{Query: "!solid ubuntu"}, "category files (!solid)"},
+		{"allowed shortcut bang", searxng.SearchParams{Query: "!gh mcp"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := deny.check(context.Background(), tt.params)
+			if tt.denied == "" {
+				if err != nil {
+					t.Errorf("check = %v, want nil", err)
+				}
+				return
+			}
+			var policyErr *policyError
+			if !errors.As(err, &policyErr) || !strings.Contains(err.Error(), tt.denied) {
+				t.Errorf("check = %v, want a policy error naming %s", err, tt.denied)
+			}
+		})
+	}
+
+	if newDenylist(&DenyConfig{}) != nil || newDenylist(nil).check(context.Background(), searxng.SearchParams{Engines: []string{"piratebay"}}) != nil {
+		t.Error("an empty deny config must deny nothing")
+	}
+}
+
+func TestSearchV2Denied(t *testing.T) {
+	fake := useFakeInstance(t)
+	useState(t, func(s *serverState) { s.denylist = newDenylist(&DenyConfig{Categories: []string{"files"}}) })
+
+	for _, arguments := range []map[string]interface{}{
+		{"query": "ubuntu iso", "categories": "files"},
+		{"query": "ubuntu iso", "categories": "files", "dry_run": true},
+	} {
+		result, err := callTool(t, searxngSearchV2Handler, arguments)
+		if err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+		text, _ := mcp.AsTextContent(result.Content[0])
+		if !result.IsError || !strings.Contains(text.Text, "policy forbids") {
+			t.Errorf("%v: result = %q, want a policy error", arguments, text.Text)
+		}
+	}
+	if _, ok := fake.LastRequest("/search"); ok {
+		t.Error("a denied search reached the instance")
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// maxSearchSnapshots is the number of searches diff_searches remembers the
+// results of.
+const maxSearchSnapshots = 100
+
+// diffSide describes one of the two compared result lists.
+type diffSide struct {
+	Query string `json:"query"`
+	// At is when the instance returned the results.
+	At      string `json:"at"`
+	Results int    `json:"results"`
+}
+
+type diffEntry struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Position is 1-based, in the list the entry is from.
+	Position int `json:"position"`
+}
+
+type diffChange struct {
+	URL            string `json:"url"`
+	Title          string `json:"title"`
+	PositionBefore int    `json:"position_before"`
+	PositionAfter  int    `json:"position_after"`
+	TitleChanged   bool   `json:"title_changed,omitempty"`
+	ContentChanged bool   `json:"content_changed,omitempty"`
+}
+
+type searchDiffResponse struct {
+	Before *diffSide `json:"before,omitempty"`
+	After  diffSide  `json:"after"`
+	// Added are in After only, Removed in Before only; Changed are in
+	// both but moved or reworded, Unchanged counts the others.
+	Added     []diffEntry  `json:"added"`
+	Removed   []diffEntry  `json:"removed"`
+	Changed   []diffChange `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	// Note is set when there was nothing to compare with yet.
+	Note string `json:"note,omitempty"`
+}
+
+// searchSnapshot is the result list of a search at a time.
+type searchSnapshot struct {
+	at      time.Time
+	results []searxng.SearchResult
+}
+
+// snapshotStore remembers the latest results of the searches diff_searches
+// ran, the "before" of its next call.
+type snapshotStore struct {
+	mu        sync.Mutex
+	snapshots map[string]searchSnapshot
+}
+
+var searchSnapshots = &snapshotStore{snapshots: make(map[string]searchSnapshot)}
+
+// snapshotKey identifies a search by its prepared parameters.
+func snapshotKey(params searxng.SearchParams) string {
+	data, _ := json.Marshal(params)
+	return string(data)
+}
+
+func (s *snapshotStore) get(key string) (searchSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[key]
+	return snapshot, ok
+}
+
+// put stores a snapshot, dropping the oldest one when full.
+func (s *snapshotStore) put(key string, snapshot searchSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.snapshots[key]; !ok && len(s.snapshots) >= maxSearchSnapshots {
+		oldest := ""
+		for k, v := range s.snapshots {
+			if oldest == "" || v.at.Before(s.snapshots[oldest].at) {
+				oldest = k
+			}
+		}
+		delete(s.snapshots, oldest)
+	}
+	s.snapshots[key] = snapshot
+}
+
+// cachedSnapshot reads the response cache entry of a search, whatever its
+// age.
+func cachedSnapshot(ctx context.Context, params searxng.SearchParams) (searchSnapshot, bool) {
+	if searchCache == nil {
+		return searchSnapshot{}, false
+	}
+	key, err := searchCacheKey(ctx, "search", params)
+	if err != nil {
+		return searchSnapshot{}, false
+	}
+	data, stored, ok := searchCache.store.get(key)
+	if !ok {
+		return searchSnapshot{}, false
+	}
+	var response searxng.SearchResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return searchSnapshot{}, false
+	}
+	return searchSnapshot{at: stored, results: response.Results}, true
+}
+
+// diffResults compares two result lists by page.
+func diffResults(before, after []searxng.SearchResult) searchDiffResponse {
+	diff := searchDiffResponse{Added: []diffEntry{}, Removed: []diffEntry{}, Changed: []diffChange{}}
+	positions := make(map[string]int, len(before))
+	for i, r := range before {
+		if _, ok := positions[pageKey(r.URL)]; !ok {
+			positions[pageKey(r.URL)] = i
+		}
+	}
+	matched := make(map[string]bool, len(after))
+	for i, r := range after {
+		key := pageKey(r.URL)
+		if matched[key] {
+			continue
+		}
+		matched[key] = true
+		j, ok := positions[key]
+		if !ok {
+			diff.Added = append(diff.Added, diffEntry{URL: r.URL, Title: r.Title, Position: i + 1})
+			continue
+		}
+		old := before[j]
+		change := diffChange{
+			URL:            r.URL,
+			Title:          r.Title,
+			PositionBefore: j + 1,
+			PositionAfter:  i + 1,
+			TitleChanged:   collapse(old.Title) != collapse(r.Title),
+			ContentChanged: collapse(old.Content) != collapse(r.Content),
+		}
+		if change.PositionBefore == change.PositionAfter && !change.TitleChanged && !change.ContentChanged {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, change)
+	}
+	for key, j := range positions {
+		if !matched[key] {
+			diff.Removed = append(diff.Removed, diffEntry{URL: before[j].URL, Title: before[j].Title, Position: j + 1})
+		}
+	}
+	slices.SortFunc(diff.Removed, func(a, b diffEntry) int { return a.Position - b.Position })
+	return diff
+}
+
+func diffSearchesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	params, err := searchParamsFromArguments(ctx, arguments)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return invalidArgumentsResult(errors.New("query must be a non-empty string")), nil
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	otherQuery, _ := arguments["other_query"].(string)
+
+	if otherQuery = strings.TrimSpace(otherQuery); otherQuery != "" {
+		// Two queries: both may come from the cache.
+		other := params
+		other.Query = otherQuery
+		other.Categories = slices.Clone(params.Categories)
+		other.Engines = slices.Clone(params.Engines)
+		before, beforeMeta, err := runSearch(ctx, params)
+		if err != nil {
+			return upstreamErrorResult("search", err), nil
+		}
+		after, afterMeta, err := runSearch(ctx, other)
+		if err != nil {
+			return upstreamErrorResult("search", err), nil
+		}
+		diff := diffResults(before.Results, after.Results)
+		diff.Before = &diffSide{Query: params.Query, At: searchTime(beforeMeta), Results: len(before.Results)}
+		diff.After = diffSide{Query: other.Query, At: searchTime(afterMeta), Results: len(after.Results)}
+		return structuredResult(diff)
+	}
+
+	// One query at two times: the results of the previous call, or the
+	// cached response, against a fresh search.
+	prepareSearch(ctx, &params)
+	key := snapshotKey(params)
+	previous, ok := searchSnapshots.get(key)
+	if !ok {
+		previous, ok = cachedSnapshot(ctx, params)
+	}
+	result, err := freshSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	if err != nil {
+		return upstreamErrorResult("search", err), nil
+	}
+	suspendedEngines.record(result.UnresponsiveEngines)
+	now := time.Now()
+	searchSnapshots.put(key, searchSnapshot{at: now, results: result.Results})
+
+	after := diffSide{Query: params.Query, At: now.UTC().Format(time.RFC3339), Results: len(result.Results)}
+	if !ok {
+		return structuredResult(searchDiffResponse{
+			After:   after,
+			Added:   []diffEntry{},
+			Removed: []diffEntry{},
+			Changed: []diffChange{},
+			Note:    "no earlier results of this search to compare with: these results are the baseline, call diff_searches again later to see what changed",
+		})
+	}
+	diff := diffResults(previous.results, result.Results)
+	diff.Before = &diffSide{Query: params.Query, At: previous.at.UTC().Format(time.RFC3339), Results: len(previous.results)}
+	diff.After = after
+	return structuredResult(diff)
+}
+
+// searchTime is when the instance answered a search: its cache time, or
+// now.
+func searchTime(meta searchMeta) string {
+	if meta.CachedAt != "" {
+		return meta.CachedAt
+	}
+	return time.Now().UTC().Format(time.RFC3339)
+}
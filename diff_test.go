@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestDiffResults(t *testing.T) {
+	before := []searxng.SearchResult{
+		{Title: "A", URL: "https://a.example/"},
+		{Title: "B", URL: "https://b.example/"},
+		{Title: "C", URL: "https://c.example/", Content: "old"},
+		{Title: "D", URL: "https://d.example/"},
+	}
+	after := []searxng.SearchResult{
+		{Title: "A", URL: "https://a.example/?utm_source=x"},
+		{Title: "C", URL: "https://c.example/", Content: "new"},
+		{Title: "E", URL: "https://e.example/"},
+		{Title: "D", URL: "https://d.example/"},
+	}
+	diff := diffResults(before, after)
+	if want := []diffEntry{{URL: "https://e.example/", Title: "E", Position: 3}}; !reflect.DeepEqual(diff.Added, want) {
+		t.Errorf("added = %+v", diff.Added)
+	}
+	if want := []diffEntry{{URL: "https://b.example/", Title: "B", Position: 2}}; !reflect.DeepEqual(diff.Removed, want) {
+		t.Errorf("removed = %+v", diff.Removed)
+	}
+	want := []diffChange{{URL: "https://c.example/", Title: "C", PositionBefore: 3, PositionAfter: 2, ContentChanged: true}}
+	if !reflect.DeepEqual(diff.Changed, want) || diff.Unchanged != 2 {
+		t.Errorf("changed = %+v, unchanged %d", diff.Changed, diff.Unchanged)
+	}
+}
+
+func TestDiffSearches(t *testing.T) {
+	fake := useFakeInstance(t)
+	previous := searchSnapshots
+	searchSnapshots = &snapshotStore{snapshots: make(map[string]searchSnapshot)}
+	t.Cleanup(func() { searchSnapshots = previous })
+
+	fake.SetSearchResponse(searchResults("https://a.example/", "https://b.example/"))
+	result, err := callTool(t, diffSearchesHandler, map[string]interface{}{"query": "story"})
+	if err != nil || result.IsError {
+		t.Fatalf("diff_searches: %+v, %v", result, err)
+	}
+	var diff searchDiffResponse
+	decodeResult(t, result, &diff)
+	if diff.Before != nil || diff.Note == "" || diff.After.Results != 2 {
+		t.Errorf("first call = %+v, want a baseline", diff)
+	}
+
+	fake.SetSearchResponse(searchResults("https://b.example/", "https://c.example/"))
+	result, _ = callTool(t, diffSearchesHandler, map[string]interface{}{"query": "story"})
+	diff = searchDiffResponse{}
+	decodeResult(t, result, &diff)
+	if diff.Before == nil || len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 1 {
+		t.Errorf("second call = %+v, want c added, a removed and b moved", diff)
+	}
+
+	result, _ = callTool(t, diffSearchesHandler, map[string]interface{}{"query": "story", "other_query": "story update"})
+	diff = searchDiffResponse{}
+	decodeResult(t, result, &diff)
+	if diff.Before == nil || diff.Before.Query != "story" || diff.After.Query != "story update" || diff.Unchanged != 2 {
+		t.Errorf("two queries = %+v", diff)
+	}
+}
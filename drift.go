@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// schemaIssueCount is a schema difference seen in /search responses.
+type schemaIssueCount struct {
+	searxng.SchemaIssue
+	Count     int64     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// schemaDriftTracker counts the schema differences of instance responses,
+// to notice a SearXNG upgrade that changed the response format before
+// users report empty results.
+type schemaDriftTracker struct {
+	mu     sync.Mutex
+	issues map[string]*schemaIssueCount
+}
+
+var schemaDrift = &schemaDriftTracker{issues: make(map[string]*schemaIssueCount)}
+
+// observe is the schema observer of the SearXNG client. Every new kind of
+// difference is logged once.
+func (t *schemaDriftTracker) observe(issues []searxng.SchemaIssue) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for _, issue := range issues {
+		key := issue.Kind + " " + issue.Path
+		seen, ok := t.issues[key]
+		if !ok {
+			log.Printf("SearXNG response schema changed: %s", issue)
+			seen = &schemaIssueCount{SchemaIssue: issue, FirstSeen: now}
+			t.issues[key] = seen
+		}
+		seen.Count++
+		seen.LastSeen = now
+	}
+}
+
+// snapshot returns the differences seen so far, by path.
+func (t *schemaDriftTracker) snapshot() []schemaIssueCount {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	issues := make([]schemaIssueCount, 0, len(t.issues))
+	for _, issue := range t.issues {
+		issues = append(issues, *issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		return issues[i].Kind < issues[j].Kind
+	})
+	return issues
+}
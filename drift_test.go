@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestSchemaDriftTracker(t *testing.T) {
+	tracker := &schemaDriftTracker{issues: make(map[string]*schemaIssueCount)}
+	mismatch := searxng.SchemaIssue{Kind: searxng.SchemaTypeMismatch, Path: "corrections", Detail: "want string array, got array"}
+	tracker.observe([]searxng.SchemaIssue{mismatch, {Kind: searxng.SchemaUnknownField, Path: "results[].rank"}})
+	tracker.observe([]searxng.SchemaIssue{mismatch})
+
+	issues := tracker.snapshot()
+	if len(issues) != 2 || issues[0].Path != "corrections" || issues[0].Count != 2 || issues[1].Count != 1 {
+		t.Fatalf("snapshot = %+v", issues)
+	}
+
+	previous := schemaDrift
+	schemaDrift = tracker
+	t.Cleanup(func() { schemaDrift = previous })
+	fake := useFakeInstance(t)
+	fake.SetConfig(map[string]interface{}{"version": "2025.1.1"})
+	status := checkInstance(context.Background(), currentState().client)
+	if len(status.SchemaIssues) != 2 || !strings.Contains(strings.Join(status.Problems, "\n"), "-lenient-parsing") {
+		t.Errorf("status = %+v", status)
+	}
+}
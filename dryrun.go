@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// redacted replaces secrets (static query parameters, custom headers) in
+// dry run output.
+const redacted = "REDACTED"
+
+// dryRunOption declares the dry_run argument of the search tools.
+func dryRunOption() mcp.ToolOption {
+	return mcp.WithBoolean("dry_run",
+		mcp.Description("Return the resolved parameters and the upstream request without searching, to debug how arguments are applied"),
+	)
+}
+
+func isDryRun(arguments map[string]interface{}) (bool, error) {
+	dryRun, _, err := boolArgument(arguments, "dry_run")
+	return dryRun, err
+}
+
+// resolvedParams are the search parameters after defaults and server side
+// transformations were applied.
+type resolvedParams struct {
+	Query      string   `json:"query"`
+	Categories []string `json:"categories,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+	Language   string   `json:"language,omitempty"`
+	Page       int      `json:"page,omitempty"`
+	TimeRange  string   `json:"time_range,omitempty"`
+	SafeSearch int      `json:"safe_search"`
+}
+
+type upstreamRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Body    string            `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type dryRunResponse struct {
+	DryRun   bool           `json:"dry_run"`
+	Instance string         `json:"instance"`
+	Params   resolvedParams `json:"params"`
+	// Defaults are the optional arguments that were not given and took
+	// their default value.
+	Defaults []string        `json:"defaults,omitempty"`
+	Request  upstreamRequest `json:"request"`
+	Meta     searchMeta      `json:"meta"`
+}
+
+// dryRunResult describes the search params, already passed through
+// prepareSearch, would send. optional names the optional arguments of the
+// tool, to report which ones were defaulted.
+func dryRunResult(ctx context.Context, params searxng.SearchParams, meta searchMeta, arguments map[string]interface{}, optional ...string) (*mcp.CallToolResult, error) {
+	if err := stateOf(ctx).denylist.check(cachedCatalogOnly(ctx), params); err != nil {
+		return upstreamErrorResult("search", err), nil
+	}
+	req, err := newUpstreamRequest(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dryRunResponse{
+		DryRun:   true,
+		Instance: activeInstance(ctx).BaseURL,
+		Params:   newResolvedParams(ctx, params),
+		Request:  req,
+		Meta:     meta,
+	}
+	for _, name := range optional {
+		if value, ok := arguments[name]; !ok || value == "" {
+			response.Defaults = append(response.Defaults, name)
+		}
+	}
+
+	return structuredResult(response)
+}
+
+func newResolvedParams(ctx context.Context, params searxng.SearchParams) resolvedParams {
+	return resolvedParams{
+		Query:      params.Query,
+		Categories: params.Categories,
+		Engines:    params.Engines,
+		Language:   params.Language,
+		Page:       params.PageNo,
+		TimeRange:  params.TimeRange,
+		SafeSearch: max(activeInstance(ctx).SafeSearchLevel(params.SafeSearch), params.SafeSearch),
+	}
+}
+
+// newUpstreamRequest describes the request params send to the instance,
+// with the static query parameters and headers redacted.
+func newUpstreamRequest(ctx context.Context, params searxng.SearchParams) (upstreamRequest, error) {
+	req, err := activeInstance(ctx).NewSearchRequest(ctx, params)
+	if err != nil {
+		return upstreamRequest{}, fmt.Errorf("error building request: %w", err)
+	}
+	described := upstreamRequest{
+		Method:  req.Method,
+		URL:     redactURL(req.URL).String(),
+		Headers: redactHeaders(req.Header),
+	}
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		form, _ := url.ParseQuery(string(body))
+		described.Body = redactValues(form).Encode()
+	}
+	return described, nil
+}
+
+func redactURL(u *url.URL) *url.URL {
+	redactedURL := *u
+	redactedURL.RawQuery = redactValues(u.Query()).Encode()
+	return &redactedURL
+}
+
+// redactValues hides the static query parameters, which often carry
+// access tokens.
+func redactValues(values url.Values) url.Values {
+	for name := range currentState().client.QueryParams {
+		if values.Has(name) {
+			values.Set(name, redacted)
+		}
+	}
+	return values
+}
+
+// redactHeaders hides custom header values, keeping the standard ones.
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for name := range header {
+		switch name {
+		case "User-Agent", "Accept", "Content-Type":
+			headers[name] = header.Get(name)
+		default:
+			headers[name] = redacted
+		}
+	}
+	return headers
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestSearchV2DryRun(t *testing.T) {
+	fake := useFakeInstance(t)
+	useState(t, func(s *serverState) {
+		s.client = searxng.New(fake.URL,
+			searxng.WithQueryParams(url.Values{"token": {"secret"}}),
+			searxng.WithHeaders(http.Header{"X-Api-Key": {"secret"}}),
+		)
+	})
+	useConfig(t, &Config{SyntheticEngines: map[string]SyntheticEngine{
+		"go_docs": {Query: "site:go.dev"},
+	}})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{
+		"query":   "generics",
+		"engines": "go_docs",
+		"dry_run": true,
+	})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if _, searched := fake.LastRequest("/search"); searched {
+		t.Error("dry run sent a search request")
+	}
+
+	var response dryRunResponse
+	decodeResult(t, result, &response)
+	if !response.DryRun || response.Params.Query != "generics site:go.dev" {
+		t.Errorf("params = %+v", response.Params)
+	}
+	if want := []string{"profile", "categories", "language", "page", "time_range", "safe_search"}; !reflect.DeepEqual(response.Defaults, want) {
+		t.Errorf("defaults = %q, want %q", response.Defaults, want)
+	}
+	if !reflect.DeepEqual(response.Meta.SyntheticEngines, []string{"go_docs"}) {
+		t.Errorf("meta = %+v", response.Meta)
+	}
+
+	u, err := url.Parse(response.Request.URL)
+	if err != nil {
+		t.Fatalf("request URL: %v", err)
+	}
+	if response.Request.Method != "GET" || !strings.HasPrefix(response.Request.URL, fake.URL+"/search?") {
+		t.Errorf("request = %+v", response.Request)
+	}
+	if u.Query().Get("q") != "generics site:go.dev" || u.Query().Get("token") != redacted {
+		t.Errorf("request query = %v", u.Query())
+	}
+	if response.Request.Headers["X-Api-Key"] != redacted {
+		t.Errorf("headers = %v, want X-Api-Key redacted", response.Request.Headers)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// echoedRequest is a search a tool call made, as sent to the instance.
+type echoedRequest struct {
+	Kind    string          `json:"kind"`
+	Params  resolvedParams  `json:"params"`
+	Request upstreamRequest `json:"request"`
+	// CachedAt is set when the response came from the cache instead.
+	CachedAt string `json:"cached_at,omitempty"`
+}
+
+// echoRecorder collects the searches of one tool call.
+type echoRecorder struct {
+	mu       sync.Mutex
+	requests []*echoedRequest
+}
+
+type echoRecorderKey struct{}
+
+// echoUpstreamRequests is the tool middleware of -debug-echo: it runs the
+// call as usual and appends the SearXNG requests the call made as a second
+// text content, to debug how arguments were parsed without a packet
+// capture.
+func echoUpstreamRequests(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		recorder := &echoRecorder{}
+		result, err := next(context.WithValue(ctx, echoRecorderKey{}, recorder), request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		if len(recorder.requests) == 0 {
+			return result, nil
+		}
+		echo, err := json.MarshalIndent(map[string]interface{}{"upstream_requests": recorder.requests}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		result.Content = append(result.Content, mcp.NewTextContent(string(echo)))
+		return result, nil
+	}
+}
+
+// echoSearch records a search of kind in the echo of the tool call, if
+// -debug-echo is on. Mark the returned entry with cachedAt when the
+// response is served from the cache.
+func echoSearch(ctx context.Context, kind string, params searxng.SearchParams) *echoedRequest {
+	recorder, ok := ctx.Value(echoRecorderKey{}).(*echoRecorder)
+	if !ok {
+		return nil
+	}
+	entry := &echoedRequest{Kind: kind, Params: newResolvedParams(ctx, params)}
+	if req, err := newUpstreamRequest(ctx, params); err == nil {
+		entry.Request = req
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.requests = append(recorder.requests, entry)
+	return entry
+}
+
+func (e *echoedRequest) cached(stored time.Time) {
+	if e != nil {
+		e.CachedAt = stored.UTC().Format(time.RFC3339)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestEchoUpstreamRequests(t *testing.T) {
+	fake := useFakeInstance(t)
+	useState(t, func(s *serverState) {
+		s.client = searxng.New(fake.URL, searxng.WithQueryParams(url.Values{"token": {"secret"}}))
+	})
+
+	result, err := callTool(t, echoUpstreamRequests(searxngSearchV2Handler), map[string]interface{}{
+		"query":   "generics",
+		"engines": "duckduckgo,brave",
+	})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if result.IsError || len(result.Content) != 2 {
+		t.Fatalf("result = %+v, want the search and the echo", result)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+
+	var echo struct {
+		UpstreamRequests []echoedRequest `json:"upstream_requests"`
+	}
+	text, ok := mcp.AsTextContent(result.Content[1])
+	if !ok {
+		t.Fatalf("echo content = %T", result.Content[1])
+	}
+	if err := json.Unmarshal([]byte(text.Text), &echo); err != nil {
+		t.Fatalf("decode echo: %v", err)
+	}
+	if len(echo.UpstreamRequests) != 1 {
+		t.Fatalf("upstream_requests = %+v", echo.UpstreamRequests)
+	}
+	sent := echo.UpstreamRequests[0]
+	if sent.Kind != "search" || sent.Params.Query != "generics" || strings.Join(sent.Params.Engines, ",") != "duckduckgo,brave" {
+		t.Errorf("echoed params = %+v", sent)
+	}
+	if sent.Request.Method != http.MethodGet || !strings.HasPrefix(sent.Request.URL, fake.URL+"/search") {
+		t.Errorf("echoed request = %+v", sent.Request)
+	}
+	if strings.Contains(text.Text, "secret") {
+		t.Errorf("echo leaks a static query parameter: %s", text.Text)
+	}
+}
+
+func TestEchoUpstreamRequestsWithoutSearch(t *testing.T) {
+	useFakeInstance(t)
+
+	result, err := callTool(t, echoUpstreamRequests(searxngInstanceStatusHandler), nil)
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if len(result.Content) != 1 {
+		t.Errorf("%d contents, want no echo for a call without searches", len(result.Content))
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxEngineSuggestions bounds the "did you mean" candidates of an unknown
+// engine name.
+const maxEngineSuggestions = 3
+
+// engineCatalog checks engine names against the engines the instance lists
+// on /config, to reject misspelled engine names: SearXNG ignores them and
+// the search quietly returns nothing.
+type engineCatalog struct{}
+
+// engineNames is nil when engine names are not checked.
+var engineNames *engineCatalog
+
+// check returns an error naming the first engine the instance does not
+// have or has disabled, with the closest engine names. Synthetic engines
+// are left alone. Nothing is checked when /config is unavailable, offline
+// or in a dry run.
+func (c *engineCatalog) check(ctx context.Context, names []string) error {
+	if c == nil {
+		return nil
+	}
+	catalog, err := instanceConfigs.get(ctx)
+	if err != nil || len(catalog.enabled) == 0 {
+		if err != nil && !errors.Is(err, errCatalogNotFetched) {
+			log.Printf("Engine names are not checked: /config unavailable: %v", err)
+		}
+		return nil
+	}
+	known := catalog.enabled
+	for _, name := range names {
+		if _, ok := stateOf(ctx).config.SyntheticEngines[name]; ok {
+			continue
+		}
+		enabled, ok := known[strings.ToLower(name)]
+		switch {
+		case !ok:
+			message := fmt.Sprintf("unknown engine %q", name)
+			if suggestions := similarEngines(name, known); len(suggestions) > 0 {
+				message += fmt.Sprintf(", did you mean %s?", quoteAll(suggestions))
+			} else {
+				message += ", searxng_engines_info lists the engines of the instance"
+			}
+			return errors.New(message)
+		case !enabled:
+			return fmt.Errorf("engine %q is disabled on the instance", name)
+		}
+	}
+	return nil
+}
+
+// checkEngineArgument checks the engine names of the engines argument.
+// Engines the tool picks by default are not checked: the caller did not
+// choose them. A dry run checks them only against a cached /config.
+func checkEngineArgument(ctx context.Context, request mcp.CallToolRequest) error {
+	engines, ok, err := listArgument(request.GetArguments(), "engines")
+	if err != nil || !ok || isAutoEngines(engines) {
+		// A malformed argument is reported by the handler; auto is
+		// replaced by classified engines.
+		return nil
+	}
+	if dryRun, _ := isDryRun(request.GetArguments()); dryRun {
+		ctx = cachedCatalogOnly(ctx)
+	}
+	return engineNames.check(ctx, engines)
+}
+
+// similarEngines returns the enabled engines closest to name: those within
+// a few typos, and those it is a prefix of ("bing" for "bing news").
+func similarEngines(name string, known map[string]bool) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	maxDistance := max(1, len(name)/3)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for engine, enabled := range known {
+		if !enabled {
+			continue
+		}
+		if d := editDistance(name, engine); d <= maxDistance {
+			candidates = append(candidates, candidate{engine, d})
+		} else if strings.HasPrefix(engine, name+" ") {
+			candidates = append(candidates, candidate{engine, maxDistance + 1})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var names []string
+	for i := 0; i < len(candidates) && i < maxEngineSuggestions; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// editDistance is the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, " or ")
+}
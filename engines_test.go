@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// useEngineNames checks engine names against the fake instance for the
+// duration of the test.
+func useEngineNames(t *testing.T) {
+	t.Helper()
+	previous := engineNames
+	engineNames = &engineCatalog{}
+	t.Cleanup(func() { engineNames = previous })
+}
+
+func TestCheckEngineNames(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEngineNames(t)
+	useConfig(t, &Config{SyntheticEngines: map[string]SyntheticEngine{"go_docs": {Query: "site:go.dev"}}})
+	fake.SetConfig(map[string]interface{}{
+		"engines": []interface{}{
+			map[string]interface{}{"name": "google", "enabled": true},
+			map[string]interface{}{"name": "google news", "enabled": true},
+			map[string]interface{}{"name": "bing", "enabled": true},
+			map[string]interface{}{"name": "bing news", "enabled": true},
+			map[string]interface{}{"name": "yahoo", "enabled": false},
+		},
+	})
+
+	tests := []struct {
+		engines string
+		wantErr string
+	}{
+		{engines: "google, Bing News, go_docs"},
+		{engines: "google new", wantErr: `unknown engine "google new", did you mean "google news"?`},
+		{engines: "gogle", wantErr: `did you mean "google"?`},
+		{engines: "yahoo", wantErr: `engine "yahoo" is disabled on the instance`},
+		{engines: "nonexistent", wantErr: "searxng_engines_info lists the engines"},
+	}
+	for _, tt := range tests {
+		result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "engines": tt.engines})
+		if err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+		if tt.wantErr == "" {
+			if result.IsError {
+				t.Errorf("engines %q: %+v", tt.engines, result.Content)
+			}
+			continue
+		}
+		text, _ := mcp.AsTextContent(result.Content[0])
+		if !result.IsError || text == nil || !strings.Contains(text.Text, tt.wantErr) {
+			t.Errorf("engines %q: got %+v, want an error containing %q", tt.engines, result.Content, tt.wantErr)
+		}
+	}
+
+	configRequests := 0
+	for _, r := range fake.Requests() {
+		if r.Path == "/config" {
+			configRequests++
+		}
+	}
+	if configRequests != 1 {
+		t.Errorf("/config requested %d times, want once", configRequests)
+	}
+}
+
+func TestCheckEngineNamesWithoutConfig(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEngineNames(t)
+	fake.Handle("/config", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "engines": "anything"})
+	if err != nil || result.IsError {
+		t.Errorf("want the search to go ahead unchecked, got %+v, %v", result, err)
+	}
+}
+
+func TestSimilarEngines(t *testing.T) {
+	known := map[string]bool{"bing": true, "bing images": true, "bing news": true, "bind": false, "brave": true}
+	if got, want := similarEngines("bingo", known), []string{"bing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("similarEngines(bingo) = %q, want %q", got, want)
+	}
+	if got, want := similarEngines("bing ", known), []string{"bing", "bing images", "bing news"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("similarEngines(bing) = %q, want %q", got, want)
+	}
+	if got, want := similarEngines("bing new", known), []string{"bing news"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("similarEngines(bing new) = %q, want %q", got, want)
+	}
+	if got := editDistance("kitten", "sitting"); got != 3 {
+		t.Errorf("editDistance = %d, want 3", got)
+	}
+}
+
+func TestCheckEngineNamesDryRun(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEngineNames(t)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "engines": "nonexistent", "dry_run": true})
+	if err != nil || result.IsError {
+		t.Fatalf("dry run: %+v, %v", result, err)
+	}
+	if _, ok := fake.LastRequest("/config"); ok {
+		t.Error("a dry run fetched /config")
+	}
+}
+
+func TestInstanceConfigSingleFetch(t *testing.T) {
+	fake := useFakeInstance(t)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	fake.Handle("/config", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]interface{}{"engines": []interface{}{map[string]interface{}{"name": "google", "shortcut": "go", "categories": []interface{}{"general"}}}})
+	})
+
+	var wg sync.WaitGroup
+	catalogs := make([]*instanceCatalog, 5)
+	for i := range catalogs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			catalogs[i], _ = instanceConfigs.get(context.Background())
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("/config fetched %d times, want once", n)
+	}
+	for _, catalog := range catalogs {
+		if catalog == nil || catalog.shortcuts["go"] != "google" || !catalog.categories["general"] {
+			t.Fatalf("catalog = %+v", catalog)
+		}
+	}
+}
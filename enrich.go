@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// provenanceServer is the source of enrichments computed by this server
+// without contacting any other service.
+const provenanceServer = "server"
+
+// fieldProvenance records how an enrichment changed or added one result
+// field, so consumers can trust or revert it.
+type fieldProvenance struct {
+	Field string `json:"field"`
+	// Original is the value before the enrichment, empty for added fields.
+	Original       string `json:"original,omitempty"`
+	Transformation string `json:"transformation"`
+	// Source is what produced the new value: "server" or the URL of the
+	// service asked.
+	Source string `json:"source"`
+}
+
+// annotatedResult is a search result with the provenance of every field
+// enrichments touched.
+type annotatedResult struct {
+	// EvidenceID identifies the result in the evidence pool of the
+	// session, e.g. "E12".
+	EvidenceID string `json:"evidence_id,omitempty"`
+	searxng.SearchResult
+	// DetectedLanguage is the language of the title and content, empty
+	// when they carry too little signal to tell.
+	DetectedLanguage string `json:"detected_language,omitempty"`
+	// SearchLanguages are the languages of the searches that returned the
+	// result, set with the languages argument.
+	SearchLanguages []string          `json:"search_languages,omitempty"`
+	Provenance      []fieldProvenance `json:"provenance,omitempty"`
+}
+
+// setField replaces *value with newValue and records the change under
+// field. Setting the current value records nothing.
+func (r *annotatedResult) setField(field string, value *string, newValue, transformation, source string) {
+	if *value == newValue {
+		return
+	}
+	r.Provenance = append(r.Provenance, fieldProvenance{
+		Field:          field,
+		Original:       *value,
+		Transformation: transformation,
+		Source:         source,
+	})
+	*value = newValue
+}
+
+// resultEnrichers run in order on every result of enriched tools.
+var resultEnrichers = []func(r *annotatedResult){
+	stripTrackingParameters,
+	detectResultLanguage,
+}
+
+// enrichResults wraps results and runs the enrichers on them.
+func enrichResults(results []searxng.SearchResult) []annotatedResult {
+	enriched := make([]annotatedResult, len(results))
+	for i, result := range results {
+		enriched[i].SearchResult = result
+		for _, enrich := range resultEnrichers {
+			enrich(&enriched[i])
+		}
+	}
+	return enriched
+}
+
+// trackingParameters are query parameters that only identify campaigns or
+// clicks; prefixes end with "_".
+var trackingParameters = []string{
+	"utm_", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid",
+	"_hsenc", "_hsmi", "igshid", "ref_src",
+}
+
+func isTrackingParameter(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range trackingParameters {
+		if name == p || (strings.HasSuffix(p, "_") && strings.HasPrefix(name, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTrackingParameters removes click and campaign identifiers from the
+// result URL, so the same page found through different links is cited
+// under one URL.
+func stripTrackingParameters(r *annotatedResult) {
+	u, err := url.Parse(r.URL)
+	if err != nil || u.RawQuery == "" {
+		return
+	}
+	query := u.Query()
+	stripped := false
+	for name := range query {
+		if isTrackingParameter(name) {
+			query.Del(name)
+			stripped = true
+		}
+	}
+	if !stripped {
+		return
+	}
+	u.RawQuery = query.Encode()
+	r.setField("url", &r.URL, u.String(), "removed_tracking_parameters", provenanceServer)
+}
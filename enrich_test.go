@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestStripTrackingParameters(t *testing.T) {
+	tests := []struct {
+		url        string
+		want       string
+		provenance []fieldProvenance
+	}{
+		{"https://a.example/p?id=1", "https://a.example/p?id=1", nil},
+		{"https://a.example/p", "https://a.example/p", nil},
+		{
+			"https://a.example/p?id=1&utm_source=x&UTM_Medium=y&fbclid=z#top",
+			"https://a.example/p?id=1#top",
+			[]fieldProvenance{{
+				Field:          "url",
+				Original:       "https://a.example/p?id=1&utm_source=x&UTM_Medium=y&fbclid=z#top",
+				Transformation: "removed_tracking_parameters",
+				Source:         provenanceServer,
+			}},
+		},
+		{"https://a.example/?gclid=1", "https://a.example/", []fieldProvenance{{
+			Field: "url", Original: "https://a.example/?gclid=1", Transformation: "removed_tracking_parameters", Source: provenanceServer,
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got := enrichResults([]searxng.SearchResult{{URL: tt.url}})[0]
+			if got.URL != tt.want {
+				t.Errorf("url = %q, want %q", got.URL, tt.want)
+			}
+			if !reflect.DeepEqual(got.Provenance, tt.provenance) {
+				t.Errorf("provenance = %+v, want %+v", got.Provenance, tt.provenance)
+			}
+		})
+	}
+}
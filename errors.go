@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Error codes of tool error results, the categories agents recover by.
+const (
+	errorRateLimited   = "rate_limited"
+	errorInstanceDown  = "instance_down"
+	errorEngineError   = "engine_error"
+	errorInvalidParams = "invalid_params"
+	errorTimeout       = "timeout"
+	// errorMisconfigured is an instance that answers but not as a SearXNG
+	// JSON API: retrying does not help, the operator must fix it.
+	errorMisconfigured = "instance_misconfigured"
+	errorCanceled      = "canceled"
+	errorUpstream      = "upstream_error"
+)
+
+const (
+	// defaultRateLimitWait is suggested on 429 responses without
+	// Retry-After.
+	defaultRateLimitWait = 30 * time.Second
+	// serverErrorWait is suggested after 5xx responses.
+	serverErrorWait = 5 * time.Second
+)
+
+// toolError is the machine-readable part of a tool error result.
+type toolError struct {
+	Code    string `json:"error_code"`
+	Message string `json:"message,omitempty"`
+	// RetryAfterSeconds is set when the same call may succeed after this
+	// wait.
+	RetryAfterSeconds int `json:"retry_after_seconds,omitempty"`
+	// TryOtherEngines is set when the call may succeed with other or
+	// fewer engines.
+	TryOtherEngines bool   `json:"try_other_engines,omitempty"`
+	Instance        string `json:"instance,omitempty"`
+}
+
+// errorMetaKey is the _meta field of tool error results holding their
+// toolError.
+const errorMetaKey = "error"
+
+// errorResult returns a tool error result for e. The text is the message
+// followed by e as a JSON line, for clients that show the model only the
+// text, and _meta carries e as well. Errors have no structured content:
+// it would have to match the output schema of the tool.
+func errorResult(e toolError) *mcp.CallToolResult {
+	line := e
+	line.Message = ""
+	data, _ := json.Marshal(line)
+	result := mcp.NewToolResultError(e.Message + "\n" + string(data))
+	result.Meta = &mcp.Meta{AdditionalFields: map[string]any{errorMetaKey: e}}
+	return result
+}
+
+// resultError returns the toolError of a tool error result, false for
+// results without one.
+func resultError(result *mcp.CallToolResult) (toolError, bool) {
+	if result == nil || !result.IsError || result.Meta == nil {
+		return toolError{}, false
+	}
+	e, ok := result.Meta.AdditionalFields[errorMetaKey].(toolError)
+	return e, ok
+}
+
+// errorCode returns the error code of a tool error result, empty for
+// results without one.
+func errorCode(result *mcp.CallToolResult) string {
+	e, _ := resultError(result)
+	return e.Code
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// upstreamErrorResult turns a failed call to the instance into a tool error
+// result the model can act on: which instance failed, why, and what to try
+// next. action names the operation, e.g. "search".
+func upstreamErrorResult(action string, err error) *mcp.CallToolResult {
+	var policyErr *policyError
+	if errors.As(err, &policyErr) {
+		return errorResult(toolError{
+			Code:    errorInvalidParams,
+			Message: fmt.Sprintf("%s refused: %v; search without them", action, err),
+		})
+	}
+	e := classifyUpstreamError(err)
+	e.Instance = currentState().activeInstance().BaseURL
+	var openErr *searxng.CircuitOpenError
+	if errors.As(err, &openErr) {
+		e.Instance = openErr.Instance
+	}
+	e.Message = fmt.Sprintf("%s failed on SearXNG instance %s: %s", action, e.Instance, e.Message)
+	return errorResult(e)
+}
+
+// invalidArgumentsResult reports arguments the tool cannot use.
+func invalidArgumentsResult(err error) *mcp.CallToolResult {
+	return errorResult(toolError{Code: errorInvalidParams, Message: fmt.Sprintf("invalid arguments: %v", err)})
+}
+
+// engineErrorResult reports that the engines gave nothing the tool could
+// use, e.g. no answer to a conversion; other engines may.
+func engineErrorResult(message string) *mcp.CallToolResult {
+	return errorResult(toolError{Code: errorEngineError, Message: message, TryOtherEngines: true})
+}
+
+func describeUpstreamError(err error) string {
+	return classifyUpstreamError(err).Message
+}
+
+// classifyUpstreamError returns the error code of a failed call to the
+// instance, a message naming the cause with a hint, and the suggested
+// recovery.
+func classifyUpstreamError(err error) toolError {
+	var openErr *searxng.CircuitOpenError
+	if errors.As(err, &openErr) {
+		return toolError{
+			Code: errorInstanceDown,
+			Message: fmt.Sprintf("the instance failed %d times in a row and is paused until %s; retry after that or check searxng_instance_status",
+				openErr.Failures, openErr.Until.Format(time.TimeOnly)),
+			RetryAfterSeconds: max(retryAfterSeconds(time.Until(openErr.Until)), 1),
+		}
+	}
+
+	var httpErr *searxng.HTTPError
+	if errors.As(err, &httpErr) {
+		status := fmt.Sprintf("HTTP %d %s", httpErr.StatusCode, http.StatusText(httpErr.StatusCode))
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			hint, wait := "retry later", defaultRateLimitWait
+			if httpErr.RetryAfter > 0 {
+				hint, wait = fmt.Sprintf("retry after %s", httpErr.RetryAfter), httpErr.RetryAfter
+			}
+			return toolError{
+				Code:              errorRateLimited,
+				Message:           fmt.Sprintf("%s, the instance is rate limiting requests; %s or use fewer engines", status, hint),
+				RetryAfterSeconds: retryAfterSeconds(wait),
+				TryOtherEngines:   true,
+			}
+		case httpErr.StatusCode == http.StatusForbidden && httpErr.Endpoint == "/search":
+			return toolError{Code: errorMisconfigured, Message: fmt.Sprintf("%s, the instance refuses JSON searches; the operator must enable the json format in settings.yml or use -search-method post", status)}
+		case httpErr.StatusCode == http.StatusNotFound:
+			return toolError{Code: errorMisconfigured, Message: fmt.Sprintf("%s, the endpoint does not exist; check the instance URL and -path-prefix", status)}
+		case httpErr.StatusCode == http.StatusGatewayTimeout:
+			return toolError{Code: errorTimeout, Message: fmt.Sprintf("%s, the instance timed out; retry with fewer engines or a more specific query", status), TryOtherEngines: true}
+		case httpErr.StatusCode >= 500:
+			return toolError{Code: errorInstanceDown, Message: fmt.Sprintf("%s, the instance failed internally; retry in a moment", status), RetryAfterSeconds: retryAfterSeconds(serverErrorWait)}
+		}
+		return toolError{Code: errorUpstream, Message: status}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return toolError{Code: errorTimeout, Message: "the request timed out; retry with fewer engines or a more specific query", TryOtherEngines: true}
+	case errors.Is(err, context.Canceled):
+		return toolError{Code: errorCanceled, Message: "the request was canceled"}
+	case strings.Contains(err.Error(), "error parsing JSON"):
+		return toolError{Code: errorMisconfigured, Message: fmt.Sprintf("%v; the URL may not point to a SearXNG instance", err)}
+	case strings.Contains(err.Error(), "connection refused"), strings.Contains(err.Error(), "no such host"):
+		return toolError{Code: errorInstanceDown, Message: fmt.Sprintf("the instance is unreachable (%v); it may be down", err)}
+	}
+	return toolError{Code: errorUpstream, Message: err.Error()}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// maxEvidencePerSession bounds the pool of one session; further
+	// results get no evidence ID.
+	maxEvidencePerSession = 1000
+	// evidenceSessionIdle is how long the pool of an idle session is kept.
+	evidenceSessionIdle = 24 * time.Hour
+	// defaultSessionID keys the pool when the transport has no sessions.
+	defaultSessionID = "default"
+)
+
+// evidenceItem is a result collected into the evidence pool. Its ID stays
+// the same for the whole session, whichever search finds it again.
+type evidenceItem struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+	Engine  string `json:"engine,omitempty"`
+	// Queries are the searches that returned the result, first one first.
+	Queries   []string  `json:"queries"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+type sessionEvidence struct {
+	items []*evidenceItem
+	byURL map[string]*evidenceItem
+	// last are the items of the latest search, in result order.
+	last     []*evidenceItem
+	lastUsed time.Time
+}
+
+// evidencePool collects the results of every search per MCP session,
+// deduplicated by URL, so agents can cite them as [E12] across a long
+// research conversation.
+type evidencePool struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionEvidence
+}
+
+var evidence = &evidencePool{sessions: make(map[string]*sessionEvidence)}
+
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil && session.SessionID() != "" {
+		return session.SessionID()
+	}
+	return defaultSessionID
+}
+
+// session returns the evidence of the session, dropping idle sessions.
+// The caller holds p.mu.
+func (p *evidencePool) session(id string) *sessionEvidence {
+	now := time.Now()
+	for other, s := range p.sessions {
+		if now.Sub(s.lastUsed) > evidenceSessionIdle {
+			delete(p.sessions, other)
+		}
+	}
+	s, ok := p.sessions[id]
+	if !ok {
+		s = &sessionEvidence{byURL: make(map[string]*evidenceItem)}
+		p.sessions[id] = s
+	}
+	s.lastUsed = now
+	return s
+}
+
+// addResults adds results found by query to the pool of the session and
+// sets their evidence IDs.
+func (p *evidencePool) addResults(ctx context.Context, query string, results []annotatedResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.session(sessionID(ctx))
+	s.last = nil
+	for i := range results {
+		r := &results[i]
+		item, ok := s.byURL[r.URL]
+		if !ok {
+			if len(s.items) >= maxEvidencePerSession {
+				continue
+			}
+			item = &evidenceItem{
+				ID:        fmt.Sprintf("E%d", len(s.items)+1),
+				Title:     r.Title,
+				URL:       r.URL,
+				Content:   r.Content,
+				Engine:    r.Engine,
+				FirstSeen: time.Now(),
+			}
+			s.items = append(s.items, item)
+			s.byURL[r.URL] = item
+		}
+		if !containsString(item.Queries, query) {
+			item.Queries = append(item.Queries, query)
+		}
+		r.EvidenceID = item.ID
+		s.last = append(s.last, item)
+	}
+}
+
+// dropSeen removes the results the session was already given, so that
+// paginating or reformulating a query surfaces new pages only. It returns
+// the remaining results and the number of removed ones.
+func (p *evidencePool) dropSeen(ctx context.Context, results []annotatedResult) ([]annotatedResult, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.session(sessionID(ctx))
+	fresh := make([]annotatedResult, 0, len(results))
+	for _, r := range results {
+		if _, seen := s.byURL[r.URL]; !seen {
+			fresh = append(fresh, r)
+		}
+	}
+	return fresh, len(results) - len(fresh)
+}
+
+// list returns copies of the items of the session matching filter (case
+// insensitive, on title, URL and content), in ID order.
+func (p *evidencePool) list(ctx context.Context, filter string) []evidenceItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	filter = strings.ToLower(filter)
+	items := []evidenceItem{}
+	for _, item := range p.session(sessionID(ctx)).items {
+		if filter == "" || strings.Contains(strings.ToLower(item.Title+" "+item.URL+" "+item.Content), filter) {
+			items = append(items, copyEvidence(item))
+		}
+	}
+	return items
+}
+
+// get returns the items with the given IDs and the IDs that are unknown.
+func (p *evidencePool) get(ctx context.Context, ids []string) ([]evidenceItem, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.session(sessionID(ctx))
+	items := []evidenceItem{}
+	var missing []string
+	for _, id := range ids {
+		var n int
+		if _, err := fmt.Sscanf(strings.ToUpper(id), "E%d", &n); err != nil || n < 1 || n > len(s.items) {
+			missing = append(missing, id)
+			continue
+		}
+		items = append(items, copyEvidence(s.items[n-1]))
+	}
+	return items, missing
+}
+
+// latest returns copies of the items of the latest search of the session.
+func (p *evidencePool) latest(ctx context.Context) []evidenceItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items := []evidenceItem{}
+	for _, item := range p.session(sessionID(ctx)).last {
+		items = append(items, copyEvidence(item))
+	}
+	return items
+}
+
+func copyEvidence(item *evidenceItem) evidenceItem {
+	c := *item
+	c.Queries = append([]string(nil), item.Queries...)
+	return c
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func skipSeenOption() mcp.ToolOption {
+	return mcp.WithBoolean("skip_seen",
+		mcp.Description("Leave out results already returned earlier in this session, to get new pages when paginating or reformulating a query"),
+	)
+}
+
+func listEvidenceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter, _ := request.GetArguments()["filter"].(string)
+	items := evidence.list(ctx, filter)
+
+	// The listing is an index: content is left to get_evidence.
+	for i := range items {
+		items[i].Content = ""
+	}
+
+	return structuredResult(evidenceResponse{Evidence: items})
+}
+
+// evidenceIDs reads the ids argument, a list like "[E1], E12" or
+// ["E1", "[E12]"].
+func evidenceIDs(arguments map[string]interface{}) ([]string, error) {
+	list, _, err := listArgument(arguments, "ids")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, id := range list {
+		if id = strings.Trim(id, "[] "); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func getEvidenceHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ids, err := evidenceIDs(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if len(ids) == 0 {
+		return invalidArgumentsResult(errors.New("ids must list evidence IDs, e.g. \"E1,E12\"")), nil
+	}
+
+	items, missing := evidence.get(ctx, ids)
+	response := evidenceResponse{Evidence: items, UnknownIDs: missing}
+
+	return structuredResult(response)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// useEvidencePool gives the test an empty evidence pool.
+func useEvidencePool(t *testing.T) {
+	t.Helper()
+	previous := evidence
+	evidence = &evidencePool{sessions: make(map[string]*sessionEvidence)}
+	t.Cleanup(func() { evidence = previous })
+}
+
+func TestEvidencePool(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Go", "url": "https://go.dev", "content": "The Go language", "engine": "google"},
+			{"title": "Go wiki", "url": "https://go.dev/wiki?utm_source=x", "engine": "google"},
+		},
+	})
+	if _, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Rust", "url": "https://rust-lang.org", "engine": "bing"},
+			{"title": "Go", "url": "https://go.dev", "engine": "bing"},
+		},
+	})
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "languages"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if got := []string{response.Results[0].EvidenceID, response.Results[1].EvidenceID}; !reflect.DeepEqual(got, []string{"E3", "E1"}) {
+		t.Errorf("evidence IDs = %v, want [E3 E1]", got)
+	}
+
+	result, err = callTool(t, listEvidenceHandler, map[string]interface{}{"filter": "GO.DEV"})
+	if err != nil {
+		t.Fatalf("list_evidence: %v", err)
+	}
+	var listed struct {
+		Evidence []evidenceItem `json:"evidence"`
+	}
+	decodeResult(t, result, &listed)
+	if len(listed.Evidence) != 2 || listed.Evidence[1].URL != "https://go.dev/wiki" {
+		t.Fatalf("listed = %+v", listed.Evidence)
+	}
+	if got := listed.Evidence[0].Queries; !reflect.DeepEqual(got, []string{"golang", "languages"}) {
+		t.Errorf("queries of E1 = %v", got)
+	}
+	if listed.Evidence[0].Content != "" {
+		t.Error("list_evidence should leave content out")
+	}
+
+	result, err = callTool(t, getEvidenceHandler, map[string]interface{}{"ids": "[E1], e3, E99"})
+	if err != nil {
+		t.Fatalf("get_evidence: %v", err)
+	}
+	var got struct {
+		Evidence   []evidenceItem `json:"evidence"`
+		UnknownIDs []string       `json:"unknown_ids"`
+	}
+	decodeResult(t, result, &got)
+	if len(got.Evidence) != 2 || got.Evidence[0].Content != "The Go language" || got.Evidence[1].Title != "Rust" {
+		t.Errorf("evidence = %+v", got.Evidence)
+	}
+	if !reflect.DeepEqual(got.UnknownIDs, []string{"E99"}) {
+		t.Errorf("unknown_ids = %v", got.UnknownIDs)
+	}
+
+	result, _ = callTool(t, getEvidenceHandler, map[string]interface{}{"ids": " , "})
+	if !result.IsError {
+		t.Error("want an error result without ids")
+	}
+}
+
+func TestSkipSeen(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Go", "url": "https://go.dev", "engine": "google"},
+			{"title": "Tour", "url": "https://go.dev/tour", "engine": "google"},
+			{"title": "Wiki", "url": "https://go.dev/wiki", "engine": "google"},
+		},
+	})
+	if _, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "max_results": 1}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Go", "url": "https://go.dev?utm_source=x", "engine": "bing"},
+			{"title": "Tour", "url": "https://go.dev/tour", "engine": "bing"},
+			{"title": "Wiki", "url": "https://go.dev/wiki", "engine": "bing"},
+		},
+	})
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "go language", "skip_seen": true, "max_results": 1})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 || response.Results[0].URL != "https://go.dev/tour" {
+		t.Fatalf("results = %+v, want the tour only", response.Results)
+	}
+	if response.Meta.SkippedSeenResults != 1 {
+		t.Errorf("skipped_seen_results = %d, want 1", response.Meta.SkippedSeenResults)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "go language"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var unfiltered searchV2Response
+	decodeResult(t, result, &unfiltered)
+	if len(unfiltered.Results) != 3 || unfiltered.Meta.SkippedSeenResults != 0 {
+		t.Errorf("without skip_seen got %d results, %d skipped", len(unfiltered.Results), unfiltered.Meta.SkippedSeenResults)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Formats of export_results.
+const (
+	exportCSV           = "csv"
+	exportJSONL         = "jsonl"
+	exportMarkdownTable = "markdown_table"
+)
+
+func exportResultsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	format, _ := request.GetArguments()["format"].(string)
+	render, ok := map[string]func([]evidenceItem) (string, error){
+		exportCSV:           exportAsCSV,
+		exportJSONL:         exportAsJSONL,
+		exportMarkdownTable: exportAsMarkdownTable,
+	}[format]
+	if !ok {
+		return invalidArgumentsResult(fmt.Errorf("unknown format %q: use csv, jsonl or markdown_table", format)), nil
+	}
+
+	var items []evidenceItem
+	ids, err := evidenceIDs(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if len(ids) > 0 {
+		var missing []string
+		items, missing = evidence.get(ctx, ids)
+		if len(missing) > 0 {
+			return invalidArgumentsResult(fmt.Errorf("unknown evidence IDs: %s", strings.Join(missing, ", "))), nil
+		}
+	} else {
+		items = evidence.latest(ctx)
+	}
+	if len(items) == 0 {
+		return errorResult(toolError{Code: errorInvalidParams, Message: "No results to export: search first, or give evidence IDs"}), nil
+	}
+
+	text, err := render(items)
+	if err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+
+	return mcp.NewToolResultText(text), nil
+}
+
+func exportAsCSV(items []evidenceItem) (string, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write([]string{"id", "title", "url", "engine", "content", "queries"})
+	for _, item := range items {
+		row := []string{item.ID, item.Title, item.URL, item.Engine, collapse(item.Content), strings.Join(item.Queries, "; ")}
+		for i, cell := range row {
+			row[i] = csvCell(cell)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// csvCell keeps spreadsheets from evaluating a cell of page text as a
+// formula: cells starting like one are prefixed with a quote.
+func csvCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func exportAsJSONL(items []evidenceItem) (string, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+var tableCellEscaper = strings.NewReplacer("|", `\|`)
+
+func exportAsMarkdownTable(items []evidenceItem) (string, error) {
+	var b strings.Builder
+	b.WriteString("| ID | Title | URL | Engine | Content |\n|---|---|---|---|---|\n")
+	for _, item := range items {
+		cells := []string{item.ID, item.Title, item.URL, item.Engine, item.Content}
+		for i, cell := range cells {
+			cells[i] = tableCellEscaper.Replace(collapse(cell))
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+	}
+	return b.String(), nil
+}
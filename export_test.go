@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestExportResults(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+
+	if result, _ := callTool(t, exportResultsHandler, map[string]interface{}{}); errorCode(result) != errorInvalidParams {
+		t.Errorf("export without a search = %+v, want an invalid_params error", result)
+	}
+
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Old", "url": "https://old.example", "engine": "bing"},
+		},
+	})
+	callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "first"})
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Pipes | Tables", "url": "https://a.example", "content": "line one,\nline \"two\"", "engine": "google"},
+			{"title": "B", "url": "https://b.example", "engine": "google"},
+		},
+	})
+	callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "second"})
+
+	export := func(arguments map[string]interface{}) string {
+		t.Helper()
+		result, err := callTool(t, exportResultsHandler, arguments)
+		if err != nil || result.IsError {
+			t.Fatalf("export_results %v: %+v, %v", arguments, result, err)
+		}
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	records, err := csv.NewReader(strings.NewReader(export(map[string]interface{}{"format": exportCSV}))).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if len(records) != 3 || records[1][0] != "E2" || records[1][4] != `line one, line "two"` || records[2][2] != "https://b.example" {
+		t.Errorf("csv records = %q, want the latest search", records)
+	}
+
+	lines := strings.Split(strings.TrimSpace(export(map[string]interface{}{"format": exportJSONL, "ids": "E3,E1"})), "\n")
+	var item evidenceItem
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[1]), &item) != nil || item.URL != "https://old.example" {
+		t.Errorf("jsonl = %q", lines)
+	}
+
+	table := export(map[string]interface{}{"format": exportMarkdownTable})
+	if !strings.Contains(table, `| E2 | Pipes \| Tables | https://a.example | google | line one, line "two" |`) {
+		t.Errorf("markdown table:\n%s", table)
+	}
+
+	for _, arguments := range []map[string]interface{}{
+		{"format": "xlsx"},
+		{"format": exportCSV, "ids": "E9"},
+	} {
+		if result, _ := callTool(t, exportResultsHandler, arguments); !result.IsError {
+			t.Errorf("want an error result for %v", arguments)
+		}
+	}
+}
+
+func TestExportAsCSVFormulas(t *testing.T) {
+	out, err := exportAsCSV([]evidenceItem{{ID: "E1", Title: "=HYPERLINK(\"https://evil.example\")", URL: "https://a.example/", Content: "-5 degrees"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row := records[1]; row[1] != `'=HYPERLINK("https://evil.example")` || row[4] != "'-5 degrees" || row[2] != "https://a.example/" {
+		t.Errorf("row = %q, want formulas quoted", row)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/net/html"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	// maxFeedSites is the number of distinct sites checked for a query.
+	maxFeedSites = 3
+	// feedSniffBytes is how much of a probed URL is read to recognize a feed.
+	feedSniffBytes = 1024
+)
+
+// feedPaths are probed on every site; they cover the usual blog engines
+// (WordPress, Ghost, Hugo, Jekyll, Blogger, Substack, Medium).
+var feedPaths = []string{
+	"/feed", "/rss", "/rss.xml", "/feed.xml", "/atom.xml", "/index.xml",
+	"/feed/", "/feeds/posts/default",
+}
+
+// feedTypes maps the MIME types of <link rel="alternate"> tags to feed
+// formats.
+var feedTypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+}
+
+type feed struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"`
+	// Source tells how the feed was found: "link" for a <link> tag of the
+	// home page, "probe" for a common feed path.
+	Source string `json:"source"`
+}
+
+type siteFeeds struct {
+	Site  string `json:"site"`
+	Feeds []feed `json:"feeds"`
+	Error string `json:"error,omitempty"`
+}
+
+func searxngFindFeedsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	site, _ := request.GetArguments()["site"].(string)
+	query, _ := request.GetArguments()["query"].(string)
+
+	var sites []string
+	var progress *progressReporter
+	switch {
+	case site != "":
+		origin, err := siteOrigin(site)
+		if err != nil {
+			return invalidArgumentsResult(err), nil
+		}
+		sites = []string{origin}
+		progress = newProgressReporter(ctx, request, 1)
+	case query != "":
+		progress = newProgressReporter(ctx, request, 1+maxFeedSites)
+		result, _, err := runSearch(ctx, searxng.SearchParams{
+			Query:      query,
+			Categories: []string{"general"},
+			Language:   stateOf(ctx).language,
+		})
+		if err != nil {
+			return upstreamErrorResult("search", err), nil
+		}
+		sites = resultSites(result.Results, maxFeedSites)
+		progress.step(fmt.Sprintf("Searched %q", query))
+		progress.addTotal(len(sites) - maxFeedSites)
+	default:
+		return invalidArgumentsResult(errors.New("site or query is required")), nil
+	}
+
+	response := make([]siteFeeds, len(sites))
+	var wg sync.WaitGroup
+	for i, origin := range sites {
+		response[i].Site = origin
+		wg.Add(1)
+		go func(found *siteFeeds) {
+			defer wg.Done()
+			defer progress.step("Checked " + found.Site)
+			found.Feeds, found.Error = discoverFeeds(ctx, found.Site)
+		}(&response[i])
+	}
+	wg.Wait()
+
+	return structuredResult(feedsResponse{Sites: response})
+}
+
+// siteOrigin turns "example.com" or any URL of a site into its origin,
+// e.g. "https://example.com".
+func siteOrigin(site string) (string, error) {
+	if !strings.Contains(site, "://") {
+		site = "https://" + site
+	}
+	u, err := url.Parse(site)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid site %q", site)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// resultSites returns the origins of the first n distinct sites among
+// results.
+func resultSites(results []searxng.SearchResult, n int) []string {
+	var sites []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		origin, err := siteOrigin(r.URL)
+		if err != nil || seen[origin] {
+			continue
+		}
+		seen[origin] = true
+		sites = append(sites, origin)
+		if len(sites) == n {
+			break
+		}
+	}
+	return sites
+}
+
+// discoverFeeds reads the feed links of the home page of origin and probes
+// the common feed paths. Feeds found both ways are reported once, as links.
+func discoverFeeds(ctx context.Context, origin string) ([]feed, string) {
+	feeds := []feed{}
+	seen := make(map[string]bool)
+	var homeErr error
+
+	doc, base, _, err := fetchDocument(ctx, origin+"/")
+	if err != nil {
+		homeErr = err
+	} else {
+		for _, f := range feedLinks(doc, base) {
+			if !seen[f.URL] {
+				seen[f.URL] = true
+				feeds = append(feeds, f)
+			}
+		}
+	}
+
+	probed := make([]*feed, len(feedPaths))
+	var wg sync.WaitGroup
+	for i, path := range feedPaths {
+		wg.Add(1)
+		go func(i int, feedURL string) {
+			defer wg.Done()
+			if feedType, ok := probeFeed(ctx, feedURL); ok {
+				probed[i] = &feed{URL: feedURL, Type: feedType, Source: "probe"}
+			}
+		}(i, origin+path)
+	}
+	wg.Wait()
+
+	for _, f := range probed {
+		if f != nil && !seen[f.URL] && !seen[strings.TrimSuffix(f.URL, "/")] {
+			seen[f.URL] = true
+			feeds = append(feeds, *f)
+		}
+	}
+
+	if len(feeds) == 0 && homeErr != nil {
+		return feeds, homeErr.Error()
+	}
+	return feeds, ""
+}
+
+// feedLinks returns the feeds announced by <link rel="alternate"> tags.
+func feedLinks(doc *html.Node, base *url.URL) []feed {
+	var feeds []feed
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			attrs := make(map[string]string)
+			for _, a := range n.Attr {
+				attrs[strings.ToLower(a.Key)] = a.Val
+			}
+			feedType, ok := feedTypes[strings.ToLower(strings.TrimSpace(attrs["type"]))]
+			if ok && strings.Contains(strings.ToLower(attrs["rel"]), "alternate") && attrs["href"] != "" {
+				if href, err := base.Parse(attrs["href"]); err == nil {
+					feeds = append(feeds, feed{URL: href.String(), Title: attrs["title"], Type: feedType, Source: "link"})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return feeds
+}
+
+// probeFeed requests feedURL and recognizes RSS, Atom and JSON Feed
+// documents by their first bytes.
+func probeFeed(ctx context.Context, feedURL string) (string, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("User-Agent", stateOf(ctx).client.UserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9")
+
+	resp, err := stateOf(ctx).fetchPolicy.do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, feedSniffBytes))
+	return sniffFeed(head)
+}
+
+func sniffFeed(head []byte) (string, bool) {
+	head = bytes.ToLower(head)
+	switch {
+	case bytes.Contains(head, []byte("<rss")), bytes.Contains(head, []byte("<rdf:rdf")):
+		return "rss", true
+	case bytes.Contains(head, []byte("<feed")):
+		return "atom", true
+	case bytes.Contains(head, []byte("jsonfeed.org/version")):
+		return "json", true
+	}
+	return "", false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestFindFeedsHandler(t *testing.T) {
+	useFakeInstance(t)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head>
+<link rel="alternate" type="application/rss+xml" title="Blog" href="/blog/rss.xml">
+<link rel="alternate" type="application/json" href="/wp-json/">
+<link rel="stylesheet" href="/style.css">
+</head><body></body></html>`))
+		case "/blog/rss.xml":
+			w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"></rss>`))
+		case "/atom.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+		case "/feed":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html>not a feed</html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	result, err := callTool(t, searxngFindFeedsHandler, map[string]interface{}{"site": site.URL + "/some/page"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response struct {
+		Sites []siteFeeds `json:"sites"`
+	}
+	decodeResult(t, result, &response)
+	if len(response.Sites) != 1 || response.Sites[0].Site != site.URL {
+		t.Fatalf("sites = %+v", response.Sites)
+	}
+	want := []feed{
+		{URL: site.URL + "/blog/rss.xml", Title: "Blog", Type: "rss", Source: "link"},
+		{URL: site.URL + "/atom.xml", Type: "atom", Source: "probe"},
+	}
+	got := response.Sites[0].Feeds
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("feeds = %+v, want %+v", got, want)
+	}
+
+	result, _ = callTool(t, searxngFindFeedsHandler, map[string]interface{}{})
+	if !result.IsError {
+		t.Error("want an error result without site and query")
+	}
+}
+
+func TestResultSites(t *testing.T) {
+	results := []searxng.SearchResult{
+		{URL: "https://a.example/x"}, {URL: "https://a.example/y"}, {URL: "http://b.example/"}, {URL: "https://c.example"},
+	}
+	got := resultSites(results, 2)
+	if len(got) != 2 || got[0] != "https://a.example" || got[1] != "http://b.example" {
+		t.Errorf("resultSites = %q", got)
+	}
+}
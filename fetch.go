@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const maxPageBytes = 2 << 20
+
+var pageClient = &http.Client{
+	Timeout: 15 * time.Second,
+}
+
+// Page is the readable part of a fetched web page.
+type Page struct {
+	URL   string
+	Title string
+	// Blocks are the text blocks of the page (paragraphs, headings, list
+	// items, table cells) in document order.
+	Blocks []string
+	// Pairs are label/value pairs found in two-column table rows and
+	// definition lists.
+	Pairs [][2]string
+	// Size is the size of the HTML document in bytes.
+	Size int
+	// PDFPages is the page count of a PDF document, 0 for HTML pages.
+	PDFPages int
+}
+
+// Text returns the page blocks joined into plain text.
+func (p *Page) Text() string {
+	return strings.Join(p.Blocks, "\n")
+}
+
+// pageAccept is the Accept header of page fetches.
+const pageAccept = "text/html,application/xhtml+xml,application/pdf;q=0.9"
+
+// fetchPage returns the readable part of a page, from the page cache when
+// it holds the page.
+func fetchPage(ctx context.Context, pageURL string) (*Page, error) {
+	return pageCache.fetch(ctx, pageURL)
+}
+
+// downloadPage fetches and extracts a page. Given validators of a cached
+// copy, it returns errNotModified when the page did not change.
+func downloadPage(ctx context.Context, pageURL string, validators pageValidators) (*Page, pageValidators, error) {
+	resp, err := openPage(ctx, pageURL, pageAccept, validators)
+	if err != nil {
+		return nil, pageValidators{}, err
+	}
+	defer resp.Body.Close()
+	validators = pageValidators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+
+	if isPDF(resp) {
+		page, err := readPDF(ctx, pageURL, resp)
+		return page, validators, err
+	}
+	doc, size, err := parseHTML(ctx, resp)
+	if err != nil {
+		return nil, pageValidators{}, err
+	}
+	page := &Page{URL: pageURL, Size: size}
+	extractPage(doc, page)
+	return page, validators, nil
+}
+
+// fetchDocument fetches and parses an HTML page. It also returns the final
+// URL after redirects, against which relative links resolve, and the size
+// of the document.
+func fetchDocument(ctx context.Context, pageURL string) (*html.Node, *url.URL, int, error) {
+	resp, err := openPage(ctx, pageURL, "text/html,application/xhtml+xml", pageValidators{})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if isPDF(resp) {
+		return nil, nil, 0, fmt.Errorf("unsupported content type %q", resp.Header.Get("Content-Type"))
+	}
+	doc, size, err := parseHTML(ctx, resp)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return doc, resp.Request.URL, size, nil
+}
+
+// openPage requests a page the fetch policy allows and checks the response
+// status, type and size, conditionally when given validators. The caller
+// closes the body.
+func openPage(ctx context.Context, pageURL, accept string, validators pageValidators) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", stateOf(ctx).client.UserAgent)
+	req.Header.Set("Accept", accept)
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := stateOf(ctx).fetchPolicy.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP error %d", resp.StatusCode)
+	}
+	if err := stateOf(ctx).fetchPolicy.checkContentType(resp.Header.Get("Content-Type")); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if limit := stateOf(ctx).fetchPolicy.limit(isPDF(resp)); resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, fmt.Errorf("page is %d bytes, above the %d byte limit", resp.ContentLength, limit)
+	}
+	return resp, nil
+}
+
+func parseHTML(ctx context.Context, resp *http.Response) (*html.Node, int, error) {
+	body := &countingReader{r: io.LimitReader(resp.Body, stateOf(ctx).fetchPolicy.limit(false))}
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error parsing HTML: %w", err)
+	}
+	return doc, body.n, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// skippedElements never contain readable content.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "svg": true,
+	"nav": true, "header": true, "footer": true, "aside": true,
+	"form": true, "iframe": true, "template": true,
+}
+
+// blockElements end the current text block.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "td": true, "th": true, "tr": true, "dt": true, "dd": true,
+	"pre": true, "blockquote": true, "br": true, "figcaption": true,
+}
+
+func extractPage(doc *html.Node, page *Page) {
+	var current strings.Builder
+	flush := func() {
+		text := strings.Join(strings.Fields(current.String()), " ")
+		if text != "" {
+			page.Blocks = append(page.Blocks, text)
+		}
+		current.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.Data == "title" && page.Title == "" {
+				page.Title = strings.TrimSpace(nodeText(n))
+				return
+			}
+			if skippedElements[n.Data] {
+				return
+			}
+			switch n.Data {
+			case "tr":
+				if cells := rowCells(n); len(cells) == 2 {
+					page.Pairs = append(page.Pairs, [2]string{cells[0], cells[1]})
+				}
+			case "dt":
+				if dd := nextElement(n, "dd"); dd != nil {
+					page.Pairs = append(page.Pairs, [2]string{collapse(nodeText(n)), collapse(nodeText(dd))})
+				}
+			}
+		}
+		if n.Type == html.TextNode {
+			current.WriteString(n.Data)
+			current.WriteByte(' ')
+		}
+		block := n.Type == html.ElementNode && blockElements[n.Data]
+		if block {
+			flush()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			flush()
+		}
+	}
+	walk(doc)
+	flush()
+}
+
+func rowCells(tr *html.Node) []string {
+	var cells []string
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+			cells = append(cells, collapse(nodeText(c)))
+		}
+	}
+	return cells
+}
+
+func nextElement(n *html.Node, name string) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			if s.Data == name {
+				return s
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && skippedElements[n.Data] {
+			return
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
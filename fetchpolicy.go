@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	// robotsTTL is how long the robots.txt of a site is kept.
+	robotsTTL = time.Hour
+	// maxRobotsBytes is the part of a robots.txt read, as Google does.
+	maxRobotsBytes = 500 << 10
+	// maxRedirects is the redirect limit of the default HTTP client.
+	maxRedirects = 10
+)
+
+// FetchConfig restricts the tools fetching pages, images and feeds, to
+// enable them safely for untrusted clients:
+//
+//	"fetch": {"respect_robots_txt": true, "max_bytes": 1048576,
+//	          "allowed_content_types": ["text/html", "application/pdf"],
+//	          "block_private_networks": true}
+//
+// MaxBytes and AllowedContentTypes apply to pages; images and feeds have
+// their own limits and types.
+type FetchConfig struct {
+	RespectRobotsTxt bool `json:"respect_robots_txt,omitempty"`
+	// MaxBytes limits HTML pages and PDF documents alike, by default 2 MiB
+	// and 10 MiB.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// AllowedContentTypes are the media types of pages that are read,
+	// default any HTML type and PDF.
+	AllowedContentTypes []string `json:"allowed_content_types,omitempty"`
+	// PDFPages is the number of leading pages read of PDF documents,
+	// default 20.
+	PDFPages int `json:"pdf_pages,omitempty"`
+	// BlockPrivateNetworks refuses loopback, private, link-local (cloud
+	// metadata endpoints) and other non-public addresses, whatever name
+	// or redirect leads there.
+	BlockPrivateNetworks bool `json:"block_private_networks,omitempty"`
+}
+
+// fetchPolicy is the compiled fetch config.
+type fetchPolicy struct {
+	client       *http.Client
+	robots       *robotsCache
+	maxBytes     int64
+	maxPDFBytes  int64
+	pdfPages     int
+	contentTypes map[string]bool
+	blockPrivate bool
+}
+
+func newFetchPolicy(c *FetchConfig) *fetchPolicy {
+	p := &fetchPolicy{client: pageClient, maxBytes: maxPageBytes, maxPDFBytes: maxPDFBytes, pdfPages: defaultPDFPages}
+	if c == nil {
+		return p
+	}
+	if c.MaxBytes > 0 {
+		p.maxBytes, p.maxPDFBytes = c.MaxBytes, c.MaxBytes
+	}
+	if c.PDFPages > 0 {
+		p.pdfPages = c.PDFPages
+	}
+	if len(c.AllowedContentTypes) > 0 {
+		p.contentTypes = make(map[string]bool)
+		for _, contentType := range c.AllowedContentTypes {
+			p.contentTypes[strings.ToLower(strings.TrimSpace(contentType))] = true
+		}
+	}
+
+	client := *pageClient
+	if c.BlockPrivateNetworks {
+		p.blockPrivate = true
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: denyPrivateAddress}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// A proxy would be the only address checked.
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+		client.Transport = transport
+	}
+	if c.RespectRobotsTxt {
+		robotsClient := client
+		robotsClient.CheckRedirect = p.checkRedirect(p.checkAddress)
+		p.robots = &robotsCache{client: &robotsClient, entries: make(map[string]*robotsEntry)}
+	}
+	client.CheckRedirect = p.checkRedirect(func(ctx context.Context, u *url.URL) error {
+		return p.checkURL(ctx, u)
+	})
+	p.client = &client
+	return p
+}
+
+func (p *fetchPolicy) checkRedirect(check func(context.Context, *url.URL) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return check(req.Context(), req.URL)
+	}
+}
+
+// do sends req if the policy allows its URL.
+func (p *fetchPolicy) do(req *http.Request) (*http.Response, error) {
+	if err := p.checkURL(req.Context(), req.URL); err != nil {
+		return nil, err
+	}
+	return p.client.Do(req)
+}
+
+// checkURL refuses URLs that are not plain web addresses, those of private
+// networks when they are blocked, and those robots.txt disallows when it
+// is respected.
+func (p *fetchPolicy) checkURL(ctx context.Context, u *url.URL) error {
+	if err := p.checkAddress(ctx, u); err != nil {
+		return err
+	}
+	if p.robots != nil && userAgentToken() != "" {
+		if err := p.robots.check(ctx, u, userAgentToken()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// privateHosts are names of private addresses, refused before resolving.
+var privateHosts = map[string]bool{
+	"localhost":                true,
+	"metadata":                 true,
+	"metadata.google.internal": true,
+}
+
+func (p *fetchPolicy) checkAddress(_ context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if !p.blockPrivate {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if privateHosts[host] || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("fetching %s is not allowed: private network host", host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddress(ip) {
+		return fmt.Errorf("fetching %s is not allowed: private network address", host)
+	}
+	return nil
+}
+
+// denyPrivateAddress is the dialer control refusing connections to
+// non-public addresses, checked after name resolution so that no DNS
+// name or redirect reaches them.
+func denyPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(ip) {
+		return fmt.Errorf("fetching %s is not allowed: private network address", host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, private in effect.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// limit is the size limit of PDF documents or HTML pages.
+func (p *fetchPolicy) limit(pdf bool) int64 {
+	if pdf {
+		return p.maxPDFBytes
+	}
+	return p.maxBytes
+}
+
+// checkContentType refuses pages of types the policy does not allow. Without
+// an allowlist any HTML type and PDF are read.
+func (p *fetchPolicy) checkContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+	if p.contentTypes == nil {
+		if !strings.Contains(mediaType, "html") && !pdfTypes[mediaType] {
+			return fmt.Errorf("unsupported content type %q", contentType)
+		}
+		return nil
+	}
+	if !p.contentTypes[mediaType] {
+		return fmt.Errorf("content type %q is not allowed", mediaType)
+	}
+	return nil
+}
+
+// userAgentToken is the product token of the User-Agent, the name
+// robots.txt groups address, e.g. "mcp-searxng-client".
+func userAgentToken() string {
+	agent := searxng.DefaultUserAgent
+	if client := currentState().client; client != nil {
+		agent = client.UserAgent
+	}
+	token, _, _ := strings.Cut(strings.TrimSpace(agent), "/")
+	token, _, _ = strings.Cut(token, " ")
+	return strings.ToLower(token)
+}
+
+type robotsEntry struct {
+	rules   robotsRules
+	fetched time.Time
+}
+
+// robotsCache fetches and keeps the robots.txt of every site fetched.
+type robotsCache struct {
+	client  *http.Client
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+// check returns an error when the robots.txt of the site of u disallows
+// agent to fetch it.
+func (c *robotsCache) check(ctx context.Context, u *url.URL, agent string) error {
+	if u.Path == "/robots.txt" {
+		return nil
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[origin]
+	c.mu.Unlock()
+	if !ok || time.Since(entry.fetched) > robotsTTL {
+		rules, err := c.fetch(ctx, origin, agent)
+		if err != nil {
+			return fmt.Errorf("robots.txt of %s unavailable: %w", u.Host, err)
+		}
+		entry = &robotsEntry{rules: rules, fetched: time.Now()}
+		c.mu.Lock()
+		c.entries[origin] = entry
+		c.mu.Unlock()
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !entry.rules.allowed(path) {
+		return fmt.Errorf("robots.txt of %s disallows fetching %s", u.Host, path)
+	}
+	return nil
+}
+
+// fetch reads the rules of a site: a missing robots.txt (4xx) allows
+// everything, a failing one (5xx) is an error.
+func (c *robotsCache) fetch(ctx context.Context, origin, agent string) (robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", stateOf(ctx).client.UserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("HTTP error %d", resp.StatusCode)
+	case resp.StatusCode >= 400:
+		return nil, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseRobots(data, agent), nil
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// robotsRules are the rules of the group of one user agent.
+type robotsRules []robotsRule
+
+// parseRobots returns the rules of the groups naming agent, or else those
+// of the * groups.
+func parseRobots(data []byte, agent string) robotsRules {
+	var named, wildcard robotsRules
+	var matchesAgent, matchesAny, inRules bool
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				matchesAgent, matchesAny, inRules = false, false, false
+			}
+			name := strings.ToLower(value)
+			matchesAgent = matchesAgent || name == agent
+			matchesAny = matchesAny || name == "*"
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", length: len(value), pattern: robotsPattern(value)}
+			if matchesAgent {
+				named = append(named, rule)
+			}
+			if matchesAny {
+				wildcard = append(wildcard, rule)
+			}
+		}
+	}
+	if named != nil {
+		return named
+	}
+	return wildcard
+}
+
+// robotsPattern compiles a path pattern, where * matches any characters
+// and a final $ anchors the end.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowed applies the longest matching rule, allow winning ties.
+func (r robotsRules) allowed(path string) bool {
+	allowed, longest := true, -1
+	for _, rule := range r {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > longest || rule.length == longest && rule.allow {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// useFetchPolicy replaces the fetch policy for the duration of the test,
+// with a fake instance whose client gives the User-Agent.
+func useFetchPolicy(t *testing.T, c *FetchConfig) {
+	t.Helper()
+	useFakeInstance(t)
+	useState(t, func(s *serverState) { s.fetchPolicy = newFetchPolicy(c) })
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := []byte(`
+# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+
+User-agent: OtherBot
+User-agent: mcp-searxng-client
+Disallow: /no-mcp
+`)
+	tests := []struct {
+		agent, path string
+		want        bool
+	}{
+		{"somebot", "/", true},
+		{"somebot", "/private/page.html", false},
+		{"somebot", "/private/public.html", true},
+		{"somebot", "/docs/manual.pdf", false},
+		{"somebot", "/docs/manual.pdf?download=1", true},
+		{"mcp-searxng-client", "/private/page.html", true},
+		{"mcp-searxng-client", "/no-mcp/page", false},
+	}
+	for _, tt := range tests {
+		if got := parseRobots(robots, tt.agent).allowed(tt.path); got != tt.want {
+			t.Errorf("%s allowed %s = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+	if !parseRobots(nil, "somebot").allowed("/anything") {
+		t.Error("empty robots.txt disallows")
+	}
+}
+
+func TestFetchPolicyRobots(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /secret\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><p>Open page</p></body></html>"))
+	}))
+	defer site.Close()
+	useFetchPolicy(t, &FetchConfig{RespectRobotsTxt: true})
+
+	if _, err := fetchPage(context.Background(), site.URL+"/open"); err != nil {
+		t.Errorf("allowed page: %v", err)
+	}
+	if _, err := fetchPage(context.Background(), site.URL+"/secret/page"); err == nil || !strings.Contains(err.Error(), "robots.txt") {
+		t.Errorf("disallowed page: %v, want a robots.txt error", err)
+	}
+}
+
+func TestFetchPolicyContentAndSize(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file.zip" {
+			w.Header().Set("Content-Type", "application/zip")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write([]byte("<html><body><p>" + strings.Repeat("text ", 400) + "</p></body></html>"))
+	}))
+	defer site.Close()
+	useFetchPolicy(t, &FetchConfig{MaxBytes: 1000, AllowedContentTypes: []string{"text/html", "application/pdf"}})
+
+	if _, err := fetchPage(context.Background(), site.URL+"/file.zip"); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("zip file: %v, want a content type error", err)
+	}
+	if _, err := fetchPage(context.Background(), site.URL+"/page"); err == nil || !strings.Contains(err.Error(), "byte limit") {
+		t.Errorf("large page: %v, want a size error", err)
+	}
+}
+
+func TestFetchPolicyPrivateNetworks(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer site.Close()
+	useFetchPolicy(t, &FetchConfig{BlockPrivateNetworks: true})
+
+	for _, u := range []string{site.URL, "http://localhost:8080/", "http://[::1]/", "http://metadata.google.internal/", "file:///etc/passwd"} {
+		if _, err := fetchPage(context.Background(), u); err == nil {
+			t.Errorf("fetching %s succeeded", u)
+		}
+	}
+
+	target, _ := url.Parse("https://93.184.215.14/")
+	if err := currentState().fetchPolicy.checkAddress(context.Background(), target); err != nil {
+		t.Errorf("public address refused: %v", err)
+	}
+	for addr, want := range map[string]bool{
+		"8.8.8.8": true, "10.1.2.3": false, "192.168.0.1": false, "127.0.0.1": false,
+		"169.254.169.254": false, "100.64.0.1": false, "::ffff:10.0.0.1": false, "fd00:ec2::254": false,
+		"2001:4860:4860::8888": true, "0.0.0.0": false,
+	} {
+		if got := publicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resultFields are the keys of searxng_search_v2 results fields can keep.
+var resultFields = jsonFieldNames(reflect.TypeOf(annotatedResult{}))
+
+func fieldsOption() mcp.ToolOption {
+	return mcp.WithString("fields",
+		mcp.Description("Keep only these keys of each result, separated by comma, e.g. title,url to save tokens; also applies to the markdown and compact formats. One of "+strings.Join(resultFields, ", ")),
+	)
+}
+
+// jsonFieldNames lists the JSON keys of a struct type, those of embedded
+// structs included.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// resultFieldsFromArguments reads the fields argument, the keys of results
+// to keep in their spelling, case-insensitively. None keeps them all.
+func resultFieldsFromArguments(arguments map[string]interface{}) ([]string, error) {
+	names, _, err := listArgument(arguments, "fields")
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	for _, name := range names {
+		i := indexFold(resultFields, name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown result field %q: use %s", name, strings.Join(resultFields, ", "))
+		}
+		fields = append(fields, resultFields[i])
+	}
+	return fields, nil
+}
+
+func indexFold(list []string, s string) int {
+	for i, item := range list {
+		if strings.EqualFold(item, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// projectResults keeps only fields of each result.
+func projectResults(results []annotatedResult, fields []string) ([]map[string]any, error) {
+	projected := make([]map[string]any, 0, len(results))
+	for _, r := range results {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]any
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		kept := make(map[string]any, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				kept[field] = value
+			}
+		}
+		projected = append(projected, kept)
+	}
+	return projected, nil
+}
+
+// clearUnselected zeroes the fields of results that fields does not keep,
+// for the text formats.
+func clearUnselected(results []annotatedResult, fields []string) error {
+	projected, err := projectResults(results, fields)
+	if err != nil {
+		return err
+	}
+	for i, p := range projected {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		results[i] = annotatedResult{}
+		if err := json.Unmarshal(data, &results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectedSearchV2Response is a searxng_search_v2 response whose results
+// hold only the requested fields.
+type projectedSearchV2Response struct {
+	*searchV2Response
+	// Results replace those of the response.
+	Results []map[string]any `json:"results"`
+}
+
+// optionalResultKeys drops the required keys of the results of a tool
+// answering with fields: projected results may lack any of them. It goes
+// after the output schema option.
+func optionalResultKeys() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		var schema map[string]any
+		if err := json.Unmarshal(tool.RawOutputSchema, &schema); err != nil {
+			log.Printf("Output schema of %s: %v", tool.Name, err)
+			return
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		results, _ := properties["results"].(map[string]any)
+		items, _ := results["items"].(map[string]any)
+		if items == nil {
+			return
+		}
+		delete(items, "required")
+		if err := setOutputSchema(tool, schema); err != nil {
+			log.Printf("Output schema of %s: %v", tool.Name, err)
+		}
+	}
+}
+
+// projectedSearchResult answers a searxng_search_v2 call with fields in
+// format, its text formats rendering the kept fields only.
+func projectedSearchResult(ctx context.Context, response *searchV2Response, fields []string, format string) (*mcp.CallToolResult, error) {
+	results, err := projectResults(response.Results, fields)
+	if err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+	projected := projectedSearchV2Response{searchV2Response: response, Results: results}
+	if format == formatJSON {
+		return structuredResult(projected)
+	}
+	text := *response
+	text.Results = slices.Clone(response.Results)
+	if err := clearUnselected(text.Results, fields); err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+	if format == formatMarkdown {
+		return mcp.NewToolResultStructured(projected, renderMarkdown(&text, didYouMean(ctx, &text))), nil
+	}
+	return mcp.NewToolResultStructured(projected, renderCompact(&text, didYouMean(ctx, &text))), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchFields(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"query": "q",
+		"results": []interface{}{
+			map[string]interface{}{"url": "https://a.example/", "title": "A", "content": "long snippet", "engine": "bing", "publishedDate": "2024-01-02"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "fields": "Title, url"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response struct {
+		Query   string                       `json:"query"`
+		Results []map[string]json.RawMessage `json:"results"`
+	}
+	decodeResult(t, result, &response)
+	if response.Query != "q" || len(response.Results) != 1 {
+		t.Fatalf("response = %+v", response)
+	}
+	if r := response.Results[0]; len(r) != 2 || string(r["title"]) != `"A"` || string(r["url"]) != `"https://a.example/"` {
+		t.Errorf("result = %s", r)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "fields": "url", "format": "compact"})
+	if err != nil || result.IsError {
+		t.Fatalf("compact search: %+v, %v", result, err)
+	}
+	if text, _ := mcp.AsTextContent(result.Content[0]); text == nil || text.Text != "1. https://a.example/\n" {
+		t.Errorf("compact = %+v", result.Content)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "fields": "title,snippet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := mcp.AsTextContent(result.Content[0]); errorCode(result) != errorInvalidParams || text == nil || !strings.Contains(text.Text, `"snippet"`) {
+		t.Errorf("unknown field: %+v", result)
+	}
+}
+
+func TestOptionalResultKeys(t *testing.T) {
+	tool := mcp.NewTool("t", dryRunOutputSchema[searchV2Response](), optionalResultKeys())
+	var schema struct {
+		Properties struct {
+			Results struct {
+				Items map[string]json.RawMessage `json:"items"`
+			} `json:"results"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(tool.RawOutputSchema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	items := schema.Properties.Results.Items
+	if items["properties"] == nil || items["required"] != nil {
+		t.Errorf("result items schema = %s", items)
+	}
+}
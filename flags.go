@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// headerFlag collects repeated -header "Name: value" flags.
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	var parts []string
+	for name, values := range h {
+		for _, value := range values {
+			parts = append(parts, name+": "+value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h headerFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("header must be in \"Name: value\" form, got %q", value)
+	}
+	http.Header(h).Add(name, strings.TrimSpace(val))
+	return nil
+}
+
+// listFlag collects repeated string flags.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// queryParamFlag collects repeated -query-param "name=value" flags.
+type queryParamFlag url.Values
+
+func (q queryParamFlag) String() string {
+	return url.Values(q).Encode()
+}
+
+func (q queryParamFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("query parameter must be in \"name=value\" form, got %q", value)
+	}
+	url.Values(q).Add(name, val)
+	return nil
+}
+
+// cookieFlag collects repeated -cookie flags, each a cookie or a whole
+// Cookie header.
+type cookieFlag []*http.Cookie
+
+func (c *cookieFlag) String() string {
+	parts := make([]string, 0, len(*c))
+	for _, cookie := range *c {
+		parts = append(parts, cookie.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (c *cookieFlag) Set(value string) error {
+	cookies, err := http.ParseCookie(value)
+	if err != nil {
+		return fmt.Errorf("cookie must be in \"name=value\" or \"a=1; b=2\" form, got %q: %w", value, err)
+	}
+	*c = append(*c, cookies...)
+	return nil
+}
+
+// fileModeFlag is an octal file mode flag such as -socket-mode 0660.
+type fileModeFlag os.FileMode
+
+func (m *fileModeFlag) String() string {
+	return fmt.Sprintf("%#o", os.FileMode(*m).Perm())
+}
+
+func (m *fileModeFlag) Set(value string) error {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return fmt.Errorf("file mode must be octal permissions such as 0660, got %q", value)
+	}
+	*m = fileModeFlag(mode)
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Output formats of searxng_search_v2. JSON carries everything, markdown
+// and compact trade detail for tokens.
+const (
+	formatJSON     = "json"
+	formatMarkdown = "markdown"
+	formatCompact  = "compact"
+)
+
+const (
+	// maxDidYouMean is the number of query repairs shown.
+	maxDidYouMean = 3
+	// autocompleteTimeout bounds the autocomplete fallback of didYouMean.
+	autocompleteTimeout = 2 * time.Second
+)
+
+func formatOption() mcp.ToolOption {
+	return mcp.WithString("format",
+		mcp.Description("Output format: json (default, complete), markdown (readable list) or compact (one line per result, fewest tokens)"),
+		mcp.Enum(formatJSON, formatMarkdown, formatCompact),
+	)
+}
+
+func outputFormat(arguments map[string]interface{}) (string, error) {
+	format, _ := arguments["format"].(string)
+	switch format {
+	case "":
+		return formatJSON, nil
+	case formatJSON, formatMarkdown, formatCompact:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format %q: use json, markdown or compact", format)
+}
+
+// didYouMean collects query repairs: the instance corrections first, then
+// its suggestions. When a search found nothing and the instance offered
+// neither, the autocomplete backend is asked instead.
+func didYouMean(ctx context.Context, response *searchV2Response) []string {
+	var repairs []string
+	seen := map[string]bool{strings.ToLower(response.Query): true}
+	add := func(candidates []string) {
+		for _, candidate := range candidates {
+			key := strings.ToLower(strings.TrimSpace(candidate))
+			if key == "" || seen[key] || len(repairs) == maxDidYouMean {
+				continue
+			}
+			seen[key] = true
+			repairs = append(repairs, strings.TrimSpace(candidate))
+		}
+	}
+	add(response.Corrections)
+	add(response.Suggestions)
+
+	if len(repairs) == 0 && len(response.Results) == 0 {
+		ctx, cancel := context.WithTimeout(ctx, autocompleteTimeout)
+		defer cancel()
+		if completions, err := activeInstance(ctx).Autocomplete(ctx, response.Query); err == nil {
+			add(completions)
+		}
+	}
+	return repairs
+}
+
+func renderMarkdown(response *searchV2Response, repairs []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Results for %q\n\n", response.Query)
+	if len(repairs) > 0 {
+		fmt.Fprintf(&b, "Did you mean: %s\n\n", strings.Join(repairs, ", "))
+	}
+	for _, warning := range response.Meta.Warnings {
+		fmt.Fprintf(&b, "> Note: %s\n\n", warning)
+	}
+	for i, answer := range response.Answers {
+		fmt.Fprintf(&b, "**Answer:** %s%s\n\n", answer, answerNote(response.AnswerChecks, i))
+	}
+	if len(response.Results) == 0 {
+		b.WriteString("No results.\n")
+		if report := response.NoResults; report != nil {
+			b.WriteString("\nLikely causes:\n")
+			for _, c := range report.Causes {
+				fmt.Fprintf(&b, "- %s\n", c.Detail)
+			}
+			if len(report.NextActions) > 0 {
+				b.WriteString("\nTry next:\n")
+				for _, a := range report.NextActions {
+					fmt.Fprintf(&b, "- %s\n", a)
+				}
+			}
+		}
+	}
+	for i, r := range response.Results {
+		fmt.Fprintf(&b, "%d. %s%s\n", i+1, evidenceTag(r.EvidenceID), markdownLink(r.Title, r.URL))
+		if r.Content != "" {
+			fmt.Fprintf(&b, "   %s\n", markdownEscape(collapse(r.Content)))
+		}
+		source := r.Engine
+		if r.PublishedDate != "" {
+			source += " · " + r.PublishedDate
+		}
+		if len(r.SearchLanguages) > 0 {
+			source += " · " + languageTag(r)
+		}
+		if i < len(response.Groups) && response.Groups[i].Count > 1 {
+			source += fmt.Sprintf(" · %d results from %s", response.Groups[i].Count, response.Groups[i].Domain)
+		}
+		if source != "" {
+			fmt.Fprintf(&b, "   _%s_\n", source)
+		}
+	}
+	return b.String()
+}
+
+func renderCompact(response *searchV2Response, repairs []string) string {
+	var b strings.Builder
+	if len(repairs) > 0 {
+		fmt.Fprintf(&b, "did you mean: %s\n", strings.Join(repairs, " | "))
+	}
+	for _, warning := range response.Meta.Warnings {
+		fmt.Fprintf(&b, "note: %s\n", warning)
+	}
+	for i, answer := range response.Answers {
+		fmt.Fprintf(&b, "answer: %s%s\n", answer, answerNote(response.AnswerChecks, i))
+	}
+	for i, r := range response.Results {
+		more := ""
+		if i < len(response.Groups) && response.Groups[i].Count > 1 {
+			more = fmt.Sprintf(" (+%d)", response.Groups[i].Count-1)
+		}
+		if len(r.SearchLanguages) > 0 {
+			more += " [" + languageTag(r) + "]"
+		}
+		fmt.Fprintf(&b, "%d. %s%s%s\n", i+1, evidenceTag(r.EvidenceID), joinNonEmpty(" - ", collapse(r.Title), r.URL), more)
+	}
+	if len(response.Results) == 0 {
+		b.WriteString("no results\n")
+		if report := response.NoResults; report != nil {
+			for _, c := range report.Causes {
+				fmt.Fprintf(&b, "cause: %s\n", c.Detail)
+			}
+			for _, a := range report.NextActions {
+				fmt.Fprintf(&b, "try: %s\n", a)
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownLink renders a result title linking to its URL; results projected
+// with fields may lack either.
+func markdownLink(title, url string) string {
+	switch {
+	case url == "":
+		return "**" + markdownEscape(title) + "**"
+	case title == "":
+		title = url
+	}
+	return fmt.Sprintf("**[%s](%s)**", markdownEscape(title), url)
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// evidenceTag renders an evidence ID as the "[E12] " citation prefix.
+func evidenceTag(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "[" + id + "] "
+}
+
+var markdownEscaper = strings.NewReplacer("[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`")
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
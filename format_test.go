@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchV2Formats(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"query": "golnag",
+		"results": []map[string]interface{}{
+			{"title": "The Go [Programming] Language", "url": "https://go.dev", "content": "Go is\n an open source language", "engine": "google"},
+		},
+		"corrections": []string{"golang"},
+		"suggestions": []string{"golang tutorial", "Golang", "go language", "golang jobs"},
+	})
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{formatMarkdown, []string{
+			"Did you mean: golang, golang tutorial, go language\n",
+			`1. [E1] **[The Go \[Programming\] Language](https://go.dev)**`,
+			"   Go is an open source language\n",
+			"   _google_\n",
+		}},
+		{formatCompact, []string{
+			"did you mean: golang | golang tutorial | go language\n",
+			"1. [E1] The Go [Programming] Language - https://go.dev\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golnag", "format": tt.format})
+			if err != nil || result.IsError {
+				t.Fatalf("handler: %+v, %v", result, err)
+			}
+			text := result.Content[0].(mcp.TextContent).Text
+			for _, want := range tt.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("output lacks %q:\n%s", want, text)
+				}
+			}
+		})
+	}
+
+	result, _ := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "format": "xml"})
+	if !result.IsError {
+		t.Error("want an error result for an unknown format")
+	}
+}
+
+func TestDidYouMeanAutocompleteFallback(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{"query": "golnag", "results": []interface{}{}})
+	fake.Handle("/autocompleter", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["golnag", ["golang", "golang generics"]]`))
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golnag", "format": formatCompact})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(text, "did you mean: golang | golang generics\n") || !strings.Contains(text, "no results") {
+		t.Errorf("output = %q", text)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// geocodingEngines resolve the near argument through the map category.
+var geocodingEngines = []string{"openstreetmap"}
+
+// coordinatesPattern matches "lat,lon" and "lat lon".
+var coordinatesPattern = regexp.MustCompile(`^\s*(-?\d{1,2}(?:\.\d+)?)\s*[,;\s]\s*(-?\d{1,3}(?:\.\d+)?)\s*$`)
+
+// countryLanguages are the main search languages of countries, by ISO
+// 3166 code; they complete the locale when the query language is unknown.
+var countryLanguages = map[string]string{
+	"ar": "es", "at": "de", "au": "en", "be": "nl", "bg": "bg", "br": "pt",
+	"by": "ru", "ca": "en", "ch": "de", "cl": "es", "cn": "zh", "co": "es",
+	"cz": "cs", "de": "de", "dk": "da", "ee": "et", "eg": "ar", "es": "es",
+	"fi": "fi", "fr": "fr", "gb": "en", "gr": "el", "hk": "zh", "hr": "hr",
+	"hu": "hu", "id": "id", "ie": "en", "il": "he", "in": "en", "it": "it",
+	"jp": "ja", "kr": "ko", "kz": "ru", "lt": "lt", "lv": "lv", "mx": "es",
+	"my": "ms", "nl": "nl", "no": "nb", "nz": "en", "pe": "es", "ph": "en",
+	"pl": "pl", "pt": "pt", "ro": "ro", "rs": "sr", "ru": "ru", "sa": "ar",
+	"se": "sv", "sg": "en", "si": "sl", "sk": "sk", "th": "th", "tr": "tr",
+	"tw": "zh", "ua": "uk", "us": "en", "vn": "vi", "za": "en",
+}
+
+// geoBias describes how the near argument was applied to a search.
+type geoBias struct {
+	Near      string   `json:"near"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// Place is the place the near argument resolved to.
+	Place       string `json:"place,omitempty"`
+	Locality    string `json:"locality,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	// Locale is the language and region sent to the instance.
+	Locale string `json:"locale,omitempty"`
+	// QueryAddition is the place appended to the query.
+	QueryAddition string `json:"query_addition,omitempty"`
+	// Warning is set when the place could not be resolved; the search
+	// then runs with what near gave directly.
+	Warning string `json:"-"`
+}
+
+func nearOption() mcp.ToolOption {
+	return mcp.WithString("near",
+		mcp.Description(`Bias results towards a place for local queries ("best bakery", "plumber"): a place name such as "Kreuzberg, Berlin" or coordinates "52.50,13.42". Sets the search region and adds the place to the query`),
+	)
+}
+
+// applyNear resolves the near argument, if any, and biases params towards
+// it: the locale gets the region of the place and the query its name.
+// Only invalid arguments fail; an unresolved place is reported in the
+// Warning of the returned bias. Dry runs do not resolve the place, which
+// takes a search: they only describe it, as an unresolved place.
+func applyNear(ctx context.Context, arguments map[string]interface{}, params *searxng.SearchParams) (*geoBias, error) {
+	near, _ := arguments["near"].(string)
+	if near = strings.TrimSpace(near); near == "" {
+		return nil, nil
+	}
+	bias := &geoBias{Near: near}
+	term := near
+	if m := coordinatesPattern.FindStringSubmatch(near); m != nil {
+		lat, _ := strconv.ParseFloat(m[1], 64)
+		lon, _ := strconv.ParseFloat(m[2], 64)
+		if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("near coordinates %q are out of range", near)
+		}
+		bias.Latitude, bias.Longitude = &lat, &lon
+		term = ""
+	}
+
+	if dryRun, _ := isDryRun(arguments); dryRun {
+		bias.Warning = fmt.Sprintf("near %q is resolved only when searching, not in dry runs", near)
+	} else if place, err := geocode(ctx, near); err != nil {
+		bias.Warning = fmt.Sprintf("near %q could not be resolved: %v", near, err)
+	} else if place == nil {
+		bias.Warning = fmt.Sprintf("near %q matched no place", near)
+	} else {
+		bias.Place = place.Title
+		if place.Address != nil {
+			bias.Locality = place.Address.Locality
+			if bias.Locality == "" {
+				bias.Locality = place.Address.Name
+			}
+			bias.Country = place.Address.Country
+			bias.CountryCode = strings.ToLower(place.Address.CountryCode)
+		}
+		if bias.Latitude == nil {
+			lat, lon := float64(place.Latitude), float64(place.Longitude)
+			bias.Latitude, bias.Longitude = &lat, &lon
+		} else {
+			term = bias.Locality
+		}
+	}
+
+	if bias.CountryCode != "" {
+		if locale := regionLocale(params.Language, params.Query, bias.CountryCode); locale != "" {
+			params.Language = locale
+			bias.Locale = locale
+		}
+	}
+	if term != "" && !strings.Contains(strings.ToLower(params.Query), strings.ToLower(term)) {
+		params.Query += " " + term
+		bias.QueryAddition = term
+	}
+	return bias, nil
+}
+
+// geocode returns the best place the map engines find for near, or nil.
+func geocode(ctx context.Context, near string) (*searxng.MapResult, error) {
+	params := searxng.SearchParams{
+		Query:      near,
+		Categories: []string{"map"},
+		Engines:    geocodingEngines,
+	}
+	result, _, err := cachedSearch(ctx, "geocode", params, func() (*searxng.MapSearchResponse, error) {
+		return activeInstance(ctx).SearchMap(ctx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
+
+// regionLocale returns the locale searching from country: the requested
+// language, the one detected from the query or else the country's, with
+// the country as region. It returns "" when the language already names a
+// region or none can be told.
+func regionLocale(language, query, country string) string {
+	if strings.Contains(language, "-") {
+		return ""
+	}
+	if language == autoLanguage {
+		language, _ = detectLanguage(query)
+	}
+	if language == "" || language == fallbackLanguage {
+		language = countryLanguages[country]
+	}
+	if language == "" {
+		return ""
+	}
+	return language + "-" + strings.ToUpper(country)
+}
+
+// addNear records bias, if any, in the meta.
+func (m *searchMeta) addNear(bias *geoBias) {
+	if bias == nil {
+		return
+	}
+	m.Near = bias
+	if bias.Warning != "" {
+		m.Warnings = append(m.Warnings, bias.Warning)
+	}
+}
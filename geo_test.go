@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// useGeocoder answers map searches of the fake instance with places and
+// other searches with the default results.
+func useGeocoder(t *testing.T, places []map[string]interface{}) *[]string {
+	t.Helper()
+	fake := useFakeInstance(t)
+	var searches []string
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{"query": r.Form.Get("q"), "results": []interface{}{}}
+		if r.Form.Get("categories") == "map" {
+			response["results"] = places
+		} else {
+			searches = append(searches, r.Form.Get("q")+"|"+r.Form.Get("language"))
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	return &searches
+}
+
+func TestSearchNearPlace(t *testing.T) {
+	searches := useGeocoder(t, []map[string]interface{}{
+		{"title": "Berlin", "url": "https://www.openstreetmap.org/relation/62422", "engine": "openstreetmap",
+			"latitude": "52.5170365", "longitude": "13.3888599",
+			"address": map[string]interface{}{"name": "Berlin", "country": "Deutschland", "country_code": "de"}},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "best bakery", "near": "Berlin"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	if len(*searches) != 1 || (*searches)[0] != "best bakery Berlin|de-DE" {
+		t.Errorf("searches = %q", *searches)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	near := response.Meta.Near
+	if near == nil || near.CountryCode != "de" || near.Locale != "de-DE" || near.QueryAddition != "Berlin" || near.Latitude == nil || *near.Latitude != 52.5170365 {
+		t.Errorf("meta.near = %+v", near)
+	}
+
+	// Dry runs describe the place without resolving it.
+	*searches = nil
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "best bakery", "near": "Berlin", "dry_run": true})
+	if err != nil || result.IsError {
+		t.Fatalf("dry run: %+v, %v", result, err)
+	}
+	if len(*searches) != 0 {
+		t.Errorf("dry run searched %q", *searches)
+	}
+}
+
+func TestSearchNearCoordinates(t *testing.T) {
+	searches := useGeocoder(t, []map[string]interface{}{
+		{"title": "Kreuzberg", "url": "https://www.openstreetmap.org/node/1", "engine": "openstreetmap", "latitude": 52.49, "longitude": 13.41,
+			"address": map[string]interface{}{"locality": "Kreuzberg", "country_code": "DE"}},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "Klempner", "near": "52.50, 13.42", "language": "de"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	if len(*searches) != 1 || (*searches)[0] != "Klempner Kreuzberg|de-DE" {
+		t.Errorf("searches = %q", *searches)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if near := response.Meta.Near; near == nil || *near.Latitude != 52.50 || *near.Longitude != 13.42 {
+		t.Errorf("meta.near = %+v", near)
+	}
+}
+
+func TestSearchNearUnresolved(t *testing.T) {
+	searches := useGeocoder(t, nil)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "plumber", "near": "Atlantis"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	if len(*searches) != 1 || (*searches)[0] != "plumber Atlantis|all" {
+		t.Errorf("searches = %q", *searches)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Meta.Warnings) != 1 || response.Meta.Near.Locale != "" {
+		t.Errorf("meta = %+v", response.Meta)
+	}
+
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "plumber", "near": "95, 10"})
+	if !result.IsError {
+		t.Errorf("out of range coordinates were accepted")
+	}
+}
+
+func TestRegionLocale(t *testing.T) {
+	for _, tt := range []struct{ language, query, country, want string }{
+		{"auto", "what is the best bakery for the holidays", "de", "en-DE"},
+		{"auto", "best bakery", "de", "de-DE"},
+		{"auto", "boulangerie", "fr", "fr-FR"},
+		{"fr", "anything", "ca", "fr-CA"},
+		{"all", "x", "jp", "ja-JP"},
+		{"en-GB", "x", "de", ""},
+		{"auto", "x", "zz", ""},
+	} {
+		if got := regionLocale(tt.language, tt.query, tt.country); got != tt.want {
+			t.Errorf("regionLocale(%q, %q, %q) = %q, want %q", tt.language, tt.query, tt.country, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// groupModes are the values of group_by.
+var groupModes = []string{"domain"}
+
+// resultGroup is the results of one domain in group_by=domain mode.
+type resultGroup struct {
+	Domain string `json:"domain"`
+	Count  int    `json:"count"`
+	// Representative is the URL of the best-ranked result of the domain,
+	// the one returned in results.
+	Representative string `json:"representative"`
+	// More are the URLs of the other results of the domain, in rank order.
+	More []string `json:"more,omitempty"`
+}
+
+func groupByOption() mcp.ToolOption {
+	return mcp.WithString("group_by",
+		mcp.Description("Group results: domain returns the best-ranked result of each site, with the number of results per site in groups, so a few sites do not take all the top slots"),
+		mcp.Enum(groupModes...),
+	)
+}
+
+// groupByDomain keeps the best-ranked result of each domain, in rank
+// order, and returns the groups in the same order.
+func groupByDomain(results []annotatedResult) ([]annotatedResult, []resultGroup) {
+	var representatives []annotatedResult
+	var groups []resultGroup
+	index := make(map[string]int)
+	for _, r := range results {
+		domain := resultDomain(r.URL)
+		if i, ok := index[domain]; ok {
+			groups[i].Count++
+			groups[i].More = append(groups[i].More, r.URL)
+			continue
+		}
+		index[domain] = len(groups)
+		representatives = append(representatives, r)
+		groups = append(groups, resultGroup{Domain: domain, Count: 1, Representative: r.URL})
+	}
+	return representatives, groups
+}
+
+// resultDomain is the hostname of rawURL without "www.", or rawURL itself
+// when it has none.
+func resultDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchV2GroupByDomain(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "A1", "url": "https://www.a.example/1", "engine": "google"},
+			{"title": "A2", "url": "https://a.example/2", "engine": "google"},
+			{"title": "B1", "url": "https://b.example/1", "engine": "google"},
+			{"title": "A3", "url": "https://a.example/3", "engine": "google"},
+			{"title": "C1", "url": "https://c.example/1", "engine": "google"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "group_by": "domain", "max_results": 2})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 2 || response.Results[0].Title != "A1" || response.Results[1].Title != "B1" {
+		t.Fatalf("results = %+v, want A1 and B1", response.Results)
+	}
+	if len(response.Groups) != 2 {
+		t.Fatalf("groups = %+v, want 2", response.Groups)
+	}
+	want := resultGroup{Domain: "a.example", Count: 3, Representative: "https://www.a.example/1", More: []string{"https://a.example/2", "https://a.example/3"}}
+	if g := response.Groups[0]; g.Domain != want.Domain || g.Count != want.Count || g.Representative != want.Representative || strings.Join(g.More, " ") != strings.Join(want.More, " ") {
+		t.Errorf("groups[0] = %+v, want %+v", g, want)
+	}
+	if response.Groups[1].Count != 1 || response.Meta.ReturnedResults != 2 {
+		t.Errorf("groups[1] = %+v, meta = %+v", response.Groups[1], response.Meta)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "group_by": "domain", "format": "compact"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if text, _ := mcp.AsTextContent(result.Content[0]); !strings.Contains(text.Text, "https://www.a.example/1 (+2)") || strings.Contains(text.Text, "A2") {
+		t.Errorf("compact output:\n%s\nwant A1 with the 2 other a.example results counted", text.Text)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "group_by": "topic"})
+	if err != nil || !result.IsError {
+		t.Errorf("group_by topic = %v, %v, want an invalid argument result", result, err)
+	}
+}
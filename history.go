@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	historyToolName    = "searxng_history"
+	historyResourceURI = "searxng://history"
+	defaultHistorySize = 20
+)
+
+// callParams returns the arguments of a tool call other than the query.
+func callParams(arguments map[string]interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(arguments))
+	for name, value := range arguments {
+		if name != "query" {
+			params[name] = value
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// resultCount reads the length of the "results" list of a JSON tool result.
+func resultCount(result *mcp.CallToolResult) *int {
+	if result == nil || result.IsError || len(result.Content) == 0 {
+		return nil
+	}
+	text, ok := mcp.AsTextContent(result.Content[0])
+	if !ok {
+		return nil
+	}
+	var decoded struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(text.Text), &decoded); err != nil || decoded.Results == nil {
+		return nil
+	}
+	n := len(decoded.Results)
+	return &n
+}
+
+func searxngHistoryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	limit := defaultHistorySize
+	if n, ok, err := intArgument(request.GetArguments(), "limit"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > 0 {
+		limit = min(n, recentSearchesSize)
+	}
+	tool, _ := request.GetArguments()["tool"].(string)
+
+	session := sessionID(ctx)
+	calls := []recentSearch{}
+	for _, call := range recentSearches.list() {
+		if call.Session != session || call.Tool == historyToolName || (tool != "" && call.Tool != tool) {
+			continue
+		}
+		calls = append(calls, call)
+		if len(calls) == limit {
+			break
+		}
+	}
+
+	return structuredResult(historyResponse{Calls: calls})
+}
+
+// historyResourceHandler serves the tool calls of the reading session. The
+// calls of all sessions are only shown on the dashboard, behind its
+// credentials: queries must not leak between the clients of a server.
+func historyResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	session := sessionID(ctx)
+	calls := []recentSearch{}
+	for _, call := range recentSearches.list() {
+		if call.Session == session {
+			calls = append(calls, call)
+		}
+	}
+	jsonResult, err := json.MarshalIndent(historyResponse{Calls: calls}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      historyResourceURI,
+			MIMEType: "application/json",
+			Text:     string(jsonResult),
+		},
+	}, nil
+}
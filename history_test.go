@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// useRecentLog gives the test an empty recent call log.
+func useRecentLog(t *testing.T, private bool) {
+	t.Helper()
+	previous := recentSearches
+	recentSearches = newRecentLog(recentSearchesSize)
+	recentSearches.private = private
+	t.Cleanup(func() { recentSearches = previous })
+}
+
+func TestHistory(t *testing.T) {
+	useFakeInstance(t)
+	useEvidencePool(t)
+	useRecentLog(t, false)
+
+	search := observeToolCalls(searxngSearchV2Handler)
+	for _, arguments := range []map[string]interface{}{
+		{"query": "golang", "engines": "duckduckgo"},
+		{"query": "rust", "format": formatCompact},
+	} {
+		var request mcp.CallToolRequest
+		request.Params.Name = "searxng_search_v2"
+		request.Params.Arguments = arguments
+		if _, err := search(context.Background(), request); err != nil {
+			t.Fatalf("search: %v", err)
+		}
+	}
+
+	result, err := callTool(t, observeToolCalls(searxngHistoryHandler), map[string]interface{}{"limit": float64(5)})
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var history struct {
+		Calls []recentSearch `json:"calls"`
+	}
+	decodeResult(t, result, &history)
+	if len(history.Calls) != 2 {
+		t.Fatalf("calls = %+v, want the two searches", history.Calls)
+	}
+	recentSearches.add(recentSearch{Tool: "searxng_search_v2", Session: "other", Query: "secret"})
+	latest, first := history.Calls[0], history.Calls[1]
+	if latest.Query != "rust" || latest.Results != nil {
+		t.Errorf("latest call = %+v, want rust without a result count for compact output", latest)
+	}
+	if first.Query != "golang" || first.Params["engines"] != "duckduckgo" || first.Results == nil || *first.Results == 0 {
+		t.Errorf("first call = %+v", first)
+	}
+
+	contents, err := historyResourceHandler(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("resource: %v", err)
+	}
+	var all struct {
+		Calls []recentSearch `json:"calls"`
+	}
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Calls) != 3 {
+		t.Errorf("resource lists %d calls, want the 3 of the session including the history call", len(all.Calls))
+	}
+}
+
+func TestHistoryPrivacyMode(t *testing.T) {
+	useRecentLog(t, true)
+	recentSearches.add(recentSearch{Tool: "searxng_search_v2", Session: defaultSessionID, Query: "secret", Params: map[string]interface{}{"engines": "x"}})
+
+	result, _ := callTool(t, searxngHistoryHandler, nil)
+	var history struct {
+		Calls []recentSearch `json:"calls"`
+	}
+	decodeResult(t, result, &history)
+	if len(history.Calls) != 1 || history.Calls[0].Query != "" || history.Calls[0].Params != nil {
+		t.Errorf("calls = %+v, want the query and params dropped", history.Calls)
+	}
+}
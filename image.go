@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultImageBytes = 1 << 20
+	maxImageBytes     = 5 << 20
+)
+
+// imageTypes are the image formats vision models accept.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// fetchedImage describes the image content of a fetch_image result.
+type fetchedImage struct {
+	URL      string `json:"url"`
+	MIMEType string `json:"mime_type"`
+	Bytes    int    `json:"bytes"`
+}
+
+func fetchImageHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	imageURL, _ := request.GetArguments()["url"].(string)
+	if u, err := url.Parse(imageURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidArgumentsResult(errors.New("url must be an http or https image URL, e.g. the img_src or thumbnail_src of an image search result")), nil
+	}
+	limit := defaultImageBytes
+	if n, ok, err := intArgument(request.GetArguments(), "max_bytes"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > 0 {
+		limit = min(n, maxImageBytes)
+	}
+
+	data, mimeType, err := fetchImage(ctx, imageURL, limit)
+	if err != nil {
+		e := toolError{Code: errorUpstream, Message: fmt.Sprintf("Cannot fetch image %s: %v", imageURL, err)}
+		// The other codes of classifyUpstreamError describe the instance.
+		if code := classifyUpstreamError(err).Code; code == errorTimeout || code == errorCanceled {
+			e.Code = code
+		}
+		return errorResult(e), nil
+	}
+
+	description, err := json.Marshal(fetchedImage{URL: imageURL, MIMEType: mimeType, Bytes: len(data)})
+	if err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+
+	return mcp.NewToolResultImage(string(description), base64.StdEncoding.EncodeToString(data), mimeType), nil
+}
+
+// fetchImage downloads an image of at most limit bytes. Its type comes
+// from the Content-Type header, or from its content when the server sends
+// a generic one.
+func fetchImage(ctx context.Context, imageURL string, limit int) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", stateOf(ctx).client.UserAgent)
+	req.Header.Set("Accept", "image/png,image/jpeg,image/gif,image/webp")
+
+	resp, err := stateOf(ctx).fetchPolicy.do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP error %d", resp.StatusCode)
+	}
+	if resp.ContentLength > int64(limit) {
+		return nil, "", fmt.Errorf("image is %d bytes, above the %d byte limit: raise max_bytes or fetch the thumbnail", resp.ContentLength, limit)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading image: %w", err)
+	}
+	if len(data) > limit {
+		return nil, "", fmt.Errorf("image is above the %d byte limit: raise max_bytes or fetch the thumbnail", limit)
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !imageTypes[mimeType] {
+		mimeType = http.DetectContentType(data)
+	}
+	if !imageTypes[mimeType] {
+		return nil, "", fmt.Errorf("unsupported content type %q, want PNG, JPEG, GIF or WebP", mimeType)
+	}
+	return data, mimeType, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestFetchImage(t *testing.T) {
+	useFakeInstance(t)
+
+	var pixel bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.White)
+	if err := png.Encode(&pixel, img); err != nil {
+		t.Fatal(err)
+	}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pixel.png":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pixel.Bytes())
+		case "/large.png":
+			w.Write(bytes.Repeat([]byte{0}, 4096))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	result, err := callTool(t, fetchImageHandler, map[string]interface{}{"url": site.URL + "/pixel.png"})
+	if err != nil || result.IsError || len(result.Content) != 2 {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	content, ok := mcp.AsImageContent(result.Content[1])
+	if !ok || content.MIMEType != "image/png" || content.Data != base64.StdEncoding.EncodeToString(pixel.Bytes()) {
+		t.Errorf("image content = %+v", result.Content[1])
+	}
+
+	for _, tt := range []struct {
+		arguments map[string]interface{}
+		wantErr   string
+		wantCode  string
+	}{
+		{map[string]interface{}{"url": site.URL + "/large.png", "max_bytes": 1024}, "above the 1024 byte limit", errorUpstream},
+		{map[string]interface{}{"url": site.URL + "/page.html"}, "unsupported content type", errorUpstream},
+		{map[string]interface{}{"url": site.URL + "/missing.png"}, "HTTP error 404", errorUpstream},
+		{map[string]interface{}{"url": "file:///etc/passwd"}, "url must be an http or https image URL", errorInvalidParams},
+	} {
+		result, err := callTool(t, fetchImageHandler, tt.arguments)
+		if err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+		text, _ := mcp.AsTextContent(result.Content[0])
+		if !result.IsError || !strings.Contains(text.Text, tt.wantErr) || errorCode(result) != tt.wantCode {
+			t.Errorf("%v: got %q, want a %s error containing %q", tt.arguments["url"], text.Text, tt.wantCode, tt.wantErr)
+		}
+	}
+}
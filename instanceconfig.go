@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	// engineCatalogTTL is how long the /config of the instance is reused.
+	engineCatalogTTL = 10 * time.Minute
+	// instanceConfigRetry is how long a failed /config fetch is reused, so
+	// a broken /config costs one request per retry period.
+	instanceConfigRetry = time.Minute
+)
+
+// errCatalogNotFetched is returned instead of fetching /config when the
+// instance must not be contacted: in offline mode and in dry runs.
+var errCatalogNotFetched = errors.New("the instance config is not fetched in offline mode or dry runs")
+
+// instanceCatalog is the /config document of an instance, with the
+// lookups the checks of the search arguments need.
+type instanceCatalog struct {
+	config *searxng.InstanceConfig
+	// enabled[name] is whether the engine is enabled, by lower-case name.
+	enabled map[string]bool
+	// engines maps lower-case engine names to their config.
+	engines map[string]searxng.EngineConfig
+	// shortcuts maps lower-case bang shortcuts, without their "!", to
+	// engine names.
+	shortcuts map[string]string
+	// categories holds the lower-case names of the categories of the
+	// instance: its tabs and those of its engines.
+	categories map[string]bool
+}
+
+func newInstanceCatalog(instanceConfig *searxng.InstanceConfig) *instanceCatalog {
+	c := &instanceCatalog{
+		config:     instanceConfig,
+		enabled:    make(map[string]bool, len(instanceConfig.Engines)),
+		engines:    make(map[string]searxng.EngineConfig, len(instanceConfig.Engines)),
+		shortcuts:  make(map[string]string),
+		categories: make(map[string]bool),
+	}
+	for _, category := range instanceConfig.Categories {
+		c.categories[strings.ToLower(category)] = true
+	}
+	for _, engine := range instanceConfig.Engines {
+		name := strings.ToLower(engine.Name)
+		c.enabled[name] = engine.Enabled
+		c.engines[name] = engine
+		if engine.Shortcut != "" {
+			c.shortcuts[strings.ToLower(engine.Shortcut)] = engine.Name
+		}
+		for _, category := range engine.Categories {
+			c.categories[strings.ToLower(category)] = true
+		}
+	}
+	return c
+}
+
+// engineCategories returns the categories of the engine named name, nil
+// for engines the instance does not have.
+func (c *instanceCatalog) engineCategories(name string) []string {
+	return c.engines[strings.ToLower(name)].Categories
+}
+
+// categoryEngines returns the names of the enabled engines in any of
+// categories, in the order of the config.
+func (c *instanceCatalog) categoryEngines(categories []string) []string {
+	wanted := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		wanted[strings.ToLower(category)] = true
+	}
+	var engines []string
+	for _, engine := range c.config.Engines {
+		if !engine.Enabled {
+			continue
+		}
+		for _, category := range engine.Categories {
+			if wanted[strings.ToLower(category)] {
+				engines = append(engines, engine.Name)
+				break
+			}
+		}
+	}
+	return engines
+}
+
+// defaultEngines returns the engines a search without engines goes to:
+// the enabled engines of categories, or of the general category. It is
+// nil when the instance config is not available.
+func defaultEngines(ctx context.Context, categories []string) []string {
+	catalog, err := instanceConfigs.get(ctx)
+	if err != nil {
+		return nil
+	}
+	if len(categories) == 0 {
+		categories = []string{"general"}
+	}
+	return catalog.categoryEngines(categories)
+}
+
+// instanceConfigCache caches the /config of the active instance, shared by
+// the engine, category, bang and denylist checks. The fetch runs outside
+// the lock: concurrent calls wait for the fetch in flight, and calls
+// served from the cache never wait behind a slow /config.
+type instanceConfigCache struct {
+	mu       sync.Mutex
+	instance string
+	fetched  time.Time
+	catalog  *instanceCatalog
+	err      error
+	// pending is closed when the fetch in flight ends, nil when no fetch
+	// is in flight.
+	pending chan struct{}
+}
+
+var instanceConfigs = &instanceConfigCache{}
+
+type cachedCatalogOnlyKey struct{}
+
+// cachedCatalogOnly marks ctx so that instanceConfigCache.get returns only
+// what is cached, without contacting the instance, as dry runs must.
+func cachedCatalogOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cachedCatalogOnlyKey{}, true)
+}
+
+// get returns the catalog of the active instance, fetching /config again
+// after engineCatalogTTL or a failover. In offline mode, and with a ctx
+// from cachedCatalogOnly, only a cached catalog is returned.
+func (c *instanceConfigCache) get(ctx context.Context) (*instanceCatalog, error) {
+	instance := activeInstance(ctx)
+	for {
+		c.mu.Lock()
+		if c.instance == instance.BaseURL && c.fresh() {
+			catalog, err := c.catalog, c.err
+			c.mu.Unlock()
+			return catalog, err
+		}
+		if offlineMode() || ctx.Value(cachedCatalogOnlyKey{}) != nil {
+			c.mu.Unlock()
+			return nil, errCatalogNotFetched
+		}
+		pending := c.pending
+		if pending == nil {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := make(chan struct{})
+	c.pending = pending
+	c.mu.Unlock()
+
+	var catalog *instanceCatalog
+	instanceConfig, err := instance.GetInstanceConfig(ctx)
+	if err == nil {
+		catalog = newInstanceCatalog(instanceConfig)
+	}
+	c.mu.Lock()
+	c.instance, c.fetched, c.catalog, c.err = instance.BaseURL, time.Now(), catalog, err
+	c.pending = nil
+	c.mu.Unlock()
+	close(pending)
+	return catalog, err
+}
+
+// fresh reports whether the cached fetch can be reused. c.mu is held.
+func (c *instanceConfigCache) fresh() bool {
+	if c.fetched.IsZero() {
+		return false
+	}
+	if c.err != nil {
+		return time.Since(c.fetched) < instanceConfigRetry
+	}
+	return time.Since(c.fetched) < engineCatalogTTL
+}
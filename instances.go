@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// activeInstance returns the client tool calls go to: the instance of the
+// state of ctx, or the first fallback whose circuit is not open while its
+// circuit is. With every circuit open it returns the instance, which fails
+// fast.
+func activeInstance(ctx context.Context) *searxng.Client {
+	return stateOf(ctx).activeInstance()
+}
+
+func (s *serverState) activeInstance() *searxng.Client {
+	if !circuitOpen(s.client) {
+		return s.client
+	}
+	for _, fallback := range s.fallbacks {
+		if !circuitOpen(fallback) {
+			return fallback
+		}
+	}
+	return s.client
+}
+
+func circuitOpen(client *searxng.Client) bool {
+	return client.Breaker != nil && client.Breaker.Open()
+}
+
+// allInstances returns the instance of the state of ctx followed by the
+// fallbacks.
+func allInstances(ctx context.Context) []*searxng.Client {
+	return stateOf(ctx).allInstances()
+}
+
+func (s *serverState) allInstances() []*searxng.Client {
+	return append([]*searxng.Client{s.client}, s.fallbacks...)
+}
+
+// describeCircuits summarizes the circuit breakers for the dashboard.
+func describeCircuits() string {
+	var parts []string
+	for _, client := range currentState().allInstances() {
+		if client.Breaker == nil {
+			continue
+		}
+		state := client.Breaker.State()
+		part := fmt.Sprintf("%s: %s", client.BaseURL, state.State)
+		if state.State != searxng.CircuitClosed {
+			part += fmt.Sprintf(" after %d failures, until %s", state.ConsecutiveFailures, state.OpenUntil.Format("15:04:05"))
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "Circuit breakers disabled"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeConnections summarizes the upstream requests and connections of
+// each instance for the dashboard.
+func describeConnections() string {
+	var parts []string
+	for _, client := range currentState().allInstances() {
+		s := client.PoolStats()
+		part := fmt.Sprintf("%s: %d in flight", client.BaseURL, s.InFlight)
+		if s.MaxConcurrent > 0 {
+			part += fmt.Sprintf(" of at most %d, %d waiting", s.MaxConcurrent, s.Waiting)
+		}
+		part += fmt.Sprintf(", %d requests on %d new and %d reused connections", s.Requests, s.NewConns, s.ReusedConns)
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
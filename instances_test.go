@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+func TestFallbackWhileCircuitOpen(t *testing.T) {
+	primary := useFakeInstance(t)
+	primary.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+	fallback := searxngtest.NewServer()
+	defer fallback.Close()
+	useState(t, func(s *serverState) {
+		s.client = searxng.New(primary.URL, searxng.WithCircuitBreaker(1, time.Hour))
+		s.fallbacks = []*searxng.Client{searxng.New(fallback.URL, searxng.WithCircuitBreaker(1, time.Hour))}
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "first"})
+	if err != nil || !result.IsError {
+		t.Fatalf("first search = %+v, %v; want the primary's error", result, err)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "second"})
+	if err != nil || result.IsError {
+		t.Fatalf("second search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if response.Meta.Instance != fallback.URL {
+		t.Errorf("meta.instance = %q, want the fallback %q", response.Meta.Instance, fallback.URL)
+	}
+
+	// With every circuit open, searches fail fast on the primary.
+	currentState().fallbacks[0].Breaker = &searxng.CircuitBreaker{Failures: 1, Cooldown: time.Hour}
+	fallback.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+	callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "third"})
+	requests := len(primary.Requests())
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "fourth"})
+	if !result.IsError || len(primary.Requests()) != requests {
+		t.Fatalf("search with every circuit open = %+v", result)
+	}
+	if text, _ := mcp.AsTextContent(result.Content[0]); !strings.Contains(text.Text, primary.URL) || !strings.Contains(text.Text, "paused") {
+		t.Errorf("error = %q", text.Text)
+	}
+}
+
+func TestAggregateInstances(t *testing.T) {
+	primary := useFakeInstance(t)
+	second := searxngtest.NewServer()
+	defer second.Close()
+	broken := searxngtest.NewServer()
+	defer broken.Close()
+	broken.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+	useState(t, func(s *serverState) {
+		s.fallbacks = []*searxng.Client{searxng.New(second.URL), searxng.New(broken.URL)}
+	})
+	previousMode := instanceMode
+	instanceMode = instanceAggregate
+	t.Cleanup(func() { instanceMode = previousMode })
+
+	primary.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Only primary", "url": "https://primary.example/", "engine": "google"},
+			{"title": "Shared", "url": "https://shared.example/page", "engine": "google"},
+		},
+		"suggestions": []string{"golang tutorial"},
+	})
+	second.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Shared", "url": "http://www.shared.example/page/", "engine": "bing"},
+			{"title": "Only second", "url": "https://second.example/", "engine": "bing"},
+		},
+		"suggestions": []string{"golang tutorial", "golang generics"},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+
+	var titles []string
+	for _, r := range response.Results {
+		titles = append(titles, r.Title)
+	}
+	if got := strings.Join(titles, ", "); got != "Shared, Only primary, Only second" {
+		t.Errorf("results = %s, want the result of both instances first", got)
+	}
+	if got := response.Results[0].Instances; len(got) != 2 || got[0] != primary.URL || got[1] != second.URL {
+		t.Errorf("instances of the shared result = %q", got)
+	}
+	if response.Meta.Instance != instanceAggregate || len(response.Meta.AggregatedInstances) != 2 {
+		t.Errorf("meta = %+v, want the two answering instances", response.Meta)
+	}
+	if len(response.Meta.Warnings) != 1 || !strings.Contains(response.Meta.Warnings[0], broken.URL) {
+		t.Errorf("warnings = %q, want the failed instance", response.Meta.Warnings)
+	}
+	if strings.Join(response.Suggestions, ", ") != "golang tutorial, golang generics" {
+		t.Errorf("suggestions = %q", response.Suggestions)
+	}
+}
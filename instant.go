@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// defaultAnswerEngines are the engines that produce answers and infoboxes:
+// encyclopedias, dictionaries and the currency converter. SearXNG answerers
+// and plugins (unit conversion, calculator) answer whatever the engines.
+var defaultAnswerEngines = []string{"wikipedia", "wikidata", "wiktionary", "wordnik", "duckduckgo definitions", "currency"}
+
+// infobox is the summary card of an entity, with the pages it was drawn
+// from.
+type infobox struct {
+	Title   string `json:"title"`
+	URL     string `json:"url,omitempty"`
+	Content string `json:"content,omitempty"`
+	Engine  string `json:"engine,omitempty"`
+	Image   string `json:"image,omitempty"`
+	// Attributes are the facts of the card, e.g. "Population: 3.6 million".
+	Attributes []infoboxAttribute `json:"attributes,omitempty"`
+	Sources    []infoboxSource    `json:"sources,omitempty"`
+}
+
+type infoboxAttribute struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+type infoboxSource struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+type instantAnswerResponse struct {
+	Query     string           `json:"query"`
+	Answers   []searxng.Answer `json:"answers"`
+	Infoboxes []infobox        `json:"infoboxes"`
+	// Hint is set when nothing answered the question directly.
+	Hint                string                       `json:"hint,omitempty"`
+	UnresponsiveEngines []searxng.UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+func searxngAnswerHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, ok := request.GetArguments()["query"].(string)
+	if !ok {
+		return invalidArgumentsResult(errors.New("query must be a string")), nil
+	}
+
+	params := searxng.SearchParams{
+		Query:    query,
+		Engines:  append([]string(nil), defaultAnswerEngines...),
+		Language: stateOf(ctx).language,
+	}
+
+	if engines, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.Engines = engines
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if language, ok := request.GetArguments()["language"].(string); ok && language != "" {
+		params.Language = language
+	}
+
+	dryRun, err := isDryRun(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	meta := prepareSearch(ctx, &params)
+	if dryRun {
+		return dryRunResult(ctx, params, meta, request.GetArguments(), "engines", "language")
+	}
+
+	result, cachedAt, err := cachedSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	if err != nil {
+		return upstreamErrorResult("answer search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
+	}
+
+	response := instantAnswerResponse{
+		Query:               result.Query,
+		Answers:             result.Answers,
+		Infoboxes:           make([]infobox, 0, len(result.Infoboxes)),
+		UnresponsiveEngines: result.UnresponsiveEngines,
+	}
+	if response.Answers == nil {
+		response.Answers = []searxng.Answer{}
+	}
+	for _, raw := range result.Infoboxes {
+		if box, ok := newInfobox(raw); ok {
+			response.Infoboxes = append(response.Infoboxes, box)
+		}
+	}
+	if len(response.Answers) == 0 && len(response.Infoboxes) == 0 {
+		response.Hint = "no direct answer; use searxng_search_v2 for a full result list"
+	}
+
+	return structuredResult(response)
+}
+
+// newInfobox reads an infobox of a SearXNG response. Its id is the URL of
+// the entity, and its urls link the pages the card was drawn from.
+func newInfobox(raw interface{}) (infobox, bool) {
+	var box struct {
+		Infobox    string `json:"infobox"`
+		ID         string `json:"id"`
+		Content    string `json:"content"`
+		Engine     string `json:"engine"`
+		ImgSrc     string `json:"img_src"`
+		Attributes []struct {
+			Label string      `json:"label"`
+			Value interface{} `json:"value"`
+		} `json:"attributes"`
+		URLs []struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+		} `json:"urls"`
+	}
+	data, err := json.Marshal(raw)
+	if err != nil || json.Unmarshal(data, &box) != nil || box.Infobox == "" {
+		return infobox{}, false
+	}
+
+	result := infobox{
+		Title:   box.Infobox,
+		Content: strings.TrimSpace(box.Content),
+		Engine:  box.Engine,
+		Image:   box.ImgSrc,
+	}
+	if strings.HasPrefix(box.ID, "http://") || strings.HasPrefix(box.ID, "https://") {
+		result.URL = box.ID
+	}
+	for _, a := range box.Attributes {
+		value := ""
+		switch v := a.Value.(type) {
+		case string:
+			value = v
+		case nil:
+		default:
+			value = fmt.Sprint(v)
+		}
+		if a.Label != "" && value != "" {
+			result.Attributes = append(result.Attributes, infoboxAttribute{Label: a.Label, Value: value})
+		}
+	}
+	for _, u := range box.URLs {
+		if u.URL != "" {
+			result.Sources = append(result.Sources, infoboxSource{Title: u.Title, URL: u.URL})
+		}
+	}
+	return result, true
+}
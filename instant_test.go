@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestAnswerTool(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"query":   "population of berlin",
+		"results": []map[string]interface{}{{"title": "Berlin", "url": "https://en.wikipedia.org/wiki/Berlin", "engine": "wikipedia"}},
+		"answers": []interface{}{
+			map[string]interface{}{"answer": "3.7 million", "url": "https://www.wikidata.org/wiki/Q64", "engine": "wikidata"},
+		},
+		"infoboxes": []map[string]interface{}{{
+			"infobox": "Berlin",
+			"id":      "https://en.wikipedia.org/wiki/Berlin",
+			"content": " Capital of Germany ",
+			"engine":  "wikidata",
+			"attributes": []map[string]interface{}{
+				{"label": "Population", "value": "3,677,472"},
+				{"label": "Area", "value": 891.7},
+				{"label": "Empty", "value": nil},
+			},
+			"urls": []map[string]interface{}{
+				{"title": "Wikipedia", "url": "https://en.wikipedia.org/wiki/Berlin"},
+				{"title": "Wikidata", "url": "https://www.wikidata.org/wiki/Q64"},
+			},
+		}},
+	})
+
+	result, err := callTool(t, searxngAnswerHandler, map[string]interface{}{"query": "population of berlin"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("engines") != "wikipedia,wikidata,wiktionary,wordnik,duckduckgo definitions,currency" {
+		t.Errorf("engines = %q", req.Query.Get("engines"))
+	}
+
+	var response instantAnswerResponse
+	decodeResult(t, result, &response)
+	if want := []searxng.Answer{{Answer: "3.7 million", URL: "https://www.wikidata.org/wiki/Q64", Engine: "wikidata"}}; !reflect.DeepEqual(response.Answers, want) {
+		t.Errorf("answers = %+v", response.Answers)
+	}
+	want := []infobox{{
+		Title:   "Berlin",
+		URL:     "https://en.wikipedia.org/wiki/Berlin",
+		Content: "Capital of Germany",
+		Engine:  "wikidata",
+		Attributes: []infoboxAttribute{
+			{Label: "Population", Value: "3,677,472"},
+			{Label: "Area", Value: "891.7"},
+		},
+		Sources: []infoboxSource{
+			{Title: "Wikipedia", URL: "https://en.wikipedia.org/wiki/Berlin"},
+			{Title: "Wikidata", URL: "https://www.wikidata.org/wiki/Q64"},
+		},
+	}}
+	if !reflect.DeepEqual(response.Infoboxes, want) {
+		t.Errorf("infoboxes = %+v\nwant %+v", response.Infoboxes, want)
+	}
+	if response.Hint != "" {
+		t.Errorf("hint = %q", response.Hint)
+	}
+}
+
+func TestAnswerToolNoAnswer(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{{"title": "Something", "url": "https://example.com", "engine": "wikipedia"}},
+	})
+
+	result, err := callTool(t, searxngAnswerHandler, map[string]interface{}{"query": "obscure question", "engines": "wikipedia"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var response instantAnswerResponse
+	decodeResult(t, result, &response)
+	if len(response.Answers) != 0 || len(response.Infoboxes) != 0 || response.Hint == "" {
+		t.Errorf("response = %+v, want no answer and a hint", response)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// autoLanguage is the language argument value asking the server to detect
+// the language from the query.
+const autoLanguage = "auto"
+
+// languagePattern matches the values of the default language, set with
+// -default-language or the default_language key of the config.
+var languagePattern = regexp.MustCompile(`^(auto|all|[a-z]{2,3}(-[A-Za-z]{2})?)$`)
+
+// checkDefaultLanguage checks a default language.
+func checkDefaultLanguage(language string) error {
+	if !languagePattern.MatchString(language) {
+		return fmt.Errorf("invalid default language %q: use auto, all or a language code such as ru or pt-BR", language)
+	}
+	return nil
+}
+
+// languageHint describes the default of the language argument for tool
+// descriptions.
+func languageHint() string {
+	defaultLanguage := currentState().language
+	switch defaultLanguage {
+	case autoLanguage:
+		return "default auto: detected from the query"
+	case fallbackLanguage:
+		return "default all: every language"
+	}
+	return "default " + defaultLanguage + ", auto detects it from the query"
+}
+
+// fallbackLanguage is sent when the language of a query cannot be told,
+// e.g. for "golang generics": it searches all languages.
+const fallbackLanguage = "all"
+
+// scriptLanguages maps scripts used by a single major language. Kana is
+// checked before Han since Japanese mixes both.
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// languageWords are frequent short words of the languages sharing the
+// Latin and Cyrillic scripts, matched as whole words.
+var languageWords = map[string][]string{
+	"en": {"the", "and", "how", "what", "with", "for", "is", "of", "to", "does", "why", "best"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "wie", "mit", "für", "ein", "eine", "was", "warum", "ich", "von"},
+	"fr": {"le", "la", "les", "des", "est", "et", "pour", "comment", "une", "avec", "pourquoi", "dans", "du"},
+	"es": {"el", "los", "las", "es", "y", "para", "como", "cómo", "una", "con", "por", "qué", "del", "la", "de"},
+	"it": {"il", "lo", "gli", "è", "per", "come", "una", "con", "che", "perché", "della"},
+	"pt": {"o", "os", "as", "é", "para", "como", "uma", "com", "não", "por", "que", "do", "da", "de"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "hoe", "met", "voor", "wat", "waarom", "van"},
+	"ru": {"и", "в", "на", "как", "что", "это", "не", "для", "с", "по", "почему"},
+	"uk": {"і", "в", "на", "як", "що", "це", "не", "для", "з", "чому"},
+}
+
+// languageNgrams are letter n-grams typical of each language, matched
+// inside words; "_" marks a word boundary.
+var languageNgrams = map[string][]string{
+	"en": {"_th", "ing", "ng_", "tio", "wh", "ght"},
+	"de": {"sch", "ung", "cht", "_ge", "ei"},
+	"fr": {"_qu", "eau", "oi", "ux_", "ère"},
+	"es": {"ció", "ón_", "ar_", "ado"},
+	"it": {"zio", "gli", "_ch", "tto"},
+	"pt": {"ção", "ões", "ão_", "nh", "lh"},
+	"nl": {"ij", "oo", "aa", "ee"},
+	"ru": {"ть_", "ого", "ени", "ый_", "ой_", "ий_", "ния"},
+	"uk": {"ння", "ськ", "ти_", "ію"},
+}
+
+// languageLetters are letters that (almost) only one language of a script
+// uses; each counts as strong evidence.
+var languageLetters = map[rune]string{
+	'ß': "de", 'ä': "de", 'ö': "de", 'ü': "de",
+	'ç': "fr", 'è': "fr", 'ê': "fr", 'à': "fr", 'œ': "fr",
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ã': "pt", 'õ': "pt",
+	'ы': "ru", 'э': "ru", 'ъ': "ru", 'ё': "ru",
+	'і': "uk", 'ї': "uk", 'є': "uk", 'ґ': "uk",
+}
+
+// detectLanguage guesses the language code of a query. It reports false
+// when the query carries too little signal, which is common for short
+// keyword queries.
+func detectLanguage(text string) (string, bool) {
+	for _, s := range scriptLanguages {
+		for _, r := range text {
+			if unicode.Is(s.script, r) {
+				return s.language, true
+			}
+		}
+	}
+
+	scores := make(map[string]int)
+	lower := strings.ToLower(text)
+	for _, r := range lower {
+		if language, ok := languageLetters[r]; ok {
+			scores[language] += 3
+		}
+	}
+
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		padded := "_" + word + "_"
+		for language, common := range languageWords {
+			for _, w := range common {
+				if w == word {
+					scores[language] += 2
+				}
+			}
+		}
+		for language, ngrams := range languageNgrams {
+			for _, ngram := range ngrams {
+				if strings.Contains(padded, ngram) {
+					scores[language]++
+				}
+			}
+		}
+	}
+
+	best, bestScore, second := "", 0, 0
+	for language, score := range scores {
+		if score > bestScore || (score == bestScore && language < best) {
+			best, bestScore, second = language, score, bestScore
+		} else if score > second {
+			second = score
+		}
+	}
+	if bestScore < 3 || bestScore == second {
+		return "", false
+	}
+	return best, true
+}
+
+// resolveLanguage replaces the auto language of params by the detected
+// one. It reports whether the language was detected.
+func resolveLanguage(params *searxng.SearchParams) bool {
+	if params.Language != autoLanguage {
+		return false
+	}
+	language, ok := detectLanguage(params.Query)
+	if !ok {
+		params.Language = fallbackLanguage
+		return false
+	}
+	params.Language = language
+	return true
+}
+
+// detectResultLanguage sets the detected language of a result from its
+// title and content.
+func detectResultLanguage(r *annotatedResult) {
+	r.DetectedLanguage = resultLanguage(r.SearchResult)
+}
+
+// languageFilter keeps the results in the languages of only_language.
+type languageFilter []string
+
+func languageFilterFromArguments(arguments map[string]interface{}) (languageFilter, error) {
+	languages, ok, err := listArgument(arguments, "only_language")
+	if err != nil || !ok {
+		return nil, err
+	}
+	var filter languageFilter
+	for _, language := range languages {
+		language = strings.ToLower(language)
+		if !languagePattern.MatchString(language) || language == autoLanguage || language == fallbackLanguage {
+			return nil, fmt.Errorf("only_language must be language codes such as en or de,fr, got %q", language)
+		}
+		base, _, _ := strings.Cut(language, "-")
+		filter = append(filter, base)
+	}
+	return filter, nil
+}
+
+// resultLanguage returns the detected language of a result, empty when
+// its title and content carry too little signal to tell.
+func resultLanguage(r searxng.SearchResult) string {
+	language, _ := detectLanguage(r.Title + " " + collapse(r.Content))
+	return language
+}
+
+// Process removes the results detected in another language. Results of
+// no detected language are kept: short snippets often carry too little
+// signal.
+func (f languageFilter) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	kept := make([]searxng.SearchResult, 0, len(results))
+	for _, r := range results {
+		if language := resultLanguage(r); language != "" && !containsString(f, language) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// countResultLanguages counts the detected languages of results in meta.
+func countResultLanguages(results []searxng.SearchResult, meta *searchMeta) {
+	for _, r := range results {
+		language := resultLanguage(r)
+		if language == "" {
+			continue
+		}
+		if meta.ResultLanguages == nil {
+			meta.ResultLanguages = make(map[string]int)
+		}
+		meta.ResultLanguages[language]++
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"how to install the latest version of python", "en"},
+		{"what is the best laptop for programming", "en"},
+		{"как установить последнюю версию питона", "ru"},
+		{"лучший ноутбук для программирования", "ru"},
+		{"wie installiere ich die neueste Version von Python", "de"},
+		{"Größe der Schüler in Deutschland", "de"},
+		{"comment installer la dernière version de python", "fr"},
+		{"pourquoi le ciel est bleu", "fr"},
+		{"cómo instalar la última versión de python", "es"},
+		{"як встановити останню версію пайтона", "uk"},
+		{"東京の天気", "ja"},
+		{"北京天气预报", "zh"},
+		{"서울 날씨", "ko"},
+		{"golang generics", ""},
+		{"iphone 15", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, ok := detectLanguage(tt.query)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("detectLanguage = %q, want no detection", got)
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Errorf("detectLanguage = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	params := searxng.SearchParams{Query: "как установить питон", Language: autoLanguage}
+	if !resolveLanguage(&params) || params.Language != "ru" {
+		t.Errorf("language = %q, want ru", params.Language)
+	}
+
+	params = searxng.SearchParams{Query: "golang", Language: autoLanguage}
+	if resolveLanguage(&params) || params.Language != fallbackLanguage {
+		t.Errorf("language = %q, want the %q fallback", params.Language, fallbackLanguage)
+	}
+
+	params = searxng.SearchParams{Query: "как установить питон", Language: "en"}
+	if resolveLanguage(&params) || params.Language != "en" {
+		t.Errorf("explicit language was replaced by %q", params.Language)
+	}
+}
+
+func TestSearchV2DetectsLanguage(t *testing.T) {
+	fake := useFakeInstance(t)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "как установить питон"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if response.Meta.Language != "ru" || !response.Meta.LanguageDetected {
+		t.Errorf("meta = %+v, want the detected ru language", response.Meta)
+	}
+	req, _ := fake.LastRequest("/search")
+	if got := req.Query.Get("language"); got != "ru" {
+		t.Errorf("language = %q, want ru", got)
+	}
+}
+
+func TestCheckDefaultLanguage(t *testing.T) {
+	for _, language := range []string{"auto", "all", "ru", "pt-BR"} {
+		if err := checkDefaultLanguage(language); err != nil {
+			t.Errorf("checkDefaultLanguage(%q) = %v", language, err)
+		}
+	}
+	for _, language := range []string{"", "russian", "ru_RU", "RU"} {
+		if err := checkDefaultLanguage(language); err == nil {
+			t.Errorf("checkDefaultLanguage(%q) accepted", language)
+		}
+	}
+}
+
+func TestSearchV2DefaultLanguage(t *testing.T) {
+	fake := useFakeInstance(t)
+
+	tests := []struct {
+		defaultLanguage string
+		arguments       map[string]interface{}
+		want            string
+	}{
+		{"all", map[string]interface{}{"query": "как установить питон"}, "all"},
+		{"de", map[string]interface{}{"query": "golang generics"}, "de"},
+		{"de", map[string]interface{}{"query": "golang generics", "language": "fr"}, "fr"},
+		{"de", map[string]interface{}{"query": "как установить питон", "language": "auto"}, "ru"},
+	}
+	for _, tt := range tests {
+		useState(t, func(s *serverState) { s.language = tt.defaultLanguage })
+		if _, err := callTool(t, searxngSearchV2Handler, tt.arguments); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+		req, _ := fake.LastRequest("/search")
+		if got := req.Query.Get("language"); got != tt.want {
+			t.Errorf("default %s, arguments %v: language = %q, want %q", tt.defaultLanguage, tt.arguments, got, tt.want)
+		}
+	}
+}
+
+func TestSearchV2OnlyLanguage(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "How to install the Go toolchain", "url": "https://example.com/en", "content": "What is the best way to install it and why"},
+			{"title": "Wie man die Go Toolchain installiert", "url": "https://example.de/de", "content": "Das ist nicht schwer, für alle Systeme"},
+			{"title": "Как установить Go", "url": "https://example.ru/ru", "content": "Это просто и для всех систем"},
+			{"title": "Go 1.23", "url": "https://go.dev/"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "install go", "only_language": "en,de-AT"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	var got []string
+	for _, r := range response.Results {
+		got = append(got, r.URL+" "+r.DetectedLanguage)
+	}
+	want := []string{"https://example.com/en en", "https://example.de/de de", "https://go.dev/ "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+	if m := response.Meta; m.LanguageFilteredResults != 1 || !reflect.DeepEqual(m.ResultLanguages, map[string]int{"en": 1, "de": 1, "ru": 1}) {
+		t.Errorf("meta languages %v, %d filtered", m.ResultLanguages, m.LanguageFilteredResults)
+	}
+
+	if result, _ := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "install go", "only_language": "auto"}); !result.IsError {
+		t.Error("only_language auto accepted")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// socketMode is the permissions of unix sockets, set by -socket-mode.
+// Only the owner and the group may connect by default.
+var socketMode fileModeFlag = 0o660
+
+// listenAddr is an address the sse server listens on: host:port on the
+// "tcp" network, or a socket path on the "unix" network.
+type listenAddr struct {
+	network string
+	address string
+}
+
+func (a listenAddr) String() string {
+	if a.network == "unix" {
+		return "unix:" + a.address
+	}
+	return a.address
+}
+
+// hostNamePattern matches DNS host names such as localhost or
+// mcp.internal.example.com.
+var hostNamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*\.?$`)
+
+// tcpListenAddr validates host and port and joins them, bracketing IPv6
+// literals. An empty host listens on every interface; "::" does so on
+// both IPv4 and IPv6.
+func tcpListenAddr(host, port string) (listenAddr, error) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host != "" && (!hostNamePattern.MatchString(host) || strings.Trim(host, "0123456789.") == "") {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return listenAddr{}, fmt.Errorf("invalid host %q: want an IP address, e.g. 127.0.0.1 or ::1, or a host name", host)
+		}
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return listenAddr{}, fmt.Errorf("invalid port %q: want a number from 1 to 65535", port)
+	}
+	return listenAddr{network: "tcp", address: net.JoinHostPort(host, port)}, nil
+}
+
+// parseListenAddrs reads the comma-separated addresses of -listen, e.g.
+// "[::1]:8892,127.0.0.1:8892" or "unix:/run/mcp-searxng.sock".
+func parseListenAddrs(spec string) ([]listenAddr, error) {
+	var addrs []listenAddr
+	seen := make(map[listenAddr]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var addr listenAddr
+		if path, ok := strings.CutPrefix(entry, "unix:"); ok {
+			if path == "" {
+				return nil, fmt.Errorf("listen address %q: missing socket path", entry)
+			}
+			addr = listenAddr{network: "unix", address: path}
+		} else {
+			host, port, err := net.SplitHostPort(entry)
+			if err != nil {
+				return nil, fmt.Errorf("listen address %q: want host:port, e.g. 127.0.0.1:8892 or [::1]:8892", entry)
+			}
+			if addr, err = tcpListenAddr(host, port); err != nil {
+				return nil, fmt.Errorf("listen address %q: %w", entry, err)
+			}
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("no listen address")
+	}
+	return addrs, nil
+}
+
+// serverAddrs returns the addresses of the sse server: those of -listen
+// when given, else -h and -p.
+func serverAddrs(listen, host, port string) ([]listenAddr, error) {
+	if strings.TrimSpace(listen) != "" {
+		return parseListenAddrs(listen)
+	}
+	addr, err := tcpListenAddr(host, port)
+	if err != nil {
+		return nil, err
+	}
+	return []listenAddr{addr}, nil
+}
+
+// transportAddrs returns the addresses of the sse transport, or the socket
+// of the unix transport, which serves the same SSE endpoint.
+func transportAddrs(transport, listen, host, port, socket string) ([]listenAddr, error) {
+	if transport == "unix" {
+		if socket == "" {
+			return nil, errors.New("the unix transport needs -socket, e.g. -socket /run/mcp-searxng.sock")
+		}
+		return []listenAddr{{network: "unix", address: socket}}, nil
+	}
+	return serverAddrs(listen, host, port)
+}
+
+// listenAll opens a listener on every address. Either all of them are
+// open, or none is and the error names the address that failed.
+func listenAll(addrs []listenAddr) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		var l net.Listener
+		var err error
+		if addr.network == "unix" {
+			l, err = listenUnix(addr.address, os.FileMode(socketMode))
+		} else {
+			l, err = net.Listen(addr.network, addr.address)
+		}
+		if err != nil {
+			for _, open := range listeners {
+				open.Close()
+			}
+			return nil, fmt.Errorf("listening on %s: %w", addr, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on the socket at path with the given permissions. A
+// stale socket left by a server that did not shut down cleanly is
+// replaced; a socket another server still answers on, or a file that is
+// not a socket, is left alone. The socket is removed when the listener
+// closes.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	return l, nil
+}
+
+// firstTCPAddr returns the first host:port of addrs, which the base URL,
+// the log and -healthcheck use.
+func firstTCPAddr(addrs []listenAddr) (host, port string, ok bool) {
+	for _, addr := range addrs {
+		if addr.network == "tcp" {
+			host, port, err := net.SplitHostPort(addr.address)
+			return host, port, err == nil
+		}
+	}
+	return "", "", false
+}
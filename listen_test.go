@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerAddrs(t *testing.T) {
+	tests := []struct {
+		listen, host, port string
+		want               []listenAddr
+		err                string
+	}{
+		{host: "0.0.0.0", port: "8892", want: []listenAddr{{"tcp", "0.0.0.0:8892"}}},
+		{host: "::1", port: "8892", want: []listenAddr{{"tcp", "[::1]:8892"}}},
+		{host: "[::]", port: "8892", want: []listenAddr{{"tcp", "[::]:8892"}}},
+		{host: "", port: "8892", want: []listenAddr{{"tcp", ":8892"}}},
+		{host: "mcp.internal", port: "80", want: []listenAddr{{"tcp", "mcp.internal:80"}}},
+		{
+			listen: "[::1]:8892, 127.0.0.1:8892,[::1]:8892,unix:/run/mcp.sock", host: "bad host", port: "x",
+			want: []listenAddr{{"tcp", "[::1]:8892"}, {"tcp", "127.0.0.1:8892"}, {"unix", "/run/mcp.sock"}},
+		},
+		{host: "bad host", port: "8892", err: "invalid host"},
+		{host: "300.1.1.1", port: "8892", err: "invalid host"},
+		{host: "127.0.0.1", port: "http", err: "invalid port"},
+		{host: "127.0.0.1", port: "70000", err: "invalid port"},
+		{listen: "::1:8892", err: "want host:port"},
+		{listen: "unix:", err: "missing socket path"},
+	}
+	for _, tt := range tests {
+		got, err := serverAddrs(tt.listen, tt.host, tt.port)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("serverAddrs(%q, %q, %q) error = %v, want %q", tt.listen, tt.host, tt.port, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("serverAddrs(%q, %q, %q) = %v, %v, want %v", tt.listen, tt.host, tt.port, got, err, tt.want)
+		}
+	}
+}
+
+func TestListenAll(t *testing.T) {
+	listeners, err := listenAll([]listenAddr{{"tcp", "127.0.0.1:0"}, {"tcp", "127.0.0.1:0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("%d listeners, want 2", len(listeners))
+	}
+	taken := listeners[0].Addr().String()
+	for _, l := range listeners {
+		l.Close()
+	}
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	if _, err := listenAll([]listenAddr{{"tcp", taken}, {"tcp", busy.Addr().String()}}); err == nil || !strings.Contains(err.Error(), busy.Addr().String()) {
+		t.Errorf("error = %v, want one naming the busy address", err)
+	}
+	// The first listener was closed again: its address is free.
+	if l, err := net.Listen("tcp", taken); err != nil {
+		t.Errorf("first address left open: %v", err)
+	} else {
+		l.Close()
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.sock")
+	addrs, err := transportAddrs("unix", "", "0.0.0.0", "8892", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transportAddrs("unix", "", "0.0.0.0", "8892", ""); err == nil {
+		t.Error("unix transport without -socket accepted")
+	}
+
+	listeners, err := listenAll(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o660 {
+		t.Errorf("socket mode = %v, %v, want 0660", info.Mode().Perm(), err)
+	}
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listeners[0])
+	if err := healthcheckServer(addrs); err != nil {
+		t.Errorf("healthcheck over the socket: %v", err)
+	}
+	if _, err := listenUnix(path, 0o600); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("second server: %v, want an in use error", err)
+	}
+	server.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket left after close: %v", err)
+	}
+
+	// A socket nobody answers on any more is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	l, err := listenUnix(path, 0o600)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	l.Close()
+
+	regular := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(regular, nil, 0o600)
+	if _, err := listenUnix(regular, 0o600); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("regular file: %v, want a not a socket error", err)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	// localStatsWindow is the number of recent searches of an engine its
+	// local stats cover.
+	localStatsWindow = 100
+	// brokenEngineSearches and brokenEngineErrorRate make an engine
+	// chronically broken: at least that many recent searches, failing at
+	// least at that rate. engines=auto leaves such engines out.
+	brokenEngineSearches  = 10
+	brokenEngineErrorRate = 0.5
+	// localStatsSaveInterval is how often changed stats are written to
+	// -engine-stats-file.
+	localStatsSaveInterval = time.Minute
+)
+
+// engineOutcome is an engine in a search the server sent: the latency of
+// the whole search, the results the engine contributed, or its error.
+type engineOutcome struct {
+	At        time.Time `json:"at"`
+	LatencyMS int64     `json:"latency_ms"`
+	Results   int       `json:"results"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// localEngineStats records how the engines behaved in the searches of this
+// server, unlike searxng_engine_stats, which reports the statistics of the
+// instance: all its users, reset when it restarts.
+type localEngineStats struct {
+	mu      sync.Mutex
+	path    string
+	dirty   bool
+	engines map[string][]engineOutcome
+}
+
+var engineOutcomes = &localEngineStats{engines: make(map[string][]engineOutcome)}
+
+// loadLocalEngineStats reads the stats persisted at path, if any; an empty
+// path keeps them in memory.
+func loadLocalEngineStats(path string) (*localEngineStats, error) {
+	s := &localEngineStats{path: path, engines: make(map[string][]engineOutcome)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading engine stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.engines); err != nil {
+		return nil, fmt.Errorf("error parsing engine stats %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// record adds the outcome of a search to the stats of its engines: those
+// requested, those that returned results and those that failed.
+func (s *localEngineStats) record(ctx context.Context, requested []string, result *searxng.SearchResponse, elapsed time.Duration) {
+	now := time.Now()
+	outcomes := make(map[string]*engineOutcome)
+	outcome := func(engine string) *engineOutcome {
+		engine = strings.ToLower(strings.TrimSpace(engine))
+		if engine == "" {
+			return nil
+		}
+		if o, ok := outcomes[engine]; ok {
+			return o
+		}
+		o := &engineOutcome{At: now, LatencyMS: elapsed.Milliseconds()}
+		outcomes[engine] = o
+		return o
+	}
+	for _, engine := range realEngines(ctx, requested) {
+		outcome(engine)
+	}
+	for _, r := range result.Results {
+		if o := outcome(r.Engine); o != nil {
+			o.Results++
+		}
+	}
+	for _, e := range result.UnresponsiveEngines {
+		if o := outcome(e.Name); o != nil {
+			o.Error = e.Reason
+			if o.Error == "" {
+				o.Error = "unresponsive"
+			}
+		}
+	}
+
+	s.add(outcomes)
+}
+
+// recordFailure adds a search that failed as a whole, timing out, failing
+// on the instance or refused by its open circuit breaker, as an error of
+// each requested engine. Searches the caller canceled or a policy refused
+// say nothing about the engines and are not recorded.
+func (s *localEngineStats) recordFailure(ctx context.Context, requested []string, err error, elapsed time.Duration) {
+	var policyErr *policyError
+	if errors.As(err, &policyErr) {
+		return
+	}
+	code := classifyUpstreamError(err).Code
+	switch code {
+	case errorTimeout, errorInstanceDown, errorRateLimited, errorUpstream:
+	default:
+		return
+	}
+	now := time.Now()
+	outcomes := make(map[string]*engineOutcome)
+	for _, engine := range realEngines(ctx, requested) {
+		if engine = strings.ToLower(strings.TrimSpace(engine)); engine != "" {
+			outcomes[engine] = &engineOutcome{At: now, LatencyMS: elapsed.Milliseconds(), Error: code}
+		}
+	}
+	s.add(outcomes)
+}
+
+// add appends outcomes to the history of their engines.
+func (s *localEngineStats) add(outcomes map[string]*engineOutcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for engine, o := range outcomes {
+		history := append(s.engines[engine], *o)
+		if len(history) > localStatsWindow {
+			history = slices.Clone(history[len(history)-localStatsWindow:])
+		}
+		s.engines[engine] = history
+	}
+	s.dirty = s.dirty || len(outcomes) > 0
+}
+
+// realEngines returns requested without the synthetic engines.
+func realEngines(ctx context.Context, requested []string) []string {
+	var engines []string
+	for _, engine := range requested {
+		if _, synthetic := stateOf(ctx).config.SyntheticEngines[engine]; !synthetic {
+			engines = append(engines, engine)
+		}
+	}
+	return engines
+}
+
+// localEngineSummary describes the recent searches of an engine.
+type localEngineSummary struct {
+	Engine    string  `json:"engine"`
+	Searches  int     `json:"searches"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// AvgLatencyMS and P90LatencyMS are those of the searches the engine
+	// answered in.
+	AvgLatencyMS int64   `json:"avg_latency_ms"`
+	P90LatencyMS int64   `json:"p90_latency_ms"`
+	AvgResults   float64 `json:"avg_results"`
+	LastSearch   string  `json:"last_search"`
+	LastError    string  `json:"last_error,omitempty"`
+	LastErrorAt  string  `json:"last_error_at,omitempty"`
+	// Broken is set for chronically failing engines, which engines=auto
+	// leaves out.
+	Broken bool `json:"broken,omitempty"`
+}
+
+func summarizeOutcomes(engine string, history []engineOutcome) localEngineSummary {
+	summary := localEngineSummary{Engine: engine, Searches: len(history)}
+	var latencies []int64
+	results := 0
+	for _, o := range history {
+		results += o.Results
+		if o.Error != "" {
+			summary.Errors++
+			summary.LastError, summary.LastErrorAt = o.Error, o.At.UTC().Format(time.RFC3339)
+			continue
+		}
+		latencies = append(latencies, o.LatencyMS)
+	}
+	if len(history) > 0 {
+		summary.ErrorRate = float64(summary.Errors) / float64(len(history))
+		summary.AvgResults = float64(results) / float64(len(history))
+		summary.LastSearch = history[len(history)-1].At.UTC().Format(time.RFC3339)
+	}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		var total int64
+		for _, l := range latencies {
+			total += l
+		}
+		summary.AvgLatencyMS = total / int64(len(latencies))
+		summary.P90LatencyMS = latencies[(len(latencies)*9)/10]
+	}
+	summary.Broken = summary.Searches >= brokenEngineSearches && summary.ErrorRate >= brokenEngineErrorRate
+	return summary
+}
+
+// summaries describes the engines, the most failing first.
+func (s *localEngineStats) summaries() []localEngineSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summaries := make([]localEngineSummary, 0, len(s.engines))
+	for engine, history := range s.engines {
+		summaries = append(summaries, summarizeOutcomes(engine, history))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].ErrorRate != summaries[j].ErrorRate {
+			return summaries[i].ErrorRate > summaries[j].ErrorRate
+		}
+		return summaries[i].Engine < summaries[j].Engine
+	})
+	return summaries
+}
+
+// broken reports whether engine has been failing chronically.
+func (s *localEngineStats) broken(engine string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return summarizeOutcomes(engine, s.engines[strings.ToLower(engine)]).Broken
+}
+
+// save writes the stats to their file when they changed.
+func (s *localEngineStats) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" || !s.dirty {
+		return nil
+	}
+	data, err := json.Marshal(s.engines)
+	if err != nil {
+		return fmt.Errorf("error serializing engine stats: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("error writing engine stats: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// run saves the stats every localStatsSaveInterval until ctx ends.
+func (s *localEngineStats) run(ctx context.Context) {
+	ticker := time.NewTicker(localStatsSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.save(); err != nil {
+				log.Printf("Engine stats: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type localEngineStatsResponse struct {
+	Engines []localEngineSummary `json:"engines"`
+	// Window is the number of recent searches per engine the stats cover.
+	Window int `json:"window"`
+}
+
+func localEngineStatsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter, _, err := listArgument(request.GetArguments(), "engines")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	response := localEngineStatsResponse{Engines: []localEngineSummary{}, Window: localStatsWindow}
+	for _, summary := range engineOutcomes.summaries() {
+		if len(filter) > 0 && !slices.ContainsFunc(filter, func(name string) bool { return strings.EqualFold(name, summary.Engine) }) {
+			continue
+		}
+		response.Engines = append(response.Engines, summary)
+	}
+	return structuredResult(response)
+}
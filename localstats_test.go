@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// useLocalEngineStats gives the test empty local engine stats persisted at
+// path.
+func useLocalEngineStats(t *testing.T, path string) *localEngineStats {
+	t.Helper()
+	stats, err := loadLocalEngineStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := engineOutcomes
+	engineOutcomes = stats
+	t.Cleanup(func() { engineOutcomes = previous })
+	return stats
+}
+
+func TestLocalEngineStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "engines.json")
+	stats := useLocalEngineStats(t, path)
+	useConfig(t, &Config{})
+
+	for i := 0; i < 12; i++ {
+		result := &searxng.SearchResponse{Results: []searxng.SearchResult{{URL: "https://a.example/", Engine: "duckduckgo"}}}
+		if i%4 != 0 {
+			result.UnresponsiveEngines = []searxng.UnresponsiveEngine{{Name: "google", Reason: "CAPTCHA"}}
+		}
+		stats.record(context.Background(), []string{"google", "duckduckgo"}, result, time.Duration(i+1)*100*time.Millisecond)
+	}
+	if err := stats.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := useLocalEngineStats(t, path)
+	summaries := loaded.summaries()
+	if len(summaries) != 2 || summaries[0].Engine != "google" || summaries[1].Engine != "duckduckgo" {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+	google, duckduckgo := summaries[0], summaries[1]
+	if google.Searches != 12 || google.Errors != 9 || google.ErrorRate != 0.75 || !google.Broken || google.LastError != "CAPTCHA" {
+		t.Errorf("google = %+v", google)
+	}
+	if google.AvgLatencyMS != 500 || google.P90LatencyMS != 900 {
+		t.Errorf("google latency = %d avg, %d p90, want 500, 900", google.AvgLatencyMS, google.P90LatencyMS)
+	}
+	if duckduckgo.Errors != 0 || duckduckgo.AvgResults != 1 || duckduckgo.Broken {
+		t.Errorf("duckduckgo = %+v", duckduckgo)
+	}
+
+	// The general class leaves the engines to the instance.
+	if classification := classifyQuery(context.Background(), "cats"); classification.Engines != nil || classification.AvoidedEngines != nil {
+		t.Errorf("classification = %+v", classification)
+	}
+	if engines, avoided := avoidBrokenEngines([]string{"google", "bing"}); !reflect.DeepEqual(engines, []string{"bing"}) || !reflect.DeepEqual(avoided, []string{"google"}) {
+		t.Errorf("avoidBrokenEngines = %q, %q", engines, avoided)
+	}
+}
+
+func TestLocalEngineStatsTool(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	useLocalEngineStats(t, "")
+	fake.SetSearchResponse(map[string]interface{}{
+		"query": "q",
+		"results": []interface{}{
+			map[string]interface{}{"url": "https://a.example/", "title": "A", "engine": "bing"},
+		},
+		"unresponsive_engines": []interface{}{[]interface{}{"brave", "timeout"}},
+	})
+	if _, _, err := runSearch(context.Background(), searxng.SearchParams{Query: "q", Engines: []string{"bing", "brave"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := callTool(t, localEngineStatsHandler, map[string]interface{}{"engines": "brave"})
+	if err != nil || result.IsError {
+		t.Fatalf("local engine stats: %+v, %v", result, err)
+	}
+	var response localEngineStatsResponse
+	decodeResult(t, result, &response)
+	if len(response.Engines) != 1 || response.Engines[0].Engine != "brave" || response.Engines[0].LastError != "timeout" || response.Engines[0].Searches != 1 {
+		t.Errorf("engines = %+v", response.Engines)
+	}
+}
+
+func TestLocalEngineStatsFailedSearches(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	stats := useLocalEngineStats(t, "")
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusBadGateway)
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "engines": "google,bing"})
+	if err != nil || !result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	summaries := stats.summaries()
+	if len(summaries) != 2 {
+		t.Fatalf("summaries = %+v, want the two requested engines", summaries)
+	}
+	for _, s := range summaries {
+		if s.Searches != 1 || s.Errors != 1 || s.LastError != errorInstanceDown {
+			t.Errorf("%s = %+v, want a failed search", s.Engine, s)
+		}
+	}
+
+	// Refused searches are not the engines' fault.
+	useState(t, func(s *serverState) { s.denylist = newDenylist(&DenyConfig{Engines: []string{"google"}}) })
+	callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "engines": "google"})
+	if s := stats.summaries(); s[0].Searches != 1 || s[1].Searches != 1 {
+		t.Errorf("summaries = %+v after a refused search", s)
+	}
+}
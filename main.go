@@ -2,279 +2,1418 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+
+	"go_mcp_server_searxng/pkg/searxng"
 )
 
-var searxngClient *SearXNGClient
+// shutdownTimeout is how long open connections get to finish after SIGTERM.
+const shutdownTimeout = 10 * time.Second
 
 func main() {
 	var transport string
 	var host string
 	var port string
+	var listen string
+	var socketPath string
 	var searxngURL string
+	var userAgent string
+	var searchMethod string
+	var pathPrefix string
+	queryParams := url.Values{}
+	var preferences string
+	var cookies cookieFlag
+	var monitorState string
+	var monitorTTL time.Duration
+	var monitorsFile string
+	var engineStatsFile string
+	var logFile string
+	var thumbnailProxyURL string
+	var thumbnailSecret string
+	var monitorWebhook string
+	var privacyMode bool
+	var redactQueries bool
+	var v1Tools bool
+	var v1Sunset string
+	var configPath string
+	var watchConfig bool
+	var engineCooldown time.Duration
+	var adminHost string
+	var minSafeSearch int
+	var cacheDir string
+	var cacheTTL time.Duration
+	var pageCacheTTL time.Duration
+	var offline bool
+	var lenientParsing bool
+	var htmlFallback bool
+	var adminPort string
+	var fallbackURLs listFlag
+	var breakerFailures int
+	var breakerCooldown time.Duration
+	var maxIdleConns int
+	var maxConnsPerHost int
+	var maxConcurrentUpstream int
+	var debugEcho bool
+	var otlpEndpoint string
+	var healthcheckMode bool
+	var checkEngines bool
+	var strictStartup bool
+	var recordFixtures string
+	var language string
+	headers := http.Header{}
 
-	flag.StringVar(&transport, "t", "sse", "Transport type (stdio or sse)")
+	flag.StringVar(&transport, "t", "sse", "Transport type (stdio, sse, or unix: the sse server on the unix socket -socket)")
 	flag.StringVar(&host, "h", "0.0.0.0", "Host of sse server")
 	flag.StringVar(&port, "p", "8892", "Port of sse server")
-	flag.StringVar(&searxngURL, "searxng", "http://127.0.0.1:8080", "SearXNG instance URL")
-	flag.Parse()
+	flag.StringVar(&socketPath, "socket", "", "Path of the unix socket of the unix transport, e.g. /run/mcp-searxng.sock")
+	flag.Var(&socketMode, "socket-mode", "Permissions of unix sockets, octal")
+	flag.StringVar(&listen, "listen", "", "Comma-separated addresses of the sse server, e.g. \"[::1]:8892,127.0.0.1:8892\" or \"unix:/run/mcp-searxng.sock\"; overrides -h and -p")
+	flag.StringVar(&searxngURL, "searxng", "http://127.0.0.1:8080", "SearXNG instance URL; overrides instances of the config")
+	flag.StringVar(&instanceMode, "instance-mode", instanceFailover, "How general searches use the instances: failover (the first healthy one) or aggregate (all of them in parallel, results merged)")
+	flag.Var(&fallbackURLs, "searxng-fallback", "Fallback SearXNG instance URL, used in order while the circuit breakers of the instances before it are open (repeatable)")
+	flag.IntVar(&breakerFailures, "breaker-failures", 5, "Consecutive failed requests (errors, timeouts, 5xx, 429) after which an instance is paused, 0 disables the circuit breakers")
+	flag.DurationVar(&breakerCooldown, "breaker-cooldown", time.Minute, "How long a failing instance is paused before a trial request")
+	flag.IntVar(&maxIdleConns, "max-idle-conns", searxng.DefaultMaxIdleConnsPerHost, "Idle connections kept open per SearXNG instance for reuse")
+	flag.IntVar(&maxConnsPerHost, "max-conns-per-host", 0, "Most connections, idle and active, open per SearXNG instance (0: no limit)")
+	flag.IntVar(&maxConcurrentUpstream, "max-concurrent-upstream", 0, "Most requests in flight per SearXNG instance across all sessions; others wait for a slot (0: no limit)")
+	flag.StringVar(&userAgent, "user-agent", searxng.DefaultUserAgent, "User-Agent sent to the SearXNG instance")
+	flag.Var(headerFlag(headers), "header", "Extra header sent to the SearXNG instance, \"Name: value\" (repeatable)")
+	flag.StringVar(&searchMethod, "search-method", "get", "HTTP method for search requests (get or post)")
+	flag.StringVar(&pathPrefix, "path-prefix", "", "Path prefix of the SearXNG endpoints, for instances served under a secret path")
+	flag.Var(queryParamFlag(queryParams), "query-param", "Static query parameter added to every SearXNG request, \"name=value\" (repeatable)")
+	flag.StringVar(&preferences, "preferences", "", "Saved preferences of the SearXNG instance (the string or URL to restore them from its preferences page), applied to every search on it but not on the fallbacks")
+	flag.Var(&cookies, "cookie", "Cookies sent to the SearXNG instance but not the fallbacks, \"name=value\" or a Cookie header \"a=1; b=2\" (repeatable)")
+	flag.StringVar(&monitorState, "monitor-state", "", "File persisting URLs already reported per news monitor (empty keeps them in memory)")
+	flag.DurationVar(&monitorTTL, "monitor-ttl", 72*time.Hour, "How long a reported URL is remembered per news monitor")
+	flag.StringVar(&monitorsFile, "monitors-file", "", "File persisting the saved searches of create_monitor (empty keeps them in memory)")
+	flag.StringVar(&engineStatsFile, "engine-stats-file", "", "File persisting the latency and errors of each engine in the recent searches of the server across restarts (empty keeps them in memory)")
+	flag.StringVar(&monitorWebhook, "monitor-webhook", "", "URL new results of saved searches are POSTed to as JSON")
+	flag.StringVar(&adminHost, "admin-host", "127.0.0.1", "Host of the admin listener")
+	flag.StringVar(&adminPort, "admin-port", "", "Serve /metrics, /healthz and /admin on this separate port instead of the sse server port (also works with stdio)")
+	flag.StringVar(&thumbnailProxyURL, "thumbnail-proxy", "", "Serve downsized image search thumbnails at /thumb of the sse server and link them in results under this public URL of the server, e.g. https://mcp.example.com (costs the bandwidth of every preview)")
+	flag.StringVar(&thumbnailSecret, "thumbnail-secret", "", "Key signing the /thumb URLs of -thumbnail-proxy, shared by replicas (default: random, URLs stop working on restart)")
+	flag.StringVar(&dashboardAuth, "dashboard-auth", "", "Enable the /dashboard page of the sse server, protected by these \"user:password\" credentials")
+	flag.BoolVar(&strictArguments, "strict-arguments", false, "Accept only JSON numbers and booleans for numeric and boolean tool arguments, rejecting string encodings like \"2\" or \"true\"")
+	flag.IntVar(&maxResponseBytes, "max-response-bytes", 0, "Cut plain text tool responses above this size and refuse JSON ones with an error asking for less, 0 for no limit")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL tool call and SearXNG request spans are exported to, e.g. http://localhost:4318 (default: OTEL_EXPORTER_OTLP_ENDPOINT, tracing off when unset)")
+	flag.StringVar(&recordFixtures, "record-fixtures", "", "Save every SearXNG request and response to this directory as an anonymized JSON fixture, replayed in tests by searxngtest.Server.ServeFixtures")
+	flag.BoolVar(&debugEcho, "debug-echo", false, "Append the SearXNG requests each tool call made (URL, method, body, resolved parameters) to its result")
+	flag.BoolVar(&privacyMode, "privacy-mode", false, "Do not keep query texts of recent searches")
+	flag.BoolVar(&redactQueries, "log-redact-queries", false, "Mask emails, tokens, card and phone numbers and the redact_patterns of the config in queries before they reach logs, error stats and the history")
+	flag.Float64Var(&sloObjectives.Availability, "slo-availability", sloObjectives.Availability, "Availability objective of tool calls used for burn rate metrics")
+	flag.DurationVar(&sloObjectives.Latency, "slo-latency", sloObjectives.Latency, "Duration a tool call must finish within to count as fast")
+	flag.Float64Var(&sloObjectives.LatencyTarget, "slo-latency-target", sloObjectives.LatencyTarget, "Objective ratio of tool calls finishing within -slo-latency")
+	flag.BoolVar(&v1Tools, "v1-tools", true, "Register the v1 searxng_search tool next to searxng_search_v2")
+	flag.StringVar(&v1Sunset, "v1-sunset", "", "Date (YYYY-MM-DD) after which the v1 searxng_search tool is no longer registered")
+	flag.StringVar(&language, "default-language", "", "Language of searches not given one: auto (detect it from the query), all, or a language code such as ru; overrides default_language of the config (default auto)")
+	flag.StringVar(&logFile, "log-file", "", "Append logs to this file instead of stderr, e.g. to keep them with the stdio transport")
+	flag.StringVar(&configPath, "config", "", "Path to a JSON config file, reloaded on SIGHUP")
+	flag.BoolVar(&watchConfig, "watch-config", false, "Reload the -config file when it changes")
+	flag.Float64Var(&defaultMinScore, "min-score", 0, "Default min_score of searxng_search_v2: results the instance scored below it are dropped, unscored ones kept (0 keeps all)")
+	flag.IntVar(&minSafeSearch, "min-safe-search", 0, "Lowest safe search level of every search (0 off, 1 moderate, 2 strict); tool calls cannot go below it")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Directory of a persistent search response cache")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 0, "How long cached search responses are served; enables an in-memory cache without -cache-dir, 0 with -cache-dir never expires")
+	flag.DurationVar(&pageCacheTTL, "page-cache-ttl", 10*time.Minute, "How long the extracted content of fetched pages is served before it is revalidated with its ETag or Last-Modified, persisted in -cache-dir when set; 0 disables the page cache")
+	flag.BoolVar(&htmlFallback, "html-fallback", true, "Parse the HTML results page of instances that refuse the JSON format with 403")
+	flag.BoolVar(&lenientParsing, "lenient-parsing", false, "Drop SearXNG response fields whose type changed instead of failing the search")
+	flag.BoolVar(&offline, "offline", false, "Serve only cached search responses from -cache-dir, never contacting the instance")
+	flag.DurationVar(&engineCooldown, "engine-cooldown", time.Hour, "How long engines reported as suspended (CAPTCHA, rate limit) are left out of searches, 0 disables")
+	flag.BoolVar(&checkEngines, "check-engines", true, "Reject engine names the instance does not list on /config, suggesting the closest ones")
+	flag.BoolVar(&strictStartup, "strict-startup", false, "Refuse to start when the startup check finds a SearXNG instance unusable (unreachable, or JSON refused without -html-fallback) instead of logging it")
+	flag.BoolVar(&healthcheckMode, "healthcheck", false, "Check /healthz of the server started with the same -t, -h, -p and -admin-port flags, exit 0 when healthy and 1 otherwise")
+	flag.Usage = func() { usage(flag.CommandLine.Output(), flag.CommandLine) }
+	command, args, err := splitCommand(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch command {
+	case commandVersion:
+		fmt.Println(versionInfo())
+		return
+	case commandCompletion:
+		if len(args) != 1 {
+			log.Fatalf("Usage: %s completion %s", flag.CommandLine.Name(), strings.Join(completionShells, "|"))
+		}
+		script, err := completionScript(args[0], flag.CommandLine)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Print(script)
+		return
+	}
+	var search *cliSearch
+	var query string
+	if command == commandSearch {
+		search = addSearchFlags(flag.CommandLine)
+		words, err := parseInterspersed(flag.CommandLine, args)
+		if err != nil {
+			os.Exit(2)
+		}
+		if query = strings.Join(words, " "); query == "" {
+			log.Fatalf("Usage: %s search [flags] QUERY", flag.CommandLine.Name())
+		}
+	} else {
+		flag.CommandLine.Parse(args)
+	}
+
+	if healthcheckMode {
+		var err error
+		switch {
+		case adminPort != "":
+			err = healthcheck(localAddr(adminHost, adminPort))
+		case transport != "sse" && transport != "unix":
+			err = errors.New("the stdio transport serves /healthz only with -admin-port")
+		default:
+			var addrs []listenAddr
+			if addrs, err = transportAddrs(transport, listen, host, port, socketPath); err == nil {
+				err = healthcheckServer(addrs)
+			}
+		}
+		if err != nil {
+			log.Printf("Health check failed: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Stdout carries the protocol of the stdio transport: logs go to
+	// stderr or -log-file, never there.
+	var logOutput io.Writer = os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			log.Fatalf("Log file error: %v", err)
+		}
+		defer f.Close()
+		logOutput = f
+	}
+	log.SetOutput(logOutput)
+
+	// Container runtimes stop the server with SIGTERM: shut down promptly,
+	// closing the cache, instead of waiting to be killed.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	config := &Config{}
+	if configPath != "" {
+		config, err = loadConfig(configPath)
+		if err != nil {
+			log.Fatalf("Config error: %v", err)
+		}
+	}
+
+	// Flags win over the config, also when it is reloaded: -searxng and
+	// -searxng-fallback over instances, -default-language over
+	// default_language.
+	instanceFlags := false
+	flag.Visit(func(f *flag.Flag) {
+		instanceFlags = instanceFlags || f.Name == "searxng" || f.Name == "searxng-fallback"
+	})
+	if !instanceFlags && len(config.Instances) > 0 {
+		searxngURL, fallbackURLs = config.Instances[0], config.Instances[1:]
+	}
+
+	var redactor *queryRedactor
+	if redactQueries {
+		redactor, err = newQueryRedactor(config.RedactPatterns)
+		if err != nil {
+			log.Fatalf("Config error: %v", err)
+		}
+		log.SetOutput(redactingWriter{w: logOutput, r: redactor})
+	}
+
+	languageFlag := language
+	if language == "" {
+		language = config.DefaultLanguage
+	}
+	if language == "" {
+		language = autoLanguage
+	}
+	if err := checkDefaultLanguage(language); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if instanceMode != instanceFailover && instanceMode != instanceAggregate {
+		log.Fatalf("Invalid -instance-mode %q: must be failover or aggregate", instanceMode)
+	}
+
+	if searchMethod != "get" && searchMethod != "post" {
+		log.Fatalf("Invalid -search-method %q: must be get or post", searchMethod)
+	}
+
+	if thumbnailProxyURL != "" {
+		if transport != "sse" && transport != "unix" {
+			log.Fatalf("-thumbnail-proxy needs the sse or unix transport")
+		}
+		if !isHTTPURL(thumbnailProxyURL) {
+			log.Fatalf("Invalid -thumbnail-proxy %q: must be an http or https URL", thumbnailProxyURL)
+		}
+		thumbnails = newThumbnailProxy(thumbnailProxyURL, thumbnailSecret)
+	}
+
+	if defaultMinScore < 0 {
+		log.Fatalf("Invalid -min-score %g: must not be negative", defaultMinScore)
+	}
+
+	if minSafeSearch < 0 || minSafeSearch > 2 {
+		log.Fatalf("Invalid -min-safe-search %d: must be 0, 1 or 2", minSafeSearch)
+	}
+
+	if tracingEnabled(otlpEndpoint) {
+		shutdownTracing, err := setupTracing(ctx, otlpEndpoint)
+		if err != nil {
+			log.Fatalf("Tracing error: %v", err)
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := shutdownTracing(shutdownCtx); err != nil {
+				log.Printf("Tracing shutdown error: %v", err)
+			}
+		}()
+	}
+
+	clientOptions := []searxng.Option{
+		searxng.WithUserAgent(userAgent),
+		searxng.WithHeaders(headers),
+		searxng.WithSearchMethod(searchMethod),
+		searxng.WithPathPrefix(pathPrefix),
+		searxng.WithQueryParams(queryParams),
+		searxng.WithMinSafeSearch(minSafeSearch),
+		searxng.WithSchemaObserver(schemaDrift.observe),
+		searxng.WithLenientParsing(lenientParsing),
+		searxng.WithHTMLFallback(htmlFallback),
+		searxng.WithMaxIdleConnsPerHost(maxIdleConns),
+		searxng.WithMaxConnsPerHost(maxConnsPerHost),
+	}
+	if tracingEnabled(otlpEndpoint) {
+		clientOptions = append(clientOptions, searxng.WithRoundTripper(newTracingTransport))
+	}
+	if recordFixtures != "" {
+		// Queries are anonymized with the patterns of -log-redact-queries,
+		// whether or not logs are redacted.
+		anonymizer, err := newQueryRedactor(config.RedactPatterns)
+		if err != nil {
+			log.Fatalf("Config error: %v", err)
+		}
+		clientOptions = append(clientOptions, searxng.WithFixtureRecorder(recordFixtures, anonymizer.redact, func(err error) {
+			log.Printf("Fixtures: %v", err)
+		}))
+		log.Printf("Recording SearXNG fixtures to %s", recordFixtures)
+	}
+	// Each instance gets its own breaker. Saved preferences and cookies
+	// are those of the primary instance, meaningless to the others.
+	newClient := func(instanceURL string) *searxng.Client {
+		options := append(slices.Clip(clientOptions),
+			searxng.WithCircuitBreaker(breakerFailures, breakerCooldown),
+			searxng.WithMaxConcurrent(maxConcurrentUpstream),
+		)
+		if strings.TrimSuffix(instanceURL, "/") == strings.TrimSuffix(searxngURL, "/") {
+			options = append(options, searxng.WithPreferences(preferences), searxng.WithCookies(cookies))
+		}
+		return searxng.New(instanceURL, options...)
+	}
+	client := newClient(searxngURL)
+	var fallbacks []*searxng.Client
+	for _, fallbackURL := range fallbackURLs {
+		fallbacks = append(fallbacks, newClient(fallbackURL))
+	}
+
+	if c := config.Canary; c != nil {
+		method := c.SearchMethod
+		if method == "" {
+			method = searchMethod
+		}
+		canary = newCanaryMirror(*c,
+			searxng.WithUserAgent(userAgent),
+			searxng.WithSearchMethod(method),
+			searxng.WithLenientParsing(lenientParsing),
+			searxng.WithCircuitBreaker(breakerFailures, breakerCooldown),
+		)
+		log.Printf("Mirroring %g%% of searches to canary instance %s", c.Percent, c.URL)
+	}
+
+	recentSearches.private = privacyMode
+	if checkEngines {
+		engineNames = &engineCatalog{}
+	}
+	suspendedEngines = newSuspensionTracker(engineCooldown)
+	initial, err := newServerState(config)
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	initial.client, initial.fallbacks = client, fallbacks
+	initial.language, initial.redactor = language, redactor
+	state.Store(initial)
+
+	if command == commandCheck {
+		if !runCheck(ctx, os.Stdout, currentState().allInstances()) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if configPath != "" {
+		reloader := &configReloader{path: configPath, language: languageFlag, instanceFlags: instanceFlags, logOutput: logOutput, redact: redactQueries, newClient: newClient}
+		reloader.handleSignals(ctx)
+		if watchConfig {
+			if err := reloader.watch(ctx); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+	} else if watchConfig {
+		log.Fatalf("-watch-config needs a -config file")
+	}
 
-	searxngClient = NewSearXNGClient(searxngURL)
+	if offline && cacheDir == "" {
+		log.Fatalf("-offline needs a -cache-dir to replay")
+	}
+	if cacheDir != "" || cacheTTL > 0 {
+		searchCache, err = newResponseCache(cacheDir, cacheTTL, offline)
+		if err != nil {
+			log.Fatalf("Cache error: %v", err)
+		}
+		defer searchCache.store.close()
+	}
+	if pageCacheTTL > 0 {
+		pageCache, err = newReaderCache(cacheDir, pageCacheTTL)
+		if err != nil {
+			log.Fatalf("Cache error: %v", err)
+		}
+		defer pageCache.store.close()
+	}
 
+	if command == commandSearch {
+		if err := runSearchCommand(ctx, os.Stdout, search.arguments(query)); err != nil {
+			log.Fatalf("Search failed: %v", err)
+		}
+		return
+	}
+
+	monitorSeen, err = newSeenStore(monitorState, monitorTTL)
+	if err != nil {
+		log.Fatalf("Monitor state error: %v", err)
+	}
+	if monitorWebhook != "" {
+		if u, err := url.Parse(monitorWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Fatalf("Invalid -monitor-webhook %q: must be an http or https URL", monitorWebhook)
+		}
+	}
+	monitors, err = newMonitorRegistry(monitorsFile, monitorWebhook, monitorSeen)
+	if err != nil {
+		log.Fatalf("Monitors error: %v", err)
+	}
+	engineOutcomes, err = loadLocalEngineStats(engineStatsFile)
+	if err != nil {
+		log.Fatalf("Engine stats error: %v", err)
+	}
+	defer func() {
+		if err := engineOutcomes.save(); err != nil {
+			log.Printf("Engine stats: %v", err)
+		}
+	}()
+
+	// The check runs in the background unless it decides whether to
+	// start: stdio clients wait for the server.
+	switch {
+	case offline:
+	case strictStartup:
+		if err := checkInstancesAtStartup(ctx, currentState().allInstances()); err != nil {
+			log.Fatalf("Startup check failed (-strict-startup): %v", err)
+		}
+	default:
+		instances := currentState().allInstances()
+		go func() {
+			if err := checkInstancesAtStartup(ctx, instances); err != nil {
+				log.Printf("Warning: %v; searches may fail", err)
+			}
+		}()
+	}
+
+	// The first middleware is the outermost: the state snapshot covers
+	// the others.
+	serverOptions := []server.ServerOption{
+		server.WithToolHandlerMiddleware(snapshotState),
+		server.WithToolHandlerMiddleware(observeToolCalls),
+		server.WithToolHandlerMiddleware(limitResponseSize),
+		server.WithToolHandlerMiddleware(applySearchDefaults),
+	}
+	if debugEcho {
+		serverOptions = append(serverOptions, server.WithToolHandlerMiddleware(echoUpstreamRequests))
+	}
+	if tracingEnabled(otlpEndpoint) {
+		// First, so the span covers the other middlewares.
+		serverOptions = append([]server.ServerOption{server.WithToolHandlerMiddleware(traceToolCalls)}, serverOptions...)
+	}
 	mcpServer := server.NewMCPServer(
 		"go_mcp_server_searxng",
-		"1.0.0",
+		version,
+		serverOptions...,
 	)
 
-	searchTool := mcp.NewTool("searxng_search",
-		mcp.WithDescription("Search information through SearXNG. Supports various categories and search engines."),
-		mcp.WithString("query",
-			mcp.Required(),
-			mcp.Description("Search query"),
+	// The tools search and read the web and change nothing; mcp.NewTool
+	// marks tools destructive by default, which makes clients ask before
+	// each call.
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		tool.Annotations.ReadOnlyHint = mcp.ToBoolPtr(true)
+		tool.Annotations.DestructiveHint = mcp.ToBoolPtr(false)
+		registerToolArguments(tool)
+		mcpServer.AddTool(tool, handler)
+	}
+	// Monitors and the session defaults change server state, unlike the
+	// other tools.
+	addStatefulTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		tool.Annotations.ReadOnlyHint = mcp.ToBoolPtr(false)
+		tool.Annotations.DestructiveHint = mcp.ToBoolPtr(false)
+		registerToolArguments(tool)
+		mcpServer.AddTool(tool, handler)
+	}
+
+	searchDescription := "Search information through SearXNG. Supports various categories and search engines."
+	if v1Tools && v1Sunset != "" {
+		sunset, err := time.Parse("2006-01-02", v1Sunset)
+		if err != nil {
+			log.Fatalf("Invalid -v1-sunset %q: %v", v1Sunset, err)
+		}
+		if time.Now().After(sunset) {
+			log.Printf("Deprecation window of searxng_search ended on %s, only searxng_search_v2 is registered", v1Sunset)
+			v1Tools = false
+		}
+		searchDescription = fmt.Sprintf("Deprecated, use searxng_search_v2 (this tool is removed after %s). %s", v1Sunset, searchDescription)
+	}
+
+	if v1Tools {
+		searchTool := mcp.NewTool("searxng_search",
+			append([]mcp.ToolOption{mcp.WithDescription(searchDescription), outputSchema[searchV1Response]()}, searchArgumentOptions()...)...,
+		)
+
+		addTool(searchTool, searxngSearchHandler)
+	}
+
+	searchV2Tool := mcp.NewTool("searxng_search_v2",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Search information through SearXNG. Supports various categories and search engines. Returns results with a meta block describing how the search was performed."),
+			dryRunOutputSchema[searchV2Response](),
+			optionalResultKeys(),
+			mcp.WithNumber("max_results",
+				mcp.Description("Maximum number of results to return"),
+			),
+			formatOption(),
+			fieldsOption(),
+			rawFormatOption(),
+			minScoreOption(),
+			modeOption(),
+			languagesOption(),
+			mcp.WithString("only_language",
+				mcp.Description("Keep only results detected in these languages (comma-separated codes, e.g. en or en,de); results too short to tell are kept. Every result carries its detected_language"),
+			),
+			verifyAnswersOption(),
+			skipSeenOption(),
+			nearOption(),
+			includeSitesOption(),
+			groupByOption(),
+			dryRunOption(),
+		}, append(searchArgumentOptions(), dateRangeOptions()...)...)...,
+	)
+
+	addTool(searchV2Tool, searxngSearchV2Handler)
+
+	searchAndReadTool := mcp.NewTool("searxng_search_and_read",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Search through SearXNG, fetch the top result pages concurrently and return their main text excerpts in one response. Use it instead of a search followed by page fetches"),
+			dryRunOutputSchema[searchAndReadResponse](),
+			mcp.WithNumber("pages",
+				mcp.Description("Number of top result pages to read (default 3, max 5)"),
+			),
+			mcp.WithNumber("excerpt_chars",
+				mcp.Description("Maximum excerpt length per page in characters (default 2000, max 10000)"),
+			),
+			mcp.WithNumber("prefetch",
+				mcp.Description("Read this many top result pages (max 10) and return the pages of best quality among them, scored by main text length and text-to-markup ratio, to skip pages with little content"),
+			),
+			verifyAnswersOption(),
+			skipSeenOption(),
+			nearOption(),
+			includeSitesOption(),
+			dryRunOption(),
+		}, append(searchArgumentOptions(), dateRangeOptions()...)...)...,
+	)
+
+	addTool(searchAndReadTool, searxngSearchAndReadHandler)
+
+	enginesInfoTool := mcp.NewTool("searxng_engines_info",
+		mcp.WithDescription("Get information about available SearXNG search engines and categories"),
+		outputSchema[map[string]any](),
+	)
+
+	addTool(enginesInfoTool, searxngEnginesInfoHandler)
+
+	categoriesTool := mcp.NewTool("searxng_categories",
+		mcp.WithDescription("List the search categories of the instance with a description, the bang selecting each and the engines searching it, enabled and disabled. Use it to pick the categories or engines of a search; much smaller than searxng_engines_info"),
+		outputSchema[categoriesResponse](),
+		mcp.WithString("category",
+			mcp.Description("Only describe this category, e.g. it or science"),
 		),
-		mcp.WithString("categories",
-			mcp.Description("Search categories (general, images, videos, news, music, files, science, it). Multiple values separated by comma"),
+		mcp.WithBoolean("include_disabled",
+			mcp.Description("Also list the engines disabled on the instance (default true)"),
 		),
-		mcp.WithString("engines",
-			mcp.Description("Search engines (google, bing, duckduckgo, yandex, etc.). Multiple values separated by comma"),
+	)
+
+	addTool(categoriesTool, searxngCategoriesHandler)
+
+	listBangsTool := mcp.NewTool("list_bangs",
+		mcp.WithDescription("List the bang shortcuts of the SearXNG instance from its /config: category bangs like !images and engine bangs like !gh for github or !yt for youtube, with the engine and categories each selects. Search with one using bang_search"),
+		outputSchema[bangCatalogResponse](),
+		mcp.WithString("category",
+			mcp.Description("Only the bangs of this category, e.g. it or videos"),
+		),
+		mcp.WithBoolean("include_disabled",
+			mcp.Description("Also list the bangs of engines disabled on the instance (default false)"),
+		),
+	)
+
+	addTool(listBangsTool, listBangsHandler)
+
+	bangSearchTool := mcp.NewTool("bang_search",
+		mcp.WithDescription("Search with a bang shortcut of the instance, e.g. gh to search github or yt to search youtube, without knowing the engine identifiers. Unknown bangs are rejected with the closest ones instead of being searched as words. Returns what searxng_search_v2 returns"),
+		dryRunOutputSchema[searchV2Response](),
+		optionalResultKeys(),
+		mcp.WithString("bang",
+			mcp.Required(),
+			mcp.Description("Bang to apply: a shortcut with or without its ! (gh, !gh), a category (images) or an engine name (github); list_bangs lists them"),
+		),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query, without the bang"),
 		),
 		mcp.WithString("language",
-			mcp.Description("Search language (ru, en, de, fr, etc.)"),
+			mcp.Description("Search language (ru, en, de, fr, etc.), "+languageHint()),
 		),
 		mcp.WithNumber("page",
 			mcp.Description("Page number of results (default 1)"),
 		),
 		mcp.WithString("time_range",
 			mcp.Description("Time range (day, week, month, year)"),
+			mcp.Enum(timeRanges...),
 		),
-		mcp.WithNumber("safe_search",
-			mcp.Description("Safe search (0 - disabled, 1 - moderate, 2 - strict)"),
+		mcp.WithNumber("max_results",
+			mcp.Description("Maximum number of results to return"),
 		),
+		formatOption(),
+		fieldsOption(),
+		minScoreOption(),
+		dryRunOption(),
 	)
 
-	mcpServer.AddTool(searchTool, searxngSearchHandler)
-
-	enginesInfoTool := mcp.NewTool("searxng_engines_info",
-		mcp.WithDescription("Get information about available SearXNG search engines and categories"),
-	)
-
-	mcpServer.AddTool(enginesInfoTool, searxngEnginesInfoHandler)
+	addTool(bangSearchTool, bangSearchHandler)
 
 	imageSearchTool := mcp.NewTool("searxng_image_search",
 		mcp.WithDescription("Specialized image search through SearXNG"),
+		dryRunOutputSchema[imageSearchResponse](),
 		mcp.WithString("query",
 			mcp.Required(),
 			mcp.Description("Search query for images"),
 		),
 		mcp.WithString("engines",
-			mcp.Description("Image search engines (google images, bing images, flickr, etc.)"),
+			mcp.Description("Image search engines (google images, bing images, flickr, etc.)"+syntheticEnginesHint()),
 		),
 		mcp.WithNumber("page",
 			mcp.Description("Page number of results"),
 		),
+		dryRunOption(),
 	)
 
-	mcpServer.AddTool(imageSearchTool, searxngImageSearchHandler)
+	addTool(imageSearchTool, searxngImageSearchHandler)
 
-	newsSearchTool := mcp.NewTool("searxng_news_search",
-		mcp.WithDescription("Specialized news search through SearXNG"),
+	fetchImageTool := mcp.NewTool("fetch_image",
+		mcp.WithDescription("Download an image, e.g. from an image search result, and return it as image content for vision-capable models to look at. PNG, JPEG, GIF and WebP are supported"),
+		mcp.WithString("url",
+			mcp.Required(),
+			mcp.Description("Image URL: the img_src of an image search result, or its thumbnail_src for a smaller download"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description(fmt.Sprintf("Largest image to download in bytes (default %d, max %d)", defaultImageBytes, maxImageBytes)),
+		),
+	)
+
+	addTool(fetchImageTool, fetchImageHandler)
+
+	codeSearchTool := mcp.NewTool("searxng_code_search",
+		mcp.WithDescription("Search code, repositories, packages and programming Q&A through SearXNG developer engines. Results carry repo, stars, package version and license where the engine provides them"),
+		dryRunOutputSchema[codeSearchResponse](),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("Search query for news"),
+			mcp.Description("Search query, e.g. a library name or an error message"),
 		),
-		mcp.WithString("time_range",
-			mcp.Description("Time range for news (day, week, month, year)"),
+		mcp.WithString("engines",
+			mcp.Description("Developer engines (github, gitlab, stackoverflow, docker hub, npm, pypi, crates.io, etc.), default: "+strings.Join(defaultCodeEngines, ", ")+syntheticEnginesHint()),
 		),
-		mcp.WithString("language",
-			mcp.Description("News language"),
+		mcp.WithNumber("page",
+			mcp.Description("Page number of results"),
+		),
+		dryRunOption(),
+	)
+
+	addTool(codeSearchTool, searxngCodeSearchHandler)
+
+	musicSearchTool := mcp.NewTool("searxng_music_search",
+		mcp.WithDescription("Search tracks, albums and lyrics through SearXNG music engines. Results carry artist, album, duration and a streaming URL where the engine provides them"),
+		dryRunOutputSchema[musicSearchResponse](),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query, e.g. an artist, a track or a line of lyrics"),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Music engines (bandcamp, soundcloud, genius, mixcloud, deezer, etc.), default: "+strings.Join(defaultMusicEngines, ", ")+syntheticEnginesHint()),
 		),
 		mcp.WithNumber("page",
 			mcp.Description("Page number of results"),
 		),
+		dryRunOption(),
 	)
 
-	mcpServer.AddTool(newsSearchTool, searxngNewsSearchHandler)
+	addTool(musicSearchTool, searxngMusicSearchHandler)
 
-	if transport == "sse" {
-		sseServer := server.NewSSEServer(mcpServer, server.WithBaseURL(fmt.Sprintf("http://localhost:%s", port)))
-		log.Printf("SSE server listening on %s:%s URL: http://127.0.0.1:%s/sse", host, port, port)
-		log.Printf("Using SearXNG instance: %s", searxngURL)
-		if err := sseServer.Start(fmt.Sprintf("%s:%s", host, port)); err != nil {
+	answerTool := mcp.NewTool("searxng_answer",
+		mcp.WithDescription("Answer a quick factual question (definition, entity facts, currency or unit conversion) from Wikipedia, Wikidata, dictionaries and converters. Returns only the instant answers and infoboxes with their sources, without a result list"),
+		dryRunOutputSchema[instantAnswerResponse](),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Question or entity, e.g. \"population of Berlin\", \"define serendipity\" or \"100 usd in eur\""),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Answering engines, default: "+strings.Join(defaultAnswerEngines, ", ")+syntheticEnginesHint()),
+		),
+		mcp.WithString("language",
+			mcp.Description("Language of the answer (ru, en, de, fr, etc.), "+languageHint()),
+		),
+		dryRunOption(),
+	)
+
+	addTool(answerTool, searxngAnswerHandler)
+
+	refineQueryTool := mcp.NewTool("searxng_refine_query",
+		mcp.WithDescription("Rephrase a search query: returns only the spelling corrections and related queries of the engines and the autocomplete expansions, with the number of results the query finds, without the results themselves. Use it to iterate on the wording of a query cheaply before a full search"),
+		dryRunOutputSchema[refineResponse](),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Query to refine"),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Comma-separated engines to ask, default: those of the instance"+syntheticEnginesHint()),
+		),
+		mcp.WithString("language",
+			mcp.Description("Language of the query (ru, en, de, fr, etc.), "+languageHint()),
+		),
+		dryRunOption(),
+	)
+
+	addTool(refineQueryTool, searxngRefineQueryHandler)
+
+	classifyQueryTool := mcp.NewTool("classify_query",
+		mcp.WithDescription("Classify a search query (code, news, science, map, videos, images, music or general) and return the categories, engines and time range engines=auto would search it with, and the words that decided it. Nothing is searched"),
+		outputSchema[queryClassification](),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Query to classify"),
+		),
+	)
+
+	addTool(classifyQueryTool, searxngClassifyQueryHandler)
+
+	diffSearchesTool := mcp.NewTool("diff_searches",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Compare the results of two searches by URL: added, removed and changed (moved or reworded) results. Give other_query to compare two queries, both possibly from the cache; without it the query is searched afresh and compared with its results of the previous diff_searches call or of the cache, to track how the coverage of a developing story or a search page changes"),
+			outputSchema[searchDiffResponse](),
+			mcp.WithString("other_query",
+				mcp.Description("Second query, compared with query using the same categories, engines and language. Default: query itself, at an earlier time"),
+			),
+		}, searchArgumentOptions()...)...,
+	)
+
+	addTool(diffSearchesTool, diffSearchesHandler)
+
+	convertTool := mcp.NewTool("searxng_convert",
+		mcp.WithDescription("Convert an amount between currencies or units with the currency engine and unit converter of SearXNG. Returns the converted value, its unit and the answer text"),
+		outputSchema[parsedAnswer](),
+		mcp.WithNumber("amount",
+			mcp.Description("Amount to convert, default: 1"),
+		),
+		mcp.WithString("from",
+			mcp.Required(),
+			mcp.Description("Currency code or unit to convert from, e.g. usd or km"),
+		),
+		mcp.WithString("to",
+			mcp.Required(),
+			mcp.Description("Currency code or unit to convert to, e.g. eur or mi"),
+		),
+	)
+
+	addTool(convertTool, searxngConvertHandler)
+
+	calculateTool := mcp.NewTool("searxng_calculate",
+		mcp.WithDescription("Evaluate an arithmetic expression with the calculator of SearXNG. Returns the value and the answer text"),
+		outputSchema[parsedAnswer](),
+		mcp.WithString("expression",
+			mcp.Required(),
+			mcp.Description("Arithmetic expression, e.g. (12.5 * 4) / 3"),
+		),
+	)
+
+	addTool(calculateTool, searxngCalculateHandler)
+
+	weatherTool := mcp.NewTool("searxng_weather",
+		mcp.WithDescription("Get the current weather and forecast of a location from the weather engines of SearXNG. Returns the conditions with their units, or the text report of older instances"),
+		outputSchema[weatherReport](),
+		mcp.WithString("location",
+			mcp.Required(),
+			mcp.Description("City or place, e.g. Berlin or \"Paris, France\""),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Weather engines, default: "+strings.Join(defaultWeatherEngines, ", ")+syntheticEnginesHint()),
+		),
+		mcp.WithString("language",
+			mcp.Description("Language of the report (ru, en, de, fr, etc.), "+languageHint()),
+		),
+	)
+
+	addTool(weatherTool, searxngWeatherHandler)
+
+	newsSearchTool := mcp.NewTool("searxng_news_search",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Specialized news search through SearXNG"),
+			dryRunOutputSchema[newsSearchResponse](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Search query for news"),
+			),
+			profileOption(),
+			mcp.WithString("time_range",
+				mcp.Description("Time range for news (day, week, month, year)"),
+				mcp.Enum(timeRanges...),
+			),
+			mcp.WithString("language",
+				mcp.Description("News language (ru, en, de, fr, etc.), "+languageHint()),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number of results"),
+			),
+			mcp.WithString("monitor",
+				mcp.Description("Monitor ID for recurring searches: results already reported for this monitor are skipped"),
+			),
+			mcp.WithString("max_age",
+				mcp.Description("Drop results published longer ago than this, e.g. 6h, 3d or 2w, even when time_range is looser. Results without a published date are dropped. Every result carries its age, e.g. \"3 hours ago\""),
+			),
+			dryRunOption(),
+		}, dateRangeOptions()...)...,
+	)
+
+	addTool(newsSearchTool, searxngNewsSearchHandler)
+
+	instanceStatusTool := mcp.NewTool("searxng_instance_status",
+		mcp.WithDescription("Check the configured SearXNG instance: reachability, latency, whether the JSON format is enabled, version and engines reporting errors. Use it when searches return nothing"),
+		outputSchema[instanceStatusResponse](),
+	)
+
+	addTool(instanceStatusTool, searxngInstanceStatusHandler)
+
+	engineStatsTool := mcp.NewTool("searxng_engine_stats",
+		mcp.WithDescription("Get per-engine reliability, average response time and recent error types from the instance statistics. Use it to pick engines that currently work"),
+		outputSchema[engineStatsResponse](),
+		mcp.WithString("engines",
+			mcp.Description("Only report these engines, separated by comma"),
+		),
+	)
+
+	addTool(engineStatsTool, searxngEngineStatsHandler)
+
+	localEngineStatsTool := mcp.NewTool("searxng_local_engine_stats",
+		mcp.WithDescription("Get the error rate, latency and results of each engine over the recent searches of this server, kept across restarts. engines=auto leaves out the engines marked broken"),
+		outputSchema[localEngineStatsResponse](),
+		mcp.WithString("engines",
+			mcp.Description("Only report these engines, separated by comma"),
+		),
+	)
+
+	addTool(localEngineStatsTool, localEngineStatsHandler)
+
+	compareTool := mcp.NewTool("compare",
+		mcp.WithDescription("Compare products or specs: searches each item, reads its top sources and returns an attribute/value matrix with the source URL of every cell"),
+		outputSchema[compareResponse](),
+		mcp.WithString("items",
+			mcp.Required(),
+			mcp.Description("Items to compare, separated by comma (2 to 6 items)"),
+		),
+		mcp.WithString("aspect",
+			mcp.Description("What to compare, appended to each item query (default \"specifications\")"),
+		),
+		mcp.WithString("attributes",
+			mcp.Description("Only return these attributes, separated by comma"),
+		),
+		mcp.WithNumber("sources",
+			mcp.Description("Number of top sources read per item (default 3, max 5)"),
+		),
+	)
+
+	addTool(compareTool, searxngCompareHandler)
+
+	listEvidenceTool := mcp.NewTool("list_evidence",
+		mcp.WithDescription("List the evidence pool of this session: every result returned by searxng_search_v2 and searxng_search_and_read so far, deduplicated, with stable IDs like E12 to cite as [E12]"),
+		outputSchema[evidenceResponse](),
+		mcp.WithString("filter",
+			mcp.Description("Only list evidence whose title, URL or content contains this text"),
+		),
+	)
+
+	addTool(listEvidenceTool, listEvidenceHandler)
+
+	getEvidenceTool := mcp.NewTool("get_evidence",
+		mcp.WithDescription("Get evidence items of this session by ID, with their content and the queries that found them"),
+		outputSchema[evidenceResponse](),
+		mcp.WithString("ids",
+			mcp.Required(),
+			mcp.Description("Evidence IDs separated by comma, e.g. \"E1,E12\""),
+		),
+	)
+
+	addTool(getEvidenceTool, getEvidenceHandler)
+
+	exportResultsTool := mcp.NewTool("export_results",
+		mcp.WithDescription("Export search results as CSV, JSON Lines or a Markdown table, ready to paste into a spreadsheet or report. Exports the results of the latest search of this session, or the given evidence IDs"),
+		mcp.WithString("format",
+			mcp.Required(),
+			mcp.Description("Export format: csv, jsonl or markdown_table"),
+			mcp.Enum(exportCSV, exportJSONL, exportMarkdownTable),
+		),
+		mcp.WithString("ids",
+			mcp.Description("Evidence IDs to export, separated by comma, e.g. \"E1,E12\"; default: the results of the latest search"),
+		),
+	)
+
+	addTool(exportResultsTool, exportResultsHandler)
+
+	summarizeResultsTool := mcp.NewTool("summarize_results",
+		mcp.WithDescription("Summarize search results into bullet points citing their sources as [n], written by your own model through MCP sampling (stdio transport, clients with sampling support). Summarizes the results of the latest search of this session, or the given evidence IDs"),
+		outputSchema[resultSummary](),
+		mcp.WithString("ids",
+			mcp.Description(fmt.Sprintf("Evidence IDs to summarize, separated by comma, e.g. \"E1,E12\"; default: the results of the latest search. At most %d are used", maxSummarySources)),
+		),
+		mcp.WithString("focus",
+			mcp.Description("Question or aspect the summary should focus on"),
+		),
+		mcp.WithNumber("max_bullets",
+			mcp.Description(fmt.Sprintf("Maximum number of bullet points, default: %d, max: %d", defaultSummaryBullets, maxSummaryBullets)),
+		),
+	)
+
+	addTool(summarizeResultsTool, summarizeResultsHandler)
+	mcpServer.EnableSampling()
+
+	historyTool := mcp.NewTool(historyToolName,
+		mcp.WithDescription("List the recent tool calls of this session, newest first: query, other arguments, result count and time. Use it to recall what was already searched"),
+		outputSchema[historyResponse](),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Number of calls to return, default: %d, max: %d", defaultHistorySize, recentSearchesSize)),
+		),
+		mcp.WithString("tool",
+			mcp.Description("Only list calls of this tool, e.g. searxng_search_v2"),
+		),
+	)
+
+	addTool(historyTool, searxngHistoryHandler)
+
+	addStatefulTool(mcp.NewTool(setDefaultsToolName, setSearchDefaultsOptions()...), setSearchDefaultsHandler)
+
+	mcpServer.AddResource(mcp.NewResource(historyResourceURI, "Tool call history",
+		mcp.WithResourceDescription("Recent tool calls of the current session, newest first"),
+		mcp.WithMIMEType("application/json"),
+	), historyResourceHandler)
+
+	findFeedsTool := mcp.NewTool("find_feeds",
+		mcp.WithDescription("Find RSS/Atom/JSON feed URLs of a site, from its <link rel=\"alternate\"> tags and common feed paths. Give a site, or a query to check the top result sites"),
+		outputSchema[feedsResponse](),
+		mcp.WithString("site",
+			mcp.Description("Domain or URL of the site, e.g. go.dev"),
+		),
+		mcp.WithString("query",
+			mcp.Description("Search query; the feeds of the first 3 result sites are returned. Ignored when site is given"),
+		),
+	)
+
+	addTool(findFeedsTool, searxngFindFeedsHandler)
+
+	createMonitorTool := mcp.NewTool("create_monitor",
+		mcp.WithDescription("Save a search the server re-runs every interval: results not found by earlier runs are kept as hits in the searxng://monitors/{id} resource (clients subscribed to it are notified) and POSTed to the webhook of the server, if configured. The first run is the baseline and reports nothing. Use it to watch for news or new pages on a topic"),
+		outputSchema[savedSearch](),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query to watch"),
+		),
+		mcp.WithString("interval",
+			mcp.Description("Time between runs, e.g. 15m, 6h or 24h; at least 1m. Default: 1h"),
+		),
+		mcp.WithString("categories",
+			mcp.Description("Search categories, e.g. news. Multiple values separated by comma"),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Search engines. Multiple values separated by comma"+syntheticEnginesHint()),
+		),
+		mcp.WithString("language",
+			mcp.Description("Search language (ru, en, de, fr, etc.), "+languageHint()),
+		),
+		mcp.WithString("time_range",
+			mcp.Description("Time range of each run (day, week, month, year)"),
+			mcp.Enum(timeRanges...),
+		),
+	)
+
+	addStatefulTool(createMonitorTool, createMonitorHandler)
+
+	listMonitorsTool := mcp.NewTool("list_monitors",
+		mcp.WithDescription("List the saved searches of create_monitor with their last run, without their hits: read the searxng://monitors/{id} resource of a monitor for those"),
+		outputSchema[monitorsResponse](),
+	)
+
+	addTool(listMonitorsTool, listMonitorsHandler)
+
+	deleteMonitorTool := mcp.NewTool("delete_monitor",
+		mcp.WithDescription("Delete a saved search of create_monitor and stop its runs"),
+		outputSchema[monitorsResponse](),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("Monitor ID, e.g. M1"),
+		),
+	)
+
+	addStatefulTool(deleteMonitorTool, deleteMonitorHandler)
+
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(monitorResourcePrefix+"{id}", "Saved search",
+		mcp.WithTemplateDescription("A saved search of create_monitor with its recent new results, newest first"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), monitorResourceHandler)
+	monitors.notify = func(uri string) {
+		mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+	}
+	monitors.start(ctx)
+	go engineOutcomes.run(ctx)
+
+	if adminPort != "" {
+		addr, err := tcpListenAddr(adminHost, adminPort)
+		if err != nil {
+			log.Fatalf("Invalid admin address: %v", err)
+		}
+		go serveAdmin(addr.address)
+	}
+
+	if transport == "sse" || transport == "unix" {
+		addrs, err := transportAddrs(transport, listen, host, port, socketPath)
+		if err != nil {
+			log.Fatalf("Invalid listen address: %v", err)
+		}
+		listeners, err := listenAll(addrs)
+		if err != nil {
 			log.Fatalf("Server error: %v", err)
 		}
+		baseURL, localURL := "http://localhost", ""
+		if tcpHost, tcpPort, ok := firstTCPAddr(addrs); ok {
+			baseURL += ":" + tcpPort
+			localURL = "http://" + localAddr(tcpHost, tcpPort)
+		}
+
+		mux := http.NewServeMux()
+		httpServer := &http.Server{Handler: mux}
+		sseServer := server.NewSSEServer(mcpServer,
+			server.WithBaseURL(baseURL),
+			server.WithHTTPServer(httpServer),
+			server.WithSSEContextFunc(traceContextFromRequest),
+		)
+		mux.Handle("/", sseServer)
+		if thumbnails != nil {
+			mux.Handle(thumbnailPath, thumbnails)
+		}
+		if adminPort == "" {
+			registerAdminHandlers(mux)
+			if dashboardAuth != "" && localURL != "" {
+				log.Printf("Dashboard available at %s/dashboard", localURL)
+			}
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			<-ctx.Done()
+			log.Printf("Shutting down")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			// Open SSE streams never go idle: Shutdown closes their sessions.
+			if err := sseServer.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Shutdown error: %v", err)
+			}
+		}()
+
+		for _, addr := range addrs {
+			log.Printf("SSE server listening on %s", addr)
+		}
+		if localURL != "" {
+			log.Printf("SSE endpoint URL: %s/sse", localURL)
+		}
+		log.Printf("Using SearXNG instance: %s", searxngURL)
+		serveErrors := make(chan error, len(listeners))
+		for _, l := range listeners {
+			go func() { serveErrors <- httpServer.Serve(l) }()
+		}
+		for range listeners {
+			if err := <-serveErrors; !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Server error: %v", err)
+			}
+		}
+		<-stopped
 	} else {
 		log.Printf("Stdio server started. Using SearXNG instance: %s", searxngURL)
-		if err := server.ServeStdio(mcpServer); err != nil {
+		// ServeStdio stops on SIGTERM and SIGINT itself.
+		if err := server.ServeStdio(mcpServer, server.WithErrorLogger(log.Default())); err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("Server error: %v", err)
 		}
+		log.Printf("Shutting down")
 	}
 }
 
 func searxngSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query, ok := request.Params.Arguments["query"].(string)
-	if !ok {
-		return nil, errors.New("query must be a string")
+	params, err := searchParamsFromArguments(ctx, request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
 	}
-
-	params := SearchParams{
-		Query:      query,
-		Categories: []string{"general"},
-		Engines:    []string{"google"},
-		Language:   "en",
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
 	}
 
-	if categories, ok := request.Params.Arguments["categories"].(string); ok && categories != "" {
-		params.Categories = strings.Split(categories, ",")
-		for i := range params.Categories {
-			params.Categories[i] = strings.TrimSpace(params.Categories[i])
-		}
+	result, meta, err := runSearch(ctx, params)
+	if err != nil {
+		return upstreamErrorResult("search", err), nil
 	}
+	processResults(stateOf(ctx).pipeline, result)
+	processResults(stateOf(ctx).blockedImages, result)
 
-	if engines, ok := request.Params.Arguments["engines"].(string); ok && engines != "" {
-		params.Engines = strings.Split(engines, ",")
-		for i := range params.Engines {
-			params.Engines[i] = strings.TrimSpace(params.Engines[i])
-		}
+	response := searchV1Response{
+		Query:               result.Query,
+		NumberOfResults:     result.NumberOfResults,
+		Results:             result.Results,
+		Suggestions:         result.Suggestions,
+		Corrections:         result.Corrections,
+		QueryTransformation: meta.QueryTransformation,
+		ExternalBangs:       meta.ExternalBangs,
 	}
-
-	if language, ok := request.Params.Arguments["language"].(string); ok && language != "" {
-		params.Language = language
+	if len(result.Answers) > 0 {
+		response.Answers = answerTexts(result.Answers)
 	}
 
-	if pageFloat, ok := request.Params.Arguments["page"].(float64); ok {
-		params.PageNo = int(pageFloat)
-	}
+	return structuredResult(response)
+}
 
-	if timeRange, ok := request.Params.Arguments["time_range"].(string); ok {
-		params.TimeRange = timeRange
+func searxngEnginesInfoHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	instanceConfig, err := activeInstance(ctx).GetEngines(ctx)
+	if err != nil {
+		return upstreamErrorResult("getting engines information", err), nil
 	}
-
-	if safeSearchFloat, ok := request.Params.Arguments["safe_search"].(float64); ok {
-		params.SafeSearch = int(safeSearchFloat)
+	if len(stateOf(ctx).config.SyntheticEngines) > 0 {
+		instanceConfig["synthetic_engines"] = stateOf(ctx).config.SyntheticEngines
 	}
 
-	result, err := searxngClient.Search(params)
-	if err != nil {
-		return nil, fmt.Errorf("search error: %w", err)
+	return structuredResult(instanceConfig)
+}
+
+func searxngImageSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, ok := request.GetArguments()["query"].(string)
+	if !ok {
+		return invalidArgumentsResult(errors.New("query must be a string")), nil
 	}
 
-	response := map[string]interface{}{
-		"query":             result.Query,
-		"number_of_results": result.NumberOfResults,
-		"results":           result.Results,
+	params := searxng.SearchParams{
+		Query:      query,
+		Categories: []string{"images"},
+		Engines:    []string{"google images"},
+		Language:   stateOf(ctx).language,
 	}
 
-	if len(result.Answers) > 0 {
-		response["answers"] = result.Answers
+	if engines, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.Engines = engines
 	}
-	if len(result.Suggestions) > 0 {
-		response["suggestions"] = result.Suggestions
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
 	}
-	if len(result.Corrections) > 0 {
-		response["corrections"] = result.Corrections
+
+	if page, ok, err := intArgument(request.GetArguments(), "page"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.PageNo = page
 	}
 
-	jsonResult, err := json.MarshalIndent(response, "", "  ")
+	dryRun, err := isDryRun(request.GetArguments())
 	if err != nil {
-		return nil, fmt.Errorf("result serialization error: %w", err)
+		return invalidArgumentsResult(err), nil
 	}
 
-	return mcp.NewToolResultText(string(jsonResult)), nil
-}
+	meta := prepareSearch(ctx, &params)
+	if dryRun {
+		return dryRunResult(ctx, params, meta, request.GetArguments(), "engines", "page")
+	}
 
-func searxngEnginesInfoHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	config, err := searxngClient.GetEngines()
+	result, cachedAt, err := cachedSearch(ctx, "images", params, func() (*searxng.ImageSearchResponse, error) {
+		return activeInstance(ctx).SearchImages(ctx, params)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error getting engines information: %w", err)
+		return upstreamErrorResult("image search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
 	}
 
-	jsonResult, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("result serialization error: %w", err)
+	response := imageSearchResponse{
+		ImageSearchResponse: result,
+		QueryTransformation: meta.QueryTransformation,
+		ExternalBangs:       meta.ExternalBangs,
 	}
+	if blocked := stateOf(ctx).blockedImages; len(blocked) > 0 {
+		allowed := blocked.processImages(result.Results)
+		response.BlockedResults = len(result.Results) - len(allowed)
+		result.Results = allowed
+	}
+	response.Results = proxiedImageResults(result.Results)
 
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	return structuredResult(response)
 }
 
-func searxngImageSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query, ok := request.Params.Arguments["query"].(string)
+func searxngNewsSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, ok := request.GetArguments()["query"].(string)
 	if !ok {
-		return nil, errors.New("query must be a string")
+		return invalidArgumentsResult(errors.New("query must be a string")), nil
 	}
 
-	params := SearchParams{
+	params := searxng.SearchParams{
 		Query:      query,
-		Categories: []string{"images"},
-		Engines:    []string{"google images"},
-		Language:   "en",
+		Categories: []string{"news"},
+		Engines:    []string{"google news"},
+		Language:   stateOf(ctx).language,
 	}
 
-	if engines, ok := request.Params.Arguments["engines"].(string); ok && engines != "" {
-		params.Engines = strings.Split(engines, ",")
-		for i := range params.Engines {
-			params.Engines[i] = strings.TrimSpace(params.Engines[i])
-		}
+	if err := applyProfileArgument(ctx, request.GetArguments(), &params); err != nil {
+		return invalidArgumentsResult(err), nil
 	}
 
-	if pageFloat, ok := request.Params.Arguments["page"].(float64); ok {
-		params.PageNo = int(pageFloat)
+	if timeRange, ok, err := enumArgument(request.GetArguments(), "time_range", timeRanges); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.TimeRange = timeRange
 	}
 
-	result, err := searxngClient.Search(params)
+	if language, ok := request.GetArguments()["language"].(string); ok && language != "" {
+		params.Language = language
+	}
+
+	if page, ok, err := intArgument(request.GetArguments(), "page"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.PageNo = page
+	}
+
+	dates, err := publishedRangeFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	// max_age applies even when the time range is looser: SearXNG ranges
+	// are coarse and engines apply them loosely.
+	now := time.Now()
+	if value, _ := request.GetArguments()["max_age"].(string); value != "" {
+		maxAge, err := parseMaxAge(value)
+		if err != nil {
+			return invalidArgumentsResult(err), nil
+		}
+		if after := now.Add(-maxAge); after.After(dates.After) {
+			dates.After = after
+		}
+	}
+	dates.narrowTimeRange(&params)
+
+	dryRun, err := isDryRun(request.GetArguments())
 	if err != nil {
-		return nil, fmt.Errorf("image search error: %w", err)
+		return invalidArgumentsResult(err), nil
+	}
+	if dryRun {
+		meta := prepareSearch(ctx, &params)
+		return dryRunResult(ctx, params, meta, request.GetArguments(), "profile", "time_range", "language", "page")
 	}
 
-	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	result, meta, err := runSearch(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("result serialization error: %w", err)
+		return upstreamErrorResult("news search", err), nil
 	}
 
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	response := newsSearchResponse{
+		SearchResponse:      result,
+		QueryTransformation: meta.QueryTransformation,
+		ExternalBangs:       meta.ExternalBangs,
+	}
+	response.BlockedResults = processResults(stateOf(ctx).blockedImages, result)
+	result.Results, response.DateFilteredResults, _ = dates.filter(result.Results)
+	if monitor, ok := request.GetArguments()["monitor"].(string); ok && monitor != "" {
+		fresh, skipped, err := monitorSeen.filterNew(monitor, result.Results)
+		if err != nil {
+			log.Printf("Monitor %s: %v", monitor, err)
+		}
+		result.Results = fresh
+		response.Monitor, response.SkippedSeen = monitor, &skipped
+	}
+	response.Results = make([]newsResult, len(result.Results))
+	for i, r := range result.Results {
+		response.Results[i].SearchResult = r
+		if published, ok := parsePublishedDate(r.PublishedDate); ok {
+			response.Results[i].Age = formatAge(published, now)
+		}
+	}
+
+	return structuredResult(response)
 }
 
-func searxngNewsSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query, ok := request.Params.Arguments["query"].(string)
+// searchOptionalArguments are the optional arguments of searchArgumentOptions.
+var searchOptionalArguments = []string{"profile", "categories", "engines", "language", "page", "time_range", "safe_search"}
+
+// searchArgumentOptions declares the arguments shared by every version of
+// the general search tool.
+func searchArgumentOptions() []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Search query. SearXNG syntax works: bangs select a category or engine (!images, !wp for wikipedia, !!wp is searched as !wp), :fr sets the language, site: and filetype: are passed through"),
+		),
+		mcp.WithBoolean("raw_query",
+			mcp.Description("Send the query as typed with only the given arguments: no default categories, engines or language. Queries with bangs get no default categories and engines anyway"),
+		),
+		profileOption(),
+		mcp.WithString("categories",
+			mcp.Description("Search categories of the instance, usually "+strings.Join(searchCategories, ", ")+" (see searxng_categories). Multiple values separated by comma"),
+		),
+		mcp.WithString("engines",
+			mcp.Description("Search engines (google, bing, duckduckgo, yandex, etc.). Multiple values separated by comma. auto picks the engines, and the category and time range not given, from the query (see classify_query)"+syntheticEnginesHint()),
+		),
+		mcp.WithString("language",
+			mcp.Description("Search language (ru, en, de, fr, etc.), "+languageHint()),
+		),
+		mcp.WithNumber("page",
+			mcp.Description("Page number of results (default 1)"),
+		),
+		mcp.WithString("time_range",
+			mcp.Description("Time range (day, week, month, year)"),
+			mcp.Enum(timeRanges...),
+		),
+		mcp.WithNumber("safe_search",
+			mcp.Description("Safe search (0 - disabled, 1 - moderate, 2 - strict)"),
+			mcp.Min(minSafeSearch),
+			mcp.Max(maxSafeSearch),
+		),
+	}
+}
+
+// searchParamsFromArguments maps the general search tool arguments to
+// searxng.SearchParams, filling in the defaults.
+func searchParamsFromArguments(ctx context.Context, arguments map[string]interface{}) (searxng.SearchParams, error) {
+	query, ok := arguments["query"].(string)
 	if !ok {
-		return nil, errors.New("query must be a string")
+		return searxng.SearchParams{}, errors.New("query must be a string")
 	}
 
-	params := SearchParams{
-		Query:      query,
-		Categories: []string{"news"},
-		Engines:    []string{"google news"},
-		Language:   "en",
+	params := searxng.SearchParams{Query: query}
+
+	if err := applyProfileArgument(ctx, arguments, &params); err != nil {
+		return searxng.SearchParams{}, err
 	}
 
-	if timeRange, ok := request.Params.Arguments["time_range"].(string); ok {
-		params.TimeRange = timeRange
+	if categories, ok, err := listArgument(arguments, "categories"); err != nil {
+		return searxng.SearchParams{}, err
+	} else if ok {
+		if err := checkCategories(ctx, categories); err != nil {
+			return searxng.SearchParams{}, err
+		}
+		params.Categories = categories
+	}
+
+	if engines, ok, err := listArgument(arguments, "engines"); err != nil {
+		return searxng.SearchParams{}, err
+	} else if ok {
+		params.Engines = engines
 	}
 
-	if language, ok := request.Params.Arguments["language"].(string); ok && language != "" {
+	if language, ok := arguments["language"].(string); ok && language != "" {
 		params.Language = language
 	}
 
-	if pageFloat, ok := request.Params.Arguments["page"].(float64); ok {
-		params.PageNo = int(pageFloat)
+	if page, ok, err := intArgument(arguments, "page"); err != nil {
+		return searxng.SearchParams{}, err
+	} else if ok {
+		params.PageNo = page
 	}
 
-	result, err := searxngClient.Search(params)
-	if err != nil {
-		return nil, fmt.Errorf("news search error: %w", err)
+	if timeRange, ok, err := enumArgument(arguments, "time_range", timeRanges); err != nil {
+		return searxng.SearchParams{}, err
+	} else if ok {
+		params.TimeRange = timeRange
+	}
+
+	if safeSearch, ok, err := intArgument(arguments, "safe_search"); err != nil {
+		return searxng.SearchParams{}, err
+	} else if ok {
+		if safeSearch < minSafeSearch || safeSearch > maxSafeSearch {
+			return searxng.SearchParams{}, fmt.Errorf("safe_search must be 0 (disabled), 1 (moderate) or 2 (strict), got %d", safeSearch)
+		}
+		params.SafeSearch = safeSearch
+	}
+
+	// Engines the classification leaves unset go to the instance's
+	// defaults, not to google.
+	classified := false
+	if isAutoEngines(params.Engines) {
+		params.Engines = nil
+		if classification := autoClassification(ctx, arguments); classification != nil {
+			classification.applyTo(&params)
+			classified = true
+		}
 	}
 
-	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	raw, _, err := boolArgument(arguments, "raw_query")
 	if err != nil {
-		return nil, fmt.Errorf("result serialization error: %w", err)
+		return searxng.SearchParams{}, err
+	}
+	// Default categories and engines would override the bangs of the
+	// query; a raw query leaves the language to SearXNG too.
+	if !raw && len(queryBangs(query)) == 0 {
+		if params.Categories == nil {
+			params.Categories = []string{"general"}
+		}
+		if params.Engines == nil && !classified {
+			params.Engines = []string{"google"}
+		}
+	}
+	if !raw && params.Language == "" {
+		params.Language = stateOf(ctx).language
 	}
 
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	return params, nil
 }
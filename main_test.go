@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+// useFakeInstance points the handlers at a fake SearXNG instance for the
+// duration of the test.
+func useFakeInstance(t *testing.T) *searxngtest.Server {
+	t.Helper()
+	fake := searxngtest.NewServer()
+	useState(t, func(s *serverState) { s.client, s.fallbacks = searxng.New(fake.URL), nil })
+	previousConfigs := instanceConfigs
+	instanceConfigs = &instanceConfigCache{}
+	t.Cleanup(func() {
+		instanceConfigs = previousConfigs
+		fake.Close()
+	})
+	return fake
+}
+
+// useConfig replaces the server config for the duration of the test.
+func useConfig(t *testing.T, cfg *Config) {
+	t.Helper()
+	built, err := newServerState(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useState(t, func(s *serverState) {
+		s.config, s.queryClasses, s.pipeline, s.blockedImages = cfg, built.queryClasses, built.pipeline, built.blockedImages
+	})
+}
+
+// useState changes the server state for the duration of the test.
+func useState(t *testing.T, update func(s *serverState)) {
+	t.Helper()
+	previous := state.Load()
+	next := *previous
+	update(&next)
+	state.Store(&next)
+	t.Cleanup(func() { state.Store(previous) })
+}
+
+func callTool(t *testing.T, handler server.ToolHandlerFunc, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	t.Helper()
+	var request mcp.CallToolRequest
+	request.Params.Arguments = arguments
+	return handler(context.Background(), request)
+}
+
+// decodeResult unmarshals the JSON text content of a tool result.
+func decodeResult(t *testing.T, result *mcp.CallToolResult, out interface{}) {
+	t.Helper()
+	if result == nil || len(result.Content) == 0 {
+		t.Fatal("empty tool result")
+	}
+	text, ok := mcp.AsTextContent(result.Content[0])
+	if !ok {
+		t.Fatalf("content is %T, want text", result.Content[0])
+	}
+	if err := json.Unmarshal([]byte(text.Text), out); err != nil {
+		t.Fatalf("result is not JSON: %v\n%s", err, text.Text)
+	}
+}
+
+func TestSearchParamsFromArguments(t *testing.T) {
+	useFakeInstance(t)
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		want      searxng.SearchParams
+		wantErr   bool
+	}{
+		{
+			name:      "defaults",
+			arguments: map[string]interface{}{"query": "golang"},
+			want: searxng.SearchParams{
+				Query:      "golang",
+				Categories: []string{"general"},
+				Engines:    []string{"google"},
+				Language:   autoLanguage,
+			},
+		},
+		{
+			name: "all arguments",
+			arguments: map[string]interface{}{
+				"query":       "golang",
+				"categories":  "it, science",
+				"engines":     " github ,stackoverflow",
+				"language":    "ru",
+				"page":        float64(2),
+				"time_range":  "month",
+				"safe_search": float64(1),
+			},
+			want: searxng.SearchParams{
+				Query:      "golang",
+				Categories: []string{"it", "science"},
+				Engines:    []string{"github", "stackoverflow"},
+				Language:   "ru",
+				PageNo:     2,
+				TimeRange:  "month",
+				SafeSearch: 1,
+			},
+		},
+		{
+			name:      "empty lists keep defaults",
+			arguments: map[string]interface{}{"query": "golang", "categories": "", "engines": ""},
+			want: searxng.SearchParams{
+				Query:      "golang",
+				Categories: []string{"general"},
+				Engines:    []string{"google"},
+				Language:   autoLanguage,
+			},
+		},
+		{
+			name:      "lists as arrays",
+			arguments: map[string]interface{}{"query": "golang", "categories": []interface{}{"it", " science "}, "engines": []interface{}{"duckduckgo", ""}},
+			want: searxng.SearchParams{
+				Query:      "golang",
+				Categories: []string{"it", "science"},
+				Engines:    []string{"duckduckgo"},
+				Language:   autoLanguage,
+			},
+		},
+		{
+			name:      "bangs drop the default categories and engines",
+			arguments: map[string]interface{}{"query": "!images cats"},
+			want:      searxng.SearchParams{Query: "!images cats", Language: autoLanguage},
+		},
+		{
+			name:      "bangs keep explicit engines",
+			arguments: map[string]interface{}{"query": "!wp golang", "engines": "bing"},
+			want:      searxng.SearchParams{Query: "!wp golang", Engines: []string{"bing"}, Language: autoLanguage},
+		},
+		{
+			name:      "raw query",
+			arguments: map[string]interface{}{"query": "golang :de", "raw_query": true, "page": float64(2)},
+			want:      searxng.SearchParams{Query: "golang :de", PageNo: 2},
+		},
+		{
+			name:      "hallucinated time range",
+			arguments: map[string]interface{}{"query": "golang", "time_range": "last_week"},
+			wantErr:   true,
+		},
+		{
+			name:      "safe search out of range",
+			arguments: map[string]interface{}{"query": "golang", "safe_search": float64(3)},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown category",
+			arguments: map[string]interface{}{"query": "golang", "categories": "it, programming"},
+			wantErr:   true,
+		},
+		{
+			name:      "engines of another type",
+			arguments: map[string]interface{}{"query": "golang", "engines": map[string]interface{}{"google": true}},
+			wantErr:   true,
+		},
+		{
+			name:      "numbers as strings",
+			arguments: map[string]interface{}{"query": "golang", "page": " 3", "safe_search": "2"},
+			want: searxng.SearchParams{
+				Query:      "golang",
+				Categories: []string{"general"},
+				Engines:    []string{"google"},
+				Language:   autoLanguage,
+				PageNo:     3,
+				SafeSearch: 2,
+			},
+		},
+		{
+			name:      "non numeric page",
+			arguments: map[string]interface{}{"query": "golang", "page": "second"},
+			wantErr:   true,
+		},
+		{
+			name:      "missing query",
+			arguments: map[string]interface{}{"engines": "google"},
+			wantErr:   true,
+		},
+		{
+			name:      "non string query",
+			arguments: map[string]interface{}{"query": float64(42)},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchParamsFromArguments(context.Background(), tt.arguments)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("searchParamsFromArguments: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	fake := useFakeInstance(t)
+
+	result, err := callTool(t, searxngSearchHandler, map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	var response struct {
+		Query   string                 `json:"query"`
+		Results []searxng.SearchResult `json:"results"`
+	}
+	decodeResult(t, result, &response)
+	if response.Query != "golang" || len(response.Results) != len(searxngtest.DefaultResults) {
+		t.Errorf("response = %+v", response)
+	}
+
+	req, _ := fake.LastRequest("/search")
+	if got := req.Query.Get("engines"); got != "google" {
+		t.Errorf("engines = %q, want the google default", got)
+	}
+}
+
+func TestSearchV2HandlerMeta(t *testing.T) {
+	useFakeInstance(t)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{
+		"query":       "golang",
+		"max_results": float64(1),
+		"page":        float64(2),
+	})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 {
+		t.Errorf("len(results) = %d, want max_results 1", len(response.Results))
+	}
+	if response.Meta.Page != 2 || response.Meta.ReturnedResults != 1 || response.Meta.NumberOfResults != 2 {
+		t.Errorf("meta = %+v", response.Meta)
+	}
+}
+
+func TestSearchHandlerUpstreamError(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.Close()
+
+	result, err := callTool(t, searxngSearchHandler, map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatalf("handler returned a protocol error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("want an error result when the instance is down")
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if !strings.Contains(text.Text, currentState().client.BaseURL) || !strings.Contains(text.Text, "unreachable") {
+		t.Errorf("error message %q does not name the instance and cause", text.Text)
+	}
+}
+
+func TestDescribeUpstreamError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rate limited", &searxng.HTTPError{StatusCode: 429, Endpoint: "/search", RetryAfter: 30 * time.Second}, "retry after 30s"},
+		{"json disabled", &searxng.HTTPError{StatusCode: 403, Endpoint: "/search"}, "enable the json format"},
+		{"server error", fmt.Errorf("wrapped: %w", &searxng.HTTPError{StatusCode: 502}), "retry in a moment"},
+		{"timeout", context.DeadlineExceeded, "timed out"},
+		{"bad json", errors.New("error parsing JSON: unexpected end"), "may not point to a SearXNG instance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeUpstreamError(tt.err); !strings.Contains(got, tt.want) {
+				t.Errorf("describeUpstreamError = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHandlerRateLimited(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"})
+	if err != nil || !result.IsError {
+		t.Fatalf("want an error result, got %+v, %v", result, err)
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if !strings.Contains(text.Text, "HTTP 429") || !strings.Contains(text.Text, "retry after 12s") {
+		t.Errorf("error message = %q", text.Text)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		err             error
+		code            string
+		retryAfter      int
+		tryOtherEngines bool
+	}{
+		{&searxng.HTTPError{StatusCode: 429, Endpoint: "/search", RetryAfter: 1500 * time.Millisecond}, errorRateLimited, 2, true},
+		{&searxng.HTTPError{StatusCode: 429, Endpoint: "/search"}, errorRateLimited, 30, true},
+		{&searxng.HTTPError{StatusCode: 503}, errorInstanceDown, 5, false},
+		{&searxng.HTTPError{StatusCode: 504}, errorTimeout, 0, true},
+		{&searxng.HTTPError{StatusCode: 403, Endpoint: "/search"}, errorMisconfigured, 0, false},
+		{&searxng.CircuitOpenError{Instance: "http://a", Failures: 5, Until: time.Now().Add(20 * time.Second)}, errorInstanceDown, 20, false},
+		{fmt.Errorf("search: %w", context.DeadlineExceeded), errorTimeout, 0, true},
+		{errors.New("dial tcp: connection refused"), errorInstanceDown, 0, false},
+		{errors.New("something else"), errorUpstream, 0, false},
+	}
+	for _, tt := range tests {
+		got := classifyUpstreamError(tt.err)
+		if got.Code != tt.code || got.RetryAfterSeconds != tt.retryAfter || got.TryOtherEngines != tt.tryOtherEngines {
+			t.Errorf("%v: %+v, want code %s, retry after %d, try other engines %v", tt.err, got, tt.code, tt.retryAfter, tt.tryOtherEngines)
+		}
+	}
+}
+
+func TestErrorResultCarriesCode(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+
+	result, _ := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"})
+	e, ok := resultError(result)
+	if !ok || e.Code != errorRateLimited || e.RetryAfterSeconds != 12 || e.Instance != currentState().client.BaseURL {
+		t.Errorf("error in _meta = %+v", result.Meta)
+	}
+	if result.StructuredContent != nil {
+		t.Errorf("error result has structured content %+v", result.StructuredContent)
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	_, line, _ := strings.Cut(text.Text, "\n")
+	var decoded toolError
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil || decoded.Code != errorRateLimited || decoded.Message != "" {
+		t.Errorf("error line %q: %+v, %v", line, decoded, err)
+	}
+
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{})
+	if code := errorCode(result); code != errorInvalidParams {
+		t.Errorf("missing query: error code %q, want %s", code, errorInvalidParams)
+	}
+}
+
+func TestInvalidArgumentsResult(t *testing.T) {
+	result, err := callTool(t, searxngSearchHandler, map[string]interface{}{})
+	if err != nil || !result.IsError {
+		t.Fatalf("want an error result for a missing query, got %+v, %v", result, err)
+	}
+}
+
+func TestExpandSyntheticEngines(t *testing.T) {
+	useConfig(t, &Config{SyntheticEngines: map[string]SyntheticEngine{
+		"company_docs": {Query: "site:docs.example.com"},
+		"py_docs":      {Query: "site:docs.python.org", Engines: []string{"duckduckgo"}},
+	}})
+
+	tests := []struct {
+		name         string
+		engines      []string
+		wantQuery    string
+		wantEngines  []string
+		wantExpanded []string
+	}{
+		{"no synthetic engine", []string{"google"}, "q", []string{"google"}, nil},
+		{"query only preset", []string{"company_docs"}, "q site:docs.example.com", nil, []string{"company_docs"}},
+		{"mixed", []string{"google", "py_docs", "duckduckgo"}, "q site:docs.python.org", []string{"google", "duckduckgo"}, []string{"py_docs"}},
+		{"two presets", []string{"company_docs", "py_docs"}, "q site:docs.example.com OR site:docs.python.org", []string{"duckduckgo"}, []string{"company_docs", "py_docs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := searxng.SearchParams{Query: "q", Engines: tt.engines}
+			expanded := expandSyntheticEngines(context.Background(), &params)
+			if params.Query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", params.Query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(params.Engines, tt.wantEngines) {
+				t.Errorf("engines = %q, want %q", params.Engines, tt.wantEngines)
+			}
+			if !reflect.DeepEqual(expanded, tt.wantExpanded) {
+				t.Errorf("expanded = %q, want %q", expanded, tt.wantExpanded)
+			}
+		})
+	}
+}
+
+func TestSyntheticEngineConfigForms(t *testing.T) {
+	var cfg Config
+	data := `{"synthetic_engines": {"a": "site:a.example", "b": {"query": "site:b.example", "engines": ["bing"]}}}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if cfg.SyntheticEngines["a"].Query != "site:a.example" {
+		t.Errorf("a = %+v", cfg.SyntheticEngines["a"])
+	}
+	if b := cfg.SyntheticEngines["b"]; b.Query != "site:b.example" || len(b.Engines) != 1 {
+		t.Errorf("b = %+v", b)
+	}
+}
+
+func TestSearchV2HTMLFallback(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.DisableJSON()
+	useState(t, func(s *serverState) { s.client = searxng.New(fake.URL, searxng.WithHTMLFallback(true)) })
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 2 || response.Results[0].Source != searxng.SourceHTMLFallback {
+		t.Fatalf("results = %+v, want the default results read from HTML", response.Results)
+	}
+	if len(response.Meta.Warnings) != 1 || !strings.Contains(response.Meta.Warnings[0], "format=json") {
+		t.Errorf("warnings = %q, want the HTML fallback warning", response.Meta.Warnings)
+	}
+}
+
+// TestConcurrentCalls runs the handlers of several sessions at once, as the
+// SSE transport does; run it with -race.
+func TestConcurrentCalls(t *testing.T) {
+	useFakeInstance(t)
+	useEvidencePool(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query := fmt.Sprintf("golang %d", i%3)
+			for _, handler := range []server.ToolHandlerFunc{searxngSearchV2Handler, searxngSearchHandler, searxngRefineQueryHandler} {
+				result, err := callTool(t, handler, map[string]interface{}{"query": query})
+				if err != nil || result.IsError {
+					t.Errorf("call: %+v, %v", result, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if s := currentState().client.PoolStats(); s.InFlight != 0 || s.Requests == 0 {
+		t.Errorf("pool stats = %+v, want no request left in flight", s)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolStats accumulates the outcome of every call of one tool.
+type toolStats struct {
+	Calls         int64
+	Errors        int64
+	TotalDuration time.Duration
+	LastError     string
+	LastCall      time.Time
+	// ResponseBytes is the total size of the responses, MaxResponseBytes
+	// the largest one.
+	ResponseBytes    int64
+	MaxResponseBytes int64
+	// Truncated counts the responses cut or refused by -max-response-bytes.
+	Truncated int64
+}
+
+// AvgDuration is the mean call duration.
+func (s toolStats) AvgDuration() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Calls)
+}
+
+// AvgResponseBytes is the mean response size.
+func (s toolStats) AvgResponseBytes() int64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.ResponseBytes / s.Calls
+}
+
+type toolMetrics struct {
+	mu      sync.Mutex
+	started time.Time
+	tools   map[string]*toolStats
+	slo     map[string]*sloTracker
+}
+
+var metrics = &toolMetrics{
+	started: time.Now(),
+	tools:   make(map[string]*toolStats),
+	slo:     make(map[string]*sloTracker),
+}
+
+// stats returns the stats of tool, creating them. The caller holds m.mu.
+func (m *toolMetrics) stats(tool string) *toolStats {
+	stats := m.tools[tool]
+	if stats == nil {
+		stats = &toolStats{}
+		m.tools[tool] = stats
+		m.slo[tool] = &sloTracker{}
+	}
+	return stats
+}
+
+func (m *toolMetrics) record(tool string, duration time.Duration, errMsg string, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats(tool)
+	now := time.Now()
+	stats.Calls++
+	stats.TotalDuration += duration
+	stats.ResponseBytes += int64(size)
+	stats.MaxResponseBytes = max(stats.MaxResponseBytes, int64(size))
+	stats.LastCall = now
+	if errMsg != "" {
+		stats.Errors++
+		stats.LastError = errMsg
+	}
+	m.slo[tool].record(now, errMsg == "", duration)
+}
+
+// truncated counts a response of tool cut or refused for its size.
+func (m *toolMetrics) truncated(tool string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(tool).Truncated++
+}
+
+// sloIndicators returns the indicators of every tool for every window,
+// indexed like sloWindows.
+func (m *toolMetrics) sloIndicators() map[string][]sloIndicator {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	indicators := make(map[string][]sloIndicator, len(m.slo))
+	for tool, tracker := range m.slo {
+		for _, w := range sloWindows {
+			indicators[tool] = append(indicators[tool], tracker.indicator(now, w.Duration))
+		}
+	}
+	return indicators
+}
+
+// snapshot returns a copy of the per-tool stats sorted by tool name.
+func (m *toolMetrics) snapshot() ([]string, map[string]toolStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.tools))
+	stats := make(map[string]toolStats, len(m.tools))
+	for name, s := range m.tools {
+		names = append(names, name)
+		stats[name] = *s
+	}
+	sort.Strings(names)
+	return names, stats
+}
+
+// observeToolCalls is the tool middleware feeding the metrics and the
+// recent search log.
+func observeToolCalls(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+		duration := time.Since(start)
+
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		} else if code := errorCode(result); code != "" {
+			errMsg = "tool returned an error result: " + code
+		} else if result != nil && result.IsError {
+			errMsg = "tool returned an error result"
+		}
+		errMsg = stateOf(ctx).redactor.redact(errMsg)
+
+		tool := request.Params.Name
+		size := responseSize(result)
+		metrics.record(tool, duration, errMsg, size)
+		query, _ := request.GetArguments()["query"].(string)
+		recentSearches.add(recentSearch{
+			Time:     start,
+			Session:  sessionID(ctx),
+			Tool:     tool,
+			Query:    query,
+			Params:   callParams(request.GetArguments()),
+			Results:  resultCount(result),
+			Duration: duration,
+			Error:    errMsg,
+			Bytes:    size,
+			Tokens:   approxTokens(size),
+		})
+
+		return result, err
+	}
+}
+
+// metricsHandler exposes the tool metrics and SLO indicators in the
+// Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	names, stats := metrics.snapshot()
+	indicators := metrics.sloIndicators()
+
+	var b strings.Builder
+	family := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	family("searxng_mcp_uptime_seconds", "gauge", "Seconds since the server started.")
+	fmt.Fprintf(&b, "searxng_mcp_uptime_seconds %g\n", time.Since(metrics.started).Seconds())
+
+	family("searxng_mcp_tool_calls_total", "counter", "Tool calls.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "searxng_mcp_tool_calls_total{tool=%q} %d\n", name, stats[name].Calls)
+	}
+	family("searxng_mcp_tool_errors_total", "counter", "Tool calls that failed.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "searxng_mcp_tool_errors_total{tool=%q} %d\n", name, stats[name].Errors)
+	}
+	family("searxng_mcp_tool_duration_seconds_total", "counter", "Total time spent in tool calls.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "searxng_mcp_tool_duration_seconds_total{tool=%q} %g\n", name, stats[name].TotalDuration.Seconds())
+	}
+
+	family("searxng_mcp_tool_response_bytes_total", "counter", "Total size of tool responses; about a quarter of it in tokens.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "searxng_mcp_tool_response_bytes_total{tool=%q} %d\n", name, stats[name].ResponseBytes)
+	}
+	family("searxng_mcp_tool_response_bytes_max", "gauge", "Size of the largest tool response.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "searxng_mcp_tool_response_bytes_max{tool=%q} %d\n", name, stats[name].MaxResponseBytes)
+	}
+	family("searxng_mcp_tool_responses_truncated_total", "counter", "Tool responses cut or refused by -max-response-bytes.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "searxng_mcp_tool_responses_truncated_total{tool=%q} %d\n", name, stats[name].Truncated)
+	}
+
+	family("searxng_mcp_slo_availability_objective", "gauge", "Target ratio of successful tool calls.")
+	fmt.Fprintf(&b, "searxng_mcp_slo_availability_objective %g\n", sloObjectives.Availability)
+	family("searxng_mcp_slo_latency_objective_seconds", "gauge", "Duration a tool call must finish within to count as fast.")
+	fmt.Fprintf(&b, "searxng_mcp_slo_latency_objective_seconds %g\n", sloObjectives.Latency.Seconds())
+	family("searxng_mcp_slo_latency_target", "gauge", "Target ratio of fast tool calls.")
+	fmt.Fprintf(&b, "searxng_mcp_slo_latency_target %g\n", sloObjectives.LatencyTarget)
+
+	family("searxng_mcp_schema_issues_total", "counter", "SearXNG responses differing from the expected schema, by kind and field.")
+	for _, issue := range schemaDrift.snapshot() {
+		fmt.Fprintf(&b, "searxng_mcp_schema_issues_total{kind=%q,path=%q} %d\n", issue.Kind, issue.Path, issue.Count)
+	}
+
+	s := currentState()
+	if s.client != nil && s.client.Breaker != nil {
+		family("searxng_mcp_circuit_open", "gauge", "Whether requests to the instance fail fast because its circuit breaker is open (1) or not (0).")
+		for _, client := range s.allInstances() {
+			open := 0
+			if circuitOpen(client) {
+				open = 1
+			}
+			fmt.Fprintf(&b, "searxng_mcp_circuit_open{instance=%q} %d\n", client.BaseURL, open)
+		}
+	}
+
+	if s.client != nil {
+		instances := s.allInstances()
+		family("searxng_mcp_upstream_in_flight", "gauge", "Requests to the instance awaiting or reading their response.")
+		for _, client := range instances {
+			fmt.Fprintf(&b, "searxng_mcp_upstream_in_flight{instance=%q} %d\n", client.BaseURL, client.PoolStats().InFlight)
+		}
+		family("searxng_mcp_upstream_waiting", "gauge", "Requests held back by -max-concurrent-upstream.")
+		for _, client := range instances {
+			fmt.Fprintf(&b, "searxng_mcp_upstream_waiting{instance=%q} %d\n", client.BaseURL, client.PoolStats().Waiting)
+		}
+		family("searxng_mcp_upstream_connections_total", "counter", "Requests to the instance by whether they reused a pooled connection.")
+		for _, client := range instances {
+			s := client.PoolStats()
+			fmt.Fprintf(&b, "searxng_mcp_upstream_connections_total{instance=%q,reused=\"false\"} %d\n", client.BaseURL, s.NewConns)
+			fmt.Fprintf(&b, "searxng_mcp_upstream_connections_total{instance=%q,reused=\"true\"} %d\n", client.BaseURL, s.ReusedConns)
+		}
+	}
+
+	if canary != nil {
+		s := canary.snapshot()
+		family("searxng_mcp_canary_searches_total", "counter", "Searches mirrored to the canary instance.")
+		fmt.Fprintf(&b, "searxng_mcp_canary_searches_total %d\n", s.Searches)
+		family("searxng_mcp_canary_errors_total", "counter", "Mirrored searches that failed.")
+		fmt.Fprintf(&b, "searxng_mcp_canary_errors_total %d\n", s.Errors)
+		family("searxng_mcp_canary_duration_seconds_total", "counter", "Time spent in successful mirrored searches, by instance.")
+		fmt.Fprintf(&b, "searxng_mcp_canary_duration_seconds_total{instance=\"canary\"} %g\n", s.Duration.Seconds())
+		fmt.Fprintf(&b, "searxng_mcp_canary_duration_seconds_total{instance=\"production\"} %g\n", s.PrimaryDuration.Seconds())
+		family("searxng_mcp_canary_results_total", "counter", "Results of successful mirrored searches, by instance.")
+		fmt.Fprintf(&b, "searxng_mcp_canary_results_total{instance=\"canary\"} %d\n", s.Results)
+		fmt.Fprintf(&b, "searxng_mcp_canary_results_total{instance=\"production\"} %d\n", s.PrimaryResults)
+		family("searxng_mcp_canary_overlap_total", "counter", "Sum over successful mirrored searches of the share of top production results the canary also returned.")
+		fmt.Fprintf(&b, "searxng_mcp_canary_overlap_total %g\n", s.OverlapSum)
+	}
+
+	if counts := suspendedEngines.unresponsiveCounts(); len(counts) > 0 {
+		keys := make([]unresponsiveKey, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].Engine != keys[j].Engine {
+				return keys[i].Engine < keys[j].Engine
+			}
+			return keys[i].Reason < keys[j].Reason
+		})
+		family("searxng_mcp_unresponsive_engines_total", "counter", "Engines the instance reported unresponsive in a search, by engine and reason.")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "searxng_mcp_unresponsive_engines_total{engine=%q,reason=%q} %d\n", key.Engine, key.Reason, counts[key])
+		}
+	}
+
+	windowFamily := func(name, help string, value func(sloIndicator) float64) {
+		family(name, "gauge", help)
+		for _, tool := range names {
+			for i, w := range sloWindows {
+				fmt.Fprintf(&b, "%s{tool=%q,window=%q} %g\n", name, tool, w.Name, value(indicators[tool][i]))
+			}
+		}
+	}
+	windowFamily("searxng_mcp_tool_window_calls", "Tool calls within the window.",
+		func(i sloIndicator) float64 { return float64(i.Total) })
+	windowFamily("searxng_mcp_tool_success_ratio", "Ratio of successful tool calls within the window.",
+		func(i sloIndicator) float64 { return i.SuccessRatio })
+	windowFamily("searxng_mcp_tool_latency_ratio", "Ratio of tool calls within the latency objective.",
+		func(i sloIndicator) float64 { return i.LatencyRatio })
+	windowFamily("searxng_mcp_tool_availability_burn_rate", "Availability error budget burn rate within the window.",
+		sloIndicator.AvailabilityBurnRate)
+	windowFamily("searxng_mcp_tool_latency_burn_rate", "Latency error budget burn rate within the window.",
+		sloIndicator.LatencyBurnRate)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, b.String())
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Search modes of searxng_search_v2.
+const (
+	modeQuick      = "quick"
+	modeThorough   = "thorough"
+	modeEverything = "everything"
+)
+
+var searchModeNames = []string{modeQuick, modeThorough, modeEverything}
+
+// everythingResults is the number of results everything mode keeps per
+// category.
+const everythingResults = 3
+
+// searchMode is a preset trading latency for recall. The zero mode runs
+// the search as given.
+type searchMode struct {
+	name string
+	// engines replace the default engine when the call names none.
+	engines []string
+	// maxEngines is the most engines searched, 0 for no limit.
+	maxEngines int
+	// pages is the number of consecutive result pages merged.
+	pages int
+	// categories are searched in parallel, each with the engines the
+	// instance enables for it, when the call names none.
+	categories []string
+	timeout    time.Duration
+	// warnings describe how the preset changed the arguments.
+	warnings []string
+}
+
+var searchModes = map[string]searchMode{
+	modeQuick: {
+		name:       modeQuick,
+		maxEngines: 1,
+		pages:      1,
+		timeout:    5 * time.Second,
+	},
+	modeThorough: {
+		name:    modeThorough,
+		engines: []string{"google", "duckduckgo", "bing", "brave"},
+		pages:   3,
+		timeout: 45 * time.Second,
+	},
+	modeEverything: {
+		name:       modeEverything,
+		pages:      1,
+		categories: []string{"general", "news", "images", "videos", "science"},
+		timeout:    20 * time.Second,
+	},
+}
+
+func modeOption() mcp.ToolOption {
+	return mcp.WithString("mode",
+		mcp.Description(fmt.Sprintf("Trade latency for recall: quick searches one engine, one page, within 5 seconds; thorough searches google, duckduckgo, bing and brave (or the given engines) on 3 pages merged without duplicates, within 45 seconds; everything searches the general, news, images, videos and science categories (or the given ones) in parallel and returns the top %d results of each with meta.category_breakdown, to decide where to dig deeper. Default: the search as given", everythingResults)),
+		mcp.Enum(searchModeNames...),
+	)
+}
+
+// searchModeFromArguments reads the mode argument and applies its preset
+// to params. Engines chosen by the call, its profile or bangs are kept;
+// quick mode only cuts them to one, and without any picks the first
+// default engine of the instance for the categories.
+func searchModeFromArguments(ctx context.Context, arguments map[string]interface{}, params *searxng.SearchParams) (searchMode, error) {
+	name, ok, err := enumArgument(arguments, "mode", searchModeNames)
+	if err != nil || !ok {
+		return searchMode{}, err
+	}
+	mode := searchModes[name]
+	_, engines := arguments["engines"]
+	_, profile := arguments["profile"]
+	if mode.engines != nil && !engines && !profile && len(queryBangs(params.Query)) == 0 {
+		params.Engines = append([]string(nil), mode.engines...)
+	}
+	if mode.categories != nil {
+		if _, ok := arguments["categories"]; ok {
+			mode.categories = params.Categories
+		}
+		// Each category gets the engines of the instance, unless the
+		// call chose them.
+		if !engines && !profile {
+			params.Engines = nil
+		}
+	}
+	if mode.maxEngines > 0 && len(params.Engines) == 0 && len(queryBangs(params.Query)) == 0 {
+		if engines := defaultEngines(ctx, params.Categories); len(engines) > 0 {
+			params.Engines, _ = avoidBrokenEngines(engines)
+		}
+	}
+	if mode.maxEngines > 0 && len(params.Engines) > mode.maxEngines {
+		mode.warnings = append(mode.warnings, fmt.Sprintf("%s mode searched only %v of the engines %v", mode.name, params.Engines[:mode.maxEngines], params.Engines))
+		params.Engines = params.Engines[:mode.maxEngines]
+	}
+	return mode, nil
+}
+
+// describe records the mode in meta.
+func (m searchMode) describe(meta *searchMeta) {
+	meta.Mode = m.name
+	meta.Warnings = append(meta.Warnings, m.warnings...)
+	if m.pages > 1 {
+		meta.PagesSearched = m.pages
+	}
+}
+
+// search runs the search within the time box of the mode. Thorough mode
+// searches its pages in parallel and merges them: only the first page must
+// succeed.
+func (m searchMode) search(ctx context.Context, params searxng.SearchParams) (*searxng.SearchResponse, searchMeta, error) {
+	if m.name == "" {
+		return runSearch(ctx, params)
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	if m.categories != nil {
+		return m.searchCategories(ctx, params)
+	}
+
+	meta := prepareSearch(ctx, &params)
+	m.describe(&meta)
+	if m.pages <= 1 {
+		return executeSearch(ctx, params, meta)
+	}
+
+	first := max(params.PageNo, 1)
+	results := make([]*searxng.SearchResponse, m.pages)
+	metas := make([]searchMeta, m.pages)
+	errs := make([]error, m.pages)
+	var wg sync.WaitGroup
+	for i := range m.pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			page := params
+			page.PageNo = first + i
+			pageMeta := meta
+			pageMeta.Warnings = slices.Clip(meta.Warnings)
+			results[i], metas[i], errs[i] = executeSearch(ctx, page, pageMeta)
+		}()
+	}
+	wg.Wait()
+	if errs[0] != nil {
+		return nil, metas[0], errs[0]
+	}
+
+	meta = metas[0]
+	var pages []*searxng.SearchResponse
+	for i, result := range results {
+		if errs[i] != nil {
+			meta.Warnings = append(meta.Warnings, fmt.Sprintf("page %d left out: %s", first+i, describeUpstreamError(errs[i])))
+			continue
+		}
+		pages = append(pages, result)
+		meta.ElapsedMS = max(meta.ElapsedMS, metas[i].ElapsedMS)
+	}
+	merged, duplicates := mergePages(pages)
+	meta.PagesSearched = len(pages)
+	meta.DuplicateResults = duplicates
+	meta.UnresponsiveEngines = merged.UnresponsiveEngines
+	meta.ReturnedResults = len(merged.Results)
+	return merged, meta, nil
+}
+
+// mergePages appends the results of consecutive pages in order, leaving
+// out those an earlier page returned, and returns how many were left out.
+// Engines return overlapping pages, the more so the more engines.
+func mergePages(pages []*searxng.SearchResponse) (*searxng.SearchResponse, int) {
+	merged := *pages[0]
+	merged.Results, merged.Suggestions, merged.Corrections, merged.UnresponsiveEngines = nil, nil, nil, nil
+	seen := make(map[string]bool)
+	seenEngines := make(map[searxng.UnresponsiveEngine]bool)
+	duplicates := 0
+	for _, page := range pages {
+		for _, r := range page.Results {
+			key := aggregateKey(r.URL)
+			if seen[key] {
+				duplicates++
+				continue
+			}
+			seen[key] = true
+			merged.Results = append(merged.Results, r)
+		}
+		merged.Suggestions = appendNew(merged.Suggestions, page.Suggestions)
+		merged.Corrections = appendNew(merged.Corrections, page.Corrections)
+		for _, e := range page.UnresponsiveEngines {
+			if !seenEngines[e] {
+				seenEngines[e] = true
+				merged.UnresponsiveEngines = append(merged.UnresponsiveEngines, e)
+			}
+		}
+	}
+	return &merged, duplicates
+}
+
+// categoryBreakdown summarizes the search of a category in everything
+// mode.
+type categoryBreakdown struct {
+	Category string `json:"category"`
+	// Results is the number of results the instance returned,
+	// NumberOfResults its estimate of the total when it gives one.
+	Results         int `json:"results"`
+	NumberOfResults int `json:"number_of_results,omitempty"`
+	// Error is set when the category could not be searched.
+	Error string `json:"error,omitempty"`
+}
+
+// searchCategories searches each category of the mode in parallel and
+// keeps the top results of each, in category order, without the results
+// an earlier category returned. Only one category must succeed.
+func (m searchMode) searchCategories(ctx context.Context, params searxng.SearchParams) (*searxng.SearchResponse, searchMeta, error) {
+	results := make([]*searxng.SearchResponse, len(m.categories))
+	metas := make([]searchMeta, len(m.categories))
+	errs := make([]error, len(m.categories))
+	var wg sync.WaitGroup
+	for i, category := range m.categories {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := params
+			p.Categories = []string{category}
+			p.Engines = slices.Clone(params.Engines)
+			results[i], metas[i], errs[i] = runSearch(ctx, p)
+		}()
+	}
+	wg.Wait()
+
+	var meta searchMeta
+	var merged *searxng.SearchResponse
+	var breakdown []categoryBreakdown
+	var blocked int
+	seen := make(map[string]bool)
+	for i, category := range m.categories {
+		if errs[i] != nil {
+			breakdown = append(breakdown, categoryBreakdown{Category: category, Error: describeUpstreamError(errs[i])})
+			continue
+		}
+		result := results[i]
+		breakdown = append(breakdown, categoryBreakdown{Category: category, Results: len(result.Results), NumberOfResults: result.NumberOfResults})
+		if merged == nil {
+			first := *result
+			first.Results, first.UnresponsiveEngines = nil, nil
+			merged, meta = &first, metas[i]
+			meta.Warnings = slices.Clip(meta.Warnings)
+		} else {
+			meta.Warnings = append(meta.Warnings, metas[i].Warnings...)
+			meta.ElapsedMS = max(meta.ElapsedMS, metas[i].ElapsedMS)
+			merged.Suggestions = appendNew(merged.Suggestions, result.Suggestions)
+			merged.Corrections = appendNew(merged.Corrections, result.Corrections)
+		}
+		kept := 0
+		for _, r := range result.Results {
+			if kept == everythingResults {
+				break
+			}
+			if r.Category == "" {
+				r.Category = category
+			}
+			if r.Category == "images" && stateOf(ctx).blockedImages.blocks(r.URL) {
+				blocked++
+				continue
+			}
+			if key := aggregateKey(r.URL); !seen[key] {
+				seen[key] = true
+				merged.Results = append(merged.Results, r)
+				kept++
+			}
+		}
+		for _, e := range result.UnresponsiveEngines {
+			if !slices.Contains(merged.UnresponsiveEngines, e) {
+				merged.UnresponsiveEngines = append(merged.UnresponsiveEngines, e)
+			}
+		}
+	}
+	if merged == nil {
+		return nil, metas[0], errs[0]
+	}
+	m.describe(&meta)
+	meta.Categories = m.categories
+	meta.Engines = params.Engines
+	meta.CategoryBreakdown = breakdown
+	meta.BlockedResults = blocked
+	meta.UnresponsiveEngines = merged.UnresponsiveEngines
+	meta.NumberOfResults = 0
+	meta.ReturnedResults = len(merged.Results)
+	return merged, meta, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSearchModes(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	var mu sync.Mutex
+	var pages, engines []string
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("pageno")
+		if page == "" {
+			page = "1"
+		}
+		mu.Lock()
+		pages = append(pages, page)
+		engines = append(engines, r.URL.Query().Get("engines"))
+		mu.Unlock()
+		if page == "3" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// Page 2 repeats the last result of page 1.
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"query": r.URL.Query().Get("q"),
+			"results": []map[string]interface{}{
+				{"title": "A", "url": fmt.Sprintf("https://example.com/%s", page), "engine": "google"},
+				{"title": "B", "url": "https://example.com/shared", "engine": "bing"},
+			},
+		})
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "mode": "thorough"})
+	if err != nil || result.IsError {
+		t.Fatalf("thorough: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	var urls []string
+	for _, r := range response.Results {
+		urls = append(urls, r.URL)
+	}
+	if want := []string{"https://example.com/1", "https://example.com/shared", "https://example.com/2"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("urls = %v, want %v", urls, want)
+	}
+	if m := response.Meta; m.Mode != modeThorough || m.PagesSearched != 2 || m.DuplicateResults != 1 || len(m.Warnings) != 1 {
+		t.Errorf("meta = %+v, want 2 pages, 1 duplicate and a warning for page 3", m)
+	}
+	if len(pages) != 3 || engines[0] != "google,duckduckgo,bing,brave" {
+		t.Errorf("requested pages %v with engines %v", pages, engines)
+	}
+
+	pages, engines = nil, nil
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "mode": "quick", "engines": "bing,google"})
+	var quick searchV2Response
+	decodeResult(t, result, &quick)
+	if len(pages) != 1 || engines[0] != "bing" || quick.Meta.Mode != modeQuick || len(quick.Results) != 2 {
+		t.Errorf("quick: pages %v, engines %v, meta %+v", pages, engines, quick.Meta)
+	}
+
+	// Without engines, quick mode searches the first default engine.
+	pages, engines = nil, nil
+	callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "mode": "quick"})
+	if len(engines) != 1 || engines[0] != "google" {
+		t.Errorf("quick without engines searched %v, want google", engines)
+	}
+
+	if result, _ := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "mode": "slow"}); !result.IsError {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestEverythingMode(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		category := r.URL.Query().Get("categories")
+		if r.URL.Query().Get("engines") != "" {
+			t.Errorf("%s searched with engines %q", category, r.URL.Query().Get("engines"))
+		}
+		if category == "science" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		var results []map[string]interface{}
+		for i := range 5 {
+			results = append(results, map[string]interface{}{"title": category, "url": fmt.Sprintf("https://example.com/%s/%d", category, i)})
+		}
+		// Every category repeats the first general result.
+		results = append([]map[string]interface{}{{"title": "shared", "url": "https://example.com/general/0"}}, results...)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"query": "q", "number_of_results": 100, "results": results})
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "mode": "everything"})
+	if err != nil || result.IsError {
+		t.Fatalf("everything: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 12 || response.Results[3].URL != "https://example.com/news/0" || response.Results[3].Category != "news" {
+		t.Errorf("%d results, 4th %+v: want the top 3 of 4 categories", len(response.Results), response.Results[3])
+	}
+	breakdown := response.Meta.CategoryBreakdown
+	if len(breakdown) != 5 || breakdown[1] != (categoryBreakdown{Category: "news", Results: 6, NumberOfResults: 100}) || breakdown[4].Error == "" {
+		t.Errorf("breakdown = %+v", breakdown)
+	}
+
+	result, _ = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "mode": "everything", "categories": "it,music"})
+	response = searchV2Response{}
+	decodeResult(t, result, &response)
+	if got := response.Meta.Categories; !reflect.DeepEqual(got, []string{"it", "music"}) {
+		t.Errorf("categories = %v", got)
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	monitorResourcePrefix  = "searxng://monitors/"
+	defaultMonitorInterval = time.Hour
+	minMonitorInterval     = time.Minute
+	// maxMonitors bounds the searches the server re-runs on its own.
+	maxMonitors = 50
+	// maxMonitorHits is the number of recent hits kept per monitor.
+	maxMonitorHits = 50
+	webhookTimeout = 10 * time.Second
+)
+
+// savedSearch is a query the server re-runs every interval, reporting the
+// results that are new since the previous runs.
+type savedSearch struct {
+	ID              string   `json:"id"`
+	Query           string   `json:"query"`
+	Categories      []string `json:"categories,omitempty"`
+	Engines         []string `json:"engines,omitempty"`
+	Language        string   `json:"language,omitempty"`
+	TimeRange       string   `json:"time_range,omitempty"`
+	IntervalSeconds int64    `json:"interval_seconds"`
+	Resource        string   `json:"resource"`
+	CreatedAt       string   `json:"created_at"`
+	LastRun         string   `json:"last_run,omitempty"`
+	Runs            int      `json:"runs"`
+	// LastError is the error of the last run, search or webhook.
+	LastError string `json:"last_error,omitempty"`
+	// Hits are the new results of the runs, newest first; the results of
+	// the first run are the baseline and not hits.
+	Hits []monitorHit `json:"hits,omitempty"`
+}
+
+type monitorHit struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+	Engine  string `json:"engine,omitempty"`
+	FoundAt string `json:"found_at"`
+}
+
+// monitorNotification is the body POSTed to -monitor-webhook.
+type monitorNotification struct {
+	Monitor  string       `json:"monitor"`
+	Query    string       `json:"query"`
+	Resource string       `json:"resource"`
+	RunAt    string       `json:"run_at"`
+	Hits     []monitorHit `json:"hits"`
+}
+
+type monitorsResponse struct {
+	Monitors []savedSearch `json:"monitors"`
+}
+
+func (m *savedSearch) interval() time.Duration {
+	return time.Duration(m.IntervalSeconds) * time.Second
+}
+
+func (m *savedSearch) params() searxng.SearchParams {
+	return searxng.SearchParams{
+		Query:      m.Query,
+		Categories: append([]string(nil), m.Categories...),
+		Engines:    append([]string(nil), m.Engines...),
+		Language:   m.Language,
+		TimeRange:  m.TimeRange,
+	}
+}
+
+// monitorRegistry holds the saved searches and runs them on schedule.
+// Which results a monitor already reported is kept in the seen store,
+// under the monitor ID prefixed with "saved:".
+type monitorRegistry struct {
+	mu       sync.Mutex
+	path     string
+	webhook  string
+	seen     *seenStore
+	monitors map[string]*savedSearch
+	nextID   int
+	// ctx is the lifetime of the scheduled runs, nil until start.
+	ctx     context.Context
+	cancels map[string]context.CancelFunc
+	// notify, when set, tells subscribed clients a monitor resource
+	// changed.
+	notify func(uri string)
+}
+
+var monitors *monitorRegistry
+
+var errTooManyMonitors = fmt.Errorf("there are already %d monitors, delete_monitor one first", maxMonitors)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// newMonitorRegistry loads the monitors saved in path; an empty path keeps
+// them in memory only. New hits are POSTed to webhook when set.
+func newMonitorRegistry(path, webhook string, seen *seenStore) (*monitorRegistry, error) {
+	r := &monitorRegistry{
+		path:     path,
+		webhook:  webhook,
+		seen:     seen,
+		monitors: make(map[string]*savedSearch),
+		cancels:  make(map[string]context.CancelFunc),
+	}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading monitors: %w", err)
+	}
+	var saved []*savedSearch
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("error parsing monitors %s: %w", path, err)
+	}
+	for _, m := range saved {
+		r.monitors[m.ID] = m
+		if n, err := strconv.Atoi(strings.TrimPrefix(m.ID, "M")); err == nil {
+			r.nextID = max(r.nextID, n)
+		}
+	}
+	return r, nil
+}
+
+// start schedules the loaded monitors and those created later, until ctx
+// ends.
+func (r *monitorRegistry) start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+	for _, m := range r.monitors {
+		r.schedule(m.ID, m.interval())
+	}
+}
+
+// schedule runs the monitor every interval. The caller holds r.mu.
+func (r *monitorRegistry) schedule(id string, interval time.Duration) {
+	if r.ctx == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.cancels[id] = cancel
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Errors are kept in LastError.
+				r.run(withState(ctx), id)
+			}
+		}
+	}()
+}
+
+// create saves a monitor and runs it once for the baseline of results
+// later runs are compared with.
+func (r *monitorRegistry) create(ctx context.Context, m savedSearch) (savedSearch, error) {
+	r.mu.Lock()
+	if len(r.monitors) >= maxMonitors {
+		r.mu.Unlock()
+		return savedSearch{}, errTooManyMonitors
+	}
+	r.nextID++
+	m.ID = fmt.Sprintf("M%d", r.nextID)
+	m.Resource = monitorResourcePrefix + m.ID
+	m.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	r.monitors[m.ID] = &m
+	r.mu.Unlock()
+
+	err := r.run(ctx, m.ID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	created, ok := r.monitors[m.ID]
+	if !ok {
+		return savedSearch{}, fmt.Errorf("monitor %s was deleted", m.ID)
+	}
+	if err != nil {
+		delete(r.monitors, m.ID)
+		return savedSearch{}, err
+	}
+	if err := r.save(); err != nil {
+		return savedSearch{}, err
+	}
+	r.schedule(m.ID, m.interval())
+	return *created, nil
+}
+
+// remove deletes a monitor and stops its runs.
+func (r *monitorRegistry) remove(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.monitors[id]; !ok {
+		return false, nil
+	}
+	delete(r.monitors, id)
+	if cancel, ok := r.cancels[id]; ok {
+		cancel()
+		delete(r.cancels, id)
+	}
+	return true, r.save()
+}
+
+// get returns a copy of a monitor.
+func (r *monitorRegistry) get(id string) (savedSearch, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.monitors[id]
+	if !ok {
+		return savedSearch{}, false
+	}
+	copied := *m
+	copied.Hits = append([]monitorHit(nil), m.Hits...)
+	return copied, true
+}
+
+// list returns the monitors by ID, without their hits.
+func (r *monitorRegistry) list() []savedSearch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]savedSearch, 0, len(r.monitors))
+	for _, m := range r.monitors {
+		copied := *m
+		copied.Hits = nil
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(list[i].ID, "M"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(list[j].ID, "M"))
+		return a < b
+	})
+	return list
+}
+
+// run searches a monitor's query once and reports the results not seen in
+// earlier runs. It returns the error of the search, if any.
+func (r *monitorRegistry) run(ctx context.Context, id string) error {
+	r.mu.Lock()
+	m, ok := r.monitors[id]
+	if !ok {
+		r.mu.Unlock()
+		return nil
+	}
+	params := m.params()
+	baseline := m.Runs == 0
+	r.mu.Unlock()
+
+	// Monitors bypass the response cache: a cached response would hide
+	// the results published since.
+	prepareSearch(ctx, &params)
+	result, searchErr := freshSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	var hits []monitorHit
+	var seenErr error
+	if searchErr == nil {
+		suspendedEngines.record(result.UnresponsiveEngines)
+		var fresh []searxng.SearchResult
+		fresh, _, seenErr = r.seen.filterNew("saved:"+id, result.Results)
+		now := time.Now().UTC().Format(time.RFC3339)
+		for _, res := range fresh {
+			hits = append(hits, monitorHit{Title: res.Title, URL: res.URL, Content: res.Content, Engine: res.Engine, FoundAt: now})
+		}
+		if baseline {
+			hits = nil
+		}
+	}
+
+	var webhookErr error
+	if len(hits) > 0 && r.webhook != "" {
+		webhookErr = r.post(ctx, monitorNotification{
+			Monitor:  id,
+			Query:    params.Query,
+			Resource: monitorResourcePrefix + id,
+			RunAt:    time.Now().UTC().Format(time.RFC3339),
+			Hits:     hits,
+		})
+	}
+
+	r.mu.Lock()
+	m, ok = r.monitors[id]
+	if !ok {
+		r.mu.Unlock()
+		return searchErr
+	}
+	m.Runs++
+	m.LastRun = time.Now().UTC().Format(time.RFC3339)
+	m.LastError = ""
+	switch {
+	case searchErr != nil:
+		m.LastError = describeUpstreamError(searchErr)
+	case webhookErr != nil:
+		m.LastError = webhookErr.Error()
+	case seenErr != nil:
+		m.LastError = seenErr.Error()
+	}
+	if len(hits) > 0 {
+		m.Hits = append(hits, m.Hits...)
+		if len(m.Hits) > maxMonitorHits {
+			m.Hits = m.Hits[:maxMonitorHits]
+		}
+	}
+	if m.LastError != "" {
+		log.Printf("Monitor %s: %s", id, m.LastError)
+	}
+	if !baseline {
+		if err := r.save(); err != nil {
+			log.Printf("Monitor %s: %v", id, err)
+		}
+	}
+	notify := r.notify
+	r.mu.Unlock()
+
+	if len(hits) > 0 && notify != nil {
+		notify(monitorResourcePrefix + id)
+	}
+	return searchErr
+}
+
+// post sends new hits to the webhook.
+func (r *monitorRegistry) post(ctx context.Context, notification monitorNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error serializing webhook notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", stateOf(ctx).client.UserAgent)
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// save writes the monitors to the file. The caller holds r.mu.
+func (r *monitorRegistry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	list := make([]*savedSearch, 0, len(r.monitors))
+	for _, m := range r.monitors {
+		list = append(list, m)
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("error serializing monitors: %w", err)
+	}
+	if err := writeFileAtomic(r.path, data); err != nil {
+		return fmt.Errorf("error writing monitors: %w", err)
+	}
+	return nil
+}
+
+func createMonitorHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	arguments := request.GetArguments()
+	query, _ := arguments["query"].(string)
+	if query = strings.TrimSpace(query); query == "" {
+		return invalidArgumentsResult(errors.New("query must be a non-empty string")), nil
+	}
+	m := savedSearch{Query: query, Language: stateOf(ctx).language}
+
+	interval := defaultMonitorInterval
+	if text, ok := arguments["interval"].(string); ok && text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return invalidArgumentsResult(fmt.Errorf("interval must be a duration such as 15m or 24h, got %q", text)), nil
+		}
+		if d < minMonitorInterval {
+			return invalidArgumentsResult(fmt.Errorf("interval must be at least %s, got %s", minMonitorInterval, d)), nil
+		}
+		interval = d
+	}
+	m.IntervalSeconds = int64(interval / time.Second)
+
+	if categories, ok, err := listArgument(arguments, "categories"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		if err := checkCategories(ctx, categories); err != nil {
+			return invalidArgumentsResult(err), nil
+		}
+		m.Categories = categories
+	}
+	if engines, ok, err := listArgument(arguments, "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		m.Engines = engines
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if language, ok := arguments["language"].(string); ok && language != "" {
+		m.Language = language
+	}
+	if timeRange, ok, err := enumArgument(arguments, "time_range", timeRanges); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		m.TimeRange = timeRange
+	}
+
+	created, err := monitors.create(ctx, m)
+	if errors.Is(err, errTooManyMonitors) {
+		return invalidArgumentsResult(err), nil
+	} else if err != nil {
+		return upstreamErrorResult("monitor search", err), nil
+	}
+	return structuredResult(created)
+}
+
+func listMonitorsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return structuredResult(monitorsResponse{Monitors: monitors.list()})
+}
+
+func deleteMonitorHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.GetArguments()["id"].(string)
+	id = strings.TrimPrefix(strings.TrimSpace(id), monitorResourcePrefix)
+	deleted, err := monitors.remove(id)
+	if err != nil {
+		return errorResult(toolError{Code: errorUpstream, Message: err.Error()}), nil
+	}
+	if !deleted {
+		return invalidArgumentsResult(fmt.Errorf("no monitor %q, list_monitors lists them", id)), nil
+	}
+	return structuredResult(monitorsResponse{Monitors: monitors.list()})
+}
+
+// monitorResourceHandler serves a monitor with its recent hits.
+func monitorResourceHandler(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(request.Params.URI, monitorResourcePrefix)
+	m, ok := monitors.get(id)
+	if !ok {
+		return nil, fmt.Errorf("no monitor %q", id)
+	}
+	jsonResult, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(jsonResult),
+		},
+	}, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// useMonitors replaces the monitor registry for the duration of the test.
+func useMonitors(t *testing.T, path, webhook string) *monitorRegistry {
+	t.Helper()
+	seen, err := newSeenStore("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := newMonitorRegistry(path, webhook, seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := monitors
+	monitors = registry
+	t.Cleanup(func() { monitors = previous })
+	return registry
+}
+
+func searchResults(urls ...string) map[string]interface{} {
+	results := make([]map[string]interface{}, 0, len(urls))
+	for _, u := range urls {
+		results = append(results, map[string]interface{}{"title": u, "url": u, "engine": "google"})
+	}
+	return map[string]interface{}{"query": "q", "results": results}
+}
+
+func TestMonitors(t *testing.T) {
+	fake := useFakeInstance(t)
+	useCache(t, "", time.Hour, false)
+	var mu sync.Mutex
+	var notifications []monitorNotification
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n monitorNotification
+		json.NewDecoder(r.Body).Decode(&n)
+		mu.Lock()
+		notifications = append(notifications, n)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+	path := filepath.Join(t.TempDir(), "monitors.json")
+	registry := useMonitors(t, path, webhook.URL)
+	var updated []string
+	registry.notify = func(uri string) { updated = append(updated, uri) }
+
+	fake.SetSearchResponse(searchResults("https://a.example/", "https://b.example/"))
+	result, err := callTool(t, createMonitorHandler, map[string]interface{}{"query": "golang release", "interval": "30m", "categories": "news"})
+	if err != nil || result.IsError {
+		t.Fatalf("create_monitor: %+v, %v", result, err)
+	}
+	var created savedSearch
+	decodeResult(t, result, &created)
+	if created.ID != "M1" || created.IntervalSeconds != 1800 || created.Runs != 1 || len(created.Hits) != 0 {
+		t.Errorf("created = %+v, want a baseline run without hits", created)
+	}
+
+	fake.SetSearchResponse(searchResults("https://a.example/", "https://c.example/"))
+	if err := registry.run(context.Background(), created.ID); err != nil {
+		t.Fatal(err)
+	}
+	searches := 0
+	for _, req := range fake.Requests() {
+		if req.Path == "/search" {
+			searches++
+		}
+	}
+	if searches != 2 {
+		t.Errorf("instance received %d searches, want 2: runs skip the cache", searches)
+	}
+	if len(notifications) != 1 || len(notifications[0].Hits) != 1 || notifications[0].Hits[0].URL != "https://c.example/" {
+		t.Errorf("notifications = %+v, want the new result", notifications)
+	}
+	if len(updated) != 1 || updated[0] != "searxng://monitors/M1" {
+		t.Errorf("updated resources = %v", updated)
+	}
+
+	var request mcp.ReadResourceRequest
+	request.Params.URI = "searxng://monitors/M1"
+	contents, err := monitorResourceHandler(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m savedSearch
+	json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &m)
+	if m.Runs != 2 || len(m.Hits) != 1 {
+		t.Errorf("resource = %+v, want 2 runs and 1 hit", m)
+	}
+
+	// The monitors survive a restart.
+	reloaded, err := newMonitorRegistry(path, "", registry.seen)
+	if err != nil || len(reloaded.list()) != 1 || reloaded.nextID != 1 {
+		t.Errorf("reloaded = %+v, %v", reloaded, err)
+	}
+
+	result, err = callTool(t, deleteMonitorHandler, map[string]interface{}{"id": "M1"})
+	if err != nil || result.IsError || len(registry.list()) != 0 {
+		t.Errorf("delete_monitor: %+v, %v", result, err)
+	}
+	result, _ = callTool(t, deleteMonitorHandler, map[string]interface{}{"id": "M1"})
+	if errorCode(result) != errorInvalidParams {
+		t.Errorf("deleting twice: %+v", result)
+	}
+}
+
+func TestCreateMonitorArguments(t *testing.T) {
+	useFakeInstance(t)
+	useMonitors(t, "", "")
+	for _, arguments := range []map[string]interface{}{
+		{"query": " "},
+		{"query": "q", "interval": "10s"},
+		{"query": "q", "interval": "soon"},
+		{"query": "q", "time_range": "decade"},
+	} {
+		result, err := callTool(t, createMonitorHandler, arguments)
+		if err != nil || errorCode(result) != errorInvalidParams {
+			t.Errorf("create_monitor(%v) = %+v, %v, want invalid_params", arguments, result, err)
+		}
+	}
+}
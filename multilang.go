@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// maxSearchLanguages bounds the languages of one call: each costs a
+// search.
+const maxSearchLanguages = 5
+
+func languagesOption() mcp.ToolOption {
+	return mcp.WithString("languages",
+		mcp.Description(fmt.Sprintf("Run the query once per language in parallel (comma-separated codes, at most %d, e.g. ru,en) and merge the results, each tagged with the search_languages that returned it, to compare the coverage of a topic across languages. meta.language_breakdown gives the results of each language. Replaces language", maxSearchLanguages)),
+	)
+}
+
+// languageBreakdown summarizes the search of a language of the languages
+// argument.
+type languageBreakdown struct {
+	Language string `json:"language"`
+	// Results is the number of results the instance returned,
+	// NumberOfResults its estimate of the total when it gives one.
+	Results         int `json:"results"`
+	NumberOfResults int `json:"number_of_results,omitempty"`
+	// Error is set when the language could not be searched.
+	Error string `json:"error,omitempty"`
+}
+
+// languageSearch runs a search once per language of the languages
+// argument. Without languages it runs the search as given.
+type languageSearch struct {
+	languages []string
+	// found maps the aggregateKey of each merged result to the languages
+	// whose search returned it.
+	found map[string][]string
+}
+
+// languagesFromArguments reads the languages argument, which excludes
+// language.
+func languagesFromArguments(arguments map[string]interface{}) (*languageSearch, error) {
+	values, ok, err := listArgument(arguments, "languages")
+	if err != nil || !ok {
+		return &languageSearch{}, err
+	}
+	if language, _ := arguments["language"].(string); language != "" {
+		return nil, errors.New("give either language or languages, not both")
+	}
+	var languages []string
+	for _, language := range values {
+		if !languagePattern.MatchString(language) || language == autoLanguage || language == "all" {
+			return nil, fmt.Errorf("languages must be language codes such as ru or pt-BR, got %q", language)
+		}
+		if !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+	if len(languages) > maxSearchLanguages {
+		return nil, fmt.Errorf("languages takes at most %d languages, got %d", maxSearchLanguages, len(languages))
+	}
+	return &languageSearch{languages: languages}, nil
+}
+
+// describe records the languages in the meta of a dry run.
+func (s *languageSearch) describe(meta *searchMeta) {
+	if len(s.languages) > 0 {
+		meta.Language, meta.LanguageDetected = "", false
+		meta.Languages = s.languages
+	}
+}
+
+// search runs the search with mode in each language in parallel and
+// merges the results rank by rank, one of each language in turn, without
+// duplicates. Only one language must succeed.
+func (s *languageSearch) search(ctx context.Context, mode searchMode, params searxng.SearchParams) (*searxng.SearchResponse, searchMeta, error) {
+	if len(s.languages) == 0 {
+		return mode.search(ctx, params)
+	}
+	results := make([]*searxng.SearchResponse, len(s.languages))
+	metas := make([]searchMeta, len(s.languages))
+	errs := make([]error, len(s.languages))
+	var wg sync.WaitGroup
+	for i, language := range s.languages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := params
+			p.Language = language
+			p.Categories = slices.Clone(params.Categories)
+			p.Engines = slices.Clone(params.Engines)
+			results[i], metas[i], errs[i] = mode.search(ctx, p)
+		}()
+	}
+	wg.Wait()
+
+	var meta searchMeta
+	var merged *searxng.SearchResponse
+	var breakdown []languageBreakdown
+	var succeeded []*searxng.SearchResponse
+	var languages []string
+	for i, language := range s.languages {
+		if errs[i] != nil {
+			breakdown = append(breakdown, languageBreakdown{Language: language, Error: describeUpstreamError(errs[i])})
+			continue
+		}
+		result := results[i]
+		breakdown = append(breakdown, languageBreakdown{Language: language, Results: len(result.Results), NumberOfResults: result.NumberOfResults})
+		if merged == nil {
+			first := *result
+			first.Results, first.UnresponsiveEngines = nil, nil
+			merged, meta = &first, metas[i]
+			meta.Warnings = slices.Clip(meta.Warnings)
+		} else {
+			meta.Warnings = appendNew(meta.Warnings, metas[i].Warnings)
+			meta.ElapsedMS = max(meta.ElapsedMS, metas[i].ElapsedMS)
+			merged.Answers = append(merged.Answers, result.Answers...)
+			merged.Infoboxes = append(merged.Infoboxes, result.Infoboxes...)
+			merged.Suggestions = appendNew(merged.Suggestions, result.Suggestions)
+			merged.Corrections = appendNew(merged.Corrections, result.Corrections)
+		}
+		for _, e := range result.UnresponsiveEngines {
+			if !slices.Contains(merged.UnresponsiveEngines, e) {
+				merged.UnresponsiveEngines = append(merged.UnresponsiveEngines, e)
+			}
+		}
+		succeeded = append(succeeded, result)
+		languages = append(languages, language)
+	}
+	if merged == nil {
+		return nil, metas[0], errs[0]
+	}
+
+	s.found = make(map[string][]string)
+	for rank := 0; ; rank++ {
+		more := false
+		for i, result := range succeeded {
+			if rank >= len(result.Results) {
+				continue
+			}
+			more = true
+			r := result.Results[rank]
+			key := aggregateKey(r.URL)
+			if _, ok := s.found[key]; !ok {
+				merged.Results = append(merged.Results, r)
+			}
+			if !slices.Contains(s.found[key], languages[i]) {
+				s.found[key] = append(s.found[key], languages[i])
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	for _, found := range s.found {
+		slices.SortFunc(found, func(a, b string) int {
+			return slices.Index(s.languages, a) - slices.Index(s.languages, b)
+		})
+	}
+	s.describe(&meta)
+	meta.LanguageBreakdown = breakdown
+	meta.UnresponsiveEngines = merged.UnresponsiveEngines
+	meta.NumberOfResults = 0
+	meta.ReturnedResults = len(merged.Results)
+	return merged, meta, nil
+}
+
+// tag sets the search_languages of the enriched results from the results
+// they were built from, in the same order.
+func (s *languageSearch) tag(enriched []annotatedResult, results []searxng.SearchResult) {
+	if s.found == nil {
+		return
+	}
+	for i := range enriched {
+		enriched[i].SearchLanguages = s.found[aggregateKey(results[i].URL)]
+	}
+}
+
+// languageTag renders the search languages of a result for the text
+// formats.
+func languageTag(r annotatedResult) string {
+	return strings.Join(r.SearchLanguages, ", ")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchLanguages(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		results := map[string][]interface{}{
+			"ru": {
+				map[string]interface{}{"url": "https://ria.example/1", "title": "РИА", "engine": "yandex"},
+				map[string]interface{}{"url": "https://wiki.example/event", "title": "Event", "engine": "wikipedia"},
+			},
+			"en": {
+				map[string]interface{}{"url": "https://wiki.example/event", "title": "Event", "engine": "wikipedia"},
+				map[string]interface{}{"url": "https://bbc.example/1", "title": "BBC", "engine": "google"},
+				map[string]interface{}{"url": "https://cnn.example/1", "title": "CNN", "engine": "google"},
+			},
+		}[r.Form.Get("language")]
+		if results == nil {
+			http.Error(w, "engine error", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"query": r.Form.Get("q"), "results": results})
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "event", "languages": "ru,en,xx"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	var urls []string
+	tags := make(map[string][]string)
+	for _, r := range response.Results {
+		urls = append(urls, r.URL)
+		tags[r.URL] = r.SearchLanguages
+	}
+	want := []string{"https://ria.example/1", "https://wiki.example/event", "https://bbc.example/1", "https://cnn.example/1"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("results = %q, want %q", urls, want)
+	}
+	if !reflect.DeepEqual(tags["https://wiki.example/event"], []string{"ru", "en"}) || !reflect.DeepEqual(tags["https://bbc.example/1"], []string{"en"}) {
+		t.Errorf("search_languages = %v", tags)
+	}
+	meta := response.Meta
+	if !reflect.DeepEqual(meta.Languages, []string{"ru", "en", "xx"}) || meta.Language != "" || len(meta.LanguageBreakdown) != 3 {
+		t.Fatalf("meta = %+v", meta)
+	}
+	if b := meta.LanguageBreakdown; b[0].Results != 2 || b[1].Results != 3 || b[2].Error == "" {
+		t.Errorf("language_breakdown = %+v", b)
+	}
+
+	// A default language of the session does not conflict with languages.
+	t.Cleanup(func() { searchDefaultsStore = &defaultsStore{sessions: make(map[string]*sessionDefaults)} })
+	registerToolArguments(mcp.NewTool("searxng_search_v2", append(searchArgumentOptions(), languagesOption())...))
+	if result, err := callTool(t, setSearchDefaultsHandler, map[string]interface{}{"language": "de"}); err != nil || result.IsError {
+		t.Fatalf("set_search_defaults: %+v, %v", result, err)
+	}
+	var request mcp.CallToolRequest
+	request.Params.Name = "searxng_search_v2"
+	request.Params.Arguments = map[string]interface{}{"query": "event", "languages": "ru,en"}
+	if result, err := applySearchDefaults(searxngSearchV2Handler)(context.Background(), request); err != nil || result.IsError {
+		t.Errorf("languages with a default language: %+v, %v", result, err)
+	}
+
+	for _, arguments := range []map[string]interface{}{
+		{"query": "event", "languages": "ru,en", "language": "de"},
+		{"query": "event", "languages": "auto"},
+		{"query": "event", "languages": "en,de,fr,es,it,ru"},
+		{"query": "event", "languages": "ru,en", "raw_format": "json"},
+	} {
+		result, err := callTool(t, searxngSearchV2Handler, arguments)
+		if err != nil || errorCode(result) != errorInvalidParams {
+			t.Errorf("%v: %+v, %v", arguments, result, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// defaultMusicEngines are the music engines searched by
+// searxng_music_search unless engines are given.
+var defaultMusicEngines = []string{"bandcamp", "soundcloud", "genius"}
+
+// bylinePattern matches the "from Album by Artist" subheads bandcamp and
+// similar engines put in the content of tracks and albums.
+var bylinePattern = regexp.MustCompile(`(?i)^\s*(?:from\s+(.+?)\s+)?by\s+(.+?)\s*$`)
+
+// musicResult is a result of searxng_music_search with the track fields
+// derived from what the engine provides, empty when it provides none.
+type musicResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+	Engine  string `json:"engine"`
+	Artist  string `json:"artist,omitempty"`
+	Album   string `json:"album,omitempty"`
+	// DurationSeconds and Duration ("m:ss") are set when the engine
+	// reports the length of the track.
+	DurationSeconds int    `json:"duration_seconds,omitempty"`
+	Duration        string `json:"duration,omitempty"`
+	// StreamURL plays the track: a direct audio stream when the engine
+	// has one, otherwise its embeddable player.
+	StreamURL     string `json:"stream_url,omitempty"`
+	Thumbnail     string `json:"thumbnail,omitempty"`
+	PublishedDate string `json:"published_date,omitempty"`
+}
+
+type musicSearchResponse struct {
+	Query               string                       `json:"query"`
+	NumberOfResults     int                          `json:"number_of_results"`
+	Results             []musicResult                `json:"results"`
+	Suggestions         []string                     `json:"suggestions,omitempty"`
+	Corrections         []string                     `json:"corrections,omitempty"`
+	UnresponsiveEngines []searxng.UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+func searxngMusicSearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, ok := request.GetArguments()["query"].(string)
+	if !ok {
+		return invalidArgumentsResult(errors.New("query must be a string")), nil
+	}
+
+	params := searxng.SearchParams{
+		Query:      query,
+		Categories: []string{"music"},
+		Engines:    append([]string(nil), defaultMusicEngines...),
+		Language:   stateOf(ctx).language,
+	}
+
+	if engines, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.Engines = engines
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	if page, ok, err := intArgument(request.GetArguments(), "page"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.PageNo = page
+	}
+
+	dryRun, err := isDryRun(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	meta := prepareSearch(ctx, &params)
+	if dryRun {
+		return dryRunResult(ctx, params, meta, request.GetArguments(), "engines", "page")
+	}
+
+	result, cachedAt, err := cachedSearch(ctx, "music", params, func() (*searxng.MusicSearchResponse, error) {
+		return activeInstance(ctx).SearchMusic(ctx, params)
+	})
+	if err != nil {
+		return upstreamErrorResult("music search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
+	}
+
+	response := musicSearchResponse{
+		Query:               result.Query,
+		NumberOfResults:     result.NumberOfResults,
+		Results:             make([]musicResult, len(result.Results)),
+		Suggestions:         result.Suggestions,
+		Corrections:         result.Corrections,
+		UnresponsiveEngines: result.UnresponsiveEngines,
+	}
+	for i, r := range result.Results {
+		response.Results[i] = newMusicResult(r)
+	}
+
+	return structuredResult(response)
+}
+
+func newMusicResult(r searxng.MusicResult) musicResult {
+	result := musicResult{
+		Title:         r.Title,
+		URL:           r.URL,
+		Content:       r.Content,
+		Engine:        r.Engine,
+		Artist:        strings.TrimSpace(r.Author),
+		Thumbnail:     r.Thumbnail,
+		PublishedDate: r.PublishedDate,
+		StreamURL:     r.AudioSrc,
+	}
+	if result.StreamURL == "" {
+		result.StreamURL = r.IframeSrc
+	}
+
+	if m := bylinePattern.FindStringSubmatch(r.Content); m != nil {
+		result.Album = m[1]
+		if result.Artist == "" {
+			result.Artist = m[2]
+		}
+	}
+	if result.Artist == "" {
+		result.Artist = titleArtist(r.Title)
+	}
+
+	if seconds := int(time.Duration(r.Length).Round(time.Second).Seconds()); seconds > 0 {
+		result.DurationSeconds = seconds
+		result.Duration = formatTrackLength(seconds)
+	}
+	return result
+}
+
+// titleArtist reads the artist from "Song by Artist" (genius) and
+// "Artist - Song" titles.
+func titleArtist(title string) string {
+	if i := strings.LastIndex(title, " by "); i > 0 {
+		return strings.TrimSpace(title[i+len(" by "):])
+	}
+	if artist, _, ok := strings.Cut(title, " - "); ok {
+		return strings.TrimSpace(artist)
+	}
+	return ""
+}
+
+// formatTrackLength formats seconds as "m:ss", or "h:mm:ss" from an hour.
+func formatTrackLength(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMusicSearch(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Angel", "url": "https://massive.bandcamp.com/track/angel", "engine": "bandcamp",
+				"content": "from Mezzanine by Massive Attack", "length": "6:19", "iframe_src": "https://bandcamp.com/EmbeddedPlayer/track=1"},
+			{"title": "Teardrop by Massive Attack", "url": "https://genius.com/massive-attack-teardrop-lyrics", "engine": "genius"},
+			{"title": "Massive Attack - Unfinished Sympathy", "url": "https://soundcloud.com/ma/unfinished", "engine": "soundcloud",
+				"author": "massiveattack", "length": 308, "audio_src": "https://cdn.example.com/unfinished.mp3"},
+		},
+	})
+
+	result, err := callTool(t, searxngMusicSearchHandler, map[string]interface{}{"query": "massive attack"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("categories") != "music" || req.Query.Get("engines") != "bandcamp,soundcloud,genius" {
+		t.Errorf("request = %v", req.Query)
+	}
+
+	var response musicSearchResponse
+	decodeResult(t, result, &response)
+	want := []musicResult{
+		{Title: "Angel", URL: "https://massive.bandcamp.com/track/angel", Content: "from Mezzanine by Massive Attack", Engine: "bandcamp",
+			Artist: "Massive Attack", Album: "Mezzanine", DurationSeconds: 379, Duration: "6:19", StreamURL: "https://bandcamp.com/EmbeddedPlayer/track=1"},
+		{Title: "Teardrop by Massive Attack", URL: "https://genius.com/massive-attack-teardrop-lyrics", Engine: "genius", Artist: "Massive Attack"},
+		{Title: "Massive Attack - Unfinished Sympathy", URL: "https://soundcloud.com/ma/unfinished", Engine: "soundcloud",
+			Artist: "massiveattack", DurationSeconds: 308, Duration: "5:08", StreamURL: "https://cdn.example.com/unfinished.mp3"},
+	}
+	if !reflect.DeepEqual(response.Results, want) {
+		t.Errorf("results = %+v\nwant %+v", response.Results, want)
+	}
+}
+
+func TestFormatTrackLength(t *testing.T) {
+	for seconds, want := range map[int]string{59: "0:59", 308: "5:08", 3723: "1:02:03"} {
+		if got := formatTrackLength(seconds); got != want {
+			t.Errorf("formatTrackLength(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+}
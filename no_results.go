@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxAlternativeEngines bounds the engines suggested to retry an empty
+	// search with.
+	maxAlternativeEngines = 3
+	// specificQueryWords is the query length past which an empty search
+	// is blamed on the query being too specific.
+	specificQueryWords = 8
+)
+
+// noResultsReport explains an empty search so the agent does not have to
+// guess: the likely causes, most likely first, and the retries worth
+// trying.
+type noResultsReport struct {
+	Causes      []noResultsCause `json:"causes"`
+	NextActions []nextAction     `json:"next_actions"`
+}
+
+type noResultsCause struct {
+	// Cause is one of engines_unresponsive, engines_blocked,
+	// engines_avoided, results_filtered, narrow_time_range, language,
+	// page_past_end, specific_query or nothing_found.
+	Cause  string `json:"cause"`
+	Detail string `json:"detail"`
+}
+
+// nextAction is a retry of the search: the same arguments with Arguments
+// set and RemoveArguments left out.
+type nextAction struct {
+	Description     string                 `json:"description"`
+	Arguments       map[string]interface{} `json:"arguments,omitempty"`
+	RemoveArguments []string               `json:"remove_arguments,omitempty"`
+}
+
+// String renders the action for the text formats.
+func (a nextAction) String() string {
+	var changes []string
+	for name, value := range a.Arguments {
+		changes = append(changes, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(changes)
+	for _, name := range a.RemoveArguments {
+		changes = append(changes, "without "+name)
+	}
+	if len(changes) == 0 {
+		return a.Description
+	}
+	return fmt.Sprintf("%s (%s)", a.Description, strings.Join(changes, ", "))
+}
+
+// explainNoResults builds the report of a search_v2 response without
+// results from its meta and the arguments of the call. repairs are the
+// corrections and suggestions of didYouMean.
+func explainNoResults(ctx context.Context, arguments map[string]interface{}, response *searchV2Response, repairs []string) *noResultsReport {
+	meta := &response.Meta
+	report := &noResultsReport{Causes: []noResultsCause{}, NextActions: []nextAction{}}
+	cause := func(name, format string, args ...interface{}) {
+		report.Causes = append(report.Causes, noResultsCause{Cause: name, Detail: fmt.Sprintf(format, args...)})
+	}
+	action := func(a nextAction) {
+		report.NextActions = append(report.NextActions, a)
+	}
+
+	// The engines that did not answer.
+	failed := make(map[string]bool)
+	var blocked, unresponsive []string
+	suspended := make(map[string]bool)
+	for _, s := range meta.SuspendedEngines {
+		suspended[s.Engine] = true
+	}
+	for _, e := range meta.UnresponsiveEngines {
+		failed[e.Name] = true
+		if suspended[e.Name] {
+			blocked = append(blocked, fmt.Sprintf("%s (%s)", e.Name, e.Reason))
+		} else {
+			unresponsive = append(unresponsive, fmt.Sprintf("%s (%s)", e.Name, e.Reason))
+		}
+	}
+	if len(blocked) > 0 {
+		cause("engines_blocked", "the instance is rate limited or blocked by %s: their results are missing, not absent", strings.Join(blocked, ", "))
+	}
+	if len(unresponsive) > 0 {
+		cause("engines_unresponsive", "%s did not answer: their results are missing, not absent", strings.Join(unresponsive, ", "))
+	}
+	if len(meta.AvoidedEngines) > 0 {
+		var avoided []string
+		for _, s := range meta.AvoidedEngines {
+			avoided = append(avoided, s.Engine)
+			failed[s.Engine] = true
+		}
+		cause("engines_avoided", "%s were left out of the search because the instance suspended them", strings.Join(avoided, ", "))
+	}
+	if len(failed) > 0 || len(meta.Engines) > 0 {
+		if alternatives := alternativeEngines(ctx, meta.Categories, meta.Engines, failed); len(alternatives) > 0 {
+			action(nextAction{
+				Description: "Retry with other engines of the same categories",
+				Arguments:   map[string]interface{}{"engines": strings.Join(alternatives, ",")},
+			})
+		}
+	}
+
+	// The filters of this server that dropped the results found.
+	filters := []struct {
+		removed  int
+		name     string
+		argument string
+		retry    nextAction
+	}{
+		{meta.DateFilteredResults, "the published date range", "published_after", nextAction{Description: "Retry without the published date range", RemoveArguments: []string{"published_after", "published_before"}}},
+		{meta.SiteFilteredResults, "include_sites", "include_sites", nextAction{Description: "Retry without the site restriction", RemoveArguments: []string{"include_sites"}}},
+		{meta.LowScoreResults, "min_score", "min_score", nextAction{Description: "Retry without the score threshold", Arguments: map[string]interface{}{"min_score": 0}}},
+		{meta.LanguageFilteredResults, "only_language", "only_language", nextAction{Description: "Retry with results in any language", RemoveArguments: []string{"only_language"}}},
+		{meta.SkippedSeenResults, "skip_seen", "skip_seen", nextAction{Description: "Retry including the results this session already got", Arguments: map[string]interface{}{"skip_seen": false}}},
+		{meta.PipelineRemovedResults, "the result_pipeline of the config file", "", nextAction{}},
+	}
+	for _, filter := range filters {
+		if filter.removed == 0 {
+			continue
+		}
+		cause("results_filtered", "%s removed all %d results the instance found", filter.name, filter.removed)
+		if filter.argument != "" {
+			action(filter.retry)
+		}
+	}
+
+	if meta.TimeRange != "" {
+		cause("narrow_time_range", "time_range %s only searches results of the last %s", meta.TimeRange, meta.TimeRange)
+		if i := slices.Index(timeRanges, meta.TimeRange); i >= 0 && i+1 < len(timeRanges) {
+			action(nextAction{Description: "Retry with a broader time range", Arguments: map[string]interface{}{"time_range": timeRanges[i+1]}})
+		}
+		action(nextAction{Description: "Retry without a time range", RemoveArguments: []string{"time_range"}})
+	}
+	if meta.Language != "" && meta.Language != "all" && meta.Language != autoLanguage {
+		how := "requested"
+		if meta.LanguageDetected {
+			how = "detected from the query"
+		}
+		cause("language", "the search was limited to language %s (%s)", meta.Language, how)
+		action(nextAction{Description: "Retry in all languages", Arguments: map[string]interface{}{"language": "all"}})
+	}
+	if meta.Page > 1 {
+		cause("page_past_end", "page %d is past the last page of results", meta.Page)
+		action(nextAction{Description: "Retry from the first page", Arguments: map[string]interface{}{"page": 1}})
+	}
+
+	query, _ := arguments["query"].(string)
+	broader := broaderQuery(query)
+	if words := len(strings.Fields(query)); broader != query || words > specificQueryWords {
+		cause("specific_query", "the query is too specific: %s", querySpecificity(query, words))
+		if broader != query && broader != "" {
+			action(nextAction{Description: "Retry without quotes and search operators", Arguments: map[string]interface{}{"query": broader}})
+		} else {
+			action(nextAction{Description: "Retry with fewer, more general terms"})
+		}
+	}
+	for _, repair := range repairs {
+		action(nextAction{Description: "Search the correction the instance suggested", Arguments: map[string]interface{}{"query": repair}})
+	}
+
+	if len(report.Causes) == 0 {
+		cause("nothing_found", "every engine answered and none found a result for this query")
+		if len(meta.Categories) > 0 && !slices.Contains(meta.Categories, "general") {
+			action(nextAction{Description: "Retry in the general category", Arguments: map[string]interface{}{"categories": "general"}})
+		}
+	}
+	return report
+}
+
+// alternativeEngines returns the enabled engines of the categories
+// searched, general by default, other than the engines searched and
+// failed, leaving out those the local engine stats find broken.
+func alternativeEngines(ctx context.Context, categories, searched []string, failed map[string]bool) []string {
+	ctx, cancel := context.WithTimeout(ctx, autocompleteTimeout)
+	defer cancel()
+	instanceConfig, err := activeInstance(ctx).GetInstanceConfig(ctx)
+	if err != nil {
+		return nil
+	}
+	if len(categories) == 0 {
+		categories = []string{"general"}
+	}
+	var alternatives []string
+	for _, c := range instanceCategories(instanceConfig) {
+		if !slices.Contains(categories, c.Name) {
+			continue
+		}
+		for _, engine := range c.EnabledEngines {
+			if failed[engine] || slices.Contains(searched, engine) || slices.Contains(alternatives, engine) || engineOutcomes.broken(engine) {
+				continue
+			}
+			alternatives = append(alternatives, engine)
+		}
+	}
+	if len(alternatives) > maxAlternativeEngines {
+		alternatives = alternatives[:maxAlternativeEngines]
+	}
+	return alternatives
+}
+
+// broaderQuery drops the quotes and the search operators (site:,
+// filetype:, -excluded terms) of query, keeping bangs.
+func broaderQuery(query string) string {
+	var kept []string
+	for _, word := range strings.Fields(strings.ReplaceAll(query, `"`, "")) {
+		if strings.HasPrefix(word, "-") || (strings.Contains(word, ":") && !strings.Contains(word, "://")) {
+			continue
+		}
+		kept = append(kept, word)
+	}
+	if broader := strings.Join(kept, " "); broader != strings.Join(strings.Fields(query), " ") {
+		return broader
+	}
+	return query
+}
+
+func querySpecificity(query string, words int) string {
+	var reasons []string
+	if strings.Contains(query, `"`) {
+		reasons = append(reasons, "exact phrases in quotes")
+	}
+	for _, word := range strings.Fields(query) {
+		if strings.HasPrefix(word, "-") || (strings.Contains(word, ":") && !strings.Contains(word, "://")) {
+			reasons = append(reasons, "search operators")
+			break
+		}
+	}
+	if words > specificQueryWords {
+		reasons = append(reasons, fmt.Sprintf("%d words", words))
+	}
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchV2ExplainsNoResults(t *testing.T) {
+	fake := useFakeInstance(t)
+	previous := suspendedEngines
+	suspendedEngines = newSuspensionTracker(time.Hour)
+	t.Cleanup(func() { suspendedEngines = previous })
+	fake.SetConfig(map[string]interface{}{
+		"categories": []interface{}{"general", "science"},
+		"engines": []interface{}{
+			map[string]interface{}{"name": "google", "enabled": true, "categories": []interface{}{"general"}},
+			map[string]interface{}{"name": "duckduckgo", "enabled": true, "categories": []interface{}{"general"}},
+			map[string]interface{}{"name": "brave", "enabled": true, "categories": []interface{}{"general"}},
+			map[string]interface{}{"name": "bing", "enabled": false, "categories": []interface{}{"general"}},
+		},
+	})
+	fake.SetSearchResponse(map[string]interface{}{
+		"results":              []interface{}{},
+		"suggestions":          []interface{}{"golang generics"},
+		"unresponsive_engines": [][]string{{"google", "Suspended: CAPTCHA"}},
+	})
+
+	arguments := map[string]interface{}{
+		"query":      `"golang generic methods" site:go.dev`,
+		"engines":    "google,duckduckgo",
+		"time_range": "day",
+		"language":   "de",
+	}
+	result, err := callTool(t, searxngSearchV2Handler, arguments)
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if response.NoResults == nil {
+		t.Fatal("no_results missing from an empty response")
+	}
+	var causes []string
+	for _, c := range response.NoResults.Causes {
+		causes = append(causes, c.Cause)
+	}
+	if got, want := strings.Join(causes, ","), "engines_blocked,narrow_time_range,language,specific_query"; got != want {
+		t.Errorf("causes = %s, want %s", got, want)
+	}
+	var actions []string
+	for _, a := range response.NoResults.NextActions {
+		actions = append(actions, a.String())
+	}
+	for _, want := range []string{
+		"Retry with other engines of the same categories (engines=brave)",
+		"Retry with a broader time range (time_range=week)",
+		"Retry without a time range (without time_range)",
+		"Retry in all languages (language=all)",
+		"Retry without quotes and search operators (query=golang generic methods)",
+		"Search the correction the instance suggested (query=golang generics)",
+	} {
+		if !strings.Contains(strings.Join(actions, "\n"), want) {
+			t.Errorf("next actions = %q, want %q", actions, want)
+		}
+	}
+
+	fake.SetSearchResponse(map[string]interface{}{"results": []interface{}{}})
+	arguments = map[string]interface{}{"query": "golang", "categories": "science", "format": "compact"}
+	result, err = callTool(t, searxngSearchV2Handler, arguments)
+	if err != nil || result.IsError {
+		t.Fatalf("compact search: %+v, %v", result, err)
+	}
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if !strings.Contains(text.Text, "cause: every engine answered") || !strings.Contains(text.Text, "try: Retry in the general category (categories=general)") {
+		t.Errorf("compact output = %s", text.Text)
+	}
+
+	fake.SetSearchResponse(searchResults("https://go.dev/doc"))
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var found searchV2Response
+	decodeResult(t, result, &found)
+	if found.NoResults != nil {
+		t.Errorf("no_results = %+v with results", found.NoResults)
+	}
+}
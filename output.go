@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Response types of the tools whose results were plain maps, so their
+// output schemas can be generated. The other tools declare the response
+// types of their handlers.
+
+// searchV1Response is the response of the deprecated searxng_search.
+type searchV1Response struct {
+	Query           string                 `json:"query"`
+	NumberOfResults int                    `json:"number_of_results"`
+	Results         []searxng.SearchResult `json:"results"`
+	Answers         []string               `json:"answers,omitempty"`
+	Suggestions     []string               `json:"suggestions,omitempty"`
+	Corrections     []string               `json:"corrections,omitempty"`
+	// QueryTransformation is set when an overlong query was shortened.
+	QueryTransformation *queryTransformation `json:"query_transformation,omitempty"`
+	// ExternalBangs are the external bangs of the query, searched on the
+	// instance as its own bangs.
+	ExternalBangs []string `json:"external_bangs,omitempty"`
+}
+
+type imageSearchResponse struct {
+	*searxng.ImageSearchResponse
+	// Results replace those of the instance response.
+	Results []imageResult `json:"results"`
+	// BlockedResults counts the results on blocked_image_domains.
+	BlockedResults int `json:"blocked_results,omitempty"`
+	// QueryTransformation is set when an overlong query was shortened.
+	QueryTransformation *queryTransformation `json:"query_transformation,omitempty"`
+	// ExternalBangs are the external bangs of the query, searched on the
+	// instance as its own bangs.
+	ExternalBangs []string `json:"external_bangs,omitempty"`
+}
+
+type newsResult struct {
+	searxng.SearchResult
+	// Age is how long ago the result was published, e.g. "3 hours ago";
+	// empty when the engine gave no readable date.
+	Age string `json:"age,omitempty"`
+}
+
+type imageResult struct {
+	searxng.ImageResult
+	// ProxiedThumbnailSrc is the thumbnail served by the server, set with
+	// -thumbnail-proxy.
+	ProxiedThumbnailSrc string `json:"proxied_thumbnail_src,omitempty"`
+}
+
+type newsSearchResponse struct {
+	*searxng.SearchResponse
+	// Results replace those of the instance response.
+	Results []newsResult `json:"results"`
+	// DateFilteredResults were older than max_age or outside the published
+	// date range, or had no readable date.
+	DateFilteredResults int `json:"date_filtered_results,omitempty"`
+	// BlockedResults counts the image results on blocked_image_domains.
+	BlockedResults int    `json:"blocked_results,omitempty"`
+	Monitor        string `json:"monitor,omitempty"`
+	// SkippedSeen counts the results the monitor reported before; set
+	// only with a monitor.
+	SkippedSeen *int `json:"skipped_seen,omitempty"`
+	// QueryTransformation is set when an overlong query was shortened.
+	QueryTransformation *queryTransformation `json:"query_transformation,omitempty"`
+	// ExternalBangs are the external bangs of the query, searched on the
+	// instance as its own bangs.
+	ExternalBangs []string `json:"external_bangs,omitempty"`
+}
+
+type instanceStatusResponse struct {
+	Instances []*instanceStatus `json:"instances"`
+}
+
+type engineStatsResponse struct {
+	Engines []engineHealth `json:"engines"`
+	// StatsError and ErrorsError are why /stats or /stats/errors could
+	// not be read.
+	StatsError  string `json:"stats_error,omitempty"`
+	ErrorsError string `json:"errors_error,omitempty"`
+}
+
+type evidenceResponse struct {
+	Evidence   []evidenceItem `json:"evidence"`
+	UnknownIDs []string       `json:"unknown_ids,omitempty"`
+}
+
+type feedsResponse struct {
+	Sites []siteFeeds `json:"sites"`
+}
+
+type historyResponse struct {
+	Calls []recentSearch `json:"calls"`
+}
+
+// structuredResult returns response as the structured content of a tool
+// declaring its type as output schema, with the indented JSON as text
+// content for clients that read only text.
+func structuredResult(response any) (*mcp.CallToolResult, error) {
+	jsonResult, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("result serialization error: %w", err)
+	}
+	return mcp.NewToolResultStructured(response, string(jsonResult)), nil
+}
+
+// outputSchema declares T as the output schema of a tool. Tools with a
+// dry_run argument use dryRunOutputSchema instead.
+func outputSchema[T any]() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		schema, err := reflectSchema[T]()
+		if err == nil {
+			err = setOutputSchema(tool, schema)
+		}
+		if err != nil {
+			log.Printf("Output schema of %s: %v", tool.Name, err)
+		}
+	}
+}
+
+// dryRunOutputSchema declares the output schema of a tool answering with
+// T, or with a dryRunResponse when called with dry_run: the properties of
+// both, of which those both require are required.
+func dryRunOutputSchema[T any]() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		response, err := reflectSchema[T]()
+		if err != nil {
+			log.Printf("Output schema of %s: %v", tool.Name, err)
+			return
+		}
+		dryRun, err := reflectSchema[dryRunResponse]()
+		if err != nil {
+			log.Printf("Output schema of %s: %v", tool.Name, err)
+			return
+		}
+
+		properties, _ := response["properties"].(map[string]any)
+		if properties == nil {
+			properties = make(map[string]any)
+		}
+		if dryRunProperties, ok := dryRun["properties"].(map[string]any); ok {
+			for name, property := range dryRunProperties {
+				if _, ok := properties[name]; !ok {
+					properties[name] = property
+				}
+			}
+		}
+		response["properties"] = properties
+
+		required := make(map[string]bool)
+		for _, name := range schemaRequired(dryRun) {
+			required[name] = true
+		}
+		var both []string
+		for _, name := range schemaRequired(response) {
+			if required[name] {
+				both = append(both, name)
+			}
+		}
+		if len(both) > 0 {
+			response["required"] = both
+		} else {
+			delete(response, "required")
+		}
+
+		if err := setOutputSchema(tool, response); err != nil {
+			log.Printf("Output schema of %s: %v", tool.Name, err)
+		}
+	}
+}
+
+// reflectSchema generates the schema of T as mcp.WithOutputSchema does and
+// returns it as a map. Nested arrays and objects are made nullable: nil
+// slices and maps of the response types encode as null.
+func reflectSchema[T any]() (map[string]any, error) {
+	var tool mcp.Tool
+	mcp.WithOutputSchema[T]()(&tool)
+	if tool.RawOutputSchema == nil {
+		return nil, fmt.Errorf("no schema generated")
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(tool.RawOutputSchema, &schema); err != nil {
+		return nil, err
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for _, property := range properties {
+			allowNull(property)
+		}
+	}
+	return schema, nil
+}
+
+func allowNull(schema any) {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return
+	}
+	if t, ok := s["type"].(string); ok && (t == "array" || t == "object") {
+		s["type"] = []any{t, "null"}
+	}
+	if properties, ok := s["properties"].(map[string]any); ok {
+		for _, property := range properties {
+			allowNull(property)
+		}
+	}
+	allowNull(s["items"])
+	allowNull(s["additionalProperties"])
+}
+
+func setOutputSchema(tool *mcp.Tool, schema map[string]any) error {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	tool.RawOutputSchema = raw
+	return nil
+}
+
+func schemaRequired(schema map[string]any) []string {
+	list, _ := schema["required"].([]any)
+	names := make([]string, 0, len(list))
+	for _, name := range list {
+		if s, ok := name.(string); ok {
+			names = append(names, s)
+		}
+	}
+	return names
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestDryRunOutputSchema(t *testing.T) {
+	tool := mcp.NewTool("searxng_search_v2", dryRunOutputSchema[searchV2Response]())
+	var schema struct {
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(tool.RawOutputSchema, &schema); err != nil {
+		t.Fatalf("schema %s: %v", tool.RawOutputSchema, err)
+	}
+	if schema.Type != "object" {
+		t.Errorf("type = %q, want object", schema.Type)
+	}
+	for _, name := range []string{"results", "meta", "dry_run", "request"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("schema lacks property %s", name)
+		}
+	}
+	if want := []string{"meta"}; !reflect.DeepEqual(schema.Required, want) {
+		t.Errorf("required = %q, want %q: only what both responses have", schema.Required, want)
+	}
+	if !strings.Contains(string(schema.Properties["results"]), `"type":["array","null"]`) {
+		t.Errorf("results = %s, want nullable: nil slices encode as null", schema.Properties["results"])
+	}
+}
+
+func TestSearchV2StructuredContent(t *testing.T) {
+	useFakeInstance(t)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	response, ok := result.StructuredContent.(searchV2Response)
+	if !ok {
+		t.Fatalf("structured content is %T, want searchV2Response", result.StructuredContent)
+	}
+	var text searchV2Response
+	decodeResult(t, result, &text)
+	if len(text.Results) != len(response.Results) || text.Query != response.Query {
+		t.Errorf("text content %+v does not match the structured content %+v", text, response)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "golang", "format": formatMarkdown})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if _, ok := result.StructuredContent.(searchV2Response); !ok {
+		t.Errorf("markdown result has structured content %T, want searchV2Response", result.StructuredContent)
+	}
+	if text, _ := mcp.AsTextContent(result.Content[0]); json.Valid([]byte(text.Text)) {
+		t.Errorf("markdown text content is JSON: %s", text.Text)
+	}
+}
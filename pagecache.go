@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxCachedPages bounds the in-memory page cache.
+const maxCachedPages = 200
+
+// errNotModified is returned by openPage when a revalidated page did not
+// change.
+var errNotModified = errors.New("not modified")
+
+// pageValidators are the response headers a cached page is revalidated
+// with.
+type pageValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+type cachedPage struct {
+	Page       *Page          `json:"page"`
+	Validators pageValidators `json:"validators"`
+}
+
+// readerCache caches the extracted content of fetched pages, apart from
+// search responses, keyed by normalized URL. Entries are served for ttl,
+// then revalidated with their ETag or Last-Modified.
+type readerCache struct {
+	store   responseStore
+	backend string
+	ttl     time.Duration
+
+	hits        atomic.Int64
+	revalidated atomic.Int64
+	misses      atomic.Int64
+}
+
+// pageCache is nil when page caching is disabled.
+var pageCache *readerCache
+
+// newReaderCache returns a cache persisted in dir, or held in memory when
+// dir is empty.
+func newReaderCache(dir string, ttl time.Duration) (*readerCache, error) {
+	c := &readerCache{ttl: ttl}
+	if dir == "" {
+		c.store = &memoryStore{entries: make(map[string]memoryEntry), limit: maxCachedPages}
+		c.backend = "memory"
+		return c, nil
+	}
+	store, err := openBoltStore(filepath.Join(dir, "pages.db"))
+	if err != nil {
+		return nil, err
+	}
+	c.store = store
+	c.backend = "disk " + dir
+	return c, nil
+}
+
+// fetch returns the page from the cache while it is fresh, and otherwise
+// downloads it, conditionally when the cached copy has validators. The
+// fetch policy is checked first, so that a page cached before a reload
+// blocked its URL is not served.
+func (c *readerCache) fetch(ctx context.Context, pageURL string) (*Page, error) {
+	if c == nil {
+		page, _, err := downloadPage(ctx, pageURL, pageValidators{})
+		return page, err
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := stateOf(ctx).fetchPolicy.checkURL(ctx, u); err != nil {
+		return nil, err
+	}
+
+	key := normalizePageURL(pageURL)
+	var cached cachedPage
+	data, stored, ok := c.store.get(key)
+	if ok && (json.Unmarshal(data, &cached) != nil || cached.Page == nil) {
+		ok, cached = false, cachedPage{}
+	}
+	if ok && time.Since(stored) < c.ttl {
+		c.hits.Add(1)
+		return cached.Page, nil
+	}
+
+	page, validators, err := downloadPage(ctx, pageURL, cached.Validators)
+	if ok && errors.Is(err, errNotModified) {
+		c.revalidated.Add(1)
+		c.put(key, cached)
+		return cached.Page, nil
+	}
+	c.misses.Add(1)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, cachedPage{Page: page, Validators: validators})
+	return page, nil
+}
+
+func (c *readerCache) put(key string, entry cachedPage) {
+	if data, err := json.Marshal(entry); err == nil {
+		c.store.put(key, data, time.Now())
+	}
+}
+
+// describe summarizes the cache for the dashboard.
+func (c *readerCache) describe() string {
+	if c == nil {
+		return "No page cache configured"
+	}
+	return fmt.Sprintf("%s page cache, TTL %s: %d pages, %d hits, %d revalidated, %d misses",
+		c.backend, c.ttl, c.store.len(), c.hits.Load(), c.revalidated.Load(), c.misses.Load())
+}
+
+// normalizePageURL is the cache key of a page: the URL without fragment,
+// default port and tracking parameters, with a lowercase host and sorted
+// query.
+func normalizePageURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment, u.RawFragment = "", ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for name := range query {
+		if isTrackingParameter(name) {
+			query.Del(name)
+		}
+	}
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReaderCacheRevalidation(t *testing.T) {
+	var requests, notModified atomic.Int64
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Article</title></head><body><p>Cached article text</p></body></html>"))
+	}))
+	defer site.Close()
+	useFetchPolicy(t, nil)
+
+	cache, err := newReaderCache("", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageCache = cache
+	t.Cleanup(func() { pageCache = nil })
+
+	for _, u := range []string{site.URL + "/article?utm_source=feed", site.URL + "/article#comments"} {
+		page, err := fetchPage(context.Background(), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Title != "Article" {
+			t.Errorf("title = %q, want Article", page.Title)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("%d requests for a fresh page, want 1", requests.Load())
+	}
+
+	cache.ttl = 0
+	page, err := fetchPage(context.Background(), site.URL+"/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Article" || notModified.Load() != 1 {
+		t.Errorf("stale page: title %q, %d not modified responses, want the cached page revalidated", page.Title, notModified.Load())
+	}
+	if cache.hits.Load() != 1 || cache.revalidated.Load() != 1 || cache.misses.Load() != 1 {
+		t.Errorf("hits %d, revalidated %d, misses %d, want 1 each", cache.hits.Load(), cache.revalidated.Load(), cache.misses.Load())
+	}
+
+	cache.ttl = time.Hour
+	useFetchPolicy(t, &FetchConfig{BlockPrivateNetworks: true})
+	if _, err := fetchPage(context.Background(), site.URL+"/article"); err == nil {
+		t.Error("cached page of a blocked URL was served")
+	}
+}
+
+func TestNormalizePageURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"HTTPS://Example.COM:443/a?b=2&a=1#top":           "https://example.com/a?a=1&b=2",
+		"http://example.com":                              "http://example.com/",
+		"http://example.com:8080/x?utm_medium=x&fbclid=y": "http://example.com:8080/x",
+		"https://example.com/p?id=3&gclid=abc":            "https://example.com/p?id=3",
+	} {
+		if got := normalizePageURL(in); got != want {
+			t.Errorf("normalizePageURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
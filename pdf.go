@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	maxPDFBytes     = 10 << 20
+	defaultPDFPages = 20
+)
+
+// pdfTypes are the media types of PDF documents.
+var pdfTypes = map[string]bool{"application/pdf": true, "application/x-pdf": true}
+
+func isPDF(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return pdfTypes[mediaType]
+}
+
+// readPDF reads a PDF document, which must arrive whole within the size
+// limit, and extracts the text of its leading pages.
+func readPDF(ctx context.Context, pageURL string, resp *http.Response) (*Page, error) {
+	policy := stateOf(ctx).fetchPolicy
+	limit := policy.limit(true)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("PDF is above the %d byte limit", limit)
+	}
+	return extractPDF(pageURL, data, policy.pdfPages)
+}
+
+// extractPDF returns the text of the first maxPages pages of a PDF
+// document, one block per page starting with a "[Page n]" marker.
+func extractPDF(pageURL string, data []byte, maxPages int) (page *Page, err error) {
+	// The PDF reader panics on malformed documents.
+	defer func() {
+		if r := recover(); r != nil {
+			page, err = nil, fmt.Errorf("error reading PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading PDF: %w", err)
+	}
+	page = &Page{URL: pageURL, PDFPages: reader.NumPage()}
+	page.Title = collapse(reader.Trailer().Key("Info").Key("Title").Text())
+
+	for n := 1; n <= min(reader.NumPage(), maxPages); n++ {
+		p := reader.Page(n)
+		if p.V.IsNull() {
+			continue
+		}
+		lines := append([]string{fmt.Sprintf("[Page %d]", n)}, pdfLines(p.Content().Text)...)
+		if len(lines) > 1 {
+			page.Blocks = append(page.Blocks, strings.Join(lines, "\n"))
+		}
+	}
+	if len(page.Blocks) == 0 {
+		return nil, fmt.Errorf("PDF has no extractable text, it may be scanned")
+	}
+	return page, nil
+}
+
+// pdfLines joins the glyphs of a page, in content order, into lines: a
+// glyph off the baseline of the previous one starts a line, and a gap wider
+// than a narrow space separates words.
+func pdfLines(glyphs []pdf.Text) []string {
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if text := collapse(line.String()); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+	for i, glyph := range glyphs {
+		if i > 0 {
+			prev := glyphs[i-1]
+			switch {
+			case math.Abs(glyph.Y-prev.Y) > max(prev.FontSize, 1)/2:
+				flush()
+			case prev.W > 0 && glyph.X-(prev.X+prev.W) > prev.FontSize*0.15:
+				line.WriteByte(' ')
+			}
+		}
+		line.WriteString(glyph.S)
+	}
+	flush()
+	return lines
+}
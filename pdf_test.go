@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// buildPDF returns a PDF document with one page per entry of pages, each
+// line of an entry a row of text.
+func buildPDF(title string, pages ...string) []byte {
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 12 Tf 72 720 Td\n")
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(&content, "(%s) Tj 0 -14 Td\n", line)
+		}
+		content.WriteString("ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Title (%s) >>", title))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return b.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	data := buildPDF("Attention Is All You Need", "The dominant sequence transduction models\nare based on recurrent networks.", "Second page text.", "Third page text.")
+
+	page, err := extractPDF("https://arxiv.org/pdf/1706.03762", data, 2)
+	if err != nil {
+		t.Fatalf("extractPDF: %v", err)
+	}
+	if page.Title != "Attention Is All You Need" || page.PDFPages != 3 {
+		t.Errorf("page = %+v", page)
+	}
+	want := "[Page 1]\nThe dominant sequence transduction models\nare based on recurrent networks.\n[Page 2]\nSecond page text."
+	if got := page.Text(); got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+
+	if _, err := extractPDF("https://example.com/broken.pdf", []byte("%PDF-1.4 garbage"), 2); err == nil {
+		t.Error("broken PDF accepted")
+	}
+}
+
+func TestSearchAndReadPDF(t *testing.T) {
+	paper := buildPDF("Paper", "Abstract of a paper about transformers and attention mechanisms.")
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(paper)
+	}))
+	defer pages.Close()
+
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"query":   "attention paper",
+		"results": []map[string]interface{}{{"title": "Paper", "url": pages.URL + "/paper.pdf", "engine": "arxiv"}},
+	})
+
+	result, err := callTool(t, searxngSearchAndReadHandler, map[string]interface{}{"query": "attention paper"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchAndReadResponse
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 || response.Results[0].Error != "" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+	if read := response.Results[0]; read.PageTitle != "Paper" || !strings.HasPrefix(read.Excerpt, "[Page 1]\nAbstract of a paper") {
+		t.Errorf("read = %+v", read)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// ResultProcessor is a step of the result pipeline: it returns the results
+// to keep, in order, possibly changed. Processors must not modify the
+// slice they are given in place, other steps may hold it.
+type ResultProcessor interface {
+	Process(results []searxng.SearchResult) []searxng.SearchResult
+}
+
+// ResultProcessorFunc adapts a function to ResultProcessor.
+type ResultProcessorFunc func(results []searxng.SearchResult) []searxng.SearchResult
+
+func (f ResultProcessorFunc) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	return f(results)
+}
+
+// resultPipeline runs its processors in order.
+type resultPipeline []ResultProcessor
+
+func (p resultPipeline) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	for _, processor := range p {
+		results = processor.Process(results)
+	}
+	return results
+}
+
+// ProcessorConfig is a step of result_pipeline in the config file. Name
+// selects the processor; the other fields are its options.
+//
+//	"result_pipeline": [
+//	  {"name": "dedupe"},
+//	  {"name": "filter", "exclude_domains": ["pinterest.com"], "min_score": 0.5},
+//	  {"name": "rerank", "prefer_domains": ["go.dev"]},
+//	  {"name": "truncate", "max_results": 10, "max_content_chars": 300}
+//	]
+type ProcessorConfig struct {
+	Name string `json:"name"`
+	// ExcludeDomains and MinScore are the options of filter; results
+	// without a score are kept whatever MinScore.
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+	MinScore       float64  `json:"min_score,omitempty"`
+	// PreferDomains are moved first by rerank.
+	PreferDomains []string `json:"prefer_domains,omitempty"`
+	// MaxResults and MaxContentChars are the options of truncate.
+	MaxResults      int `json:"max_results,omitempty"`
+	MaxContentChars int `json:"max_content_chars,omitempty"`
+}
+
+// resultProcessors build the processors selectable in result_pipeline.
+var resultProcessors = map[string]func(c ProcessorConfig) (ResultProcessor, error){
+	"dedupe": func(c ProcessorConfig) (ResultProcessor, error) {
+		return ResultProcessorFunc(dedupeResults), nil
+	},
+	"filter": func(c ProcessorConfig) (ResultProcessor, error) {
+		if len(c.ExcludeDomains) == 0 && c.MinScore == 0 {
+			return nil, fmt.Errorf("filter needs exclude_domains or min_score")
+		}
+		return resultFilter{exclude: domainFilter(c.ExcludeDomains), minScore: c.MinScore}, nil
+	},
+	"rerank": func(c ProcessorConfig) (ResultProcessor, error) {
+		return resultRanker{prefer: domainFilter(c.PreferDomains)}, nil
+	},
+	"truncate": func(c ProcessorConfig) (ResultProcessor, error) {
+		if c.MaxResults <= 0 && c.MaxContentChars <= 0 {
+			return nil, fmt.Errorf("truncate needs max_results or max_content_chars")
+		}
+		return resultTruncator{maxResults: c.MaxResults, maxContentChars: c.MaxContentChars}, nil
+	},
+}
+
+// newResultPipeline builds the processors of configs in order.
+func newResultPipeline(configs []ProcessorConfig) (resultPipeline, error) {
+	pipeline := make(resultPipeline, 0, len(configs))
+	for i, c := range configs {
+		build, ok := resultProcessors[c.Name]
+		if !ok {
+			names := make([]string, 0, len(resultProcessors))
+			for name := range resultProcessors {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("result_pipeline step %d: unknown processor %q, want one of %s", i+1, c.Name, strings.Join(names, ", "))
+		}
+		processor, err := build(c)
+		if err != nil {
+			return nil, fmt.Errorf("result_pipeline step %d: %w", i+1, err)
+		}
+		pipeline = append(pipeline, processor)
+	}
+	return pipeline, nil
+}
+
+// processResults runs p on the results of result and returns the number
+// of results it removed.
+func processResults(p ResultProcessor, result *searxng.SearchResponse) int {
+	n := len(result.Results)
+	result.Results = p.Process(result.Results)
+	return n - len(result.Results)
+}
+
+// domainFilter matches the configured domains, subdomains included.
+func domainFilter(domains []string) siteFilter {
+	sites := make(siteFilter, 0, len(domains))
+	for _, domain := range domains {
+		if domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www."); domain != "" {
+			sites = append(sites, domain)
+		}
+	}
+	return sites
+}
+
+// dedupeResults keeps the first result of each page.
+func dedupeResults(results []searxng.SearchResult) []searxng.SearchResult {
+	seen := make(map[string]bool, len(results))
+	kept := make([]searxng.SearchResult, 0, len(results))
+	for _, r := range results {
+		key := pageKey(r.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// defaultMinScore is the min_score of searches not given one, set by
+// -min-score.
+var defaultMinScore float64
+
+func minScoreOption() mcp.ToolOption {
+	return mcp.WithNumber("min_score",
+		mcp.Description("Drop results the instance scored below this, e.g. 0.5, to cut the long tail of weak matches; results without a score are kept. 0 keeps all"),
+	)
+}
+
+// minScoreFromArguments reads min_score, defaultMinScore when not given.
+func minScoreFromArguments(arguments map[string]interface{}) (float64, error) {
+	minScore, ok, err := floatArgument(arguments, "min_score")
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return defaultMinScore, nil
+	}
+	if minScore < 0 {
+		return 0, fmt.Errorf("min_score must not be negative, got %g", minScore)
+	}
+	return minScore, nil
+}
+
+// resultFilter drops the results of excluded domains and those scored
+// below minScore.
+type resultFilter struct {
+	exclude  siteFilter
+	minScore float64
+}
+
+func (f resultFilter) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	kept := make([]searxng.SearchResult, 0, len(results))
+	for _, r := range results {
+		if len(f.exclude) > 0 && f.exclude.allows(r.URL) {
+			continue
+		}
+		if r.Score > 0 && r.Score < f.minScore {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// resultRanker orders the results of preferred domains first, then by
+// score; equal results keep the order of the instance.
+type resultRanker struct {
+	prefer siteFilter
+}
+
+func (r resultRanker) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	ranked := append([]searxng.SearchResult(nil), results...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		pi, pj := r.prefer.allows(ranked[i].URL), r.prefer.allows(ranked[j].URL)
+		if pi != pj {
+			return pi
+		}
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// resultTruncator keeps the first maxResults results and cuts their
+// content to maxContentChars characters; zero limits are not applied.
+type resultTruncator struct {
+	maxResults      int
+	maxContentChars int
+}
+
+func (t resultTruncator) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	if t.maxResults > 0 && len(results) > t.maxResults {
+		results = results[:t.maxResults]
+	}
+	if t.maxContentChars <= 0 {
+		return results
+	}
+	cut := make([]searxng.SearchResult, len(results))
+	for i, r := range results {
+		if utf8.RuneCountInString(r.Content) > t.maxContentChars {
+			r.Content = strings.TrimSpace(string([]rune(r.Content)[:t.maxContentChars])) + "…"
+		}
+		cut[i] = r
+	}
+	return cut
+}
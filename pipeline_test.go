@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func resultURLs(results []searxng.SearchResult) []string {
+	urls := make([]string, len(results))
+	for i, r := range results {
+		urls[i] = r.URL
+	}
+	return urls
+}
+
+func TestResultPipeline(t *testing.T) {
+	results := []searxng.SearchResult{
+		{URL: "https://a.example/", Score: 3, Content: "alpha beta gamma"},
+		{URL: "https://www.a.example/?utm_source=x", Score: 2},
+		{URL: "https://pins.pinterest.com/1", Score: 5},
+		{URL: "https://low.example/", Score: 0.1},
+		{URL: "https://go.dev/doc", Score: 1},
+		{URL: "https://c.example/", Score: 4},
+	}
+	pipeline, err := newResultPipeline([]ProcessorConfig{
+		{Name: "dedupe"},
+		{Name: "filter", ExcludeDomains: []string{"Pinterest.com"}, MinScore: 0.5},
+		{Name: "rerank", PreferDomains: []string{"go.dev"}},
+		{Name: "truncate", MaxResults: 3, MaxContentChars: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := pipeline.Process(results)
+	if want := []string{"https://go.dev/doc", "https://c.example/", "https://a.example/"}; !reflect.DeepEqual(resultURLs(got), want) {
+		t.Errorf("urls = %v, want %v", resultURLs(got), want)
+	}
+	if got[2].Content != "alpha…" || results[0].Content != "alpha beta gamma" {
+		t.Errorf("content = %q, input %q: want a cut copy", got[2].Content, results[0].Content)
+	}
+}
+
+func TestResultPipelineConfig(t *testing.T) {
+	for _, configs := range [][]ProcessorConfig{
+		{{Name: "sort"}},
+		{{Name: "filter"}},
+		{{Name: "truncate"}},
+	} {
+		if _, err := newResultPipeline(configs); err == nil {
+			t.Errorf("newResultPipeline(%+v) accepted an invalid step", configs)
+		}
+	}
+}
+
+func TestSearchRunsResultPipeline(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	useConfig(t, &Config{ResultPipeline: []ProcessorConfig{{Name: "dedupe"}}})
+	fake.SetSearchResponse(searchResults("https://a.example/", "https://a.example", "https://b.example/"))
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 2 || response.Meta.PipelineRemovedResults != 1 {
+		t.Errorf("%d results, meta %+v, want the duplicate removed", len(response.Results), response.Meta)
+	}
+
+	// The pipeline runs once on the merged pages of thorough mode.
+	useConfig(t, &Config{ResultPipeline: []ProcessorConfig{{Name: "truncate", MaxResults: 2}}})
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("pageno")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(searchResults("https://a.example/"+page, "https://b.example/"+page))
+	})
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "mode": "thorough"})
+	if err != nil || result.IsError {
+		t.Fatalf("thorough search: %+v, %v", result, err)
+	}
+	response = searchV2Response{}
+	decodeResult(t, result, &response)
+	if len(response.Results) != 2 || response.Meta.PipelineRemovedResults != 4 {
+		t.Errorf("%d results, meta %+v, want the 6 results of 3 pages cut to 2", len(response.Results), response.Meta)
+	}
+}
+
+func TestSearchMinScore(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []interface{}{
+			map[string]interface{}{"url": "https://a.example/", "title": "A", "score": 3.2},
+			map[string]interface{}{"url": "https://b.example/", "title": "B", "score": 0.04},
+			map[string]interface{}{"url": "https://c.example/", "title": "C"},
+		},
+	})
+	previous := defaultMinScore
+	defaultMinScore = 0.5
+	t.Cleanup(func() { defaultMinScore = previous })
+
+	for _, tt := range []struct {
+		arguments map[string]interface{}
+		urls      []string
+		dropped   int
+	}{
+		{map[string]interface{}{"query": "q"}, []string{"https://a.example/", "https://c.example/"}, 1},
+		{map[string]interface{}{"query": "q", "min_score": 5}, []string{"https://c.example/"}, 2},
+		{map[string]interface{}{"query": "q", "min_score": 0}, []string{"https://a.example/", "https://b.example/", "https://c.example/"}, 0},
+	} {
+		result, err := callTool(t, searxngSearchV2Handler, tt.arguments)
+		if err != nil || result.IsError {
+			t.Fatalf("search %v: %+v, %v", tt.arguments, result, err)
+		}
+		var response searchV2Response
+		decodeResult(t, result, &response)
+		var urls []string
+		for _, r := range response.Results {
+			urls = append(urls, r.URL)
+		}
+		if !reflect.DeepEqual(urls, tt.urls) || response.Meta.LowScoreResults != tt.dropped {
+			t.Errorf("search %v: %q, %d dropped, want %q, %d", tt.arguments, urls, response.Meta.LowScoreResults, tt.urls, tt.dropped)
+		}
+	}
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "min_score": -1})
+	if err != nil || errorCode(result) != errorInvalidParams {
+		t.Errorf("negative min_score: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,144 @@
+package searxng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// States of a CircuitBreaker.
+const (
+	CircuitClosed = "closed"
+	// CircuitOpen fails requests fast until the cool-down ends.
+	CircuitOpen = "open"
+	// CircuitHalfOpen lets one trial request through after the cool-down;
+	// its outcome closes or reopens the circuit.
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops requests to an instance after Failures consecutive
+// failures for Cooldown, so that callers do not each wait out the request
+// timeout of an instance that is down. Transport errors, timeouts, 5xx
+// and 429 responses count as failures.
+type CircuitBreaker struct {
+	Failures int
+	Cooldown time.Duration
+
+	mu          sync.Mutex
+	consecutive int
+	openUntil   time.Time
+	probing     bool
+}
+
+// CircuitState is a snapshot of a CircuitBreaker.
+type CircuitState struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenUntil           time.Time `json:"open_until,omitempty"`
+}
+
+// CircuitOpenError is returned without contacting the instance while its
+// circuit breaker is open.
+type CircuitOpenError struct {
+	Instance string
+	Failures int
+	Until    time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("instance %s is paused after %d consecutive failures, retrying after %s",
+		e.Instance, e.Failures, e.Until.Format(time.TimeOnly))
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state(time.Now())
+}
+
+// state returns the state at now. The caller holds b.mu.
+func (b *CircuitBreaker) state(now time.Time) CircuitState {
+	s := CircuitState{State: CircuitClosed, ConsecutiveFailures: b.consecutive}
+	if b.consecutive >= b.Failures && !b.openUntil.IsZero() {
+		s.State = CircuitOpen
+		s.OpenUntil = b.openUntil
+		if !now.Before(b.openUntil) {
+			s.State = CircuitHalfOpen
+		}
+	}
+	return s
+}
+
+// Open reports whether requests currently fail fast.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.state(time.Now())
+	return s.State == CircuitOpen || s.State == CircuitHalfOpen && b.probing
+}
+
+// allow returns a CircuitOpenError when a request to instance must not be
+// sent. In the half-open state it admits a single trial request.
+func (b *CircuitBreaker) allow(instance string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state(time.Now()).State {
+	case CircuitOpen:
+		return &CircuitOpenError{Instance: instance, Failures: b.consecutive, Until: b.openUntil}
+	case CircuitHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{Instance: instance, Failures: b.consecutive, Until: b.openUntil}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record counts the outcome of an admitted request.
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.consecutive = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.consecutive++
+	if b.consecutive >= b.Failures {
+		b.openUntil = time.Now().Add(b.Cooldown)
+	}
+}
+
+// release ends an admitted request without counting it, so that a
+// canceled trial request lets the next one through.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// do sends req through the circuit breaker of the client, if any.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Breaker == nil {
+		return c.send(req)
+	}
+	if err := c.Breaker.allow(c.BaseURL); err != nil {
+		return nil, err
+	}
+	resp, err := c.send(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		// The caller gave up; that says nothing about the instance.
+		c.Breaker.release()
+	case err != nil:
+		c.Breaker.record(true)
+	default:
+		c.Breaker.record(resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests)
+	}
+	return resp, err
+}
@@ -0,0 +1,539 @@
+package searxng
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultUserAgent = "MCP-SearXNG-Client/1.0"
+
+// DefaultMaxIdleConnsPerHost is the number of idle connections kept open to
+// the instance. Agents fire searches in quick bursts; the net/http default
+// of 2 makes most of them set up a new (TLS) connection.
+const DefaultMaxIdleConnsPerHost = 32
+
+// NewTransport returns the transport clients use unless WithHTTPClient
+// replaces it: connections to the instance are kept alive and reused, HTTP/2
+// is negotiated where the instance supports it, and responses are requested
+// gzip compressed and decompressed transparently.
+func NewTransport() *http.Transport {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if defaults, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaults.Clone()
+	}
+	transport.MaxIdleConns = 4 * DefaultMaxIdleConnsPerHost
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.ForceAttemptHTTP2 = true
+	transport.DisableCompression = false
+	return transport
+}
+
+// Client talks to one SearXNG instance. It is safe for concurrent use once
+// constructed; the exported fields must not be modified afterwards.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	UserAgent  string
+	Headers    http.Header
+	// SearchMethod is the HTTP method used for /search, GET or POST.
+	SearchMethod string
+	// PathPrefix is inserted between BaseURL and the endpoint path, for
+	// instances served under a secret path.
+	PathPrefix string
+	// QueryParams are appended to the URL of every request, e.g. an access
+	// token checked by the instance or its proxy.
+	QueryParams url.Values
+	// Preferences is a saved preferences string of the instance, sent with
+	// every request so searches use its engines, locale and other
+	// settings instead of the instance defaults.
+	Preferences string
+	// Cookies are sent with every request, next to those the instance
+	// set, e.g. the preference cookies of a browser session.
+	Cookies []*http.Cookie
+	// MinSafeSearch is the lowest safe search level sent, whatever the
+	// level requested per search (0 off, 1 moderate, 2 strict).
+	MinSafeSearch int
+	// SchemaObserver, when set, is called with the differences between
+	// every /search response and the expected schema, if any.
+	SchemaObserver func(issues []SchemaIssue)
+	// LenientParsing drops fields whose JSON type changed instead of
+	// failing the whole search.
+	LenientParsing bool
+	// Breaker, when set, fails requests fast while the instance keeps
+	// failing. Probe bypasses it.
+	Breaker *CircuitBreaker
+	// HTMLFallback makes Search parse the HTML results page when the
+	// instance answers the JSON format with 403 Forbidden, as many public
+	// instances do.
+	HTMLFallback bool
+
+	// preflightMu guards preflighted, set once the front page was
+	// visited: a failed visit is retried on the next POST search.
+	preflightMu sync.Mutex
+	preflighted bool
+	// jsonForbiddenAt is when the instance last refused the JSON format,
+	// in Unix nanoseconds, 0 when it accepts it.
+	jsonForbiddenAt atomic.Int64
+	// version is the version read from /config, nil until detected.
+	version atomic.Pointer[Version]
+	// limiter holds a slot per in-flight request when the number of
+	// concurrent requests is limited.
+	limiter chan struct{}
+	pool    poolCounters
+	// transport is the transport New built, the only one the connection
+	// pool options tune.
+	transport *http.Transport
+}
+
+// New returns a client for the instance at baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	transport := NewTransport()
+	c := &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		UserAgent:    DefaultUserAgent,
+		Headers:      http.Header{},
+		SearchMethod: http.MethodGet,
+		QueryParams:  url.Values{},
+		transport:    transport,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	// Keep cookies between requests: instances that hand out a session
+	// cookie on the first visit reject searches without it.
+	if c.HTTPClient.Jar == nil {
+		if jar, err := cookiejar.New(nil); err == nil {
+			c.HTTPClient.Jar = jar
+		}
+	}
+	return c
+}
+
+// defaultTransport returns the transport New built while the client
+// still uses it, nil once another client or transport replaced it.
+func (c *Client) defaultTransport() *http.Transport {
+	if c.HTTPClient.Transport != c.transport {
+		return nil
+	}
+	return c.transport
+}
+
+// endpoint returns the full URL of path on the instance with the static
+// query parameters and the given values encoded in the query string.
+func (c *Client) endpoint(path string, values url.Values) string {
+	query := url.Values{}
+	for name, vals := range c.QueryParams {
+		query[name] = append([]string(nil), vals...)
+	}
+	for name, vals := range values {
+		query[name] = append(query[name], vals...)
+	}
+	if c.Preferences != "" {
+		query.Set("preferences", c.Preferences)
+	}
+	u := c.BaseURL + c.PathPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// setHeaders applies the client default headers followed by the per-call
+// overrides, so a call can replace any default including the User-Agent.
+func (c *Client) setHeaders(req *http.Request, overrides http.Header) {
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set("Accept", "application/json")
+	for name, values := range c.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+	for name, values := range overrides {
+		req.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+	for _, cookie := range c.Cookies {
+		req.AddCookie(cookie)
+	}
+}
+
+// Search runs a search and returns the decoded JSON response. With
+// HTMLFallback, an instance refusing the JSON format is searched through
+// its HTML results page instead.
+func (c *Client) Search(ctx context.Context, params SearchParams) (*SearchResponse, error) {
+	if c.HTMLFallback && c.jsonForbidden() {
+		return c.searchHTML(ctx, params)
+	}
+	var searchResponse SearchResponse
+	err := c.search(ctx, params, &searchResponse)
+	var httpErr *HTTPError
+	if err != nil && c.HTMLFallback && errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusForbidden {
+		response, htmlErr := c.searchHTML(ctx, params)
+		if htmlErr != nil {
+			return nil, err
+		}
+		c.jsonForbiddenAt.Store(time.Now().UnixNano())
+		return response, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.jsonForbiddenAt.Store(0)
+	return &searchResponse, nil
+}
+
+// SearchImages runs an image search and keeps the image specific fields of
+// the results. Callers normally set Categories to "images".
+func (c *Client) SearchImages(ctx context.Context, params SearchParams) (*ImageSearchResponse, error) {
+	var searchResponse ImageSearchResponse
+	if err := c.search(ctx, params, &searchResponse); err != nil {
+		return nil, err
+	}
+	for i := range searchResponse.Results {
+		r := &searchResponse.Results[i]
+		r.ImgSrc = absoluteURL(r.ImgSrc)
+		r.ThumbnailSrc = absoluteURL(r.ThumbnailSrc)
+	}
+	return &searchResponse, nil
+}
+
+// SearchCode runs a search and keeps the package and repository fields of
+// the results. Callers normally set Categories to "it".
+func (c *Client) SearchCode(ctx context.Context, params SearchParams) (*CodeSearchResponse, error) {
+	var searchResponse CodeSearchResponse
+	if err := c.search(ctx, params, &searchResponse); err != nil {
+		return nil, err
+	}
+	return &searchResponse, nil
+}
+
+// SearchMusic runs a search and keeps the music specific fields of the
+// results. Callers normally set Categories to "music".
+func (c *Client) SearchMusic(ctx context.Context, params SearchParams) (*MusicSearchResponse, error) {
+	var searchResponse MusicSearchResponse
+	if err := c.search(ctx, params, &searchResponse); err != nil {
+		return nil, err
+	}
+	for i := range searchResponse.Results {
+		r := &searchResponse.Results[i]
+		r.Thumbnail = absoluteURL(r.Thumbnail)
+		r.IframeSrc = absoluteURL(r.IframeSrc)
+		r.AudioSrc = absoluteURL(r.AudioSrc)
+	}
+	return &searchResponse, nil
+}
+
+// SearchMap runs a search and keeps the coordinates and addresses of the
+// results. Callers normally set Categories to "map".
+func (c *Client) SearchMap(ctx context.Context, params SearchParams) (*MapSearchResponse, error) {
+	var searchResponse MapSearchResponse
+	if err := c.search(ctx, params, &searchResponse); err != nil {
+		return nil, err
+	}
+	return &searchResponse, nil
+}
+
+// absoluteURL turns the protocol relative URLs some engines return into
+// https URLs that clients can open directly.
+func absoluteURL(u string) string {
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
+	return u
+}
+
+// SafeSearchLevel returns the safe search level sent for the requested
+// one: raised to MinSafeSearch, or -1 when an out of range level leaves
+// the choice to the instance.
+func (c *Client) SafeSearchLevel(requested int) int {
+	if requested < 0 || requested > 2 {
+		if c.MinSafeSearch > 0 {
+			return c.MinSafeSearch
+		}
+		return -1
+	}
+	return max(requested, c.MinSafeSearch)
+}
+
+// NewSearchRequest builds the /search request for params exactly as Search
+// sends it, without sending it. It is meant for inspecting requests.
+func (c *Client) NewSearchRequest(ctx context.Context, params SearchParams) (*http.Request, error) {
+	return c.newSearchRequest(ctx, params, "json")
+}
+
+// newSearchRequest builds the /search request of params in format, one of
+// RawFormats or "" for the HTML results page.
+func (c *Client) newSearchRequest(ctx context.Context, params SearchParams, format string) (*http.Request, error) {
+	values := url.Values{}
+	values.Set("q", params.Query)
+	if format != "" {
+		values.Set("format", format)
+	}
+
+	if len(params.Categories) > 0 {
+		values.Set("categories", strings.Join(params.Categories, ","))
+	}
+
+	if len(params.Engines) > 0 {
+		values.Set("engines", strings.Join(params.Engines, ","))
+	}
+
+	if params.Language != "" {
+		values.Set("language", params.Language)
+	}
+
+	if params.PageNo > 0 {
+		values.Set("pageno", strconv.Itoa(params.PageNo))
+	}
+
+	if params.TimeRange != "" {
+		values.Set("time_range", params.TimeRange)
+	}
+
+	if level := c.SafeSearchLevel(params.SafeSearch); level >= 0 {
+		values.Set("safesearch", strconv.Itoa(level))
+	}
+
+	var req *http.Request
+	var err error
+	if c.SearchMethod == http.MethodPost {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint("/search", nil), strings.NewReader(values.Encode()))
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/search", values), nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	c.setHeaders(req, params.Headers)
+	switch format {
+	case "":
+		req.Header.Set("Accept", "text/html")
+	case "csv":
+		req.Header.Set("Accept", "text/csv")
+	case "rss":
+		req.Header.Set("Accept", "application/rss+xml")
+	}
+	if req.Method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req, nil
+}
+
+// RawFormats are the formats SearchRaw can request: those of SearXNG
+// besides its HTML page. Instances serve only the formats listed in their
+// search.formats setting.
+var RawFormats = []string{"json", "csv", "rss"}
+
+// RawResponse is a search response as the instance sent it.
+type RawResponse struct {
+	Format string
+	// ContentType is the Content-Type header of the response.
+	ContentType string
+	Body        []byte
+}
+
+// SearchRaw runs a search in format, one of RawFormats, and returns the
+// response body without parsing it, e.g. to pipe CSV or RSS results into
+// other tools.
+func (c *Client) SearchRaw(ctx context.Context, params SearchParams, format string) (*RawResponse, error) {
+	if !slices.Contains(RawFormats, format) {
+		return nil, fmt.Errorf("unsupported format %q: use %s", format, strings.Join(RawFormats, ", "))
+	}
+	body, contentType, err := c.fetchSearch(ctx, params, format)
+	if err != nil {
+		return nil, err
+	}
+	return &RawResponse{Format: format, ContentType: contentType, Body: body}, nil
+}
+
+// fetchSearch performs the /search request in format and returns the
+// response body and its content type.
+func (c *Client) fetchSearch(ctx context.Context, params SearchParams, format string) ([]byte, string, error) {
+	req, err := c.newSearchRequest(ctx, params, format)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.Method == http.MethodPost {
+		c.preflight(ctx)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, "", newHTTPError(resp, "/search", body)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading response: %w", err)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// search performs the /search request and decodes the response into out.
+func (c *Client) search(ctx context.Context, params SearchParams, out interface{}) error {
+	body, _, err := c.fetchSearch(ctx, params, "json")
+	if err != nil {
+		return err
+	}
+
+	// Rewrite the responses of other versions first, so that the schema
+	// check reports only what the compatibility layer does not handle.
+	body = normalizeSearchBody(body)
+	if c.SchemaObserver != nil || c.LenientParsing {
+		issues, sanitized := CheckSearchSchema(body)
+		if len(issues) > 0 && c.SchemaObserver != nil {
+			c.SchemaObserver(issues)
+		}
+		if c.LenientParsing {
+			body = sanitized
+		}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	return nil
+}
+
+// preflight visits the instance front page once so that the cookie jar
+// holds whatever session cookies the instance expects on POST searches.
+// Failures are ignored, and the visit retried by the next search; the
+// search itself reports any real problem.
+func (c *Client) preflight(ctx context.Context) {
+	c.preflightMu.Lock()
+	defer c.preflightMu.Unlock()
+	if c.preflighted {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/", nil), nil)
+	if err != nil {
+		return
+	}
+	c.setHeaders(req, nil)
+	req.Header.Set("Accept", "text/html")
+	resp, err := c.send(req)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	c.preflighted = resp.StatusCode < http.StatusInternalServerError
+}
+
+// GetEngines returns the raw /config document of the instance, which lists
+// its engines, categories, plugins and version.
+func (c *Client) GetEngines(ctx context.Context) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := c.getJSON(ctx, "/config", nil, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Autocomplete returns the completions the instance's autocomplete backend
+// suggests for query. It is empty when autocomplete is disabled in the
+// instance settings.
+func (c *Client) Autocomplete(ctx context.Context, query string) ([]string, error) {
+	var raw json.RawMessage
+	if err := c.getJSON(ctx, "/autocompleter", url.Values{"q": {query}}, &raw); err != nil {
+		return nil, err
+	}
+
+	// The instance answers either a plain list or the OpenSearch
+	// suggestions format [query, [completions...]].
+	var completions []string
+	if err := json.Unmarshal(raw, &completions); err == nil {
+		return completions, nil
+	}
+	var openSearch []json.RawMessage
+	if err := json.Unmarshal(raw, &openSearch); err != nil || len(openSearch) < 2 {
+		return nil, fmt.Errorf("error parsing JSON: unexpected autocomplete response %s", raw)
+	}
+	if err := json.Unmarshal(openSearch[1], &completions); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	return completions, nil
+}
+
+// GetStatsErrors returns the errors each engine reported since the instance
+// started, keyed by engine name.
+func (c *Client) GetStatsErrors(ctx context.Context) (map[string][]EngineError, error) {
+	var stats map[string][]EngineError
+	if err := c.getJSON(ctx, "/stats/errors", nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Probe requests path and reports the status code and how long the
+// instance took to answer. It does not treat non-200 codes as errors.
+func (c *Client) Probe(ctx context.Context, path string, values url.Values) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(path, values), nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	c.setHeaders(req, nil)
+
+	start := time.Now()
+	resp, err := c.send(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, time.Since(start), nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, values url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(path, values), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	c.setHeaders(req, nil)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newHTTPError(resp, path, nil)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,836 @@
+package searxng_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+func TestSearchEncodesParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params searxng.SearchParams
+		want   url.Values
+		absent []string
+	}{
+		{
+			name:   "query only",
+			params: searxng.SearchParams{Query: "golang"},
+			want:   url.Values{"q": {"golang"}, "format": {"json"}, "safesearch": {"0"}},
+			absent: []string{"categories", "engines", "language", "pageno", "time_range"},
+		},
+		{
+			name: "all parameters",
+			params: searxng.SearchParams{
+				Query:      "golang generics",
+				Categories: []string{"general", "it"},
+				Engines:    []string{"google", "duckduckgo"},
+				Language:   "de",
+				PageNo:     3,
+				TimeRange:  "week",
+				SafeSearch: 2,
+			},
+			want: url.Values{
+				"q":          {"golang generics"},
+				"format":     {"json"},
+				"categories": {"general,it"},
+				"engines":    {"google,duckduckgo"},
+				"language":   {"de"},
+				"pageno":     {"3"},
+				"time_range": {"week"},
+				"safesearch": {"2"},
+			},
+		},
+		{
+			name:   "out of range safe search is omitted",
+			params: searxng.SearchParams{Query: "q", SafeSearch: 5},
+			absent: []string{"safesearch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := searxngtest.NewServer()
+			defer fake.Close()
+
+			client := searxng.New(fake.URL)
+			if _, err := client.Search(context.Background(), tt.params); err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+
+			req, ok := fake.LastRequest("/search")
+			if !ok {
+				t.Fatal("no /search request received")
+			}
+			if req.Method != http.MethodGet {
+				t.Errorf("method = %s, want GET", req.Method)
+			}
+			for key, want := range tt.want {
+				if got := req.Query.Get(key); got != want[0] {
+					t.Errorf("%s = %q, want %q", key, got, want[0])
+				}
+			}
+			for _, key := range tt.absent {
+				if req.Query.Has(key) {
+					t.Errorf("%s = %q, want it absent", key, req.Query.Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestSearchPostWithPreflightCookie(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+
+	client := searxng.New(fake.URL, searxng.WithSearchMethod("post"))
+	if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "golang"}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 || requests[0].Path != "/" {
+		t.Fatalf("requests = %+v, want a preflight followed by the search", requests)
+	}
+	search := requests[1]
+	if search.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", search.Method)
+	}
+	if got := search.Form.Get("q"); got != "golang" {
+		t.Errorf("form q = %q, want golang", got)
+	}
+	if got := search.Header.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !strings.Contains(search.Header.Get("Cookie"), "session=fake") {
+		t.Errorf("Cookie = %q, want the preflight session cookie", search.Header.Get("Cookie"))
+	}
+}
+
+func TestSearchPostRetriesFailedPreflight(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.Handle("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "starting", http.StatusServiceUnavailable)
+	})
+
+	client := searxng.New(fake.URL, searxng.WithSearchMethod("post"))
+	ctx := context.Background()
+	client.Search(ctx, searxng.SearchParams{Query: "q"})
+	fake.Handle("/", func(w http.ResponseWriter, r *http.Request) {})
+	client.Search(ctx, searxng.SearchParams{Query: "q"})
+	client.Search(ctx, searxng.SearchParams{Query: "q"})
+
+	var paths []string
+	for _, req := range fake.Requests() {
+		paths = append(paths, req.Path)
+	}
+	if got, want := strings.Join(paths, " "), "/ /search / /search /search"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestWithHTTPClientCopiesClient(t *testing.T) {
+	transport := &http.Transport{}
+	httpClient := &http.Client{Transport: transport}
+	searxng.New("http://localhost",
+		searxng.WithHTTPClient(httpClient),
+		searxng.WithTimeout(time.Second),
+		searxng.WithMaxIdleConnsPerHost(64),
+		searxng.WithMaxConnsPerHost(8),
+	)
+	if httpClient.Timeout != 0 || httpClient.Jar != nil {
+		t.Errorf("caller's client = %+v, want it unchanged", httpClient)
+	}
+	if transport.MaxIdleConnsPerHost != 0 || transport.MaxIdleConns != 0 || transport.MaxConnsPerHost != 0 {
+		t.Errorf("caller's transport pool = %d idle per host, %d idle, %d per host, want it unchanged",
+			transport.MaxIdleConnsPerHost, transport.MaxIdleConns, transport.MaxConnsPerHost)
+	}
+}
+
+func TestRequestCustomization(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+
+	client := searxng.New(fake.URL+"/",
+		searxng.WithUserAgent("test-agent/2.0"),
+		searxng.WithHeaders(http.Header{"X-Api-Key": {"secret"}}),
+		searxng.WithPathPrefix("/s3cr3t/"),
+		searxng.WithQueryParams(url.Values{"token": {"abc"}}),
+	)
+	fake.Handle("/s3cr3t/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"query": "q", "results": []}`))
+	})
+
+	params := searxng.SearchParams{Query: "q", Headers: http.Header{"X-Api-Key": {"per-call"}}}
+	if _, err := client.Search(context.Background(), params); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	req, ok := fake.LastRequest("/s3cr3t/search")
+	if !ok {
+		t.Fatal("request was not sent under the path prefix")
+	}
+	if got := req.Header.Get("User-Agent"); got != "test-agent/2.0" {
+		t.Errorf("User-Agent = %q", got)
+	}
+	if got := req.Header.Get("X-Api-Key"); got != "per-call" {
+		t.Errorf("X-Api-Key = %q, want the per-call override", got)
+	}
+	if got := req.Query.Get("token"); got != "abc" {
+		t.Errorf("token = %q, want abc", got)
+	}
+}
+
+func TestPreferencesAndCookies(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+
+	client := searxng.New(fake.URL,
+		searxng.WithPreferences("https://searx.example.org/preferences?preferences=eJx1VMuO2zAM&save=1"),
+		searxng.WithCookies([]*http.Cookie{{Name: "language", Value: "de"}, {Name: "safesearch", Value: "2"}}),
+	)
+	if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	req, _ := fake.LastRequest("/search")
+	if got := req.Query.Get("preferences"); got != "eJx1VMuO2zAM" {
+		t.Errorf("preferences = %q, want the string of the URL", got)
+	}
+	cookie := req.Header.Get("Cookie")
+	if !strings.Contains(cookie, "language=de") || !strings.Contains(cookie, "safesearch=2") {
+		t.Errorf("Cookie = %q", cookie)
+	}
+}
+
+func TestSearchParsesResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErr    string
+		wantLen    int
+		wantDate   string
+		wantAnswer string
+	}{
+		{
+			name:       "complete",
+			body:       `{"query":"q","number_of_results":1,"results":[{"title":"t","url":"https://a","publishedDate":"2024-01-02"}],"answers":["42"]}`,
+			wantLen:    1,
+			wantDate:   "2024-01-02",
+			wantAnswer: "42",
+		},
+		{
+			name:       "answer objects",
+			body:       `{"query":"q","results":[{"url":"https://a"}],"answers":[{"answer":"42","url":"https://c.example","engine":"wikidata"}]}`,
+			wantLen:    1,
+			wantAnswer: "42",
+		},
+		{
+			name:    "missing fields",
+			body:    `{"results":[{"url":"https://a"}]}`,
+			wantLen: 1,
+		},
+		{
+			name:    "extra fields",
+			body:    `{"query":"q","results":[{"url":"https://a","thumbnail":"x","parsed_url":["https","a"]}],"unknown":{"nested":true}}`,
+			wantLen: 1,
+		},
+		{
+			name:    "malformed JSON",
+			body:    `{"results": [`,
+			wantErr: "error parsing JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := searxngtest.NewServer()
+			defer fake.Close()
+			fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			})
+
+			resp, err := searxng.New(fake.URL).Search(context.Background(), searxng.SearchParams{Query: "q"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(resp.Results) != tt.wantLen {
+				t.Fatalf("len(results) = %d, want %d", len(resp.Results), tt.wantLen)
+			}
+			if resp.Results[0].PublishedDate != tt.wantDate {
+				t.Errorf("publishedDate = %q, want %q", resp.Results[0].PublishedDate, tt.wantDate)
+			}
+			if tt.wantAnswer != "" && (len(resp.Answers) != 1 || resp.Answers[0].Answer != tt.wantAnswer) {
+				t.Errorf("answers = %+v, want %q", resp.Answers, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "format not allowed", http.StatusForbidden)
+	})
+	_, err := searxng.New(fake.URL).Search(context.Background(), searxng.SearchParams{Query: "q"})
+	if err == nil || !strings.Contains(err.Error(), "HTTP error 403") {
+		t.Errorf("err = %v, want HTTP error 403", err)
+	}
+
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	client := searxng.New(fake.URL, searxng.WithTimeout(20*time.Millisecond))
+	if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"}); err == nil {
+		t.Error("expected a timeout error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := searxng.New(fake.URL).Search(ctx, searxng.SearchParams{Query: "q"}); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
+
+func TestSearchImages(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{{
+			"title":         "Cat",
+			"url":           "https://example.com/cat",
+			"img_src":       "//images.example.com/cat.jpg",
+			"thumbnail_src": "https://images.example.com/cat_t.jpg",
+			"resolution":    "800 x 600",
+			"img_format":    "jpeg",
+		}},
+	})
+
+	resp, err := searxng.New(fake.URL).SearchImages(context.Background(), searxng.SearchParams{Query: "cat"})
+	if err != nil {
+		t.Fatalf("SearchImages: %v", err)
+	}
+	got := resp.Results[0]
+	if got.ImgSrc != "https://images.example.com/cat.jpg" {
+		t.Errorf("img_src = %q, want an absolute URL", got.ImgSrc)
+	}
+	if got.Resolution != "800 x 600" || got.ImgFormat != "jpeg" {
+		t.Errorf("result = %+v", got)
+	}
+}
+
+func TestSearchRaw(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	client := searxng.New(fake.URL)
+
+	resp, err := client.SearchRaw(context.Background(), searxng.SearchParams{Query: "cat", Engines: []string{"bing"}}, "csv")
+	if err != nil {
+		t.Fatalf("SearchRaw: %v", err)
+	}
+	if resp.Format != "csv" || resp.ContentType != "application/csv" || !strings.HasPrefix(string(resp.Body), "title,url,content,host,engine,score,type\n") {
+		t.Errorf("response = %s %q: %s", resp.Format, resp.ContentType, resp.Body)
+	}
+	request, _ := fake.LastRequest("/search")
+	if request.Query.Get("format") != "csv" || request.Query.Get("engines") != "bing" || request.Header.Get("Accept") != "text/csv" {
+		t.Errorf("request = %v %v", request.Query, request.Header)
+	}
+
+	if _, err := client.SearchRaw(context.Background(), searxng.SearchParams{Query: "cat"}, "html"); err == nil {
+		t.Error("SearchRaw accepted format html")
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.Handle("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<table>
+<tr><th>Engine name</th><th>Scores</th><th>Result count</th><th>Response time</th><th>Reliability</th></tr>
+<tr><td><a href="#">google</a></td><td>12.5</td><td>10</td><td>0.8 s</td><td>95 %</td></tr>
+<tr><td>bing</td><td></td><td></td><td></td><td>0 %</td></tr>
+</table>`))
+	})
+
+	stats, err := searxng.New(fake.URL).GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats = %+v, want 2 engines", stats)
+	}
+	want := searxng.EngineStat{Name: "google", ResponseTime: 0.8, Reliability: 95, ResultCount: 10, Score: 12.5}
+	if stats[0] != want {
+		t.Errorf("stats[0] = %+v, want %+v", stats[0], want)
+	}
+}
+
+func TestUnresponsiveEngines(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"query":"q","results":[],"unresponsive_engines":[["google","Suspended: CAPTCHA"],["bing","timeout"],["qwant","too many requests"]]}`))
+	})
+
+	resp, err := searxng.New(fake.URL).Search(context.Background(), searxng.SearchParams{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(resp.UnresponsiveEngines) != 3 {
+		t.Fatalf("unresponsive_engines = %+v", resp.UnresponsiveEngines)
+	}
+	for i, want := range []bool{true, false, true} {
+		if engine := resp.UnresponsiveEngines[i]; engine.Suspended() != want {
+			t.Errorf("%+v: Suspended() = %v, want %v", engine, engine.Suspended(), want)
+		}
+	}
+	if got := resp.UnresponsiveEngines[0]; got.Name != "google" || got.Reason != "Suspended: CAPTCHA" {
+		t.Errorf("unresponsive_engines[0] = %+v", got)
+	}
+}
+
+func TestMinSafeSearch(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	client := searxng.New(fake.URL, searxng.WithMinSafeSearch(1))
+
+	for requested, want := range map[int]string{0: "1", 1: "1", 2: "2", 7: "1"} {
+		if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q", SafeSearch: requested}); err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		req, _ := fake.LastRequest("/search")
+		if got := req.Query.Get("safesearch"); got != want {
+			t.Errorf("requested %d: safesearch = %q, want %q", requested, got, want)
+		}
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	for _, body := range []string{`["golang", "golang generics"]`, `["gol", ["golang", "golang generics"]]`} {
+		fake := searxngtest.NewServer()
+		fake.Handle("/autocompleter", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+
+		got, err := searxng.New(fake.URL).Autocomplete(context.Background(), "gol")
+		fake.Close()
+		if err != nil {
+			t.Fatalf("Autocomplete(%s): %v", body, err)
+		}
+		if len(got) != 2 || got[1] != "golang generics" {
+			t.Errorf("Autocomplete(%s) = %q", body, got)
+		}
+	}
+}
+
+func TestCheckSearchSchema(t *testing.T) {
+	body := []byte(`{
+		"query": "q",
+		"results": [
+			{"title": "A", "url": "https://a.example", "score": "high", "publishedDate": null, "engines": ["google"]},
+			{"title": "B", "url": "https://b.example", "rank": 2}
+		],
+		"answers": [{"answer": "42", "url": "https://c.example"}],
+		"corrections": [{"correction": "q2"}],
+		"answer_count": 1
+	}`)
+	issues, sanitized := searxng.CheckSearchSchema(body)
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.Kind+" "+issue.Path)
+	}
+	want := []string{
+		"unknown_field answer_count",
+		"type_mismatch corrections",
+		"missing_field number_of_results",
+		"unknown_field results[].rank",
+		"type_mismatch results[].score",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if strings.Contains(string(sanitized), `"corrections"`) || strings.Contains(string(sanitized), `"high"`) {
+		t.Errorf("sanitized body keeps mismatched fields: %s", sanitized)
+	}
+
+	if issues, _ := searxng.CheckSearchSchema([]byte(`{"query": "q", "number_of_results": 0, "results": []}`)); len(issues) != 0 {
+		t.Errorf("issues of a well-formed response: %v", issues)
+	}
+}
+
+func TestLenientParsing(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.SetSearchResponse(map[string]interface{}{
+		"number_of_results": 1,
+		"results":           []map[string]interface{}{{"title": "A", "url": "https://a.example"}},
+		"corrections":       []map[string]interface{}{{"correction": "q2"}},
+	})
+
+	var observed []searxng.SchemaIssue
+	strict := searxng.New(fake.URL, searxng.WithSchemaObserver(func(issues []searxng.SchemaIssue) {
+		observed = append(observed, issues...)
+	}))
+	if _, err := strict.Search(context.Background(), searxng.SearchParams{Query: "q"}); err == nil {
+		t.Error("want a decoding error without lenient parsing")
+	}
+	if len(observed) != 1 || observed[0].Path != "corrections" || observed[0].Kind != searxng.SchemaTypeMismatch {
+		t.Errorf("observed %v", observed)
+	}
+
+	lenient := searxng.New(fake.URL, searxng.WithLenientParsing(true))
+	response, err := lenient.Search(context.Background(), searxng.SearchParams{Query: "q"})
+	if err != nil {
+		t.Fatalf("lenient Search: %v", err)
+	}
+	if len(response.Results) != 1 || response.Corrections != nil {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestSearchCode(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "golang/go", "url": "https://github.com/golang/go", "engine": "github", "package_name": "go",
+				"popularity": 120000, "license_name": "BSD-3-Clause", "tags": []string{"go", "language"}, "homepage": nil},
+			{"title": "library/golang", "url": "https://hub.docker.com/_/golang", "engine": "docker hub", "popularity": "1000000000"},
+			{"title": "left-pad", "url": "https://www.npmjs.com/package/left-pad", "engine": "npm", "popularity": "n/a"},
+		},
+	})
+
+	resp, err := searxng.New(fake.URL).SearchCode(context.Background(), searxng.SearchParams{Query: "golang"})
+	if err != nil {
+		t.Fatalf("SearchCode: %v", err)
+	}
+	repo := resp.Results[0]
+	if repo.PackageName != "go" || repo.Popularity != 120000 || repo.LicenseName != "BSD-3-Clause" || len(repo.Tags) != 2 {
+		t.Errorf("repository result = %+v", repo)
+	}
+	if resp.Results[1].Popularity != 1e9 || resp.Results[2].Popularity != 0 {
+		t.Errorf("popularity = %v, %v", resp.Results[1].Popularity, resp.Results[2].Popularity)
+	}
+}
+
+func TestSearchMusic(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "Teardrop", "url": "https://soundcloud.com/massive/teardrop", "engine": "soundcloud", "length": 330.4,
+				"iframe_src": "//w.soundcloud.com/player/?url=teardrop"},
+			{"title": "Angel", "url": "https://massive.bandcamp.com/track/angel", "engine": "bandcamp", "length": "1:02:03"},
+			{"title": "Unknown", "url": "https://example.com/unknown", "engine": "mixcloud", "length": "live"},
+		},
+	})
+
+	resp, err := searxng.New(fake.URL).SearchMusic(context.Background(), searxng.SearchParams{Query: "massive attack"})
+	if err != nil {
+		t.Fatalf("SearchMusic: %v", err)
+	}
+	lengths := []time.Duration{330400 * time.Millisecond, time.Hour + 2*time.Minute + 3*time.Second, 0}
+	for i, want := range lengths {
+		if got := time.Duration(resp.Results[i].Length); got != want {
+			t.Errorf("result %d length = %v, want %v", i, got, want)
+		}
+	}
+	if resp.Results[0].IframeSrc != "https://w.soundcloud.com/player/?url=teardrop" {
+		t.Errorf("iframe_src = %q", resp.Results[0].IframeSrc)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	failing := true
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "upstream down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"query": "q", "results": []}`))
+	})
+
+	client := searxng.New(fake.URL, searxng.WithCircuitBreaker(2, 50*time.Millisecond))
+	for i := 0; i < 2; i++ {
+		if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"}); err == nil {
+			t.Fatal("want an HTTP error")
+		}
+	}
+	_, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"})
+	var openErr *searxng.CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.Failures != 2 || openErr.Instance != fake.URL {
+		t.Fatalf("err = %v, want a CircuitOpenError", err)
+	}
+	if n := len(fake.Requests()); n != 2 {
+		t.Errorf("instance received %d requests, want 2", n)
+	}
+	if state := client.Breaker.State(); state.State != searxng.CircuitOpen || !client.Breaker.Open() {
+		t.Errorf("state = %+v", state)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if state := client.Breaker.State(); state.State != searxng.CircuitHalfOpen {
+		t.Errorf("state after the cool-down = %+v", state)
+	}
+	// A canceled trial request neither closes the circuit nor keeps the
+	// next trial out.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.Search(ctx, searxng.SearchParams{Query: "q"})
+	if state := client.Breaker.State(); state.State != searxng.CircuitHalfOpen || state.ConsecutiveFailures != 2 {
+		t.Errorf("state after a canceled trial = %+v", state)
+	}
+	failing = false
+	if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"}); err != nil {
+		t.Fatalf("trial request: %v", err)
+	}
+	if state := client.Breaker.State(); state.State != searxng.CircuitClosed || state.ConsecutiveFailures != 0 {
+		t.Errorf("state after a successful trial = %+v", state)
+	}
+}
+
+func TestTransportReusesConnections(t *testing.T) {
+	var connections atomic.Int32
+	var encodings []string
+	var mu sync.Mutex
+	instance := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		encodings = append(encodings, fmt.Sprintf("HTTP/%d %s", r.ProtoMajor, r.Header.Get("Accept-Encoding")))
+		mu.Unlock()
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"query": "q", "results": [{"title": "t", "url": "https://a"}]}`))
+		gz.Close()
+	}))
+	instance.EnableHTTP2 = true
+	instance.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	instance.StartTLS()
+	defer instance.Close()
+
+	transport := searxng.NewTransport()
+	transport.TLSClientConfig = instance.Client().Transport.(*http.Transport).TLSClientConfig
+	client := searxng.New(instance.URL, searxng.WithHTTPClient(&http.Client{Transport: transport}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"}); err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"})
+			if err != nil || len(resp.Results) != 1 {
+				t.Errorf("concurrent Search = %+v, %v", resp, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := connections.Load(); n != 1 {
+		t.Errorf("%d connections for 13 searches, want 1", n)
+	}
+	if s := client.PoolStats(); s.Requests != 13 || s.InFlight != 0 || s.NewConns != 1 || s.ReusedConns != 12 {
+		t.Errorf("pool stats = %+v, want 13 requests on 1 new connection", s)
+	}
+	for _, encoding := range encodings {
+		if encoding != "HTTP/2 gzip" {
+			t.Errorf("request = %q, want HTTP/2 with gzip", encoding)
+		}
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	var active, peak atomic.Int32
+	instance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		w.Write([]byte(`{"query": "q", "results": []}`))
+	}))
+	defer instance.Close()
+	client := searxng.New(instance.URL, searxng.WithMaxConcurrent(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Search(context.Background(), searxng.SearchParams{Query: "q"}); err != nil {
+				t.Errorf("Search: %v", err)
+			}
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for client.PoolStats().Waiting != 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := client.PoolStats(); s.InFlight != 2 || s.Waiting != 3 || s.MaxConcurrent != 2 {
+		t.Errorf("pool stats = %+v, want 2 in flight and 3 waiting", s)
+	}
+
+	// A waiting request gives up with its context.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Search(ctx, searxng.SearchParams{Query: "q"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Search with expired context = %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if n := peak.Load(); n > 2 {
+		t.Errorf("%d concurrent requests, want at most 2", n)
+	}
+	if s := client.PoolStats(); s.InFlight != 0 || s.Waiting != 0 || s.Requests != 5 {
+		t.Errorf("pool stats after = %+v", s)
+	}
+}
+
+func TestDefaultTransport(t *testing.T) {
+	client := searxng.New("http://127.0.0.1:8080", searxng.WithMaxIdleConnsPerHost(64), searxng.WithMaxConnsPerHost(8))
+	transport, ok := client.HTTPClient.Transport.(*http.Transport)
+	if !ok || transport.MaxIdleConnsPerHost != 64 || transport.MaxConnsPerHost != 8 || !transport.ForceAttemptHTTP2 || transport.DisableCompression {
+		t.Errorf("transport = %+v", client.HTTPClient.Transport)
+	}
+	if defaults := searxng.NewTransport(); defaults.MaxIdleConnsPerHost != searxng.DefaultMaxIdleConnsPerHost {
+		t.Errorf("default MaxIdleConnsPerHost = %d", defaults.MaxIdleConnsPerHost)
+	}
+}
+
+func TestHTMLFallback(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.DisableJSON()
+
+	if _, err := searxng.New(fake.URL).Search(context.Background(), searxng.SearchParams{Query: "q"}); err == nil || !strings.Contains(err.Error(), "HTTP error 403") {
+		t.Errorf("err = %v, want HTTP error 403 without the fallback", err)
+	}
+
+	client := searxng.New(fake.URL, searxng.WithHTMLFallback(true))
+	for i := 0; i < 2; i++ {
+		resp, err := client.Search(context.Background(), searxng.SearchParams{Query: "golang", Language: "en"})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if resp.Query != "golang" || len(resp.Results) != 2 {
+			t.Fatalf("response = %+v, want the 2 default results", resp)
+		}
+		want := searxng.SearchResult{Title: "First result", URL: "https://example.com/first", Content: "First result content", Engine: "duckduckgo", Category: "general", Source: searxng.SourceHTMLFallback}
+		if !reflect.DeepEqual(resp.Results[0], want) {
+			t.Errorf("result = %+v, want %+v", resp.Results[0], want)
+		}
+	}
+	if !client.JSONForbidden() {
+		t.Error("JSONForbidden = false after a refused JSON search")
+	}
+
+	var jsonSearches int
+	for _, req := range fake.Requests() {
+		if req.Path == "/search" && req.Query.Get("format") == "json" {
+			jsonSearches++
+		}
+	}
+	// One by the client without fallback, one by the first fallback search.
+	if jsonSearches != 2 {
+		t.Errorf("JSON searches = %d, want 2: the second search goes straight to HTML", jsonSearches)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("language") != "en" || req.Header.Get("Accept") != "text/html" {
+		t.Errorf("HTML search = %+v, want the language and an HTML Accept header", req)
+	}
+}
+
+func TestHTMLFallbackParsesThemes(t *testing.T) {
+	fake := searxngtest.NewServer()
+	defer fake.Close()
+	fake.Handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Form.Get("format") == "json" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`<html><body>
+<div id="answers"><h4 class="title">Answers : </h4><div class="answer"><span>42</span><a href="https://example.com/answer" class="answer-url">example.com</a></div></div>
+<div id="corrections"><h4>Try searching for:</h4><form><input type="hidden" name="q" value="golang generics"><input type="submit" value="golang generics"></form></div>
+<div id="result_count"><small>Number of results: 1,230</small></div>
+<article class="result result-default category-news">
+  <a href="https://go.dev/blog/generics" class="url_header"><div class="url_wrapper">go.dev › blog</div></a>
+  <h3><a href="https://go.dev/blog/generics">An Introduction <span class="highlight">To Generics</span></a></h3>
+  <time class="published_date" datetime="2022-03-22 00:00:00">Mar 22, 2022</time>
+  <p class="content">Generics are a way of writing code.</p>
+  <div class="engines"><span>bing news</span><span>google news</span><a href="#" class="cache_link">cached</a></div>
+</article>
+<div class="result result-default">
+  <h4 class="result_header"><a href="https://example.org/oscar">Oscar theme</a></h4>
+  <p class="result-content">Older markup</p>
+  <div class="pull-right"><span class="label label-default">duckduckgo</span></div>
+</div>
+<article class="result"><p class="content">no link</p></article>
+<div id="sidebar"><div id="suggestions"><form><input type="hidden" name="q" value="go generics tutorial"><input type="submit" class="suggestion" value="• go generics tutorial"></form></div></div>
+</body></html>`))
+	})
+
+	resp, err := searxng.New(fake.URL, searxng.WithHTMLFallback(true)).Search(context.Background(), searxng.SearchParams{Query: "golang generic"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := []searxng.SearchResult{
+		{Title: "An Introduction To Generics", URL: "https://go.dev/blog/generics", Content: "Generics are a way of writing code.", Engine: "bing news", Category: "news", PublishedDate: "2022-03-22 00:00:00", Source: searxng.SourceHTMLFallback},
+		{Title: "Oscar theme", URL: "https://example.org/oscar", Content: "Older markup", Engine: "duckduckgo", Source: searxng.SourceHTMLFallback},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", resp.Results, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(resp.Results[i], want[i]) {
+			t.Errorf("result %d = %+v, want %+v", i, resp.Results[i], want[i])
+		}
+	}
+	if resp.NumberOfResults != 1230 {
+		t.Errorf("number of results = %d, want 1230", resp.NumberOfResults)
+	}
+	if len(resp.Answers) != 1 || !reflect.DeepEqual(resp.Answers[0], searxng.Answer{Answer: "42", URL: "https://example.com/answer"}) {
+		t.Errorf("answers = %+v", resp.Answers)
+	}
+	if fmt.Sprint(resp.Corrections) != "[golang generics]" || fmt.Sprint(resp.Suggestions) != "[go generics tutorial]" {
+		t.Errorf("corrections = %q, suggestions = %q", resp.Corrections, resp.Suggestions)
+	}
+}
@@ -0,0 +1,332 @@
+package searxng
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the version an instance reports on /config. SearXNG numbers
+// its releases by date, e.g. "2024.10.3+fa0f4ef98"; searx, which it was
+// forked from, and the first SearXNG releases use semantic versions such
+// as "1.0.0".
+type Version struct {
+	Raw   string `json:"raw"`
+	Major int    `json:"major"`
+	Minor int    `json:"minor"`
+	Patch int    `json:"patch"`
+	// Commit is the git commit after "+" or "-", if any.
+	Commit string `json:"commit,omitempty"`
+}
+
+// ParseVersion reads a version string. Unreadable parts are left zero.
+func ParseVersion(s string) Version {
+	v := Version{Raw: strings.TrimSpace(s)}
+	numbers := strings.TrimPrefix(v.Raw, "v")
+	if i := strings.IndexAny(numbers, "+-"); i >= 0 {
+		numbers, v.Commit = numbers[:i], numbers[i+1:]
+	}
+	parts := strings.SplitN(numbers, ".", 3)
+	for i, target := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if i < len(parts) {
+			*target, _ = strconv.Atoi(parts[i])
+		}
+	}
+	return v
+}
+
+// Known reports whether the instance reported a readable version.
+func (v Version) Known() bool {
+	return v.Major > 0
+}
+
+// Legacy reports a searx or early SearXNG release, from before the date
+// versions. Their responses differ the most from the current ones.
+func (v Version) Legacy() bool {
+	return v.Known() && v.Major < 2000
+}
+
+// AtLeast reports whether v is major.minor.patch or later.
+func (v Version) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+func (v Version) String() string {
+	if v.Raw == "" {
+		return "unknown"
+	}
+	return v.Raw
+}
+
+// InstanceConfig is the /config document of an instance, read tolerantly:
+// its shape varies between versions and forks.
+type InstanceConfig struct {
+	Version      Version        `json:"version"`
+	InstanceName string         `json:"instance_name,omitempty"`
+	Categories   []string       `json:"categories,omitempty"`
+	Engines      []EngineConfig `json:"engines"`
+	Plugins      []PluginConfig `json:"plugins,omitempty"`
+}
+
+// EngineConfig is an engine listed on /config.
+type EngineConfig struct {
+	Name       string   `json:"name"`
+	Shortcut   string   `json:"shortcut,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Enabled    bool     `json:"enabled"`
+	Paging     bool     `json:"paging,omitempty"`
+	TimeRange  bool     `json:"time_range_support,omitempty"`
+	SafeSearch bool     `json:"safesearch,omitempty"`
+	// Languages are the languages the engine supports: "languages" on
+	// recent versions, "supported_languages" on legacy ones.
+	Languages []string `json:"languages,omitempty"`
+}
+
+// PluginConfig is a plugin listed on /config.
+type PluginConfig struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ParseInstanceConfig reads a raw /config document. Engines come as a list
+// of objects or, on some forks, as an object keyed by name; enabled may be
+// missing, or given as its opposite "disabled"; categories may be a single
+// string.
+func ParseInstanceConfig(raw map[string]interface{}) *InstanceConfig {
+	config := &InstanceConfig{
+		Categories: stringList(raw["categories"]),
+	}
+	config.Version = ParseVersion(stringValue(raw["version"]))
+	config.InstanceName = stringValue(raw["instance_name"])
+
+	switch engines := raw["engines"].(type) {
+	case []interface{}:
+		for _, e := range engines {
+			if engine, ok := e.(map[string]interface{}); ok {
+				if parsed, ok := parseEngineConfig(stringValue(engine["name"]), engine); ok {
+					config.Engines = append(config.Engines, parsed)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for name, e := range engines {
+			engine, _ := e.(map[string]interface{})
+			if parsed, ok := parseEngineConfig(name, engine); ok {
+				config.Engines = append(config.Engines, parsed)
+			}
+		}
+		sort.Slice(config.Engines, func(i, j int) bool { return config.Engines[i].Name < config.Engines[j].Name })
+	}
+
+	if plugins, ok := raw["plugins"].([]interface{}); ok {
+		for _, p := range plugins {
+			switch plugin := p.(type) {
+			case string:
+				config.Plugins = append(config.Plugins, PluginConfig{Name: plugin, Enabled: true})
+			case map[string]interface{}:
+				if name := stringValue(plugin["name"]); name != "" {
+					config.Plugins = append(config.Plugins, PluginConfig{Name: name, Enabled: enabledValue(plugin)})
+				}
+			}
+		}
+	}
+	return config
+}
+
+func parseEngineConfig(name string, engine map[string]interface{}) (EngineConfig, bool) {
+	if name == "" {
+		return EngineConfig{}, false
+	}
+	parsed := EngineConfig{
+		Name:       name,
+		Shortcut:   stringValue(engine["shortcut"]),
+		Categories: stringList(engine["categories"]),
+		Enabled:    enabledValue(engine),
+		Paging:     engine["paging"] == true,
+		TimeRange:  engine["time_range_support"] == true,
+		SafeSearch: engine["safesearch"] == true,
+		Languages:  stringList(engine["languages"]),
+	}
+	if len(parsed.Languages) == 0 {
+		parsed.Languages = stringList(engine["supported_languages"])
+	}
+	if len(parsed.Categories) == 0 {
+		parsed.Categories = stringList(engine["category"])
+	}
+	return parsed, true
+}
+
+// Engine returns the engine named name, ignoring case.
+func (c *InstanceConfig) Engine(name string) (EngineConfig, bool) {
+	for _, engine := range c.Engines {
+		if strings.EqualFold(engine.Name, name) {
+			return engine, true
+		}
+	}
+	return EngineConfig{}, false
+}
+
+// enabledValue reads "enabled", or "disabled" where only that is set.
+// Listed engines without either are enabled.
+func enabledValue(m map[string]interface{}) bool {
+	if enabled, ok := m["enabled"].(bool); ok {
+		return enabled
+	}
+	if disabled, ok := m["disabled"].(bool); ok {
+		return !disabled
+	}
+	return true
+}
+
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
+
+// stringList reads a list of strings, a single string, or the keys of an
+// object, as legacy instances list supported languages.
+func stringList(v interface{}) []string {
+	var list []string
+	switch values := v.(type) {
+	case string:
+		if values != "" {
+			list = []string{values}
+		}
+	case []interface{}:
+		for _, value := range values {
+			if s := stringValue(value); s != "" {
+				list = append(list, s)
+			}
+		}
+	case map[string]interface{}:
+		for key := range values {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+	}
+	return list
+}
+
+// GetInstanceConfig returns the /config document of the instance, parsed
+// tolerantly, and remembers the version it reports.
+func (c *Client) GetInstanceConfig(ctx context.Context) (*InstanceConfig, error) {
+	raw, err := c.GetEngines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := ParseInstanceConfig(raw)
+	c.version.Store(&config.Version)
+	return config, nil
+}
+
+// Version returns the version the instance reported on /config, detected
+// by the first GetInstanceConfig. ok is false before that.
+func (c *Client) Version() (v Version, ok bool) {
+	if stored := c.version.Load(); stored != nil {
+		return *stored, true
+	}
+	return Version{}, false
+}
+
+// DetectVersion returns the version of the instance, requesting /config
+// only when it is not known yet.
+func (c *Client) DetectVersion(ctx context.Context) (Version, error) {
+	if v, ok := c.Version(); ok {
+		return v, nil
+	}
+	config, err := c.GetInstanceConfig(ctx)
+	if err != nil {
+		return Version{}, fmt.Errorf("detecting version: %w", err)
+	}
+	return config.Version, nil
+}
+
+// normalizeSearchBody rewrites the /search responses of other SearXNG
+// versions into the shape this package decodes:
+//
+//   - number_of_results missing, a float (legacy searx) or a string
+//     becomes an integer;
+//   - the pubdate of legacy results becomes publishedDate.
+//
+// Other differences (answers as strings, unresponsive engines as names)
+// are handled by the decoders of the types. The body is returned as is
+// when nothing needs rewriting or it is not a JSON object.
+func normalizeSearchBody(body []byte) []byte {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return body
+	}
+	changed := false
+
+	if count, ok := resultCount(response["number_of_results"]); !ok {
+		response["number_of_results"] = []byte(strconv.Itoa(count))
+		changed = true
+	}
+
+	var results []map[string]json.RawMessage
+	if raw, ok := response["results"]; ok && json.Unmarshal(raw, &results) == nil {
+		resultsChanged := false
+		for _, result := range results {
+			if pubdate, ok := result["pubdate"]; ok && !hasValue(result["publishedDate"]) {
+				result["publishedDate"] = pubdate
+				resultsChanged = true
+			}
+		}
+		if resultsChanged {
+			response["results"], _ = json.Marshal(results)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+	normalized, err := json.Marshal(response)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// resultCount reads number_of_results. ok is false when the raw value is
+// not already an integer and must be replaced by count.
+func resultCount(raw json.RawMessage) (count int, ok bool) {
+	if len(raw) == 0 {
+		return 0, false
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		return int(math.Round(f)), f == math.Trunc(f) && !bytes.ContainsAny(raw, ".eE")
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s)
+		count, _ = strconv.Atoi(digits)
+	}
+	return count, false
+}
+
+func hasValue(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null" && string(raw) != `""`
+}
@@ -0,0 +1,153 @@
+package searxng_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+// serveFixtures makes fake answer /config and /search with the responses
+// captured from a release, in testdata/<release>.
+func serveFixtures(t *testing.T, fake *searxngtest.Server, release string) {
+	t.Helper()
+	for path, file := range map[string]string{"/config": "config.json", "/search": "search.json"} {
+		body, err := os.ReadFile(filepath.Join("testdata", release, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fake.Handle(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		})
+	}
+}
+
+func TestReleaseCompatibility(t *testing.T) {
+	tests := []struct {
+		release        string
+		version        searxng.Version
+		legacy         bool
+		results        int
+		resultCount    int
+		publishedDate  string
+		unresponsive   searxng.UnresponsiveEngine
+		enabledEngines []string
+		languages      []string
+	}{
+		{
+			release:        "searx-1.0.0",
+			version:        searxng.Version{Raw: "1.0.0", Major: 1},
+			legacy:         true,
+			results:        2,
+			resultCount:    12300000,
+			publishedDate:  "2021-02-16 00:00:00",
+			unresponsive:   searxng.UnresponsiveEngine{Name: "wikidata"},
+			enabledEngines: []string{"duckduckgo", "github"},
+			languages:      []string{"de-DE", "en-US"},
+		},
+		{
+			release:        "searxng-2024.10.3",
+			version:        searxng.Version{Raw: "2024.10.3+fa0f4ef98", Major: 2024, Minor: 10, Patch: 3, Commit: "fa0f4ef98"},
+			results:        2,
+			publishedDate:  "2024-08-13T00:00:00",
+			unresponsive:   searxng.UnresponsiveEngine{Name: "brave", Reason: "Suspended: too many requests"},
+			enabledEngines: []string{"duckduckgo", "google"},
+			languages:      []string{"af", "ar", "de", "en", "fr"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.release, func(t *testing.T) {
+			fake := searxngtest.NewServer()
+			defer fake.Close()
+			serveFixtures(t, fake, tt.release)
+
+			var issues []searxng.SchemaIssue
+			client := searxng.New(fake.URL, searxng.WithSchemaObserver(func(found []searxng.SchemaIssue) { issues = found }))
+			if _, ok := client.Version(); ok {
+				t.Error("version known before detection")
+			}
+			version, err := client.DetectVersion(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != tt.version || version.Legacy() != tt.legacy {
+				t.Errorf("version = %+v (legacy %v), want %+v (legacy %v)", version, version.Legacy(), tt.version, tt.legacy)
+			}
+
+			config, err := client.GetInstanceConfig(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var enabled []string
+			for _, engine := range config.Engines {
+				if engine.Enabled {
+					enabled = append(enabled, engine.Name)
+				}
+			}
+			if !reflect.DeepEqual(enabled, tt.enabledEngines) {
+				t.Errorf("enabled engines = %v, want %v", enabled, tt.enabledEngines)
+			}
+			if engine, _ := config.Engine("DuckDuckGo"); !reflect.DeepEqual(engine.Languages, tt.languages) {
+				t.Errorf("duckduckgo languages = %v, want %v", engine.Languages, tt.languages)
+			}
+
+			resp, err := client.Search(context.Background(), searxng.SearchParams{Query: "golang"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != tt.results || resp.NumberOfResults != tt.resultCount {
+				t.Fatalf("%d results, number_of_results %d, want %d and %d", len(resp.Results), resp.NumberOfResults, tt.results, tt.resultCount)
+			}
+			if resp.Results[1].PublishedDate != tt.publishedDate {
+				t.Errorf("publishedDate = %q, want %q", resp.Results[1].PublishedDate, tt.publishedDate)
+			}
+			if len(resp.Answers) != 1 || resp.Answers[0].Answer == "" {
+				t.Errorf("answers = %+v, want one", resp.Answers)
+			}
+			if len(resp.UnresponsiveEngines) != 1 || resp.UnresponsiveEngines[0] != tt.unresponsive {
+				t.Errorf("unresponsive engines = %+v, want %+v", resp.UnresponsiveEngines, tt.unresponsive)
+			}
+			for _, issue := range issues {
+				if issue.Kind != searxng.SchemaUnknownField {
+					t.Errorf("schema issue %s left after normalization", issue)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInstanceConfigShapes(t *testing.T) {
+	config := searxng.ParseInstanceConfig(map[string]interface{}{
+		"engines": map[string]interface{}{
+			"wikipedia": map[string]interface{}{"category": "general", "disabled": true},
+			"arxiv":     map[string]interface{}{"categories": []interface{}{"science"}},
+		},
+		"plugins": []interface{}{"Hash plugin"},
+	})
+	want := []searxng.EngineConfig{
+		{Name: "arxiv", Categories: []string{"science"}, Enabled: true},
+		{Name: "wikipedia", Categories: []string{"general"}},
+	}
+	if !reflect.DeepEqual(config.Engines, want) {
+		t.Errorf("engines = %+v, want %+v", config.Engines, want)
+	}
+	if len(config.Plugins) != 1 || !config.Plugins[0].Enabled {
+		t.Errorf("plugins = %+v", config.Plugins)
+	}
+	if config.Version.Known() {
+		t.Errorf("version %v known without a version field", config.Version)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	v := searxng.ParseVersion("2023.9.20-b2ffe8e8")
+	if !v.AtLeast(2023, 9, 1) || v.AtLeast(2024, 1, 1) || v.Commit != "b2ffe8e8" {
+		t.Errorf("version %+v", v)
+	}
+}
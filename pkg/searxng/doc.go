@@ -0,0 +1,11 @@
+// Package searxng is a client for the JSON API of a SearXNG metasearch
+// instance.
+//
+//	client := searxng.New("http://127.0.0.1:8080",
+//		searxng.WithUserAgent("my-tool/1.0"),
+//	)
+//	resp, err := client.Search(ctx, searxng.SearchParams{
+//		Query:      "golang generics",
+//		Categories: []string{"it"},
+//	})
+package searxng
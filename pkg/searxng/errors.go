@@ -0,0 +1,59 @@
+package searxng
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxErrorBody limits how much of an error response is kept in HTTPError.
+const maxErrorBody = 512
+
+// HTTPError is returned when the instance answers with a status other than
+// 200 OK.
+type HTTPError struct {
+	StatusCode int
+	// Endpoint is the requested path, e.g. "/search".
+	Endpoint string
+	// Body is the beginning of the response body.
+	Body string
+	// RetryAfter is the delay requested by a Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("HTTP error %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("HTTP error %d", e.StatusCode)
+}
+
+func newHTTPError(resp *http.Response, endpoint string, body []byte) *HTTPError {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Endpoint:   endpoint,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter reads both forms of the header: delay seconds and an
+// HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d.Round(time.Second)
+		}
+	}
+	return 0
+}
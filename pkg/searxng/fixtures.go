@@ -0,0 +1,211 @@
+package searxng
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Fixture is a request to an instance and its response, recorded by
+// WithFixtureRecorder and served back by searxngtest.Server.ServeFixtures,
+// to test against the responses of a real instance without the network.
+type Fixture struct {
+	Method string `json:"method"`
+	// Path is relative to the instance URL and its path prefix.
+	Path string `json:"path"`
+	// Params are the FixtureParams of the query string and form body.
+	Params      url.Values `json:"params,omitempty"`
+	Status      int        `json:"status"`
+	ContentType string     `json:"content_type,omitempty"`
+	// Body holds JSON responses, Text the others.
+	Body json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+// FixtureParams are the request parameters fixtures keep. The others,
+// such as -query-param tokens and saved preferences, are not recorded.
+var FixtureParams = []string{"q", "format", "categories", "engines", "language", "pageno", "time_range", "safesearch"}
+
+// FixtureName returns the file name of the fixture of a request to path
+// with params: the same request always gets the same file, so recording
+// it again replaces the fixture.
+func FixtureName(path string, params url.Values) string {
+	kept := url.Values{}
+	for _, name := range FixtureParams {
+		if values, ok := params[name]; ok {
+			kept[name] = values
+		}
+	}
+	sum := sha256.Sum256([]byte(path + "?" + kept.Encode()))
+	name := strings.ReplaceAll(strings.Trim(path, "/"), "/", "_")
+	if name == "" {
+		name = "index"
+	}
+	return name + "-" + hex.EncodeToString(sum[:6]) + ".json"
+}
+
+// WithFixtureRecorder saves every request to the instance and its
+// response as a Fixture in dir. anonymize rewrites the query text, in the
+// request and in the response (its query, corrections and suggestions);
+// nil keeps it. Headers, cookies and the instance URL are never recorded.
+// Errors saving a fixture go to onError, when set: recording never fails a
+// request.
+func WithFixtureRecorder(dir string, anonymize func(string) string, onError func(error)) Option {
+	return func(c *Client) {
+		WithRoundTripper(func(base http.RoundTripper) http.RoundTripper {
+			return &fixtureRecorder{base: base, client: c, dir: dir, anonymize: anonymize, onError: onError}
+		})(c)
+	}
+}
+
+type fixtureRecorder struct {
+	base      http.RoundTripper
+	client    *Client
+	dir       string
+	anonymize func(string) string
+	onError   func(error)
+}
+
+func (r *fixtureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	params := requestParams(req)
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(r.client.BaseURL)
+	if err != nil || req.URL.Host != base.Host {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, nil
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(base.Path, "/")+r.client.PathPrefix)
+	if path == "" {
+		path = "/"
+	}
+	if err := r.save(req.Method, path, params, resp, body); err != nil && r.onError != nil {
+		r.onError(fmt.Errorf("error recording fixture of %s: %w", path, err))
+	}
+	return resp, nil
+}
+
+// save writes the fixture of a request, anonymized.
+func (r *fixtureRecorder) save(method, path string, params url.Values, resp *http.Response, body []byte) error {
+	kept := url.Values{}
+	for _, name := range FixtureParams {
+		if values, ok := params[name]; ok {
+			kept[name] = slices.Clone(values)
+		}
+	}
+	query := kept.Get("q")
+	if r.anonymize != nil && query != "" {
+		kept.Set("q", r.anonymize(query))
+	}
+	fixture := Fixture{
+		Method:      method,
+		Path:        path,
+		Params:      kept,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if mediaType, _, _ := mime.ParseMediaType(fixture.ContentType); mediaType == "application/json" && json.Valid(body) {
+		fixture.Body = r.anonymizeJSON(body)
+	} else {
+		text := string(body)
+		if anonymized := kept.Get("q"); anonymized != query {
+			text = strings.ReplaceAll(text, query, anonymized)
+		}
+		fixture.Text = text
+	}
+	if len(kept) == 0 {
+		fixture.Params = nil
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+	file := filepath.Join(r.dir, FixtureName(path, kept))
+	tmp, err := os.CreateTemp(r.dir, "."+filepath.Base(file)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// anonymizeJSON rewrites the query, corrections and suggestions of a JSON
+// object response.
+func (r *fixtureRecorder) anonymizeJSON(body []byte) json.RawMessage {
+	var object map[string]interface{}
+	if r.anonymize == nil || json.Unmarshal(body, &object) != nil {
+		return body
+	}
+	if query, ok := object["query"].(string); ok {
+		object["query"] = r.anonymize(query)
+	}
+	for _, name := range []string{"corrections", "suggestions"} {
+		values, _ := object[name].([]interface{})
+		for i, value := range values {
+			if s, ok := value.(string); ok {
+				values[i] = r.anonymize(s)
+			}
+		}
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// requestParams returns the parameters of the query string and form body
+// of req, reading the body from a copy.
+func requestParams(req *http.Request) url.Values {
+	params := req.URL.Query()
+	if req.GetBody == nil {
+		return params
+	}
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "application/x-www-form-urlencoded" {
+		return params
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return params
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return params
+	}
+	if form, err := url.ParseQuery(string(data)); err == nil {
+		for name, values := range form {
+			params[name] = append(params[name], values...)
+		}
+	}
+	return params
+}
@@ -0,0 +1,76 @@
+package searxng_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"go_mcp_server_searxng/pkg/searxng/searxngtest"
+)
+
+func TestFixtureRecordAndReplay(t *testing.T) {
+	live := searxngtest.NewServer()
+	defer live.Close()
+	live.SetSearchResponse(map[string]interface{}{
+		"results":     searxngtest.DefaultResults,
+		"suggestions": []interface{}{"mail bob@example.com archive"},
+	})
+
+	dir := t.TempDir()
+	email := regexp.MustCompile(`\S+@\S+`)
+	anonymize := func(s string) string { return email.ReplaceAllString(s, "[redacted]") }
+	var recordErr error
+	recorder := searxng.New(live.URL+"/secret",
+		searxng.WithPathPrefix("/searx"),
+		searxng.WithQueryParams(url.Values{"token": {"s3cret"}}),
+		searxng.WithHeaders(http.Header{"Authorization": {"Bearer s3cret"}}),
+		searxng.WithFixtureRecorder(dir, anonymize, func(err error) { recordErr = err }),
+	)
+	live.Handle("/secret/searx/search", func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/search"
+		live.Config.Handler.ServeHTTP(w, r)
+	})
+	ctx := context.Background()
+	want, err := recorder.Search(ctx, searxng.SearchParams{Query: "mail bob@example.com", Engines: []string{"google"}})
+	if err != nil || recordErr != nil {
+		t.Fatalf("search: %v, recording: %v", err, recordErr)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 || !strings.HasPrefix(filepath.Base(files[0]), "search-") {
+		t.Fatalf("fixtures = %q", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"bob@example.com", "s3cret", "secret/", "127.0.0.1"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("fixture leaks %q:\n%s", secret, data)
+		}
+	}
+
+	replay := searxngtest.NewServer()
+	defer replay.Close()
+	if err := replay.ServeFixtures(dir); err != nil {
+		t.Fatal(err)
+	}
+	client := searxng.New(replay.URL)
+	got, err := client.Search(ctx, searxng.SearchParams{Query: "mail [redacted]", Engines: []string{"google"}})
+	if err != nil {
+		t.Fatalf("replayed search: %v", err)
+	}
+	if !reflect.DeepEqual(got.Results, want.Results) || got.Query != "mail [redacted]" || !reflect.DeepEqual(got.Suggestions, []string{"mail [redacted] archive"}) {
+		t.Errorf("replayed = %+v, recorded %+v", got, want)
+	}
+	if _, err := client.Search(ctx, searxng.SearchParams{Query: "other"}); err == nil {
+		t.Error("search without a fixture succeeded")
+	}
+}
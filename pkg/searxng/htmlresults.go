@@ -0,0 +1,249 @@
+package searxng
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// JSONRecheckInterval is how long Search goes straight to the HTML results
+// page after the instance refused the JSON format, before trying JSON
+// again in case the instance enabled it.
+const JSONRecheckInterval = time.Hour
+
+// jsonForbidden reports whether the instance refused the JSON format less
+// than JSONRecheckInterval ago.
+func (c *Client) jsonForbidden() bool {
+	at := c.jsonForbiddenAt.Load()
+	return at != 0 && time.Since(time.Unix(0, at)) < JSONRecheckInterval
+}
+
+// JSONForbidden reports whether searches currently use the HTML results
+// page because the instance refuses the JSON format.
+func (c *Client) JSONForbidden() bool {
+	return c.HTMLFallback && c.jsonForbidden()
+}
+
+// searchHTML runs a search through the HTML results page. The page has no
+// scores and no engine errors; results are marked SourceHTMLFallback.
+func (c *Client) searchHTML(ctx context.Context, params SearchParams) (*SearchResponse, error) {
+	req, err := c.newSearchRequest(ctx, params, "")
+	if err != nil {
+		return nil, err
+	}
+	if req.Method == http.MethodPost {
+		c.preflight(ctx)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, newHTTPError(resp, "/search", body)
+	}
+
+	doc, err := html.Parse(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %w", err)
+	}
+
+	response := parseResultsPage(doc)
+	response.Query = params.Query
+	return response, nil
+}
+
+// parseResultsPage reads the results page of the simple theme and of the
+// older oscar theme. It goes by class names rather than exact structure:
+// results are elements of class "result" with the title link in a heading,
+// the snippet in a paragraph of class "content" (or "result-content") and
+// the engines in spans of an element of class "engines".
+func parseResultsPage(doc *html.Node) *SearchResponse {
+	response := &SearchResponse{Results: []SearchResult{}}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case hasClass(n, "result"):
+				if result, ok := parseResult(n); ok {
+					response.Results = append(response.Results, result)
+				}
+				return
+			case attr(n, "id") == "answers":
+				for _, answer := range findAll(n, func(n *html.Node) bool { return hasClass(n, "answer") }) {
+					text := answer
+					if span := find(answer, isElement("span")); span != nil {
+						text = span
+					}
+					a := Answer{Answer: collapse(nodeText(text))}
+					if link := find(answer, isElement("a")); link != nil {
+						a.URL = attr(link, "href")
+					}
+					if a.Answer != "" {
+						response.Answers = append(response.Answers, a)
+					}
+				}
+				return
+			case attr(n, "id") == "suggestions":
+				response.Suggestions = append(response.Suggestions, queryLinks(n)...)
+				return
+			case attr(n, "id") == "corrections":
+				response.Corrections = append(response.Corrections, queryLinks(n)...)
+				return
+			case attr(n, "id") == "result_count":
+				digits := strings.Map(func(r rune) rune {
+					if r >= '0' && r <= '9' {
+						return r
+					}
+					return -1
+				}, nodeText(n))
+				response.NumberOfResults, _ = strconv.Atoi(digits)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if response.NumberOfResults == 0 {
+		response.NumberOfResults = len(response.Results)
+	}
+	return response
+}
+
+// parseResult reads one result element; it needs at least a link.
+func parseResult(n *html.Node) (SearchResult, bool) {
+	result := SearchResult{Source: SourceHTMLFallback}
+
+	link := find(n, func(n *html.Node) bool {
+		return (n.Data == "h3" || n.Data == "h4" || n.Data == "h2") && find(n, isLink) != nil
+	})
+	if link != nil {
+		link = find(link, isLink)
+	} else {
+		link = find(n, isLink)
+	}
+	if link == nil {
+		return result, false
+	}
+	result.URL = attr(link, "href")
+	result.Title = collapse(nodeText(link))
+
+	if content := find(n, func(n *html.Node) bool {
+		return hasClass(n, "content") || hasClass(n, "result-content")
+	}); content != nil {
+		result.Content = collapse(nodeText(content))
+	}
+
+	var engines []*html.Node
+	if container := find(n, func(n *html.Node) bool { return hasClass(n, "engines") }); container != nil {
+		engines = findAll(container, isElement("span"))
+	} else {
+		engines = findAll(n, func(n *html.Node) bool { return n.Data == "span" && hasClass(n, "label-default") })
+	}
+	for _, engine := range engines {
+		if name := collapse(nodeText(engine)); name != "" {
+			result.Engine = name
+			break
+		}
+	}
+
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if category, ok := strings.CutPrefix(class, "category-"); ok {
+			result.Category = category
+		}
+	}
+	if published := find(n, isElement("time")); published != nil {
+		result.PublishedDate = attr(published, "datetime")
+	}
+	return result, true
+}
+
+// queryLinks returns the queries a suggestions or corrections block links
+// to: the q fields of its forms, or else the text of its links.
+func queryLinks(n *html.Node) []string {
+	var queries []string
+	for _, input := range findAll(n, isElement("input")) {
+		if attr(input, "name") == "q" && attr(input, "value") != "" {
+			queries = append(queries, attr(input, "value"))
+		}
+	}
+	if len(queries) > 0 {
+		return queries
+	}
+	for _, link := range findAll(n, isElement("a")) {
+		if text := strings.TrimSpace(strings.TrimPrefix(collapse(nodeText(link)), "•")); text != "" {
+			queries = append(queries, text)
+		}
+	}
+	return queries
+}
+
+func isLink(n *html.Node) bool {
+	if n.Data != "a" {
+		return false
+	}
+	href := attr(n, "href")
+	return strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")
+}
+
+func isElement(name string) func(*html.Node) bool {
+	return func(n *html.Node) bool { return n.Data == name }
+}
+
+// find returns the first element below n matching match, depth first.
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := find(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns the elements below n matching match, not looking inside
+// the matches.
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var found []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, findAll(c, match)...)
+	}
+	return found
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package searxng
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the HTTP client used for all requests. The
+// client is copied: options such as WithTimeout, and the cookie jar
+// installed when it has none, do not change the caller's client. Its
+// transport is used as is; the connection pool options leave it alone.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			copied := *httpClient
+			c.HTTPClient = &copied
+		}
+	}
+}
+
+// WithMaxIdleConnsPerHost sets how many idle connections to the instance
+// are kept for reuse. It only applies to the default transport, not to
+// that of a client given with WithHTTPClient.
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *Client) {
+		if transport := c.defaultTransport(); transport != nil && n > 0 {
+			transport.MaxIdleConnsPerHost = n
+			transport.MaxIdleConns = max(transport.MaxIdleConns, n)
+		}
+	}
+}
+
+// WithMaxConnsPerHost limits the connections to the instance, idle and
+// active; requests beyond it wait for one. It only applies to the default
+// transport, not to that of a client given with WithHTTPClient.
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *Client) {
+		if transport := c.defaultTransport(); transport != nil && n > 0 {
+			transport.MaxConnsPerHost = n
+		}
+	}
+}
+
+// WithMaxConcurrent limits the requests in flight to the instance at once,
+// whatever the number of sessions calling the client; the others wait for
+// a slot or for their context to end. It protects small self-hosted
+// instances from bursts. A limit of 0 disables it.
+func WithMaxConcurrent(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.limiter = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithRoundTripper wraps the transport of the HTTP client, e.g. to trace
+// or log every request. Options tuning the default transport must come
+// before it.
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *Client) {
+		if wrap == nil {
+			return
+		}
+		transport := c.HTTPClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		c.HTTPClient.Transport = wrap(transport)
+	}
+}
+
+// WithTimeout limits the duration of every request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.HTTPClient.Timeout = timeout
+		}
+	}
+}
+
+// WithUserAgent overrides the User-Agent sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.UserAgent = userAgent
+		}
+	}
+}
+
+// WithHeaders adds extra headers sent with every request, e.g. tokens
+// required by a reverse proxy in front of the instance.
+func WithHeaders(headers http.Header) Option {
+	return func(c *Client) {
+		for name, values := range headers {
+			for _, value := range values {
+				c.Headers.Add(name, value)
+			}
+		}
+	}
+}
+
+// WithSearchMethod selects how /search is requested. POST sends the
+// parameters as a form body, which some public instances require since they
+// block GET requests for the JSON format.
+func WithSearchMethod(method string) Option {
+	return func(c *Client) {
+		if method != "" {
+			c.SearchMethod = strings.ToUpper(method)
+		}
+	}
+}
+
+// WithPathPrefix serves every endpoint under prefix, e.g. "/s3cr3t".
+func WithPathPrefix(prefix string) Option {
+	return func(c *Client) {
+		prefix = strings.Trim(prefix, "/")
+		if prefix != "" {
+			c.PathPrefix = "/" + prefix
+		}
+	}
+}
+
+// WithQueryParams adds static query parameters to every request.
+func WithQueryParams(params url.Values) Option {
+	return func(c *Client) {
+		for name, values := range params {
+			for _, value := range values {
+				c.QueryParams.Add(name, value)
+			}
+		}
+	}
+}
+
+// WithPreferences sends the saved preferences of the instance with every
+// request. preferences is the string of the "URL to restore your
+// preferences" in the instance preferences page, or that whole URL.
+func WithPreferences(preferences string) Option {
+	return func(c *Client) {
+		preferences = strings.TrimSpace(preferences)
+		if u, err := url.Parse(preferences); err == nil && u.Query().Get("preferences") != "" {
+			preferences = u.Query().Get("preferences")
+		}
+		c.Preferences = preferences
+	}
+}
+
+// WithCookies sends cookies with every request, e.g. the preference
+// cookies of a browser session on the instance.
+func WithCookies(cookies []*http.Cookie) Option {
+	return func(c *Client) {
+		c.Cookies = append(c.Cookies, cookies...)
+	}
+}
+
+// WithMinSafeSearch enforces a safe search floor: searches requesting a
+// lower level are sent with level instead.
+func WithMinSafeSearch(level int) Option {
+	return func(c *Client) {
+		c.MinSafeSearch = min(max(level, 0), 2)
+	}
+}
+
+// WithSchemaObserver reports the schema differences of /search responses,
+// e.g. to detect an instance upgrade that changed the response format.
+func WithSchemaObserver(observer func(issues []SchemaIssue)) Option {
+	return func(c *Client) {
+		c.SchemaObserver = observer
+	}
+}
+
+// WithCircuitBreaker pauses requests for cooldown after failures
+// consecutive failed requests. A failures of 0 disables the breaker.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		if failures > 0 {
+			c.Breaker = &CircuitBreaker{Failures: failures, Cooldown: cooldown}
+		}
+	}
+}
+
+// WithHTMLFallback makes Search parse the HTML results page of instances
+// that refuse the JSON format.
+func WithHTMLFallback(fallback bool) Option {
+	return func(c *Client) {
+		c.HTMLFallback = fallback
+	}
+}
+
+// WithLenientParsing drops response fields whose JSON type changed instead
+// of failing the search.
+func WithLenientParsing(lenient bool) Option {
+	return func(c *Client) {
+		c.LenientParsing = lenient
+	}
+}
@@ -0,0 +1,102 @@
+package searxng
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+)
+
+// PoolStats describes the requests and connections of a client.
+type PoolStats struct {
+	// InFlight is the number of requests sent whose response body is not
+	// closed yet; Waiting the number held back by MaxConcurrent.
+	InFlight int64 `json:"in_flight"`
+	Waiting  int64 `json:"waiting"`
+	// MaxConcurrent is the limit of in-flight requests, 0 for none.
+	MaxConcurrent int   `json:"max_concurrent,omitempty"`
+	Requests      int64 `json:"requests"`
+	// NewConns and ReusedConns count the connections requests were sent
+	// on: a high share of new ones means the idle pool is too small.
+	NewConns    int64 `json:"new_conns"`
+	ReusedConns int64 `json:"reused_conns"`
+}
+
+// poolCounters are the counters behind PoolStats.
+type poolCounters struct {
+	inFlight    atomic.Int64
+	waiting     atomic.Int64
+	requests    atomic.Int64
+	newConns    atomic.Int64
+	reusedConns atomic.Int64
+}
+
+// PoolStats returns the current request and connection counts.
+func (c *Client) PoolStats() PoolStats {
+	return PoolStats{
+		InFlight:      c.pool.inFlight.Load(),
+		Waiting:       c.pool.waiting.Load(),
+		MaxConcurrent: cap(c.limiter),
+		Requests:      c.pool.requests.Load(),
+		NewConns:      c.pool.newConns.Load(),
+		ReusedConns:   c.pool.reusedConns.Load(),
+	}
+}
+
+// send is the only way requests reach the instance. It waits for a slot
+// when MaxConcurrent is set, which stays taken until the response body is
+// closed, and counts the connection the request went out on.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		c.pool.waiting.Add(1)
+		select {
+		case c.limiter <- struct{}{}:
+			c.pool.waiting.Add(-1)
+		case <-req.Context().Done():
+			c.pool.waiting.Add(-1)
+			return nil, req.Context().Err()
+		}
+	}
+	c.pool.inFlight.Add(1)
+	c.pool.requests.Add(1)
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			c.pool.inFlight.Add(-1)
+			if c.limiter != nil {
+				<-c.limiter
+			}
+		})
+	}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				c.pool.reusedConns.Add(1)
+			} else {
+				c.pool.newConns.Add(1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees the slot of its request when closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,254 @@
+package searxng
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Kinds of SchemaIssue.
+const (
+	// SchemaUnknownField is a field this package does not know, often
+	// added by a newer SearXNG version.
+	SchemaUnknownField = "unknown_field"
+	// SchemaMissingField is a field every response should have.
+	SchemaMissingField = "missing_field"
+	// SchemaTypeMismatch is a known field whose JSON type changed; it
+	// breaks decoding unless parsing is lenient.
+	SchemaTypeMismatch = "type_mismatch"
+)
+
+// SchemaIssue is a difference between a /search response and the schema
+// this package decodes. Path is the field, e.g. "corrections" or
+// "results[].publishedDate" for a field of the results.
+type SchemaIssue struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (i SchemaIssue) String() string {
+	if i.Detail == "" {
+		return i.Kind + " " + i.Path
+	}
+	return fmt.Sprintf("%s %s (%s)", i.Kind, i.Path, i.Detail)
+}
+
+// JSON types of the schema; "string?" also allows null.
+const (
+	jsonString         = "string"
+	jsonNullableString = "string?"
+	jsonNumber         = "number"
+	jsonArray          = "array"
+	jsonStringArray    = "string array"
+	jsonObject         = "object"
+	jsonAny            = "any"
+)
+
+// responseFields are the top level fields of /search responses. Required
+// ones are reported when missing.
+var responseFields = map[string]struct {
+	typ      string
+	required bool
+}{
+	"query":                {jsonString, true},
+	"number_of_results":    {jsonNumber, true},
+	"results":              {jsonArray, true},
+	"answers":              {jsonArray, false},
+	"corrections":          {jsonStringArray, false},
+	"infoboxes":            {jsonArray, false},
+	"suggestions":          {jsonStringArray, false},
+	"unresponsive_engines": {jsonArray, false},
+}
+
+// resultFields are the result fields SearXNG engines emit. Fields this
+// package decodes have a type; the others are only known.
+var resultFields = map[string]string{
+	"title":           jsonString,
+	"url":             jsonString,
+	"content":         jsonString,
+	"engine":          jsonString,
+	"category":        jsonString,
+	"score":           jsonNumber,
+	"publishedDate":   jsonNullableString,
+	"img_src":         jsonString,
+	"thumbnail_src":   jsonString,
+	"resolution":      jsonString,
+	"img_format":      jsonString,
+	"source":          jsonString,
+	"author":          jsonAny,
+	"package_name":    jsonNullableString,
+	"version":         jsonNullableString,
+	"maintainer":      jsonNullableString,
+	"tags":            jsonStringArray,
+	"popularity":      jsonAny,
+	"license_name":    jsonNullableString,
+	"license_url":     jsonNullableString,
+	"homepage":        jsonNullableString,
+	"source_code_url": jsonNullableString,
+	"thumbnail":       jsonNullableString,
+	"iframe_src":      jsonNullableString,
+	"audio_src":       jsonNullableString,
+	"address":         jsonAny,
+	"latitude":        jsonAny,
+	"longitude":       jsonAny,
+
+	"engines": jsonAny, "parsed_url": jsonAny, "template": jsonAny,
+	"positions": jsonAny, "pubdate": jsonAny,
+	"metadata": jsonAny, "priority": jsonAny,
+	"osm": jsonAny, "boundingbox": jsonAny, "geojson": jsonAny,
+	"length": jsonAny, "views": jsonAny, "filesize": jsonAny,
+	"seed": jsonAny, "leech": jsonAny, "magnetlink": jsonAny,
+	"torrentfile": jsonAny, "files": jsonAny, "doi": jsonAny,
+	"journal": jsonAny, "publisher": jsonAny, "type": jsonAny,
+	"authors": jsonAny, "editor": jsonAny,
+	"issn": jsonAny, "isbn": jsonAny, "pdf_url": jsonAny,
+	"html_url": jsonAny, "comments": jsonAny, "code_language": jsonAny,
+	"codelines": jsonAny, "repository": jsonAny, "img_src_size": jsonAny,
+	"embedded": jsonAny, "is_onion": jsonAny,
+	"open_group": jsonAny, "close_group": jsonAny, "highlight": jsonAny,
+}
+
+// CheckSearchSchema compares a /search response body with the expected
+// schema. It also returns the body without the fields whose type changed,
+// which decodes with the remaining fields intact.
+func CheckSearchSchema(body []byte) ([]SchemaIssue, []byte) {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return []SchemaIssue{{Kind: SchemaTypeMismatch, Path: "$", Detail: "response is not a JSON object"}}, body
+	}
+
+	var issues []SchemaIssue
+	changed := false
+	for _, name := range sortedKeys(response) {
+		field, known := responseFields[name]
+		if !known {
+			issues = append(issues, SchemaIssue{Kind: SchemaUnknownField, Path: name})
+			continue
+		}
+		if got := jsonType(response[name]); !matchesType(field.typ, response[name]) {
+			issues = append(issues, SchemaIssue{Kind: SchemaTypeMismatch, Path: name, Detail: "want " + field.typ + ", got " + got})
+			delete(response, name)
+			changed = true
+		}
+	}
+	for name, field := range responseFields {
+		if _, ok := response[name]; field.required && !ok && !hasIssue(issues, name) {
+			issues = append(issues, SchemaIssue{Kind: SchemaMissingField, Path: name})
+		}
+	}
+
+	if raw, ok := response["results"]; ok {
+		var results []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &results); err != nil {
+			issues = append(issues, SchemaIssue{Kind: SchemaTypeMismatch, Path: "results[]", Detail: "want object"})
+			delete(response, "results")
+			changed = true
+		} else {
+			resultIssues, resultsChanged := checkResults(results)
+			issues = append(issues, resultIssues...)
+			if resultsChanged {
+				response["results"], _ = json.Marshal(results)
+				changed = true
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+	if !changed {
+		return issues, body
+	}
+	sanitized, err := json.Marshal(response)
+	if err != nil {
+		return issues, body
+	}
+	return issues, sanitized
+}
+
+// checkResults reports every result field issue once and deletes the
+// fields with the wrong type.
+func checkResults(results []map[string]json.RawMessage) ([]SchemaIssue, bool) {
+	var issues []SchemaIssue
+	seen := make(map[string]bool)
+	changed := false
+	for _, result := range results {
+		for _, name := range sortedKeys(result) {
+			path := "results[]." + name
+			typ, known := resultFields[name]
+			switch {
+			case !known:
+				if !seen[path] {
+					seen[path] = true
+					issues = append(issues, SchemaIssue{Kind: SchemaUnknownField, Path: path})
+				}
+			case !matchesType(typ, result[name]):
+				if !seen[path] {
+					seen[path] = true
+					issues = append(issues, SchemaIssue{Kind: SchemaTypeMismatch, Path: path, Detail: "want " + typ + ", got " + jsonType(result[name])})
+				}
+				delete(result, name)
+				changed = true
+			}
+		}
+	}
+	return issues, changed
+}
+
+func hasIssue(issues []SchemaIssue, path string) bool {
+	for _, issue := range issues {
+		if issue.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonType names the JSON type of a raw value.
+func jsonType(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "empty"
+	}
+	switch raw[0] {
+	case '"':
+		return jsonString
+	case '{':
+		return jsonObject
+	case '[':
+		return jsonArray
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return jsonNumber
+}
+
+func matchesType(typ string, raw json.RawMessage) bool {
+	got := jsonType(raw)
+	switch typ {
+	case jsonAny:
+		return true
+	case jsonNullableString:
+		return got == jsonString || got == "null"
+	case jsonStringArray:
+		if got == "null" {
+			return true
+		}
+		var values []string
+		return got == jsonArray && json.Unmarshal(raw, &values) == nil
+	case jsonArray:
+		return got == jsonArray || got == "null"
+	}
+	return got == typ
+}
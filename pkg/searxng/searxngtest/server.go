@@ -0,0 +1,307 @@
+// Package searxngtest provides a fake SearXNG instance for tests.
+package searxngtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Request is what the fake instance received.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	// Form holds the decoded POST body.
+	Form   url.Values
+	Header http.Header
+}
+
+// Server is a fake SearXNG instance. By default it answers /search with
+// SearchResponse (echoing the query), /config with Config, /stats/errors
+// with an empty object and / with an HTML page setting a session cookie.
+type Server struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	requests       []Request
+	handlers       map[string]http.HandlerFunc
+	searchResponse map[string]interface{}
+	config         map[string]interface{}
+	jsonDisabled   bool
+	// fixtures are served by file name, see ServeFixtures.
+	fixtures map[string]searxng.Fixture
+}
+
+// DefaultResults are returned by /search unless SetSearchResponse was called.
+var DefaultResults = []map[string]interface{}{
+	{
+		"title":    "First result",
+		"url":      "https://example.com/first",
+		"content":  "First result content",
+		"engine":   "duckduckgo",
+		"category": "general",
+		"score":    2.5,
+	},
+	{
+		"title":         "Second result",
+		"url":           "https://example.org/second",
+		"content":       "Second result content",
+		"engine":        "google",
+		"category":      "general",
+		"score":         1.0,
+		"publishedDate": "2024-06-01T10:00:00",
+	},
+}
+
+// NewServer starts a fake instance. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		handlers: make(map[string]http.HandlerFunc),
+		config: map[string]interface{}{
+			"version": "2024.6.1",
+			"engines": []interface{}{
+				map[string]interface{}{"name": "google", "enabled": true, "categories": []interface{}{"general"}},
+				map[string]interface{}{"name": "duckduckgo", "enabled": true, "categories": []interface{}{"general"}},
+				map[string]interface{}{"name": "google news", "enabled": true, "categories": []interface{}{"news"}},
+			},
+			"categories": []interface{}{"general", "images", "videos", "news", "map", "music", "it", "science", "files", "social media"},
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Handle overrides the handler of path.
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[path] = handler
+}
+
+// SetSearchResponse replaces the /search response. The "query" field is
+// filled with the received query when missing.
+func (s *Server) SetSearchResponse(response map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.searchResponse = response
+}
+
+// DisableJSON makes /search refuse format=json with 403 Forbidden, as
+// instances without "json" in search.formats do, and serve the results as
+// an HTML page of the simple theme otherwise.
+func (s *Server) DisableJSON() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jsonDisabled = true
+}
+
+// SetConfig replaces the /config response.
+func (s *Server) SetConfig(config map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
+// ServeFixtures makes the fake answer with the fixtures recorded in dir by
+// searxng.WithFixtureRecorder (the -record-fixtures flag of the server)
+// instead of its defaults. Requests are matched by path and
+// searxng.FixtureParams; those without a fixture get 404 Not Found, so a
+// test cannot silently pass against the defaults. Queries anonymized when
+// they were recorded must be searched anonymized. Handlers set with Handle
+// still come first.
+func (s *Server) ServeFixtures(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	fixtures := make(map[string]searxng.Fixture, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var fixture searxng.Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return fmt.Errorf("fixture %s: %w", file, err)
+		}
+		fixtures[searxng.FixtureName(fixture.Path, fixture.Params)] = fixture
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixtures = fixtures
+	return nil
+}
+
+// Requests returns every request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// LastRequest returns the latest request to path.
+func (s *Server) LastRequest(path string) (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if s.requests[i].Path == path {
+			return s.requests[i], true
+		}
+	}
+	return Request{}, false
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Form:   r.PostForm,
+		Header: r.Header.Clone(),
+	})
+	handler := s.handlers[r.URL.Path]
+	searchResponse := s.searchResponse
+	config := s.config
+	jsonDisabled := s.jsonDisabled
+	fixtures := s.fixtures
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(w, r)
+		return
+	}
+	if fixtures != nil {
+		serveFixture(w, r, fixtures)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/search":
+		response := map[string]interface{}{}
+		if searchResponse == nil {
+			response["number_of_results"] = len(DefaultResults)
+			response["results"] = DefaultResults
+		} else {
+			for k, v := range searchResponse {
+				response[k] = v
+			}
+		}
+		if _, ok := response["query"]; !ok {
+			response["query"] = r.Form.Get("q")
+		}
+		switch {
+		case r.Form.Get("format") == "csv":
+			writeCSV(w, response)
+		case r.Form.Get("format") == "rss":
+			writeRSS(w, response)
+		case r.Form.Get("format") != "json" && jsonDisabled:
+			writeResultsPage(w, response)
+		case jsonDisabled:
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		default:
+			writeJSON(w, response)
+		}
+	case "/config":
+		writeJSON(w, config)
+	case "/stats/errors":
+		writeJSON(w, map[string]interface{}{})
+	case "/":
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "fake"})
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>SearXNG</body></html>"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveFixture writes the fixture of r.
+func serveFixture(w http.ResponseWriter, r *http.Request, fixtures map[string]searxng.Fixture) {
+	fixture, ok := fixtures[searxng.FixtureName(r.URL.Path, r.Form)]
+	if !ok {
+		http.Error(w, "no fixture for "+r.Method+" "+r.URL.Path+"?"+r.Form.Encode(), http.StatusNotFound)
+		return
+	}
+	if fixture.ContentType != "" {
+		w.Header().Set("Content-Type", fixture.ContentType)
+	}
+	if fixture.Status != 0 {
+		w.WriteHeader(fixture.Status)
+	}
+	if fixture.Body != nil {
+		w.Write(fixture.Body)
+	} else {
+		w.Write([]byte(fixture.Text))
+	}
+}
+
+// writeResultsPage renders response the way the simple theme does.
+func writeResultsPage(w http.ResponseWriter, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><body><div id=\"result_count\"><small>Number of results: %v</small></div><div id=\"urls\">\n", response["number_of_results"])
+	for _, result := range resultMaps(response) {
+		fmt.Fprintf(&b, `<article class="result result-default category-%s">
+<a href="%s" class="url_header"><div class="url_wrapper">%s</div></a>
+<h3><a href="%s">%s</a></h3>
+<p class="content">%s</p>
+<div class="engines"><span>%s</span></div>
+</article>
+`, text(result["category"]), text(result["url"]), text(result["url"]), text(result["url"]), text(result["title"]), text(result["content"]), text(result["engine"]))
+	}
+	b.WriteString("</div></body></html>")
+	w.Write([]byte(b.String()))
+}
+
+// writeCSV renders response as the csv format of SearXNG does.
+func writeCSV(w http.ResponseWriter, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/csv")
+	out := csv.NewWriter(w)
+	out.Write([]string{"title", "url", "content", "host", "engine", "score", "type"})
+	for _, result := range resultMaps(response) {
+		u, _ := url.Parse(fmt.Sprint(result["url"]))
+		out.Write([]string{fmt.Sprint(result["title"]), fmt.Sprint(result["url"]), fmt.Sprint(result["content"]), u.Host, fmt.Sprint(result["engine"]), fmt.Sprint(result["score"]), "result"})
+	}
+	out.Flush()
+}
+
+// writeRSS renders response as the rss format of SearXNG does.
+func writeRSS(w http.ResponseWriter, response map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/rss+xml")
+	var b strings.Builder
+	fmt.Fprintf(&b, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<rss version=\"2.0\"><channel><title>SearXNG search: %s</title>\n", text(response["query"]))
+	for _, result := range resultMaps(response) {
+		fmt.Fprintf(&b, "<item><title>%s</title><link>%s</link><description>%s</description></item>\n", text(result["title"]), text(result["url"]), text(result["content"]))
+	}
+	b.WriteString("</channel></rss>\n")
+	w.Write([]byte(b.String()))
+}
+
+func resultMaps(response map[string]interface{}) []map[string]interface{} {
+	data, _ := json.Marshal(response["results"])
+	var results []map[string]interface{}
+	json.Unmarshal(data, &results)
+	return results
+}
+
+func text(v interface{}) string {
+	s, _ := v.(string)
+	return html.EscapeString(s)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
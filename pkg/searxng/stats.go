@@ -0,0 +1,130 @@
+package searxng
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// GetStats returns per-engine statistics from /stats. SearXNG only renders
+// that page as HTML, so the table is parsed by its column headings, which
+// keeps working across theme and version changes of the markup.
+func (c *Client) GetStats(ctx context.Context) ([]EngineStat, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint("/stats", nil), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	c.setHeaders(req, nil)
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newHTTPError(resp, "/stats", nil)
+	}
+
+	doc, err := html.Parse(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %w", err)
+	}
+
+	return parseStatsTable(doc), nil
+}
+
+var numberRe = regexp.MustCompile(`[0-9]+(?:\.[0-9]+)?`)
+
+func parseStatsTable(doc *html.Node) []EngineStat {
+	var stats []EngineStat
+	var columns []string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			header := false
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+					continue
+				}
+				header = header || c.Data == "th"
+				cells = append(cells, collapse(nodeText(c)))
+			}
+			if header {
+				columns = make([]string, len(cells))
+				for i, cell := range cells {
+					columns[i] = strings.ToLower(cell)
+				}
+				return
+			}
+			if stat, ok := statFromRow(columns, cells); ok {
+				stats = append(stats, stat)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return stats
+}
+
+func statFromRow(columns, cells []string) (EngineStat, bool) {
+	var stat EngineStat
+	if len(columns) == 0 || len(cells) == 0 || len(cells) > len(columns) {
+		return stat, false
+	}
+	for i, cell := range cells {
+		column := columns[i]
+		switch {
+		case strings.Contains(column, "engine"):
+			stat.Name = cell
+		case strings.Contains(column, "response"):
+			stat.ResponseTime = firstNumber(cell)
+		case strings.Contains(column, "reliab"):
+			stat.Reliability = firstNumber(cell)
+		case strings.Contains(column, "result"):
+			stat.ResultCount = firstNumber(cell)
+		case strings.Contains(column, "score"):
+			stat.Score = firstNumber(cell)
+		}
+	}
+	return stat, stat.Name != ""
+}
+
+func firstNumber(s string) float64 {
+	n, _ := strconv.ParseFloat(numberRe.FindString(s), 64)
+	return n
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,326 @@
+package searxng
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SearchResult struct {
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Content       string  `json:"content"`
+	Engine        string  `json:"engine"`
+	Category      string  `json:"category"`
+	Score         float64 `json:"score,omitempty"`
+	PublishedDate string  `json:"publishedDate,omitempty"`
+	// Source is SourceHTMLFallback for results parsed from the HTML
+	// results page, empty for those of the JSON API.
+	Source string `json:"source,omitempty"`
+	// Instances are the base URLs of the instances that returned the
+	// result, set by callers merging the results of several instances.
+	Instances []string `json:"instances,omitempty"`
+}
+
+// SourceHTMLFallback marks the results of an instance that refuses the JSON
+// format, read from its HTML results page.
+const SourceHTMLFallback = "html_fallback"
+
+type SearchResponse struct {
+	Query           string         `json:"query"`
+	NumberOfResults int            `json:"number_of_results"`
+	Results         []SearchResult `json:"results"`
+	Answers         []Answer       `json:"answers,omitempty"`
+	Corrections     []string       `json:"corrections,omitempty"`
+	Infoboxes       []interface{}  `json:"infoboxes,omitempty"`
+	Suggestions     []string       `json:"suggestions,omitempty"`
+	// UnresponsiveEngines are the requested engines that returned nothing,
+	// with the reason reported by the instance.
+	UnresponsiveEngines []UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+// Answer is an instant answer. Older instances send answers as plain
+// strings, newer ones as objects naming the source page and engine.
+type Answer struct {
+	Answer string `json:"answer"`
+	URL    string `json:"url,omitempty"`
+	Engine string `json:"engine,omitempty"`
+	// Current, Forecasts and Service are set on the weather answers of
+	// the weather engines (wttr.in, open meteo) of recent instances. The
+	// conditions are kept as sent: location, temperature, condition,
+	// humidity, wind and so on, with unit values as objects.
+	Current   map[string]interface{}   `json:"current,omitempty"`
+	Forecasts []map[string]interface{} `json:"forecasts,omitempty"`
+	Service   string                   `json:"service,omitempty"`
+}
+
+func (a *Answer) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*a = Answer{Answer: text}
+		return nil
+	}
+	type plain Answer
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+func (a Answer) String() string {
+	return a.Answer
+}
+
+// UnresponsiveEngine is an entry of unresponsive_engines. The instance
+// sends it as a [name, reason] pair, e.g. ["google", "Suspended: CAPTCHA"];
+// legacy searx versions send the name alone.
+type UnresponsiveEngine struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func (e *UnresponsiveEngine) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*e = UnresponsiveEngine{Name: name}
+		return nil
+	}
+	var pair []string
+	if err := json.Unmarshal(data, &pair); err == nil {
+		*e = UnresponsiveEngine{}
+		if len(pair) > 0 {
+			e.Name = pair[0]
+		}
+		if len(pair) > 1 {
+			e.Reason = pair[1]
+		}
+		return nil
+	}
+	type plain UnresponsiveEngine
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// suspensionReasons are the errors after which the instance suspends an
+// engine for a while instead of querying it.
+var suspensionReasons = []string{"captcha", "too many requests", "access denied"}
+
+// Suspended reports whether the engine is blocked (CAPTCHA, rate limit or
+// access denied) rather than merely slow or broken. The instance reports
+// the first block as e.g. "CAPTCHA" and later requests during the
+// suspension as "Suspended: CAPTCHA".
+func (e UnresponsiveEngine) Suspended() bool {
+	reason := strings.ToLower(e.Reason)
+	if strings.HasPrefix(reason, "suspended") {
+		return true
+	}
+	for _, r := range suspensionReasons {
+		if strings.Contains(reason, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageResult is a result of the images category.
+type ImageResult struct {
+	Title        string  `json:"title"`
+	URL          string  `json:"url"`
+	Content      string  `json:"content,omitempty"`
+	Engine       string  `json:"engine"`
+	Category     string  `json:"category"`
+	Score        float64 `json:"score,omitempty"`
+	ImgSrc       string  `json:"img_src"`
+	ThumbnailSrc string  `json:"thumbnail_src,omitempty"`
+	Resolution   string  `json:"resolution,omitempty"`
+	ImgFormat    string  `json:"img_format,omitempty"`
+	Source       string  `json:"source,omitempty"`
+	Author       string  `json:"author,omitempty"`
+}
+
+type ImageSearchResponse struct {
+	Query           string        `json:"query"`
+	NumberOfResults int           `json:"number_of_results"`
+	Results         []ImageResult `json:"results"`
+	Suggestions     []string      `json:"suggestions,omitempty"`
+	Corrections     []string      `json:"corrections,omitempty"`
+	// UnresponsiveEngines are the requested engines that returned nothing.
+	UnresponsiveEngines []UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+// CodeResult is a result of the it category. Package and repository
+// engines (github, gitlab, docker hub, npm, ...) fill the package fields;
+// the others leave them empty.
+type CodeResult struct {
+	Title         string   `json:"title"`
+	URL           string   `json:"url"`
+	Content       string   `json:"content,omitempty"`
+	Engine        string   `json:"engine"`
+	Category      string   `json:"category"`
+	Score         float64  `json:"score,omitempty"`
+	PublishedDate string   `json:"publishedDate,omitempty"`
+	PackageName   string   `json:"package_name,omitempty"`
+	Version       string   `json:"version,omitempty"`
+	Maintainer    string   `json:"maintainer,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	// Popularity is the star count of repositories, the download or pull
+	// count of packages.
+	Popularity    Number `json:"popularity,omitempty"`
+	LicenseName   string `json:"license_name,omitempty"`
+	LicenseURL    string `json:"license_url,omitempty"`
+	Homepage      string `json:"homepage,omitempty"`
+	SourceCodeURL string `json:"source_code_url,omitempty"`
+}
+
+type CodeSearchResponse struct {
+	Query           string       `json:"query"`
+	NumberOfResults int          `json:"number_of_results"`
+	Results         []CodeResult `json:"results"`
+	Suggestions     []string     `json:"suggestions,omitempty"`
+	Corrections     []string     `json:"corrections,omitempty"`
+	// UnresponsiveEngines are the requested engines that returned nothing.
+	UnresponsiveEngines []UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+// MusicResult is a result of the music category.
+type MusicResult struct {
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Content       string  `json:"content,omitempty"`
+	Engine        string  `json:"engine"`
+	Category      string  `json:"category"`
+	Score         float64 `json:"score,omitempty"`
+	PublishedDate string  `json:"publishedDate,omitempty"`
+	Author        string  `json:"author,omitempty"`
+	Length        Length  `json:"length,omitempty"`
+	Thumbnail     string  `json:"thumbnail,omitempty"`
+	// IframeSrc is the embeddable player of the track.
+	IframeSrc string `json:"iframe_src,omitempty"`
+	// AudioSrc is a direct audio stream, when the engine provides one.
+	AudioSrc string `json:"audio_src,omitempty"`
+}
+
+type MusicSearchResponse struct {
+	Query           string        `json:"query"`
+	NumberOfResults int           `json:"number_of_results"`
+	Results         []MusicResult `json:"results"`
+	Suggestions     []string      `json:"suggestions,omitempty"`
+	Corrections     []string      `json:"corrections,omitempty"`
+	// UnresponsiveEngines are the requested engines that returned nothing.
+	UnresponsiveEngines []UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+// MapResult is a result of the map category, a place found by engines
+// such as openstreetmap.
+type MapResult struct {
+	Title     string      `json:"title"`
+	URL       string      `json:"url"`
+	Engine    string      `json:"engine"`
+	Latitude  Number      `json:"latitude"`
+	Longitude Number      `json:"longitude"`
+	Address   *MapAddress `json:"address,omitempty"`
+}
+
+// MapAddress is the address of a place; engines fill in what they know.
+type MapAddress struct {
+	Name        string `json:"name,omitempty"`
+	Road        string `json:"road,omitempty"`
+	HouseNumber string `json:"house_number,omitempty"`
+	Locality    string `json:"locality,omitempty"`
+	Postcode    string `json:"postcode,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+}
+
+type MapSearchResponse struct {
+	Query           string      `json:"query"`
+	NumberOfResults int         `json:"number_of_results"`
+	Results         []MapResult `json:"results"`
+	// UnresponsiveEngines are the requested engines that returned nothing.
+	UnresponsiveEngines []UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+// Length is a media duration engines send either as seconds or as a
+// "[h:]mm:ss" string. Unreadable values decode as 0.
+type Length time.Duration
+
+func (l *Length) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*l = Length(seconds * float64(time.Second))
+		return nil
+	}
+	*l = 0
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil
+	}
+	var total time.Duration
+	for _, part := range strings.Split(strings.TrimSpace(s), ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return nil
+		}
+		total = total*60 + time.Duration(n*float64(time.Second))
+	}
+	*l = Length(total)
+	return nil
+}
+
+func (l Length) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(l).Seconds())
+}
+
+// Number is a count engines send either as a JSON number or as a string.
+// Strings that are not numbers, e.g. "1.2k", decode as 0.
+type Number float64
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err == nil {
+		*n = Number(f)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		f, _ = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	*n = Number(f)
+	return nil
+}
+
+type SearchParams struct {
+	Query      string
+	Categories []string
+	Engines    []string
+	Language   string
+	PageNo     int
+	TimeRange  string
+	SafeSearch int
+	// Headers are sent with this request only, replacing client defaults
+	// with the same name.
+	Headers http.Header
+}
+
+// EngineError is one error class an engine reported, as listed by
+// /stats/errors.
+type EngineError struct {
+	Filename           string   `json:"filename"`
+	Function           string   `json:"function"`
+	LineNo             int      `json:"line_no"`
+	Code               string   `json:"code"`
+	ExceptionClassname string   `json:"exception_classname"`
+	LogMessage         string   `json:"log_message"`
+	LogParameters      []string `json:"log_parameters"`
+	Secondary          bool     `json:"secondary"`
+	Percentage         float64  `json:"percentage"`
+}
+
+// EngineStat is one row of the instance /stats page.
+type EngineStat struct {
+	Name string `json:"name"`
+	// ResponseTime is the average total response time in seconds.
+	ResponseTime float64 `json:"response_time,omitempty"`
+	// Reliability is the share of successful requests in percent.
+	Reliability float64 `json:"reliability"`
+	ResultCount float64 `json:"result_count,omitempty"`
+	Score       float64 `json:"score,omitempty"`
+}
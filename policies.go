@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// defaultQuotaWindow is the quota window of policies that set none.
+const defaultQuotaWindow = 24 * time.Hour
+
+// EnginePolicy replaces engines during a daily time window or once their
+// search quota is used up, e.g. to spare Google during the peak hours in
+// which the instance gets blocked:
+//
+//	{"engines": ["google"], "avoid_hours": "09:00-18:00", "timezone": "Europe/Berlin", "fallback": ["bing"]}
+//	{"engines": ["brave"], "quota": 2000, "quota_window": "720h", "fallback": ["duckduckgo"]}
+type EnginePolicy struct {
+	Engines []string `json:"engines"`
+	// AvoidHours is a daily "HH:MM-HH:MM" window, possibly across
+	// midnight, during which the engines are replaced.
+	AvoidHours string `json:"avoid_hours,omitempty"`
+	// TimeZone of AvoidHours, default the local time zone.
+	TimeZone string `json:"timezone,omitempty"`
+	// Quota is the number of searches an engine may receive per
+	// QuotaWindow (a Go duration, default 24h).
+	Quota       int    `json:"quota,omitempty"`
+	QuotaWindow string `json:"quota_window,omitempty"`
+	// Fallback are the engines used instead; empty just drops them.
+	Fallback []string `json:"fallback,omitempty"`
+}
+
+// policyDecision is an engine a policy replaced in a search.
+type policyDecision struct {
+	Engine   string   `json:"engine"`
+	Fallback []string `json:"fallback,omitempty"`
+	Reason   string   `json:"reason"`
+}
+
+type compiledPolicy struct {
+	EnginePolicy
+	engines map[string]bool
+	// start and end are AvoidHours in minutes after midnight; start < 0
+	// means no window.
+	start, end int
+	location   *time.Location
+	window     time.Duration
+}
+
+// policySet applies the engine policies of the config and counts the
+// searches of engines with a quota.
+type policySet struct {
+	policies []compiledPolicy
+
+	mu sync.Mutex
+	// usage holds, per engine with a quota, the times of its searches
+	// within the longest quota window.
+	usage map[string][]time.Time
+}
+
+func newPolicySet(policies []EnginePolicy) (*policySet, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	set := &policySet{usage: make(map[string][]time.Time)}
+	for i, p := range policies {
+		c := compiledPolicy{EnginePolicy: p, engines: make(map[string]bool), start: -1, location: time.Local, window: defaultQuotaWindow}
+		if len(p.Engines) == 0 {
+			return nil, fmt.Errorf("engine policy %d lists no engines", i+1)
+		}
+		if p.AvoidHours == "" && p.Quota <= 0 {
+			return nil, fmt.Errorf("engine policy %d needs avoid_hours or a quota", i+1)
+		}
+		for _, engine := range p.Engines {
+			c.engines[engine] = true
+		}
+		if p.AvoidHours != "" {
+			var startH, startM, endH, endM int
+			if _, err := fmt.Sscanf(p.AvoidHours, "%d:%d-%d:%d", &startH, &startM, &endH, &endM); err != nil ||
+				startH > 23 || endH > 24 || startM > 59 || endM > 59 || startH < 0 || endH < 0 || startM < 0 || endM < 0 {
+				return nil, fmt.Errorf("engine policy %d: avoid_hours must look like 09:00-18:00, got %q", i+1, p.AvoidHours)
+			}
+			c.start, c.end = startH*60+startM, endH*60+endM
+		}
+		if p.TimeZone != "" {
+			location, err := time.LoadLocation(p.TimeZone)
+			if err != nil {
+				return nil, fmt.Errorf("engine policy %d: %w", i+1, err)
+			}
+			c.location = location
+		}
+		if p.QuotaWindow != "" {
+			window, err := time.ParseDuration(p.QuotaWindow)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("engine policy %d: invalid quota_window %q", i+1, p.QuotaWindow)
+			}
+			c.window = window
+		}
+		set.policies = append(set.policies, c)
+	}
+	return set, nil
+}
+
+// adoptUsage carries the searches counted by old, the policies a config
+// reload replaces, over to s, so reloading does not reset quotas.
+func (s *policySet) adoptUsage(old *policySet) {
+	if s == nil || old == nil {
+		return
+	}
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for engine, times := range old.usage {
+		s.usage[engine] = slices.Clone(times)
+	}
+}
+
+// inWindow reports whether now falls in the AvoidHours window.
+func (c *compiledPolicy) inWindow(now time.Time) bool {
+	if c.start < 0 {
+		return false
+	}
+	local := now.In(c.location)
+	minute := local.Hour()*60 + local.Minute()
+	if c.start <= c.end {
+		return minute >= c.start && minute < c.end
+	}
+	return minute >= c.start || minute < c.end
+}
+
+// used returns the searches engine received within window. The caller
+// holds s.mu.
+func (s *policySet) used(engine string, window time.Duration, now time.Time) int {
+	n := 0
+	for _, t := range s.usage[engine] {
+		if now.Sub(t) < window {
+			n++
+		}
+	}
+	return n
+}
+
+// apply replaces the engines of params that a policy rules out now and
+// returns the decisions. Like suspensions, the engines are kept when
+// every one of them would be dropped without a fallback. A search without
+// engines goes to the default engines of its categories, so those are
+// checked, from the /config of the instance: when a policy rules one out,
+// the search is sent with the remaining engines and the fallbacks instead
+// of its categories, since SearXNG would add every engine of categories
+// sent along with engines.
+func (s *policySet) apply(ctx context.Context, params *searxng.SearchParams, now time.Time) []policyDecision {
+	if s == nil {
+		return nil
+	}
+	engines, defaults := params.Engines, false
+	if len(engines) == 0 {
+		engines, defaults = defaultEngines(ctx, params.Categories), true
+	}
+	if len(engines) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []string
+	var decisions []policyDecision
+	seen := make(map[string]bool)
+	add := func(engine string) {
+		if !seen[engine] {
+			seen[engine] = true
+			kept = append(kept, engine)
+		}
+	}
+	for _, engine := range engines {
+		decision, ruled := s.ruling(engine, now)
+		if !ruled {
+			add(engine)
+			continue
+		}
+		decisions = append(decisions, decision)
+		for _, fallback := range decision.Fallback {
+			add(fallback)
+		}
+	}
+	if len(decisions) == 0 || len(kept) == 0 {
+		return nil
+	}
+	params.Engines = kept
+	if defaults {
+		params.Categories = nil
+	}
+	return decisions
+}
+
+// ruling returns the decision of the first policy ruling engine out. The
+// caller holds s.mu.
+func (s *policySet) ruling(engine string, now time.Time) (policyDecision, bool) {
+	for i := range s.policies {
+		p := &s.policies[i]
+		if !p.engines[engine] {
+			continue
+		}
+		if p.inWindow(now) {
+			return policyDecision{Engine: engine, Fallback: p.Fallback, Reason: "avoided during " + p.AvoidHours}, true
+		}
+		if p.Quota > 0 && s.used(engine, p.window, now) >= p.Quota {
+			return policyDecision{Engine: engine, Fallback: p.Fallback, Reason: fmt.Sprintf("quota of %d searches per %s used up", p.Quota, p.window)}, true
+		}
+	}
+	return policyDecision{}, false
+}
+
+// count records a search, for the quotas of its engines: those of params
+// or, without any, the default engines of its categories.
+func (s *policySet) count(ctx context.Context, params searxng.SearchParams, now time.Time) {
+	if s == nil {
+		return
+	}
+	engines := params.Engines
+	if len(engines) == 0 {
+		engines = defaultEngines(ctx, params.Categories)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, engine := range engines {
+		window := time.Duration(0)
+		for _, p := range s.policies {
+			if p.engines[engine] && p.Quota > 0 {
+				window = max(window, p.window)
+			}
+		}
+		if window == 0 {
+			continue
+		}
+		times := s.usage[engine]
+		for len(times) > 0 && now.Sub(times[0]) >= window {
+			times = times[1:]
+		}
+		s.usage[engine] = append(times, now)
+	}
+}
+
+// describe summarizes the quota usage for the dashboard.
+func (s *policySet) describe() string {
+	if s == nil {
+		return "No engine policies configured"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var parts []string
+	for _, p := range s.policies {
+		for _, engine := range p.Engines {
+			if p.Quota > 0 {
+				parts = append(parts, fmt.Sprintf("%s: %d/%d searches per %s", engine, s.used(engine, p.window, now), p.Quota, p.window))
+			}
+			if p.inWindow(now) {
+				parts = append(parts, fmt.Sprintf("%s: avoided until the end of %s", engine, p.AvoidHours))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%d policies, none active", len(s.policies))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestEnginePolicyHours(t *testing.T) {
+	set, err := newPolicySet([]EnginePolicy{
+		{Engines: []string{"google"}, AvoidHours: "09:00-18:00", TimeZone: "UTC", Fallback: []string{"bing", "duckduckgo"}},
+		{Engines: []string{"yandex"}, AvoidHours: "22:00-06:00", TimeZone: "UTC"},
+	})
+	if err != nil {
+		t.Fatalf("newPolicySet: %v", err)
+	}
+	at := func(hour, minute int) time.Time { return time.Date(2024, 6, 1, hour, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		now     time.Time
+		engines []string
+		want    []string
+	}{
+		{at(10, 0), []string{"google", "bing"}, []string{"bing", "duckduckgo"}},
+		{at(8, 59), []string{"google", "bing"}, []string{"google", "bing"}},
+		{at(18, 0), []string{"google"}, []string{"google"}},
+		{at(23, 0), []string{"yandex", "bing"}, []string{"bing"}},
+		{at(5, 59), []string{"yandex", "bing"}, []string{"bing"}},
+		// Dropping every engine without a fallback keeps them.
+		{at(23, 0), []string{"yandex"}, []string{"yandex"}},
+	}
+	for _, tt := range tests {
+		params := searxng.SearchParams{Engines: tt.engines}
+		set.apply(context.Background(), &params, tt.now)
+		if !reflect.DeepEqual(params.Engines, tt.want) {
+			t.Errorf("%s %v: engines = %v, want %v", tt.now.Format("15:04"), tt.engines, params.Engines, tt.want)
+		}
+	}
+}
+
+func TestEnginePolicyDefaultEngines(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetConfig(map[string]interface{}{
+		"engines": []interface{}{
+			map[string]interface{}{"name": "google", "categories": []interface{}{"general"}, "enabled": true},
+			map[string]interface{}{"name": "duckduckgo", "categories": []interface{}{"general"}, "enabled": true},
+			map[string]interface{}{"name": "yahoo", "categories": []interface{}{"general"}, "enabled": false},
+			map[string]interface{}{"name": "google news", "categories": []interface{}{"news"}, "enabled": true},
+		},
+	})
+	set, err := newPolicySet([]EnginePolicy{{Engines: []string{"google"}, AvoidHours: "09:00-18:00", TimeZone: "UTC", Fallback: []string{"bing"}}})
+	if err != nil {
+		t.Fatalf("newPolicySet: %v", err)
+	}
+	at := func(hour int) time.Time { return time.Date(2024, 6, 1, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		now        time.Time
+		categories []string
+		engines    []string
+		kept       []string
+	}{
+		{at(10), nil, []string{"bing", "duckduckgo"}, nil},
+		{at(10), []string{"general"}, []string{"bing", "duckduckgo"}, nil},
+		{at(10), []string{"news"}, nil, []string{"news"}},
+		{at(8), nil, nil, nil},
+	}
+	for _, tt := range tests {
+		params := searxng.SearchParams{Categories: tt.categories}
+		set.apply(context.Background(), &params, tt.now)
+		if !reflect.DeepEqual(params.Engines, tt.engines) || !reflect.DeepEqual(params.Categories, tt.kept) {
+			t.Errorf("%s %v: engines %v, categories %v, want %v and %v", tt.now.Format("15:04"), tt.categories, params.Engines, params.Categories, tt.engines, tt.kept)
+		}
+	}
+}
+
+func TestEnginePolicyQuota(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	set, err := newPolicySet([]EnginePolicy{{Engines: []string{"brave"}, Quota: 2, QuotaWindow: "1h", Fallback: []string{"duckduckgo"}}})
+	if err != nil {
+		t.Fatalf("newPolicySet: %v", err)
+	}
+	useState(t, func(s *serverState) { s.policies = set })
+
+	var engines []string
+	var response searchV2Response
+	for i := 0; i < 3; i++ {
+		result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "engines": "brave"})
+		if err != nil || result.IsError {
+			t.Fatalf("handler: %+v, %v", result, err)
+		}
+		req, _ := fake.LastRequest("/search")
+		engines = append(engines, req.Query.Get("engines"))
+		decodeResult(t, result, &response)
+	}
+	if want := []string{"brave", "brave", "duckduckgo"}; !reflect.DeepEqual(engines, want) {
+		t.Errorf("engines sent = %v, want %v", engines, want)
+	}
+	if len(response.Meta.EnginePolicies) != 1 || response.Meta.EnginePolicies[0].Reason != "quota of 2 searches per 1h0m0s used up" {
+		t.Errorf("meta = %+v", response.Meta.EnginePolicies)
+	}
+	if got := set.describe(); got != "brave: 2/2 searches per 1h0m0s" {
+		t.Errorf("describe = %q", got)
+	}
+}
+
+func TestEnginePolicyValidation(t *testing.T) {
+	for _, policy := range []EnginePolicy{
+		{AvoidHours: "09:00-18:00"},
+		{Engines: []string{"google"}},
+		{Engines: []string{"google"}, AvoidHours: "9am-6pm"},
+		{Engines: []string{"google"}, AvoidHours: "09:00-18:00", TimeZone: "Mars/Olympus"},
+		{Engines: []string{"google"}, Quota: 10, QuotaWindow: "a day"},
+	} {
+		if _, err := newPolicySet([]EnginePolicy{policy}); err == nil {
+			t.Errorf("want an error for %+v", policy)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// EngineProfile is a named, curated set of search settings, so agents do
+// not need to know engine names. In the config file it is either an
+// object or just the engine list:
+//
+//	"code": ["github", "stackoverflow"]
+//	"news_ru": {"categories": ["news"], "engines": ["yandex news", "lenta"], "language": "ru"}
+type EngineProfile struct {
+	Categories []string `json:"categories,omitempty"`
+	Engines    []string `json:"engines,omitempty"`
+	Language   string   `json:"language,omitempty"`
+}
+
+func (p *EngineProfile) UnmarshalJSON(data []byte) error {
+	var engines []string
+	if err := json.Unmarshal(data, &engines); err == nil {
+		*p = EngineProfile{Engines: engines}
+		return nil
+	}
+	type plain EngineProfile
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// applyTo replaces the settings of params the profile defines.
+func (p EngineProfile) applyTo(params *searxng.SearchParams) {
+	if len(p.Categories) > 0 {
+		params.Categories = append([]string(nil), p.Categories...)
+	}
+	if len(p.Engines) > 0 {
+		params.Engines = append([]string(nil), p.Engines...)
+	}
+	if p.Language != "" {
+		params.Language = p.Language
+	}
+}
+
+// profileNames returns the configured profile names sorted.
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileOption declares the profile argument; it lists the configured
+// profiles.
+func profileOption() mcp.ToolOption {
+	description := "Engine profile expanding into categories, engines and language; explicit arguments override it"
+	if names := currentState().config.profileNames(); len(names) > 0 {
+		description += ". Available: " + strings.Join(names, ", ")
+	}
+	return mcp.WithString("profile", mcp.Description(description))
+}
+
+// applyProfileArgument applies the profile named in arguments, if any, to
+// params.
+func applyProfileArgument(ctx context.Context, arguments map[string]interface{}, params *searxng.SearchParams) error {
+	name, _ := arguments["profile"].(string)
+	if name = strings.TrimSpace(name); name == "" {
+		return nil
+	}
+	config := stateOf(ctx).config
+	profile, ok := config.Profiles[name]
+	if !ok {
+		if names := config.profileNames(); len(names) > 0 {
+			return fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(names, ", "))
+		}
+		return fmt.Errorf("unknown profile %q: no profiles are configured", name)
+	}
+	profile.applyTo(params)
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestEngineProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"profiles": {
+		"code": ["github", "stackoverflow"],
+		"news_ru": {"categories": ["news"], "engines": ["yandex news", "lenta"], "language": "ru"}
+	}}`), 0o600)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	useConfig(t, cfg)
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		want      searxng.SearchParams
+	}{
+		{
+			name:      "engine list",
+			arguments: map[string]interface{}{"query": "q", "profile": "code"},
+			want:      searxng.SearchParams{Query: "q", Categories: []string{"general"}, Engines: []string{"github", "stackoverflow"}, Language: autoLanguage},
+		},
+		{
+			name:      "full profile",
+			arguments: map[string]interface{}{"query": "q", "profile": "news_ru"},
+			want:      searxng.SearchParams{Query: "q", Categories: []string{"news"}, Engines: []string{"yandex news", "lenta"}, Language: "ru"},
+		},
+		{
+			name:      "explicit arguments win",
+			arguments: map[string]interface{}{"query": "q", "profile": "news_ru", "language": "en"},
+			want:      searxng.SearchParams{Query: "q", Categories: []string{"news"}, Engines: []string{"yandex news", "lenta"}, Language: "en"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchParamsFromArguments(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("searchParamsFromArguments: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := searchParamsFromArguments(context.Background(), map[string]interface{}{"query": "q", "profile": "video"}); err == nil {
+		t.Error("want an error for an unknown profile")
+	}
+
+	os.WriteFile(path, []byte(`{"profiles": {"empty": {}}}`), 0o600)
+	if _, err := loadConfig(path); err == nil {
+		t.Error("want an error for an empty profile")
+	}
+}
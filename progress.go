@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressReporter sends notifications/progress for a tool call whose
+// client passed a progress token, so the client can show how far a long
+// call is. A nil reporter, returned when no token was passed, does nothing.
+type progressReporter struct {
+	ctx    context.Context
+	server *server.MCPServer
+	token  mcp.ProgressToken
+
+	mu       sync.Mutex
+	progress int
+	total    int
+}
+
+// newProgressReporter returns the reporter of request, expecting total
+// steps.
+func newProgressReporter(ctx context.Context, request mcp.CallToolRequest, total int) *progressReporter {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil
+	}
+	return &progressReporter{ctx: ctx, server: srv, token: request.Params.Meta.ProgressToken, total: total}
+}
+
+// addTotal corrects the expected number of steps by n once it is known,
+// e.g. when a search returned fewer results than pages to read.
+func (p *progressReporter) addTotal(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = max(p.total+n, p.progress)
+}
+
+// step records a finished step, described by message, and reports it.
+// Steps may finish concurrently.
+func (p *progressReporter) step(message string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress++
+	p.total = max(p.total, p.progress)
+	err := p.server.SendNotificationToClient(p.ctx, "notifications/progress", map[string]any{
+		"progressToken": p.token,
+		"progress":      p.progress,
+		"total":         p.total,
+		"message":       message,
+	})
+	if err != nil {
+		log.Printf("Progress notification failed: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                   { return "progress-test" }
+
+func TestSearchAndReadProgress(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	fake.Handle("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><p>Some page text</p></body></html>"))
+	})
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"title": "One", "url": fake.URL + "/page?1", "engine": "google"},
+			{"title": "Two", "url": fake.URL + "/page?2", "engine": "google"},
+		},
+	})
+
+	srv := server.NewMCPServer("test", "1.0")
+	srv.AddTool(mcp.NewTool("searxng_search_and_read"), searxngSearchAndReadHandler)
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	ctx := srv.WithContext(context.Background(), session)
+
+	message := `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "searxng_search_and_read",
+		"arguments": {"query": "progress", "pages": 3}, "_meta": {"progressToken": "read-1"}}}`
+	if response, ok := srv.HandleMessage(ctx, json.RawMessage(message)).(mcp.JSONRPCResponse); !ok {
+		t.Fatalf("response = %+v", response)
+	}
+	close(session.notifications)
+
+	var steps []string
+	var total interface{}
+	for n := range session.notifications {
+		params := n.Params.AdditionalFields
+		total = params["total"]
+		if n.Method != "notifications/progress" || params["progressToken"] != "read-1" {
+			t.Errorf("notification = %s %v", n.Method, params)
+		}
+		steps = append(steps, params["message"].(string))
+		if params["progress"] != len(steps) {
+			t.Errorf("progress = %v, want %d", params["progress"], len(steps))
+		}
+	}
+	// The total drops from 1+3 to 1+2 once the search returned two results.
+	if total != 3 {
+		t.Errorf("final total = %v, want 3", total)
+	}
+	if len(steps) != 3 || steps[0] != `Searched "progress"` || !strings.HasPrefix(steps[2], "Read ") {
+		t.Errorf("steps = %q", steps)
+	}
+}
+
+func TestProgressWithoutToken(t *testing.T) {
+	var request mcp.CallToolRequest
+	if p := newProgressReporter(context.Background(), request, 3); p != nil {
+		t.Fatalf("reporter without a progress token = %+v", p)
+	}
+	var p *progressReporter
+	p.addTotal(1)
+	p.step("ignored")
+}
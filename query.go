@@ -0,0 +1,176 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// Engines fail silently on overlong queries: Google ignores words after the
+// 32nd and several engines reject long URLs. Queries above these limits are
+// shortened before they are sent.
+const (
+	maxQueryWords = 32
+	maxQueryChars = 400
+)
+
+// queryTransformation describes how an overlong query was shortened.
+type queryTransformation struct {
+	Original string `json:"original"`
+	Query    string `json:"query"`
+	// Steps are the applied steps in order: "removed_stopwords",
+	// "removed_duplicates", "truncated".
+	Steps   []string `json:"steps"`
+	Warning string   `json:"warning"`
+}
+
+// queryStopwords are dropped from overlong queries first; search engines
+// ignore most of them anyway.
+var queryStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "can": true, "do": true, "does": true,
+	"for": true, "from": true, "how": true, "i": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "me": true, "my": true, "of": true,
+	"on": true, "or": true, "please": true, "so": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "we": true, "what": true, "when": true,
+	"which": true, "with": true, "would": true, "you": true,
+	"и": true, "в": true, "во": true, "на": true, "с": true, "со": true,
+	"по": true, "для": true, "как": true, "что": true, "это": true, "не": true,
+	"der": true, "die": true, "das": true, "und": true, "ist": true, "mit": true,
+	"le": true, "la": true, "les": true, "et": true, "des": true, "du": true,
+}
+
+// shortenLongQuery reduces params.Query to the engine limits: stopwords and
+// repeated words are removed first, then the query is cut. Operators such
+// as site: or -exclude are always kept. It returns nil when the query fits.
+func shortenLongQuery(params *searxng.SearchParams) *queryTransformation {
+	words := strings.Fields(params.Query)
+	if queryFits(words) {
+		return nil
+	}
+	t := &queryTransformation{Original: params.Query}
+
+	var kept []string
+	for _, word := range words {
+		if isQueryOperator(word) || !queryStopwords[normalizeQueryWord(word)] {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) < len(words) {
+		t.Steps = append(t.Steps, "removed_stopwords")
+		words = kept
+	}
+
+	if !queryFits(words) {
+		seen := make(map[string]bool)
+		kept = kept[:0:0]
+		for _, word := range words {
+			key := normalizeQueryWord(word)
+			if !isQueryOperator(word) && seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, word)
+		}
+		if len(kept) < len(words) {
+			t.Steps = append(t.Steps, "removed_duplicates")
+			words = kept
+		}
+	}
+
+	t.Warning = "the query exceeded engine limits and was shortened to its key terms"
+	if !queryFits(words) {
+		words = truncateQuery(words)
+		t.Steps = append(t.Steps, "truncated")
+		t.Warning = "the query exceeded engine limits and was truncated; words after the limit were not searched, pass a shorter query with the key terms"
+	}
+
+	t.Query = strings.Join(words, " ")
+	params.Query = t.Query
+	return t
+}
+
+func queryFits(words []string) bool {
+	return len(words) <= maxQueryWords && len(strings.Join(words, " ")) <= maxQueryChars
+}
+
+// truncateQuery keeps the operators and the leading words that fit.
+func truncateQuery(words []string) []string {
+	var operators, terms []string
+	for _, word := range words {
+		if isQueryOperator(word) {
+			operators = append(operators, word)
+		} else {
+			terms = append(terms, word)
+		}
+	}
+	for len(terms) > 0 && !queryFits(append(append([]string{}, terms...), operators...)) {
+		terms = terms[:len(terms)-1]
+	}
+	return append(terms, operators...)
+}
+
+// queryOperators are the search operators engines understand, by their
+// prefix. Other words with a colon, such as 10:30 or std::vector, are
+// search terms.
+var queryOperators = []string{"site:", "filetype:", "ext:", "inurl:", "intitle:", "intext:", "allintitle:", "allinurl:", "related:"}
+
+func isQueryOperator(word string) bool {
+	if word == "OR" || (strings.HasPrefix(word, "-") && len(word) > 1) || isBang(word) {
+		return true
+	}
+	// ":fr" sets the language of the search.
+	if strings.HasPrefix(word, ":") && len(word) > 1 {
+		return true
+	}
+	lower := strings.ToLower(word)
+	for _, operator := range queryOperators {
+		if strings.HasPrefix(lower, operator) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBang reports whether word is a SearXNG bang: "!images" selects a
+// category, "!wp" an engine by its shortcut and "!!wp" an external bang.
+func isBang(word string) bool {
+	return strings.HasPrefix(word, "!") && strings.TrimLeft(word, "!") != ""
+}
+
+// queryBangs returns the bangs of query.
+func queryBangs(query string) []string {
+	var bangs []string
+	for _, word := range strings.Fields(query) {
+		if isBang(word) {
+			bangs = append(bangs, word)
+		}
+	}
+	return bangs
+}
+
+// rewriteExternalBangs turns the external bangs of params.Query into bangs
+// of the instance ("!!wp" into "!wp"): SearXNG answers external bangs with a
+// redirect to the external site, which is no search result. It returns the
+// rewritten bangs.
+func rewriteExternalBangs(params *searxng.SearchParams) []string {
+	words := strings.Fields(params.Query)
+	var rewritten []string
+	for i, word := range words {
+		if strings.HasPrefix(word, "!!") && isBang(word) {
+			rewritten = append(rewritten, word)
+			words[i] = "!" + strings.TrimLeft(word, "!")
+		}
+	}
+	if len(rewritten) > 0 {
+		params.Query = strings.Join(words, " ")
+	}
+	return rewritten
+}
+
+func normalizeQueryWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
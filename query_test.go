@@ -0,0 +1,188 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestShortenLongQuery(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	tests := []struct {
+		name      string
+		query     string
+		wantNil   bool
+		wantSteps []string
+		check     func(t *testing.T, query string)
+	}{
+		{
+			name:    "short query is untouched",
+			query:   "how to use generics in go",
+			wantNil: true,
+		},
+		{
+			name:      "stopwords are removed first",
+			query:     "what is the best way to do " + strings.Repeat("x ", 20) + "in the go language for a web server with the router and the database",
+			wantSteps: []string{"removed_stopwords"},
+			check: func(t *testing.T, query string) {
+				if strings.Contains(query, " the ") {
+					t.Errorf("query %q still has stopwords", query)
+				}
+			},
+		},
+		{
+			name:      "duplicates then truncation, operators kept",
+			query:     long + strings.Repeat("term ", 40) + "site:go.dev -reddit",
+			wantSteps: []string{"removed_duplicates"},
+			check: func(t *testing.T, query string) {
+				if query != "word term site:go.dev -reddit" {
+					t.Errorf("query = %q", query)
+				}
+			},
+		},
+		{
+			name:      "truncated",
+			query:     numberedWords(50) + " site:go.dev",
+			wantSteps: []string{"truncated"},
+			check: func(t *testing.T, query string) {
+				words := strings.Fields(query)
+				if len(words) != maxQueryWords || words[len(words)-1] != "site:go.dev" {
+					t.Errorf("query = %q, want %d words ending with the operator", query, maxQueryWords)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := searxng.SearchParams{Query: tt.query}
+			got := shortenLongQuery(&params)
+			if tt.wantNil {
+				if got != nil || params.Query != tt.query {
+					t.Errorf("got %+v, want the query untouched", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("query was not shortened")
+			}
+			if !reflect.DeepEqual(got.Steps, tt.wantSteps) {
+				t.Errorf("steps = %q, want %q", got.Steps, tt.wantSteps)
+			}
+			if got.Original != tt.query || params.Query != got.Query || got.Warning == "" {
+				t.Errorf("transformation = %+v", got)
+			}
+			if !queryFits(strings.Fields(params.Query)) {
+				t.Errorf("query %q is still too long", params.Query)
+			}
+			tt.check(t, params.Query)
+		})
+	}
+}
+
+func numberedWords(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = "w" + strconv.Itoa(i)
+	}
+	return strings.Join(words, " ")
+}
+
+func TestBangs(t *testing.T) {
+	if got, want := queryBangs("!images !!wp cats ! site:go.dev"), []string{"!images", "!!wp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queryBangs = %q, want %q", got, want)
+	}
+
+	params := searxng.SearchParams{Query: "!!wp  golang !!"}
+	if got, want := rewriteExternalBangs(&params), []string{"!!wp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rewritten = %q, want %q", got, want)
+	}
+	if params.Query != "!wp golang !!" {
+		t.Errorf("query = %q", params.Query)
+	}
+}
+
+func TestSearchV2Bangs(t *testing.T) {
+	fake := useFakeInstance(t)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "!!wp golang"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("q") != "!wp golang" || req.Query.Has("engines") || req.Query.Has("categories") {
+		t.Errorf("request = %v, want the bang alone to pick the engine", req.Query)
+	}
+
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if !reflect.DeepEqual(response.Meta.Bangs, []string{"!wp"}) || !reflect.DeepEqual(response.Meta.ExternalBangs, []string{"!!wp"}) || len(response.Meta.Warnings) != 1 {
+		t.Errorf("meta = %+v", response.Meta)
+	}
+	result, err = callTool(t, searxngSearchHandler, map[string]interface{}{"query": "!!wp golang"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var v1 searchV1Response
+	decodeResult(t, result, &v1)
+	if !reflect.DeepEqual(v1.ExternalBangs, []string{"!!wp"}) {
+		t.Errorf("search external_bangs = %q", v1.ExternalBangs)
+	}
+}
+
+func TestIsQueryOperator(t *testing.T) {
+	for word, want := range map[string]bool{
+		"site:go.dev":  true,
+		"Filetype:pdf": true,
+		"-reddit":      true,
+		":fr":          true,
+		"OR":           true,
+		"!gh":          true,
+		"10:30":        false,
+		"std::vector":  false,
+		"http://x.org": false,
+		"-":            false,
+	} {
+		if got := isQueryOperator(word); got != want {
+			t.Errorf("isQueryOperator(%q) = %v, want %v", word, got, want)
+		}
+	}
+}
+
+func TestShortenedQueryReported(t *testing.T) {
+	useFakeInstance(t)
+	query := numberedWords(50)
+
+	result, err := callTool(t, searxngSearchHandler, map[string]interface{}{"query": query})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var v1 searchV1Response
+	decodeResult(t, result, &v1)
+	if v1.QueryTransformation == nil || v1.QueryTransformation.Original != query {
+		t.Errorf("search query_transformation = %+v", v1.QueryTransformation)
+	}
+
+	result, err = callTool(t, searxngNewsSearchHandler, map[string]interface{}{"query": query})
+	if err != nil || result.IsError {
+		t.Fatalf("news search: %+v, %v", result, err)
+	}
+	var news newsSearchResponse
+	decodeResult(t, result, &news)
+	if news.QueryTransformation == nil || news.QueryTransformation.Original != query {
+		t.Errorf("news query_transformation = %+v", news.QueryTransformation)
+	}
+
+	result, err = callTool(t, searxngImageSearchHandler, map[string]interface{}{"query": query})
+	if err != nil || result.IsError {
+		t.Fatalf("image search: %+v, %v", result, err)
+	}
+	var images imageSearchResponse
+	decodeResult(t, result, &images)
+	if images.QueryTransformation == nil || images.QueryTransformation.Original != query {
+		t.Errorf("image query_transformation = %+v", images.QueryTransformation)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// rawMIMETypes are the MIME types of raw payloads whose response named
+// none.
+var rawMIMETypes = map[string]string{
+	"json": "application/json",
+	"csv":  "text/csv",
+	"rss":  "application/rss+xml",
+}
+
+func rawFormatOption() mcp.ToolOption {
+	return mcp.WithString("raw_format",
+		mcp.Description("Return the instance response in this SearXNG format as is, as an embedded resource with its MIME type, e.g. csv or rss to pipe the results into other tools. Skips result processing: format, fields, max_results and the filters do not apply. The instance must enable the format"),
+		mcp.Enum(searxng.RawFormats...),
+	)
+}
+
+// rawSearchResult runs a search prepared from a call with raw_format and
+// returns the payload of the instance unparsed. The structured content is
+// the meta block of the search, without results.
+func rawSearchResult(ctx context.Context, params searxng.SearchParams, format string) (*mcp.CallToolResult, error) {
+	meta := prepareSearch(ctx, &params)
+	if instanceMode == instanceAggregate {
+		meta.Warnings = append(meta.Warnings, "raw_format searches one instance: the first healthy one")
+	}
+	start := time.Now()
+	raw, cachedAt, err := cachedSearch(ctx, "raw_"+format, params, func() (*searxng.RawResponse, error) {
+		return activeInstance(ctx).SearchRaw(ctx, params, format)
+	})
+	if err != nil {
+		return upstreamErrorResult("search", err), nil
+	}
+	meta.ElapsedMS = time.Since(start).Milliseconds()
+	if !cachedAt.IsZero() {
+		meta.CachedAt = cachedAt.UTC().Format(time.RFC3339)
+	}
+
+	mimeType, _, err := mime.ParseMediaType(raw.ContentType)
+	if err != nil {
+		mimeType = rawMIMETypes[format]
+	}
+	uri := "searxng://search?" + url.Values{"q": {params.Query}, "format": {format}}.Encode()
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Text:     strings.ToValidUTF8(string(raw.Body), "�"),
+		})},
+		StructuredContent: searchV2Response{Query: params.Query, Meta: meta},
+	}, nil
+}
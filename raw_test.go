@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRawFormat(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []interface{}{
+			map[string]interface{}{"url": "https://a.example/post", "title": "A & B", "content": "text", "engine": "bing"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "raw_format": "rss", "fields": "url"})
+	if err != nil || result.IsError {
+		t.Fatalf("raw search: %+v, %v", result, err)
+	}
+	resource, ok := result.Content[0].(mcp.EmbeddedResource)
+	if !ok {
+		t.Fatalf("content = %#v", result.Content[0])
+	}
+	contents, ok := resource.Resource.(mcp.TextResourceContents)
+	if !ok || contents.MIMEType != "application/rss+xml" || contents.URI != "searxng://search?format=rss&q=q" {
+		t.Fatalf("resource = %+v", resource.Resource)
+	}
+	if !strings.Contains(contents.Text, "<title>A &amp; B</title><link>https://a.example/post</link>") {
+		t.Errorf("payload = %s", contents.Text)
+	}
+	response, ok := result.StructuredContent.(searchV2Response)
+	if !ok || response.Query != "q" || response.Meta.Instance == "" {
+		t.Errorf("structured content = %+v", response)
+	}
+	if request, _ := fake.LastRequest("/search"); request.Query.Get("format") != "rss" {
+		t.Errorf("searched with format %q", request.Query.Get("format"))
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "raw_format": "html"})
+	if err != nil || errorCode(result) != errorInvalidParams {
+		t.Errorf("raw_format html: %+v, %v", result, err)
+	}
+}
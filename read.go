@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultReadPages   = 3
+	maxReadPages       = 5
+	maxPrefetchPages   = 10
+	defaultExcerptSize = 2000
+	maxExcerptSize     = 10000
+	// minMainTextWords is the size below which a block is taken for
+	// navigation or boilerplate rather than main text.
+	minMainTextWords = 6
+)
+
+// readResult is a search result with an excerpt of its page.
+type readResult struct {
+	EvidenceID string `json:"evidence_id,omitempty"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Content    string `json:"content,omitempty"`
+	Engine     string `json:"engine,omitempty"`
+	// PageTitle is the title of the fetched page.
+	PageTitle string `json:"page_title,omitempty"`
+	Excerpt   string `json:"excerpt,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Quality is the readability score of the page, from 0 to 1, set
+	// with prefetch.
+	Quality float64 `json:"quality,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+type searchAndReadResponse struct {
+	Query   string       `json:"query"`
+	Meta    searchMeta   `json:"meta"`
+	Results []readResult `json:"results"`
+	Answers []string     `json:"answers,omitempty"`
+	// AnswerChecks are the answers checked against their sources, set
+	// with verify_answers.
+	AnswerChecks []answerCheck `json:"answer_checks,omitempty"`
+}
+
+func searxngSearchAndReadHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	params, err := searchParamsFromArguments(ctx, request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	pages := defaultReadPages
+	if n, ok, err := intArgument(request.GetArguments(), "pages"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > 0 {
+		pages = min(n, maxReadPages)
+	}
+	excerptSize := defaultExcerptSize
+	if n, ok, err := intArgument(request.GetArguments(), "excerpt_chars"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > 0 {
+		excerptSize = min(n, maxExcerptSize)
+	}
+	prefetch := 0
+	if n, ok, err := intArgument(request.GetArguments(), "prefetch"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > pages {
+		prefetch = min(n, maxPrefetchPages)
+	}
+	fetched := max(pages, prefetch)
+
+	dates, err := publishedRangeFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	dates.narrowTimeRange(&params)
+	sites, err := siteFilterFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	sites.addToQuery(&params)
+	near, err := applyNear(ctx, request.GetArguments(), &params)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	verify, _, err := boolArgument(request.GetArguments(), "verify_answers")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	skipSeen, _, err := boolArgument(request.GetArguments(), "skip_seen")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	dryRun, err := isDryRun(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if dryRun {
+		meta := prepareSearch(ctx, &params)
+		meta.addNear(near)
+		return dryRunResult(ctx, params, meta, request.GetArguments(), searchOptionalArguments...)
+	}
+
+	progress := newProgressReporter(ctx, request, 1+fetched)
+	result, meta, err := runSearch(ctx, params)
+	if err != nil {
+		return upstreamErrorResult("search", err), nil
+	}
+	progress.step(fmt.Sprintf("Searched %q", params.Query))
+	meta.PipelineRemovedResults = processResults(stateOf(ctx).pipeline, result)
+	meta.BlockedResults = processResults(stateOf(ctx).blockedImages, result)
+	meta.ReturnedResults = len(result.Results)
+	dates.applyTo(result, &meta)
+	sites.applyTo(result, &meta)
+	meta.addNear(near)
+
+	annotated := enrichResults(result.Results)
+	if skipSeen {
+		annotated, meta.SkippedSeenResults = evidence.dropSeen(ctx, annotated)
+	}
+	if len(annotated) > fetched {
+		annotated = annotated[:fetched]
+	}
+	progress.addTotal(len(annotated) - fetched)
+
+	reads := make([]readResult, len(annotated))
+	var wg sync.WaitGroup
+	for i, r := range annotated {
+		reads[i] = readResult{Title: r.Title, URL: r.URL, Content: r.Content, Engine: r.Engine}
+		wg.Add(1)
+		go func(read *readResult) {
+			defer wg.Done()
+			defer progress.step("Read " + read.URL)
+			page, err := fetchPage(ctx, read.URL)
+			if err != nil {
+				read.Error = err.Error()
+				return
+			}
+			text := mainText(page)
+			read.PageTitle = page.Title
+			read.Excerpt, read.Truncated = excerpt(text, excerptSize)
+			if prefetch > 0 {
+				read.Quality = readability(text, page.Size)
+			}
+		}(&reads[i])
+	}
+	wg.Wait()
+
+	if prefetch > 0 {
+		meta.PrefetchedPages = len(reads)
+		reads, annotated = bestReads(reads, annotated, pages)
+	}
+	meta.ReturnedResults = len(annotated)
+
+	response := searchAndReadResponse{
+		Query:   result.Query,
+		Meta:    meta,
+		Results: reads,
+		Answers: answerTexts(result.Answers),
+	}
+	if verify && len(result.Answers) > 0 {
+		response.AnswerChecks = verifyAnswers(ctx, result.Answers)
+	}
+
+	evidence.addResults(ctx, result.Query, annotated)
+	for i := range reads {
+		reads[i].EvidenceID = annotated[i].EvidenceID
+	}
+
+	return structuredResult(response)
+}
+
+// mainText joins the blocks of the page that look like running text,
+// falling back to the whole page when none do. PDF documents have no
+// boilerplate and are taken whole.
+func mainText(page *Page) string {
+	if page.PDFPages > 0 {
+		return page.Text()
+	}
+	var blocks []string
+	for _, block := range page.Blocks {
+		if len(strings.Fields(block)) >= minMainTextWords {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		return page.Text()
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// readability scores the main text of a page from 0 to 1: half for its
+// length, full from readableWords words, and half for its share of the
+// HTML document, full from readableTextRatio. Pages that rank high but
+// are mostly markup, ads or navigation score low.
+func readability(text string, size int) float64 {
+	const (
+		readableWords     = 400
+		readableTextRatio = 0.25
+	)
+	words := float64(len(strings.Fields(text)))
+	if words == 0 {
+		return 0
+	}
+	ratio := 1.0
+	if size > 0 {
+		ratio = float64(len(text)) / float64(size)
+	}
+	score := 0.5*min(words/readableWords, 1) + 0.5*min(ratio/readableTextRatio, 1)
+	return math.Round(score*100) / 100
+}
+
+// bestReads returns the n pages of highest quality, best first, with
+// their results; pages that failed come last. Ties keep the search order.
+func bestReads(reads []readResult, results []annotatedResult, n int) ([]readResult, []annotatedResult) {
+	order := make([]int, len(reads))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return reads[order[a]].Quality > reads[order[b]].Quality
+	})
+	if len(order) > n {
+		order = order[:n]
+	}
+	bestReads := make([]readResult, len(order))
+	bestResults := make([]annotatedResult, len(order))
+	for i, j := range order {
+		bestReads[i], bestResults[i] = reads[j], results[j]
+	}
+	return bestReads, bestResults
+}
+
+// excerpt cuts text to at most size characters at a word boundary.
+func excerpt(text string, size int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text, false
+	}
+	cut := size
+	for i := size; i > size/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(runes[:cut])) + " …", true
+}
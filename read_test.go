@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchAndReadHandler(t *testing.T) {
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Go generics</title></head><body>
+<nav><a href="/">Home</a></nav>
+<p>Menu</p>
+<p>Generics let you write functions and types that work with any of a set of types.</p>
+<p>` + strings.Repeat("More text about type parameters. ", 100) + `</p>
+</body></html>`))
+	}))
+	defer pages.Close()
+
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"query": "go generics",
+		"results": []map[string]interface{}{
+			{"title": "Tutorial", "url": pages.URL + "/tutorial", "engine": "google"},
+			{"title": "Broken", "url": pages.URL + "/broken", "engine": "google"},
+			{"title": "Not read", "url": pages.URL + "/third", "engine": "google"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchAndReadHandler, map[string]interface{}{
+		"query":         "go generics",
+		"pages":         float64(2),
+		"excerpt_chars": float64(300),
+	})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	var response searchAndReadResponse
+	decodeResult(t, result, &response)
+	if len(response.Results) != 2 || response.Meta.ReturnedResults != 2 {
+		t.Fatalf("results = %+v, want the top 2", response.Results)
+	}
+	read := response.Results[0]
+	if read.PageTitle != "Go generics" || !strings.HasPrefix(read.Excerpt, "Generics let you write") {
+		t.Errorf("read = %+v", read)
+	}
+	if !read.Truncated || len([]rune(read.Excerpt)) > 302 {
+		t.Errorf("excerpt of %d characters, truncated %v", len([]rune(read.Excerpt)), read.Truncated)
+	}
+	if strings.Contains(read.Excerpt, "Menu") {
+		t.Errorf("excerpt %q contains boilerplate", read.Excerpt)
+	}
+	if response.Results[1].Error == "" {
+		t.Errorf("broken page has no error: %+v", response.Results[1])
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got, truncated := excerpt("short text", 100); got != "short text" || truncated {
+		t.Errorf("excerpt = %q, %v", got, truncated)
+	}
+	if got, truncated := excerpt("привет мир как дела", 12); got != "привет мир …" || !truncated {
+		t.Errorf("excerpt = %q, %v", got, truncated)
+	}
+}
+
+func TestSearchAndReadPrefetch(t *testing.T) {
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/spam":
+			w.Write([]byte(`<html><body>` + strings.Repeat(`<div class="ad"><a href="/buy">Buy now</a></div>`, 200) + `</body></html>`))
+		case "/article":
+			w.Write([]byte(`<html><body><article><p>` + strings.Repeat("A thorough explanation of type parameters in Go. ", 80) + `</p></article></body></html>`))
+		default:
+			w.Write([]byte(`<html><body><p>A short note about generics in Go, nothing more.</p></body></html>`))
+		}
+	}))
+	defer pages.Close()
+
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"query": "go generics",
+		"results": []map[string]interface{}{
+			{"title": "Spam", "url": pages.URL + "/spam", "engine": "google"},
+			{"title": "Note", "url": pages.URL + "/note", "engine": "google"},
+			{"title": "Article", "url": pages.URL + "/article", "engine": "google"},
+		},
+	})
+
+	result, err := callTool(t, searxngSearchAndReadHandler, map[string]interface{}{
+		"query":    "go generics",
+		"pages":    float64(1),
+		"prefetch": float64(3),
+	})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	var response searchAndReadResponse
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 || response.Results[0].Title != "Article" {
+		t.Fatalf("results = %+v, want the article only", response.Results)
+	}
+	if response.Meta.PrefetchedPages != 3 || response.Meta.ReturnedResults != 1 {
+		t.Errorf("meta = %+v", response.Meta)
+	}
+	if q := response.Results[0].Quality; q < 0.9 || response.Results[0].EvidenceID == "" {
+		t.Errorf("result = %+v", response.Results[0])
+	}
+}
+
+func TestReadability(t *testing.T) {
+	text := strings.Repeat("word ", 400)
+	if got := readability(text, len(text)*2); got != 1 {
+		t.Errorf("readability of a long text page = %v, want 1", got)
+	}
+	if got := readability("Buy now", 10000); got > 0.1 {
+		t.Errorf("readability of a markup page = %v", got)
+	}
+	if got := readability("", 100); got != 0 {
+		t.Errorf("readability of an empty page = %v", got)
+	}
+}
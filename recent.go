@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const recentSearchesSize = 50
+
+type recentSearch struct {
+	Time    time.Time `json:"time"`
+	Session string    `json:"-"`
+	Tool    string    `json:"tool"`
+	Query   string    `json:"query,omitempty"`
+	// Params are the other arguments of the call.
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Results is the number of results returned, nil when the tool does
+	// not return a result list.
+	Results  *int          `json:"results,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+	// Bytes is the size of the response, Tokens an estimate of its cost.
+	Bytes  int `json:"response_bytes"`
+	Tokens int `json:"approx_tokens"`
+}
+
+// recentLog is a fixed size ring buffer of the latest tool calls.
+type recentLog struct {
+	mu      sync.Mutex
+	entries []recentSearch
+	next    int
+	full    bool
+	// private drops query texts before they are stored.
+	private bool
+}
+
+var recentSearches = newRecentLog(recentSearchesSize)
+
+func newRecentLog(size int) *recentLog {
+	return &recentLog{entries: make([]recentSearch, size)}
+}
+
+func (l *recentLog) add(entry recentSearch) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.private {
+		entry.Query = ""
+		entry.Params = nil
+	}
+	if redactor := currentState().redactor; redactor != nil {
+		entry.Query = redactor.redact(entry.Query)
+		if entry.Params != nil {
+			entry.Params, _ = redactor.redactValue(entry.Params).(map[string]interface{})
+		}
+		entry.Error = redactor.redact(entry.Error)
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// list returns the stored entries, newest first.
+func (l *recentLog) list() []recentSearch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+	out := make([]recentSearch, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// redactedText replaces the masked parts of queries.
+const redactedText = "[redacted]"
+
+// builtinRedactPatterns mask what queries commonly leak: email addresses,
+// bearer tokens and API keys, long hexadecimal or base64 secrets, card and
+// international phone numbers.
+var builtinRedactPatterns = []string{
+	`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`,
+	`(?i)\bbearer\s+[\w.~+/-]+=*`,
+	`(?i)\b(?:sk|pk|ghp|gho|ghs|xox[abprs]|glpat|AKIA)[-_]?[A-Za-z0-9_-]{12,}`,
+	`\b[A-Fa-f0-9]{32,}\b`,
+	`\b[A-Za-z0-9+/_-]{40,}={0,2}`,
+	`\b\d(?:[ -]?\d){12,18}\b`,
+	`\+\d[\d ().-]{7,}\d`,
+}
+
+// urlQueryPattern matches the query parameter of SearXNG URLs in error
+// messages: it is the whole query, escaped, so patterns would miss it.
+var urlQueryPattern = regexp.MustCompile(`([?&]q=)[^&\s"']*`)
+
+// queryRedactor masks sensitive parts of queries before they reach the
+// logs, the error stats and the history. A nil redactor masks nothing.
+type queryRedactor struct {
+	patterns []*regexp.Regexp
+}
+
+// newQueryRedactor compiles the builtin patterns and the configured ones.
+func newQueryRedactor(patterns []string) (*queryRedactor, error) {
+	r := &queryRedactor{}
+	for _, pattern := range builtinRedactPatterns {
+		r.patterns = append(r.patterns, regexp.MustCompile(pattern))
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// redact masks the matches of every pattern in s, and the queries of URLs.
+func (r *queryRedactor) redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	s = urlQueryPattern.ReplaceAllString(s, "${1}"+redactedText)
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedText)
+	}
+	return s
+}
+
+// redactValue masks the strings of a tool argument.
+func (r *queryRedactor) redactValue(value interface{}) interface{} {
+	if r == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		return r.redact(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.redactValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			out[name] = r.redactValue(item)
+		}
+		return out
+	}
+	return value
+}
+
+// redactingWriter is the log output masking each log line.
+type redactingWriter struct {
+	w io.Writer
+	r *queryRedactor
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
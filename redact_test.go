@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestQueryRedactor(t *testing.T) {
+	r, err := newQueryRedactor([]string{`(?i)\bjohn smith\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"password reset for jane.doe@example.com", "password reset for [redacted]"},
+		{"curl -H 'Authorization: Bearer eyJhbGciOi.abc' api", "curl -H 'Authorization: [redacted]' api"},
+		{"is sk-proj-abcdefghijklmnop valid", "is [redacted] valid"},
+		{"commit 0123456789abcdef0123456789abcdef01234567", "commit [redacted]"},
+		{"card 4111 1111 1111 1111 declined", "card [redacted] declined"},
+		{"call +49 30 1234567 now", "call [redacted] now"},
+		{"John Smith address", "[redacted] address"},
+		{"weather in berlin 2026-10-16", "weather in berlin 2026-10-16"},
+		{`Get "http://127.0.0.1:8080/search?q=john%40example.com&format=json": EOF`, `Get "http://127.0.0.1:8080/search?q=[redacted]&format=json": EOF`},
+	}
+	for _, tt := range tests {
+		if got := r.redact(tt.in); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := newQueryRedactor([]string{"("}); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	var off *queryRedactor
+	if got := off.redact("a@example.com"); got != "a@example.com" {
+		t.Errorf("nil redactor changed the text: %q", got)
+	}
+}
+
+func TestRedactedHistoryAndLogs(t *testing.T) {
+	r, _ := newQueryRedactor(nil)
+	useState(t, func(s *serverState) { s.redactor = r })
+
+	recent := newRecentLog(2)
+	recent.add(recentSearch{
+		Tool:   "searxng_search_v2",
+		Query:  "invoice of a@example.com",
+		Params: map[string]interface{}{"site": "b@example.com", "page": 2.0},
+		Error:  "search for a@example.com failed",
+	})
+	got := recent.list()[0]
+	if got.Query != "invoice of [redacted]" || got.Params["site"] != redactedText || got.Params["page"] != 2.0 || got.Error != "search for [redacted] failed" {
+		t.Errorf("entry = %+v", got)
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(redactingWriter{w: &buf, r: r}, "", 0)
+	logger.Printf("Search of %s failed", "a@example.com")
+	if buf.String() != "Search of [redacted] failed\n" {
+		t.Errorf("log = %q", buf.String())
+	}
+}
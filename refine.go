@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// refineResponse holds the rephrasings of a query, without its results.
+type refineResponse struct {
+	Query       string   `json:"query"`
+	Corrections []string `json:"corrections"`
+	Suggestions []string `json:"suggestions"`
+	// Completions are the expansions of the autocomplete backend, empty
+	// when the instance has autocomplete disabled.
+	Completions []string `json:"completions"`
+	// ResultCount is the number of results of the first page, to tell
+	// whether the query itself finds anything.
+	ResultCount         int                          `json:"result_count"`
+	UnresponsiveEngines []searxng.UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+}
+
+func searxngRefineQueryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, _ := request.GetArguments()["query"].(string)
+	if query = strings.TrimSpace(query); query == "" {
+		return invalidArgumentsResult(errors.New("query must be a non-empty string")), nil
+	}
+
+	params := searxng.SearchParams{
+		Query:    query,
+		Language: stateOf(ctx).language,
+	}
+	if engines, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.Engines = engines
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if language, ok := request.GetArguments()["language"].(string); ok && language != "" {
+		params.Language = language
+	}
+
+	dryRun, err := isDryRun(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+
+	meta := prepareSearch(ctx, &params)
+	if dryRun {
+		return dryRunResult(ctx, params, meta, request.GetArguments(), "engines", "language")
+	}
+
+	// The autocomplete backend answers faster than the engines; ask it
+	// while the search runs. Offline, only the cached search answers.
+	var completions []string
+	var wg sync.WaitGroup
+	if !offlineMode() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, autocompleteTimeout)
+			defer cancel()
+			completions, _ = activeInstance(ctx).Autocomplete(ctx, query)
+		}()
+	}
+
+	result, cachedAt, err := cachedSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	wg.Wait()
+	if err != nil {
+		return upstreamErrorResult("refine search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
+	}
+
+	seen := map[string]bool{strings.ToLower(query): true}
+	response := refineResponse{
+		Query:               query,
+		Corrections:         uniqueRephrasings(result.Corrections, seen),
+		Suggestions:         uniqueRephrasings(result.Suggestions, seen),
+		Completions:         uniqueRephrasings(completions, seen),
+		ResultCount:         len(result.Results),
+		UnresponsiveEngines: result.UnresponsiveEngines,
+	}
+	return structuredResult(response)
+}
+
+// uniqueRephrasings returns the candidates not seen yet, ignoring case and
+// surrounding spaces, and marks them seen. It is never nil.
+func uniqueRephrasings(candidates []string, seen map[string]bool) []string {
+	unique := []string{}
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		key := strings.ToLower(candidate)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, candidate)
+	}
+	return unique
+}
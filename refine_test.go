@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRefineQuery(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"results":     []map[string]interface{}{{"title": "Result", "url": "https://example.com/", "engine": "google"}},
+		"corrections": []string{"golang generics"},
+		"suggestions": []string{"Golang Generics", "golang generics tutorial", " go type parameters "},
+	})
+	fake.Handle("/autocompleter", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`["golang generic", ["golang generics tutorial", "golang generic constraints"]]`))
+	})
+
+	result, err := callTool(t, searxngRefineQueryHandler, map[string]interface{}{"query": "golang generic"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var response refineResponse
+	decodeResult(t, result, &response)
+	want := refineResponse{
+		Query:       "golang generic",
+		Corrections: []string{"golang generics"},
+		Suggestions: []string{"golang generics tutorial", "go type parameters"},
+		Completions: []string{"golang generic constraints"},
+		ResultCount: 1,
+	}
+	if !reflect.DeepEqual(response, want) {
+		t.Errorf("response = %+v, want %+v", response, want)
+	}
+
+	if result, _ := callTool(t, searxngRefineQueryHandler, map[string]interface{}{"query": " "}); !result.IsError {
+		t.Error("empty query accepted")
+	}
+}
+
+func TestRefineQueryOffline(t *testing.T) {
+	fake := useFakeInstance(t)
+	cache := useCache(t, "", time.Hour, false)
+	arguments := map[string]interface{}{"query": "golang generic"}
+	callTool(t, searxngRefineQueryHandler, arguments)
+
+	cache.offline = true
+	result, err := callTool(t, searxngRefineQueryHandler, arguments)
+	if err != nil || result.IsError {
+		t.Fatalf("offline refine: %+v, %v", result, err)
+	}
+	autocompletes := 0
+	for _, req := range fake.Requests() {
+		if req.Path == "/autocompleter" {
+			autocompletes++
+		}
+	}
+	if autocompletes != 1 {
+		t.Errorf("autocomplete requests = %d, want none offline", autocompletes-1)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// configReloadDelay lets editors finish writing the config file before a
+// change reloads it: they often write it in several steps.
+const configReloadDelay = 500 * time.Millisecond
+
+// configReloader reloads the -config file on SIGHUP and, with
+// -watch-config, when it changes: instances, profiles, filters, engine
+// policies and the rest of the config apply to the next tool calls while
+// the sessions stay open. A config that fails to load is logged and the
+// current one kept. The canary mirror needs a restart.
+type configReloader struct {
+	path string
+	// language is -default-language, which wins over the config.
+	language string
+	// instanceFlags is set when -searxng or -searxng-fallback was given,
+	// which win over the instances of the config.
+	instanceFlags bool
+	// logOutput is where logs go before redaction, with
+	// -log-redact-queries.
+	logOutput io.Writer
+	redact    bool
+	// newClient builds the client of an instance URL as main does.
+	newClient func(instanceURL string) *searxng.Client
+
+	mu sync.Mutex
+}
+
+// reload loads the config file and publishes a new serverState built from
+// it; the calls in flight keep the state they started with. Instances
+// whose URL did not change keep their client, with its circuit breaker and
+// connections; engine quotas keep their usage.
+func (r *configReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := loadConfig(r.path)
+	if err != nil {
+		return err
+	}
+	next, err := newServerState(cfg)
+	if err != nil {
+		return err
+	}
+	var redactor *queryRedactor
+	if r.redact {
+		if redactor, err = newQueryRedactor(cfg.RedactPatterns); err != nil {
+			return err
+		}
+	}
+	language := r.language
+	if language == "" {
+		language = cfg.DefaultLanguage
+	}
+	if language == "" {
+		language = autoLanguage
+	}
+	if err := checkDefaultLanguage(language); err != nil {
+		return err
+	}
+
+	current := currentState()
+	next.policies.adoptUsage(current.policies)
+	next.client, next.fallbacks = current.client, current.fallbacks
+	next.language, next.redactor = language, current.redactor
+	if redactor != nil {
+		next.redactor = redactor
+		log.SetOutput(redactingWriter{w: r.logOutput, r: redactor})
+	}
+	if len(cfg.Instances) > 0 && !r.instanceFlags {
+		next.client, next.fallbacks = r.replaceInstances(current, cfg.Instances)
+	}
+	state.Store(next)
+	return nil
+}
+
+// replaceInstances returns the clients of urls, reusing those of the
+// current instances.
+func (r *configReloader) replaceInstances(current *serverState, urls []string) (*searxng.Client, []*searxng.Client) {
+	reused := make(map[string]*searxng.Client)
+	for _, client := range current.allInstances() {
+		reused[client.BaseURL] = client
+	}
+	clients := make([]*searxng.Client, 0, len(urls))
+	for _, instanceURL := range urls {
+		client, ok := reused[strings.TrimSuffix(instanceURL, "/")]
+		if !ok {
+			client = r.newClient(instanceURL)
+			log.Printf("Config reload: added SearXNG instance %s", client.BaseURL)
+		}
+		clients = append(clients, client)
+	}
+	for baseURL, client := range reused {
+		if !slices.Contains(clients, client) {
+			log.Printf("Config reload: removed SearXNG instance %s", baseURL)
+		}
+	}
+	return clients[0], clients[1:]
+}
+
+func (r *configReloader) reloadAndLog(reason string) {
+	if err := r.reload(); err != nil {
+		log.Printf("Config reload on %s failed, keeping the current config: %v", reason, err)
+		return
+	}
+	log.Printf("Reloaded config %s on %s", r.path, reason)
+}
+
+// handleSignals reloads the config on SIGHUP until ctx ends.
+func (r *configReloader) handleSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				r.reloadAndLog("SIGHUP")
+			}
+		}
+	}()
+}
+
+// watch reloads the config when its file changes until ctx ends. It
+// watches the directory: editors and config management replace files
+// instead of writing them in place.
+func (r *configReloader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error watching config: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("error watching config: %w", err)
+	}
+	path := filepath.Clean(r.path)
+	go func() {
+		defer watcher.Close()
+		var pending <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == path && event.Has(fsnotify.Write|fsnotify.Create) {
+					pending = time.After(configReloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Config watch error: %v", err)
+			case <-pending:
+				pending = nil
+				if _, err := os.Stat(r.path); err == nil {
+					r.reloadAndLog("file change")
+				}
+			}
+		}
+	}()
+	return nil
+}
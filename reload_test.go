@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestConfigReload(t *testing.T) {
+	primary := searxng.New("http://primary.example")
+	useState(t, func(s *serverState) { s.client, s.fallbacks = primary, nil })
+	before := currentState()
+	path := filepath.Join(t.TempDir(), "config.json")
+	reloader := &configReloader{path: path, newClient: func(u string) *searxng.Client { return searxng.New(u) }}
+
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{
+		"instances": ["http://primary.example/", "http://backup.example"],
+		"profiles": {"dev": {"engines": ["github"]}},
+		"deny": {"engines": ["yandex"]},
+		"default_language": "de"
+	}`)
+	if err := reloader.reload(); err != nil {
+		t.Fatal(err)
+	}
+	s := currentState()
+	if _, ok := s.config.Profiles["dev"]; !ok || s.language != "de" {
+		t.Errorf("config = %+v, language %q", s.config, s.language)
+	}
+	if err := s.denylist.check(context.Background(), searxng.SearchParams{Engines: []string{"yandex"}}); err == nil {
+		t.Error("denylist not reloaded")
+	}
+	if s.client != primary || len(s.fallbacks) != 1 || s.fallbacks[0].BaseURL != "http://backup.example" {
+		t.Errorf("instances = %s, %v", s.client.BaseURL, s.fallbacks)
+	}
+	// A call that started before the reload keeps its snapshot.
+	ctx := context.WithValue(context.Background(), stateKey{}, before)
+	if stateOf(ctx).denylist != nil || stateOf(withState(ctx)) != before {
+		t.Error("the snapshot of a call changed with the reload")
+	}
+
+	write(`{"profiles": {"dev": {}}}`)
+	if err := reloader.reload(); err == nil {
+		t.Error("invalid config reloaded")
+	}
+	if config := currentState().config; len(config.Profiles) != 1 || len(config.Instances) != 2 {
+		t.Errorf("failed reload replaced the config: %+v", config)
+	}
+}
+
+func TestConfigReloadKeepsInstanceFlags(t *testing.T) {
+	primary := searxng.New("http://flag.example")
+	useState(t, func(s *serverState) { s.client, s.fallbacks = primary, nil })
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"instances": ["http://config.example"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloader := &configReloader{path: path, instanceFlags: true, newClient: func(u string) *searxng.Client { return searxng.New(u) }}
+	if err := reloader.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if s := currentState(); s.client != primary || len(s.fallbacks) != 0 {
+		t.Errorf("-searxng replaced by the config: %s, %v", s.client.BaseURL, s.fallbacks)
+	}
+}
+
+func TestConfigWatch(t *testing.T) {
+	useState(t, func(s *serverState) {})
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloader := &configReloader{path: path}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := reloader.watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the file as editors do.
+	next := path + ".tmp"
+	if err := os.WriteFile(next, []byte(`{"blocked_image_domains": ["ads.example"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(next, path); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if len(currentState().config.BlockedImageDomains) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("config not reloaded after the file changed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestConfigReloadDuringCalls(t *testing.T) {
+	fake := useFakeInstance(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"instances": ["`+fake.URL+`"], "deny": {"engines": ["yandex"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reloader := &configReloader{path: path, newClient: func(u string) *searxng.Client { return searxng.New(u) }}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if err := reloader.reload(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := callTool(t, snapshotState(searxngSearchV2Handler), map[string]interface{}{"query": "golang"}); err != nil {
+			t.Fatal(err)
+		}
+		metricsHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// RewriteRule rewrites the queries matching Match before they are searched,
+// e.g. to scope internal product names to their documentation:
+//
+//	"rewrite_rules": [
+//	  {"name": "acme docs", "match": "(?i)\\bacme (sdk|cli)\\b", "add_sites": ["docs.acme.com"]},
+//	  {"name": "k8s", "match": "(?i)\\bk8s\\b", "replace": "kubernetes"},
+//	  {"name": "german", "match": "(?i)\\b(bundesliga|finanzamt)\\b", "language": "de"}
+//	]
+type RewriteRule struct {
+	Name string `json:"name"`
+	// Match is a regular expression, case-sensitive unless it starts
+	// with (?i).
+	Match string `json:"match"`
+	// Replace replaces the matches, with $1 for submatches; nil leaves
+	// them, "" removes them.
+	Replace *string `json:"replace,omitempty"`
+	// AddSites restrict the search to these sites with site: operators.
+	AddSites []string `json:"add_sites,omitempty"`
+	// Language replaces the language of the search.
+	Language string `json:"language,omitempty"`
+}
+
+// appliedRewrite is a rule that rewrote a search, with the query it left.
+type appliedRewrite struct {
+	Rule  string `json:"rule"`
+	Query string `json:"query"`
+}
+
+// checkRewriteRule compiles the pattern of a rule.
+func checkRewriteRule(rule RewriteRule) (*regexp.Regexp, error) {
+	if rule.Match == "" {
+		return nil, fmt.Errorf("rewrite rule %q needs a match pattern", rule.Name)
+	}
+	if rule.Replace == nil && len(rule.AddSites) == 0 && rule.Language == "" {
+		return nil, fmt.Errorf("rewrite rule %q needs a replace, add_sites or language", rule.Name)
+	}
+	re, err := regexp.Compile(rule.Match)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite rule %q: invalid match pattern: %w", rule.Name, err)
+	}
+	return re, nil
+}
+
+// rewriteQuery applies the rewrite_rules of the config to params in order,
+// each to the query the previous ones left.
+func rewriteQuery(ctx context.Context, params *searxng.SearchParams) []appliedRewrite {
+	var applied []appliedRewrite
+	for _, rule := range stateOf(ctx).config.RewriteRules {
+		// The rules were checked by loadConfig.
+		re, err := checkRewriteRule(rule)
+		if err != nil || !re.MatchString(params.Query) {
+			continue
+		}
+		query := params.Query
+		if rule.Replace != nil {
+			query = strings.Join(strings.Fields(re.ReplaceAllString(query, *rule.Replace)), " ")
+		}
+		if sites := missingSites(query, rule.AddSites); len(sites) > 0 {
+			query += " " + strings.Join(sites, " OR ")
+		}
+		params.Query = query
+		if rule.Language != "" {
+			params.Language = rule.Language
+		}
+		name := rule.Name
+		if name == "" {
+			name = rule.Match
+		}
+		applied = append(applied, appliedRewrite{Rule: name, Query: query})
+	}
+	return applied
+}
+
+// missingSites returns the site: operators of sites query lacks.
+func missingSites(query string, sites []string) []string {
+	words := strings.Fields(strings.ToLower(query))
+	var missing []string
+	for _, site := range sites {
+		operator := "site:" + strings.ToLower(strings.TrimSpace(site))
+		if operator != "site:" && !slices.Contains(words, operator) {
+			missing = append(missing, operator)
+		}
+	}
+	return missing
+}
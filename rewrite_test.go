@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestRewriteQuery(t *testing.T) {
+	empty := ""
+	k8s := "kubernetes"
+	useConfig(t, &Config{RewriteRules: []RewriteRule{
+		{Name: "k8s", Match: `(?i)\bk8s\b`, Replace: &k8s},
+		{Name: "acme docs", Match: `(?i)\bacme (sdk|cli)\b`, AddSites: []string{"docs.acme.com", "Blog.acme.com"}},
+		{Match: `\bplease\b`, Replace: &empty},
+		{Name: "german", Match: `(?i)\bfinanzamt\b`, Language: "de"},
+	}})
+
+	tests := []struct {
+		query, want, language string
+		rules                 []string
+	}{
+		{"k8s ingress", "kubernetes ingress", "", []string{"k8s"}},
+		{"please acme sdk on k8s", "acme sdk on kubernetes site:docs.acme.com OR site:blog.acme.com", "", []string{"k8s", "acme docs", `\bplease\b`}},
+		{"acme cli site:docs.acme.com", "acme cli site:docs.acme.com site:blog.acme.com", "", []string{"acme docs"}},
+		{"Finanzamt Berlin", "Finanzamt Berlin", "de", []string{"german"}},
+		{"golang", "golang", "", nil},
+	}
+	for _, tt := range tests {
+		params := searxng.SearchParams{Query: tt.query}
+		applied := rewriteQuery(context.Background(), &params)
+		var rules []string
+		for _, a := range applied {
+			rules = append(rules, a.Rule)
+		}
+		if params.Query != tt.want || params.Language != tt.language || !reflect.DeepEqual(rules, tt.rules) {
+			t.Errorf("rewriteQuery(context.Background(), %q) = %q (language %q, rules %q), want %q (%q, %q)", tt.query, params.Query, params.Language, rules, tt.want, tt.language, tt.rules)
+		}
+	}
+}
+
+func TestCheckRewriteRule(t *testing.T) {
+	for _, rule := range []RewriteRule{
+		{Name: "no match", Language: "de"},
+		{Name: "no action", Match: "x"},
+		{Name: "bad pattern", Match: "(", Language: "de"},
+	} {
+		if _, err := checkRewriteRule(rule); err == nil {
+			t.Errorf("rule %q accepted", rule.Name)
+		}
+	}
+}
+
+func TestSearchAppliesRewrites(t *testing.T) {
+	fake := useFakeInstance(t)
+	useEvidencePool(t)
+	useConfig(t, &Config{RewriteRules: []RewriteRule{{Name: "acme docs", Match: `(?i)\bacme\b`, AddSites: []string{"docs.acme.com"}}}})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "acme install"})
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	want := []appliedRewrite{{Rule: "acme docs", Query: "acme install site:docs.acme.com"}}
+	if !reflect.DeepEqual(response.Meta.RewritesApplied, want) {
+		t.Errorf("rewrites_applied = %+v, want %+v", response.Meta.RewritesApplied, want)
+	}
+	if request, _ := fake.LastRequest("/search"); request.Query.Get("q") != "acme install site:docs.acme.com" {
+		t.Errorf("searched %q", request.Query.Get("q"))
+	}
+}
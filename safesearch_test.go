@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestSafeSearchPolicy(t *testing.T) {
+	fake := useFakeInstance(t)
+	currentState().client.MinSafeSearch = 2
+	useConfig(t, &Config{BlockedImageDomains: []string{"blocked.example"}})
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{"query": "q", "safe_search": float64(0)})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if response.Meta.SafeSearch != 2 || !response.Meta.SafeSearchEnforced {
+		t.Errorf("meta = %+v, want safe search enforced at 2", response.Meta)
+	}
+
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"url": "https://ok.example/a", "img_src": "https://ok.example/a.jpg"},
+			{"url": "https://cdn.blocked.example/b", "img_src": "https://cdn.blocked.example/b.jpg"},
+			{"url": "https://ok.example/c", "img_src": "https://blocked.example/c.jpg"},
+		},
+	})
+	result, err = callTool(t, searxngImageSearchHandler, map[string]interface{}{"query": "q"})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	var images struct {
+		Results        []searxng.ImageResult `json:"results"`
+		BlockedResults int                   `json:"blocked_results"`
+	}
+	decodeResult(t, result, &images)
+	if len(images.Results) != 1 || images.BlockedResults != 2 {
+		t.Errorf("images = %+v, want 1 result and 2 blocked", images)
+	}
+	req, _ := fake.LastRequest("/search")
+	if got := req.Query.Get("safesearch"); got != "2" {
+		t.Errorf("image safesearch = %q, want the enforced 2", got)
+	}
+}
+
+func TestBlockedImageDomainsInSearches(t *testing.T) {
+	fake := useFakeInstance(t)
+	useConfig(t, &Config{BlockedImageDomains: []string{"blocked.example"}})
+	fake.SetSearchResponse(map[string]interface{}{
+		"results": []map[string]interface{}{
+			{"url": "https://ok.example/a", "category": "images"},
+			{"url": "https://cdn.blocked.example/b", "category": "images"},
+			{"url": "https://blocked.example/article", "category": "general"},
+		},
+	})
+
+	for _, arguments := range []map[string]interface{}{
+		{"query": "q"},
+		{"query": "q", "mode": "everything"},
+	} {
+		result, err := callTool(t, searxngSearchV2Handler, arguments)
+		if err != nil || result.IsError {
+			t.Fatalf("search %v: %+v, %v", arguments, result, err)
+		}
+		var response searchV2Response
+		decodeResult(t, result, &response)
+		for _, r := range response.Results {
+			if r.URL == "https://cdn.blocked.example/b" {
+				t.Errorf("search %v returned the blocked image", arguments)
+			}
+		}
+		if response.Meta.BlockedResults == 0 || len(response.Results) == 0 {
+			t.Errorf("search %v: meta = %+v, results = %d", arguments, response.Meta, len(response.Results))
+		}
+	}
+
+	result, err := callTool(t, searxngNewsSearchHandler, map[string]interface{}{"query": "q"})
+	if err != nil || result.IsError {
+		t.Fatalf("news search: %+v, %v", result, err)
+	}
+	var news newsSearchResponse
+	decodeResult(t, result, &news)
+	if len(news.Results) != 2 || news.BlockedResults != 1 {
+		t.Errorf("news = %d results, %d blocked, want 2 and 1", len(news.Results), news.BlockedResults)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// searchMeta describes how a search was performed, so the caller can tell
+// which defaults were applied and how complete the results are.
+type searchMeta struct {
+	Instance string `json:"instance"`
+	// AggregatedInstances are the instances whose results were merged, in
+	// aggregate mode.
+	AggregatedInstances []string `json:"aggregated_instances,omitempty"`
+	Categories          []string `json:"categories,omitempty"`
+	Engines             []string `json:"engines,omitempty"`
+	Language            string   `json:"language,omitempty"`
+	// LanguageDetected is set when Language was detected from the query.
+	LanguageDetected bool `json:"language_detected,omitempty"`
+	// Languages are the languages searched in parallel with the
+	// languages argument, LanguageBreakdown the search of each.
+	Languages         []string            `json:"languages,omitempty"`
+	LanguageBreakdown []languageBreakdown `json:"language_breakdown,omitempty"`
+	Page              int                 `json:"page"`
+	TimeRange         string              `json:"time_range,omitempty"`
+	// PublishedAfter and PublishedBefore bound the published date of the
+	// returned results.
+	PublishedAfter  string `json:"published_after,omitempty"`
+	PublishedBefore string `json:"published_before,omitempty"`
+	// DateFilteredResults were removed by the published date range;
+	// UndatedResults of them had no readable date.
+	DateFilteredResults int `json:"date_filtered_results,omitempty"`
+	UndatedResults      int `json:"undated_results,omitempty"`
+	// IncludeSites are the sites the search was restricted to;
+	// SiteFilteredResults of other sites were removed.
+	IncludeSites        []string `json:"include_sites,omitempty"`
+	SiteFilteredResults int      `json:"site_filtered_results,omitempty"`
+	SafeSearch          int      `json:"safe_search"`
+	// SafeSearchEnforced is set when the server raised SafeSearch to its
+	// -min-safe-search floor.
+	SafeSearchEnforced bool  `json:"safe_search_enforced,omitempty"`
+	ElapsedMS          int64 `json:"elapsed_ms"`
+	NumberOfResults    int   `json:"number_of_results"`
+	ReturnedResults    int   `json:"returned_results"`
+	// PrefetchedPages were read by searxng_search_and_read to return the
+	// ReturnedResults of best quality.
+	PrefetchedPages int `json:"prefetched_pages,omitempty"`
+	// SyntheticEngines lists the config defined engines that were expanded.
+	SyntheticEngines []string `json:"synthetic_engines,omitempty"`
+	// UnresponsiveEngines are the engines that returned nothing, with the
+	// reason reported by the instance.
+	UnresponsiveEngines []searxng.UnresponsiveEngine `json:"unresponsive_engines,omitempty"`
+	// SuspendedEngines are the unresponsive engines the instance blocked
+	// (CAPTCHA, rate limit); they are avoided until the given time.
+	SuspendedEngines []engineSuspension `json:"suspended_engines,omitempty"`
+	// EnginePolicies are the engines replaced by the configured schedule
+	// and quota policies.
+	EnginePolicies []policyDecision `json:"engine_policies,omitempty"`
+	// AvoidedEngines were requested but left out because they are
+	// suspended.
+	AvoidedEngines []engineSuspension `json:"avoided_engines,omitempty"`
+	// CachedAt is set when the response came from the cache, to the time
+	// the instance answered.
+	CachedAt string `json:"cached_at,omitempty"`
+	// Bangs are the SearXNG bangs of the query; they select the
+	// categories and engines instead of the defaults.
+	Bangs []string `json:"bangs,omitempty"`
+	// Near describes how the near argument biased the search.
+	Near *geoBias `json:"near,omitempty"`
+	// SkippedSeenResults were left out by skip_seen because the session
+	// already got them.
+	SkippedSeenResults int `json:"skipped_seen_results,omitempty"`
+	// ResultLanguages counts the results by detected language, a hint of
+	// which need translating; LanguageFilteredResults were removed by
+	// only_language.
+	ResultLanguages         map[string]int `json:"result_languages,omitempty"`
+	LanguageFilteredResults int            `json:"language_filtered_results,omitempty"`
+	// Mode is the search mode preset applied, quick, thorough or
+	// everything.
+	Mode string `json:"mode,omitempty"`
+	// CategoryBreakdown describes the search of each category in
+	// everything mode.
+	CategoryBreakdown []categoryBreakdown `json:"category_breakdown,omitempty"`
+	// PagesSearched is the number of result pages merged in thorough
+	// mode; DuplicateResults of them were already on an earlier page.
+	PagesSearched    int `json:"pages_searched,omitempty"`
+	DuplicateResults int `json:"duplicate_results,omitempty"`
+	// PipelineRemovedResults were dropped by the result_pipeline of the
+	// config file.
+	PipelineRemovedResults int `json:"pipeline_removed_results,omitempty"`
+	// BlockedResults counts the image results on blocked_image_domains.
+	BlockedResults int `json:"blocked_results,omitempty"`
+	// LowScoreResults were scored below min_score.
+	LowScoreResults int `json:"low_score_results,omitempty"`
+	// QueryClass is the classification that picked the engines with
+	// engines=auto.
+	QueryClass *queryClassification `json:"query_class,omitempty"`
+	// RewritesApplied are the rewrite_rules of the config that changed the
+	// query, in order.
+	RewritesApplied []appliedRewrite `json:"rewrites_applied,omitempty"`
+	// QueryTransformation is set when an overlong query was shortened.
+	QueryTransformation *queryTransformation `json:"query_transformation,omitempty"`
+	// ExternalBangs are the external bangs of the query ("!!wp"), searched
+	// on the instance as its own bangs ("!wp").
+	ExternalBangs []string `json:"external_bangs,omitempty"`
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+type searchV2Response struct {
+	Query   string            `json:"query"`
+	Meta    searchMeta        `json:"meta"`
+	Results []annotatedResult `json:"results"`
+	// Groups are set with group_by=domain: Results then hold the best
+	// result of each domain, and Groups[i] describes the domain of
+	// Results[i].
+	Groups  []resultGroup `json:"groups,omitempty"`
+	Answers []string      `json:"answers,omitempty"`
+	// AnswerChecks are the answers checked against their sources, set
+	// with verify_answers.
+	AnswerChecks []answerCheck `json:"answer_checks,omitempty"`
+	Corrections  []string      `json:"corrections,omitempty"`
+	Infoboxes    []interface{} `json:"infoboxes,omitempty"`
+	Suggestions  []string      `json:"suggestions,omitempty"`
+	// NoResults explains why Results is empty and what to retry with.
+	NoResults *noResultsReport `json:"no_results,omitempty"`
+}
+
+func newSearchMeta(ctx context.Context, params searxng.SearchParams) searchMeta {
+	page := params.PageNo
+	if page == 0 {
+		page = 1
+	}
+	meta := searchMeta{
+		Instance:   activeInstance(ctx).BaseURL,
+		Categories: params.Categories,
+		Engines:    params.Engines,
+		Language:   params.Language,
+		Page:       page,
+		TimeRange:  params.TimeRange,
+		SafeSearch: params.SafeSearch,
+	}
+	if level := stateOf(ctx).client.SafeSearchLevel(params.SafeSearch); level >= 0 && level != params.SafeSearch {
+		meta.SafeSearch = level
+		meta.SafeSearchEnforced = true
+	}
+	return meta
+}
+
+// prepareSearch resolves params the way every search tool does (query
+// rewriting and shortening, language detection, synthetic engines, engine policies,
+// suspended engines) and describes what was done in the returned meta.
+func prepareSearch(ctx context.Context, params *searxng.SearchParams) searchMeta {
+	rewrites := rewriteQuery(ctx, params)
+	external := rewriteExternalBangs(params)
+	shortened := shortenLongQuery(params)
+	detected := resolveLanguage(params)
+	expanded := expandSyntheticEngines(ctx, params)
+	policed := stateOf(ctx).policies.apply(ctx, params, time.Now())
+	avoided := suspendedEngines.avoid(params)
+	meta := newSearchMeta(ctx, *params)
+	meta.LanguageDetected = detected
+	meta.SyntheticEngines = expanded
+	meta.EnginePolicies = policed
+	meta.AvoidedEngines = avoided
+	meta.Bangs = queryBangs(params.Query)
+	meta.RewritesApplied = rewrites
+	if len(external) > 0 {
+		meta.ExternalBangs = external
+		meta.Warnings = append(meta.Warnings, fmt.Sprintf("external bangs (%s) redirect away from SearXNG, searched on the instance instead", strings.Join(external, ", ")))
+	}
+	if shortened != nil {
+		meta.QueryTransformation = shortened
+		meta.Warnings = append(meta.Warnings, shortened.Warning)
+	}
+	return meta
+}
+
+// runSearch prepares params with prepareSearch and runs the search.
+func runSearch(ctx context.Context, params searxng.SearchParams) (*searxng.SearchResponse, searchMeta, error) {
+	meta := prepareSearch(ctx, &params)
+	return executeSearch(ctx, params, meta)
+}
+
+// executeSearch runs a search prepared by prepareSearch, completing meta.
+func executeSearch(ctx context.Context, params searxng.SearchParams, meta searchMeta) (*searxng.SearchResponse, searchMeta, error) {
+	start := time.Now()
+	var failed []string
+	result, cachedAt, err := cachedSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		if instanceMode == instanceAggregate {
+			result, failures, err := aggregateSearch(ctx, params)
+			failed = failures
+			return result, err
+		}
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	if err != nil {
+		engineOutcomes.recordFailure(ctx, params.Engines, err, time.Since(start))
+		return nil, meta, err
+	}
+	if instanceMode == instanceAggregate {
+		meta.Instance = instanceAggregate
+		meta.AggregatedInstances = aggregatedInstances(result)
+		for _, failure := range failed {
+			meta.Warnings = append(meta.Warnings, "instance left out of the aggregation: "+failure)
+		}
+	}
+	meta.ElapsedMS = time.Since(start).Milliseconds()
+	meta.NumberOfResults = result.NumberOfResults
+	meta.UnresponsiveEngines = result.UnresponsiveEngines
+	if len(result.UnresponsiveEngines) > 0 {
+		meta.Warnings = append(meta.Warnings, fmt.Sprintf("%s returned nothing, the results are partial: retry later or with other engines", describeUnresponsive(result.UnresponsiveEngines)))
+	}
+	if cachedAt.IsZero() {
+		meta.SuspendedEngines = suspendedEngines.record(result.UnresponsiveEngines)
+		canary.mirror(params, result, time.Since(start))
+		engineOutcomes.record(ctx, params.Engines, result, time.Since(start))
+	} else {
+		meta.CachedAt = cachedAt.UTC().Format(time.RFC3339)
+	}
+	meta.ReturnedResults = len(result.Results)
+	if len(result.Results) > 0 && result.Results[0].Source == searxng.SourceHTMLFallback {
+		meta.Warnings = append(meta.Warnings, "the instance refuses format=json, results were read from its HTML page: no scores and no engine errors")
+	}
+	return result, meta, nil
+}
+
+func searxngSearchV2Handler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	params, err := searchParamsFromArguments(ctx, request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	format, err := outputFormat(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	fields, err := resultFieldsFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	rawFormat, _, err := enumArgument(request.GetArguments(), "raw_format", searxng.RawFormats)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	maxResults, _, err := intArgument(request.GetArguments(), "max_results")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	dates, err := publishedRangeFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	dates.narrowTimeRange(&params)
+	sites, err := siteFilterFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	sites.addToQuery(&params)
+	near, err := applyNear(ctx, request.GetArguments(), &params)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	verify, _, err := boolArgument(request.GetArguments(), "verify_answers")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	skipSeen, _, err := boolArgument(request.GetArguments(), "skip_seen")
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	groupBy, _, err := enumArgument(request.GetArguments(), "group_by", groupModes)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	onlyLanguage, err := languageFilterFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	minScore, err := minScoreFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	mode, err := searchModeFromArguments(ctx, request.GetArguments(), &params)
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	languages, err := languagesFromArguments(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if rawFormat != "" && len(languages.languages) > 0 {
+		return invalidArgumentsResult(errors.New("raw_format returns the payload of one search, it cannot be combined with languages")), nil
+	}
+	dryRun, err := isDryRun(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if dryRun {
+		meta := prepareSearch(ctx, &params)
+		mode.describe(&meta)
+		languages.describe(&meta)
+		meta.QueryClass = autoClassification(ctx, request.GetArguments())
+		meta.addNear(near)
+		return dryRunResult(ctx, params, meta, request.GetArguments(), searchOptionalArguments...)
+	}
+	if rawFormat != "" {
+		return rawSearchResult(ctx, params, rawFormat)
+	}
+
+	result, meta, err := languages.search(ctx, mode, params)
+	if err != nil {
+		return upstreamErrorResult("search", err), nil
+	}
+	meta.PipelineRemovedResults = processResults(stateOf(ctx).pipeline, result)
+	meta.BlockedResults += processResults(stateOf(ctx).blockedImages, result)
+	dates.applyTo(result, &meta)
+	sites.applyTo(result, &meta)
+	if minScore > 0 {
+		meta.LowScoreResults = processResults(resultFilter{minScore: minScore}, result)
+	}
+	countResultLanguages(result.Results, &meta)
+	if len(onlyLanguage) > 0 {
+		meta.LanguageFilteredResults = processResults(onlyLanguage, result)
+	}
+	meta.ReturnedResults = len(result.Results)
+	meta.QueryClass = autoClassification(ctx, request.GetArguments())
+	meta.addNear(near)
+
+	enriched := enrichResults(result.Results)
+	languages.tag(enriched, result.Results)
+	if skipSeen {
+		enriched, meta.SkippedSeenResults = evidence.dropSeen(ctx, enriched)
+	}
+	var groups []resultGroup
+	if groupBy == "domain" {
+		enriched, groups = groupByDomain(enriched)
+	}
+	if maxResults > 0 && maxResults < len(enriched) {
+		enriched = enriched[:maxResults]
+		if groups != nil {
+			groups = groups[:maxResults]
+		}
+	}
+	meta.ReturnedResults = len(enriched)
+	evidence.addResults(ctx, result.Query, enriched)
+
+	response := searchV2Response{
+		Query:       result.Query,
+		Meta:        meta,
+		Results:     enriched,
+		Groups:      groups,
+		Answers:     answerTexts(result.Answers),
+		Corrections: result.Corrections,
+		Infoboxes:   result.Infoboxes,
+		Suggestions: result.Suggestions,
+	}
+	if verify && len(result.Answers) > 0 {
+		response.AnswerChecks = verifyAnswers(ctx, result.Answers)
+	}
+
+	var repairs []string
+	if len(enriched) == 0 {
+		repairs = didYouMean(ctx, &response)
+		response.NoResults = explainNoResults(ctx, request.GetArguments(), &response, repairs)
+	}
+
+	if fields != nil {
+		return projectedSearchResult(ctx, &response, fields, format)
+	}
+	switch format {
+	case formatMarkdown:
+		if repairs == nil {
+			repairs = didYouMean(ctx, &response)
+		}
+		return mcp.NewToolResultStructured(response, renderMarkdown(&response, repairs)), nil
+	case formatCompact:
+		if repairs == nil {
+			repairs = didYouMean(ctx, &response)
+		}
+		return mcp.NewToolResultStructured(response, renderCompact(&response, repairs)), nil
+	}
+
+	return structuredResult(response)
+}
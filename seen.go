@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// seenStore remembers which result URLs were already reported per monitor,
+// so recurring news searches only surface articles that are new. Entries
+// expire after ttl so a story that resurfaces much later is reported again.
+type seenStore struct {
+	mu   sync.Mutex
+	path string
+	ttl  time.Duration
+	// seen[monitor][url] is the time the URL was first reported.
+	seen map[string]map[string]time.Time
+}
+
+var monitorSeen *seenStore
+
+// newSeenStore loads the store from path. An empty path keeps the store in
+// memory only.
+func newSeenStore(path string, ttl time.Duration) (*seenStore, error) {
+	s := &seenStore{
+		path: path,
+		ttl:  ttl,
+		seen: make(map[string]map[string]time.Time),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading monitor state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.seen); err != nil {
+		return nil, fmt.Errorf("error parsing monitor state: %w", err)
+	}
+	s.expire(time.Now())
+	return s, nil
+}
+
+// filterNew drops results already reported for the monitor, records the
+// remaining ones as seen and returns them with the number of dropped results.
+func (s *seenStore) filterNew(monitor string, results []searxng.SearchResult) ([]searxng.SearchResult, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	urls := s.seen[monitor]
+	if urls == nil {
+		urls = make(map[string]time.Time)
+		s.seen[monitor] = urls
+	}
+
+	fresh := make([]searxng.SearchResult, 0, len(results))
+	skipped := 0
+	for _, r := range results {
+		if _, ok := urls[r.URL]; ok {
+			skipped++
+			continue
+		}
+		urls[r.URL] = now
+		fresh = append(fresh, r)
+	}
+
+	if len(fresh) == 0 {
+		return fresh, skipped, nil
+	}
+	return fresh, skipped, s.save()
+}
+
+func (s *seenStore) expire(now time.Time) {
+	if s.ttl <= 0 {
+		return
+	}
+	for monitor, urls := range s.seen {
+		for u, at := range urls {
+			if now.Sub(at) > s.ttl {
+				delete(urls, u)
+			}
+		}
+		if len(urls) == 0 {
+			delete(s.seen, monitor)
+		}
+	}
+}
+
+// save writes the store atomically so a crash never leaves a truncated file.
+func (s *seenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.seen)
+	if err != nil {
+		return fmt.Errorf("error serializing monitor state: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("error writing monitor state: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
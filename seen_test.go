@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestSeenStoreFiltersReportedURLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := newSeenStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("newSeenStore: %v", err)
+	}
+
+	first := []searxng.SearchResult{{URL: "https://a"}, {URL: "https://b"}}
+	fresh, skipped, err := store.filterNew("daily", first)
+	if err != nil || len(fresh) != 2 || skipped != 0 {
+		t.Fatalf("first run: fresh=%d skipped=%d err=%v", len(fresh), skipped, err)
+	}
+
+	// Reload from disk to check persistence.
+	store, err = newSeenStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	second := []searxng.SearchResult{{URL: "https://b"}, {URL: "https://c"}}
+	fresh, skipped, _ = store.filterNew("daily", second)
+	if len(fresh) != 1 || fresh[0].URL != "https://c" || skipped != 1 {
+		t.Errorf("second run: fresh=%+v skipped=%d", fresh, skipped)
+	}
+
+	// Monitors are independent.
+	fresh, _, _ = store.filterNew("weekly", second)
+	if len(fresh) != 2 {
+		t.Errorf("other monitor: fresh=%+v", fresh)
+	}
+}
+
+func TestSeenStoreExpiry(t *testing.T) {
+	store, _ := newSeenStore("", time.Hour)
+	store.seen["daily"] = map[string]time.Time{"https://old": time.Now().Add(-2 * time.Hour)}
+
+	fresh, skipped, _ := store.filterNew("daily", []searxng.SearchResult{{URL: "https://old"}})
+	if len(fresh) != 1 || skipped != 0 {
+		t.Errorf("expired URL not reported again: fresh=%d skipped=%d", len(fresh), skipped)
+	}
+}
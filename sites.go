@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// maxIncludeSites bounds include_sites: every site adds an operator to the
+// query, and engines drop the words of overlong queries.
+const maxIncludeSites = 10
+
+// siteFilter restricts a search to sites. The query gets site: operators so
+// the engines search only those sites, and results the engines return from
+// other sites anyway (some ignore site: or OR) are dropped.
+type siteFilter []string
+
+func includeSitesOption() mcp.ToolOption {
+	return mcp.WithString("include_sites",
+		mcp.Description(fmt.Sprintf("Only return results from these sites, subdomains included, e.g. docs.python.org,stackoverflow.com (at most %d). Multiple values separated by comma", maxIncludeSites)),
+	)
+}
+
+// siteFilterFromArguments reads include_sites. Sites may be given as
+// domains or URLs; they are reduced to lowercase hostnames.
+func siteFilterFromArguments(arguments map[string]interface{}) (siteFilter, error) {
+	values, ok, err := listArgument(arguments, "include_sites")
+	if err != nil || !ok {
+		return nil, err
+	}
+	if len(values) > maxIncludeSites {
+		return nil, fmt.Errorf("include_sites takes at most %d sites, got %d", maxIncludeSites, len(values))
+	}
+	var sites siteFilter
+	for _, value := range values {
+		site := strings.ToLower(value)
+		if strings.Contains(site, "://") {
+			if u, err := url.Parse(site); err == nil {
+				site = u.Hostname()
+			}
+		}
+		site = strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(site, "site:"), "/"), "www.")
+		if site == "" || !strings.Contains(site, ".") || strings.ContainsAny(site, " /:?#") {
+			return nil, fmt.Errorf("include_sites must be domains such as docs.python.org, got %q", value)
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// addToQuery appends the site: operators to params.Query, joined by OR.
+func (f siteFilter) addToQuery(params *searxng.SearchParams) {
+	if len(f) == 0 {
+		return
+	}
+	operators := make([]string, len(f))
+	for i, site := range f {
+		operators[i] = "site:" + site
+	}
+	params.Query = strings.TrimSpace(params.Query + " " + strings.Join(operators, " OR "))
+}
+
+// allows reports whether rawURL is on one of the sites.
+func (f siteFilter) allows(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, site := range f {
+		if host == site || strings.HasSuffix(host, "."+site) {
+			return true
+		}
+	}
+	return false
+}
+
+// Process keeps the results of the sites.
+func (f siteFilter) Process(results []searxng.SearchResult) []searxng.SearchResult {
+	kept := make([]searxng.SearchResult, 0, len(results))
+	for _, r := range results {
+		if f.allows(r.URL) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// applyTo drops the results of other sites and records the filter in meta.
+func (f siteFilter) applyTo(result *searxng.SearchResponse, meta *searchMeta) {
+	if len(f) == 0 {
+		return
+	}
+	meta.IncludeSites = f
+	meta.SiteFilteredResults = processResults(f, result)
+	meta.ReturnedResults = len(result.Results)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSiteFilterFromArguments(t *testing.T) {
+	sites, err := siteFilterFromArguments(map[string]interface{}{
+		"include_sites": "docs.python.org, https://www.StackOverflow.com/questions, site:go.dev",
+	})
+	if err != nil {
+		t.Fatalf("siteFilterFromArguments: %v", err)
+	}
+	if want := (siteFilter{"docs.python.org", "stackoverflow.com", "go.dev"}); !reflect.DeepEqual(sites, want) {
+		t.Errorf("sites = %q, want %q", sites, want)
+	}
+
+	for _, value := range []interface{}{"python", "docs.python.org/3/library", strings.Repeat("a.com,", maxIncludeSites+1), 42} {
+		if _, err := siteFilterFromArguments(map[string]interface{}{"include_sites": value}); err == nil {
+			t.Errorf("include_sites %v accepted", value)
+		}
+	}
+}
+
+func TestSiteFilterAllows(t *testing.T) {
+	sites := siteFilter{"python.org", "stackoverflow.com"}
+	for url, want := range map[string]bool{
+		"https://docs.python.org/3/":               true,
+		"https://python.org":                       true,
+		"https://STACKOVERFLOW.com/q/1":            true,
+		"https://notpython.org/":                   false,
+		"https://python.org.evil.example/":         false,
+		"https://example.com/?u=stackoverflow.com": false,
+	} {
+		if got := sites.allows(url); got != want {
+			t.Errorf("allows(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestSearchV2IncludeSites(t *testing.T) {
+	fake := useFakeInstance(t)
+
+	result, err := callTool(t, searxngSearchV2Handler, map[string]interface{}{
+		"query":         "golang",
+		"include_sites": []interface{}{"example.com", "go.dev"},
+	})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if got := req.Query.Get("q"); got != "golang site:example.com OR site:go.dev" {
+		t.Errorf("q = %q, want the site: operators", got)
+	}
+
+	var response searchV2Response
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 || response.Results[0].URL != "https://example.com/first" {
+		t.Errorf("results = %+v, want only the example.com result", response.Results)
+	}
+	if response.Meta.SiteFilteredResults != 1 || len(response.Meta.IncludeSites) != 2 || response.Meta.ReturnedResults != 1 {
+		t.Errorf("meta = %+v, want 1 result of another site filtered", response.Meta)
+	}
+}
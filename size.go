@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// bytesPerToken approximates the tokenizers of common models, which
+// average about four bytes of English or JSON text per token.
+const bytesPerToken = 4
+
+// maxResponseBytes is the size above which tool responses are cut or
+// refused, 0 for no limit.
+var maxResponseBytes int
+
+// responseSize is the size of result in bytes: texts, the base64 data of
+// images and embedded resources, and the JSON of the structured content,
+// which clients pass to the model too.
+func responseSize(result *mcp.CallToolResult) int {
+	if result == nil {
+		return 0
+	}
+	size := 0
+	if result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			size += len(data)
+		}
+	}
+	for _, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			size += len(c.Text)
+		case mcp.ImageContent:
+			size += len(c.Data)
+		case mcp.EmbeddedResource:
+			switch r := c.Resource.(type) {
+			case mcp.TextResourceContents:
+				size += len(r.Text)
+			case mcp.BlobResourceContents:
+				size += len(r.Blob)
+			}
+		}
+	}
+	return size
+}
+
+// approxTokens estimates the tokens a response of size bytes costs.
+func approxTokens(size int) int {
+	return (size + bytesPerToken - 1) / bytesPerToken
+}
+
+// limitResponseSize is the tool middleware of -max-response-bytes.
+// Oversized plain text (markdown, compact) is cut at the limit and its
+// structured content dropped; JSON cannot be cut without breaking it, so an
+// oversized JSON response is replaced by an error telling how to ask for
+// less.
+func limitResponseSize(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if err != nil || maxResponseBytes <= 0 {
+			return result, err
+		}
+		size := responseSize(result)
+		if size <= maxResponseBytes {
+			return result, nil
+		}
+		metrics.truncated(request.Params.Name)
+		log.Printf("Response of %s is %d bytes, above -max-response-bytes %d", request.Params.Name, size, maxResponseBytes)
+
+		if len(result.Content) == 1 {
+			if text, ok := result.Content[0].(mcp.TextContent); ok && !json.Valid([]byte(text.Text)) {
+				text.Text = cutText(text.Text, maxResponseBytes) +
+					fmt.Sprintf("\n\n[truncated: response of %d bytes exceeds the %d byte limit]", size, maxResponseBytes)
+				result.Content[0] = text
+				result.StructuredContent = nil
+				return result, nil
+			}
+		}
+		return errorResult(toolError{Code: errorInvalidParams, Message: fmt.Sprintf(
+			"Response of %d bytes (about %d tokens) exceeds the %d byte limit of this server. Ask for less: fewer results (max_results, pages), shorter excerpts (excerpt_chars) or format compact",
+			size, approxTokens(size), maxResponseBytes)}), nil
+	}
+}
+
+// cutText returns the longest prefix of text of at most n bytes that ends
+// on a rune boundary.
+func cutText(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func textHandler(text string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(text), nil
+	}
+}
+
+func TestLimitResponseSize(t *testing.T) {
+	previous := maxResponseBytes
+	maxResponseBytes = 21
+	t.Cleanup(func() { maxResponseBytes = previous })
+
+	tests := []struct {
+		name      string
+		text      string
+		wantText  string
+		wantError bool
+	}{
+		{name: "small", text: `{"results": []}`, wantText: `{"results": []}`},
+		{name: "markdown is cut on a rune boundary", text: "1. Привет мир, long markdown", wantText: "1. Привет ми\n"},
+		{name: "json is refused", text: `{"results": ["` + strings.Repeat("a", 40) + `"]}`, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := callTool(t, limitResponseSize(textHandler(tt.text)), nil)
+			if err != nil {
+				t.Fatalf("handler: %v", err)
+			}
+			text, _ := mcp.AsTextContent(result.Content[0])
+			if result.IsError != tt.wantError {
+				t.Fatalf("IsError = %v: %s", result.IsError, text.Text)
+			}
+			if tt.wantError {
+				if !strings.Contains(text.Text, "exceeds the 21 byte limit") {
+					t.Errorf("error = %q", text.Text)
+				}
+				return
+			}
+			if !strings.HasPrefix(text.Text, tt.wantText) {
+				t.Errorf("text = %q, want it to start with %q", text.Text, tt.wantText)
+			}
+			if len(tt.text) > maxResponseBytes && !strings.Contains(text.Text, "[truncated: response of") {
+				t.Errorf("text = %q, want a truncation note", text.Text)
+			}
+		})
+	}
+}
+
+func TestLimitResponseSizeStructured(t *testing.T) {
+	previous := maxResponseBytes
+	maxResponseBytes = 100
+	t.Cleanup(func() { maxResponseBytes = previous })
+	structured := map[string]interface{}{"results": []string{strings.Repeat("a", 200)}}
+
+	handler := func(text string) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultStructured(structured, text), nil
+		}
+	}
+	result, _ := callTool(t, limitResponseSize(handler("1. short markdown")), nil)
+	text, _ := mcp.AsTextContent(result.Content[0])
+	if result.IsError || result.StructuredContent != nil || !strings.Contains(text.Text, "[truncated: response of") {
+		t.Errorf("markdown result = %+v", result)
+	}
+	result, _ = callTool(t, limitResponseSize(handler(`{"results": []}`)), nil)
+	if !result.IsError {
+		t.Errorf("json result = %+v, want an error", result)
+	}
+}
+
+func TestResponseSizeMetrics(t *testing.T) {
+	useRecentLog(t, false)
+	handler := observeToolCalls(textHandler(strings.Repeat("x", 1000)))
+	if _, err := callTool(t, handler, map[string]interface{}{"query": "size"}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	entry := recentSearches.list()[0]
+	if entry.Bytes != 1000 || entry.Tokens != 250 {
+		t.Errorf("recent entry size = %d bytes, %d tokens", entry.Bytes, entry.Tokens)
+	}
+	_, stats := metrics.snapshot()
+	if s := stats[""]; s.MaxResponseBytes < 1000 || s.ResponseBytes < 1000 {
+		t.Errorf("stats = %+v", s)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	sloBucketWidth = time.Minute
+	sloBuckets     = 6 * 60
+)
+
+// sloObjectives are the targets burn rates are computed against.
+var sloObjectives = struct {
+	Availability  float64
+	Latency       time.Duration
+	LatencyTarget float64
+}{
+	Availability:  0.99,
+	Latency:       5 * time.Second,
+	LatencyTarget: 0.95,
+}
+
+// sloWindows are the rolling windows indicators are reported for, matching
+// the usual multi-window burn rate alerts.
+var sloWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+type sloBucket struct {
+	start int64 // unix minute
+	total int64
+	good  int64 // succeeded
+	fast  int64 // finished within the latency objective
+}
+
+// sloTracker keeps per-minute call outcomes of one tool for the largest
+// window. Callers synchronize access.
+type sloTracker struct {
+	buckets [sloBuckets]sloBucket
+}
+
+func (t *sloTracker) record(at time.Time, success bool, duration time.Duration) {
+	minute := at.Unix() / int64(sloBucketWidth/time.Second)
+	b := &t.buckets[minute%sloBuckets]
+	if b.start != minute {
+		*b = sloBucket{start: minute}
+	}
+	b.total++
+	if success {
+		b.good++
+	}
+	if duration <= sloObjectives.Latency {
+		b.fast++
+	}
+}
+
+// sloIndicator is the state of one tool over one window.
+type sloIndicator struct {
+	Total int64
+	// SuccessRatio and LatencyRatio are 1 when there were no calls.
+	SuccessRatio float64
+	LatencyRatio float64
+}
+
+// AvailabilityBurnRate is how fast the error budget is consumed: 1 means
+// exactly on budget for the objective.
+func (i sloIndicator) AvailabilityBurnRate() float64 {
+	return burnRate(i.SuccessRatio, sloObjectives.Availability)
+}
+
+func (i sloIndicator) LatencyBurnRate() float64 {
+	return burnRate(i.LatencyRatio, sloObjectives.LatencyTarget)
+}
+
+func burnRate(ratio, objective float64) float64 {
+	if objective >= 1 {
+		return 0
+	}
+	return (1 - ratio) / (1 - objective)
+}
+
+func (t *sloTracker) indicator(now time.Time, window time.Duration) sloIndicator {
+	current := now.Unix() / int64(sloBucketWidth/time.Second)
+	oldest := current - int64(window/sloBucketWidth) + 1
+
+	var total, good, fast int64
+	for _, b := range t.buckets {
+		if b.start >= oldest && b.start <= current {
+			total += b.total
+			good += b.good
+			fast += b.fast
+		}
+	}
+
+	ind := sloIndicator{Total: total, SuccessRatio: 1, LatencyRatio: 1}
+	if total > 0 {
+		ind.SuccessRatio = float64(good) / float64(total)
+		ind.LatencyRatio = float64(fast) / float64(total)
+	}
+	return ind
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLOIndicator(t *testing.T) {
+	now := time.Now()
+	var tracker sloTracker
+	tracker.record(now, true, time.Second)
+	tracker.record(now, false, time.Second)
+	tracker.record(now, true, time.Minute)
+	tracker.record(now.Add(-2*time.Hour), false, time.Second)
+
+	recent := tracker.indicator(now, 5*time.Minute)
+	if recent.Total != 3 {
+		t.Fatalf("total = %d, want 3", recent.Total)
+	}
+	if got := recent.SuccessRatio; got < 0.66 || got > 0.67 {
+		t.Errorf("success ratio = %v, want 2/3", got)
+	}
+	if got := recent.LatencyRatio; got < 0.66 || got > 0.67 {
+		t.Errorf("latency ratio = %v, want 2/3", got)
+	}
+
+	if all := tracker.indicator(now, 6*time.Hour); all.Total != 4 {
+		t.Errorf("6h total = %d, want 4", all.Total)
+	}
+	if empty := (&sloTracker{}).indicator(now, time.Hour); empty.SuccessRatio != 1 || empty.AvailabilityBurnRate() != 0 {
+		t.Errorf("empty indicator = %+v", empty)
+	}
+}
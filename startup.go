@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// startupCheckTimeout bounds the check of the instances at startup.
+const startupCheckTimeout = 15 * time.Second
+
+// checkInstancesAtStartup runs the check of the check command on the
+// instances and logs its report: reachability, whether the JSON format
+// works, the SearXNG version and the number of enabled engines. It returns
+// an error when an instance is unusable, so that a misconfiguration shows
+// in the log at startup rather than at the first failed tool call.
+func checkInstancesAtStartup(ctx context.Context, instances []*searxng.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+	defer cancel()
+	var report strings.Builder
+	ok := runCheck(ctx, &report, instances)
+	for _, line := range strings.Split(strings.TrimRight(report.String(), "\n"), "\n") {
+		log.Printf("Startup check: %s", strings.TrimSpace(line))
+	}
+	if !ok {
+		return errors.New("a SearXNG instance is unusable, see the startup check above")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestCheckInstancesAtStartup(t *testing.T) {
+	fake := useFakeInstance(t)
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	if err := checkInstancesAtStartup(context.Background(), []*searxng.Client{currentState().client}); err != nil {
+		t.Errorf("check failed on a healthy instance: %v\n%s", err, logs.String())
+	}
+	if !strings.Contains(logs.String(), "Startup check: "+currentState().client.BaseURL+": ok") {
+		t.Errorf("logs = %q", logs.String())
+	}
+
+	fake.DisableJSON()
+	logs.Reset()
+	client := searxng.New(fake.URL, searxng.WithHTMLFallback(false))
+	if err := checkInstancesAtStartup(context.Background(), []*searxng.Client{client}); err == nil {
+		t.Error("check passed on an instance refusing the JSON format")
+	}
+	if !strings.Contains(logs.String(), "FAILED") {
+		t.Errorf("logs = %q", logs.String())
+	}
+}
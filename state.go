@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// serverState is the config and the state built from it. Config reloads
+// replace it as a whole and never modify a published state, so it is read
+// without a lock: a tool call takes one snapshot when it starts and uses it
+// throughout, and a reload neither waits for the calls in flight nor shows
+// them half of the new config.
+type serverState struct {
+	config *Config
+	// client is the instance tool calls go to; fallbacks are used, in
+	// order, while its circuit breaker is open.
+	client    *searxng.Client
+	fallbacks []*searxng.Client
+	// denylist is nil when nothing is denied.
+	denylist *denylist
+	// fetchPolicy applies to every URL the server fetches besides the
+	// instance.
+	fetchPolicy *fetchPolicy
+	// policies is nil when no engine policies are configured.
+	policies *policySet
+	// queryClasses are the query classes of the config, compiled.
+	queryClasses []compiledQueryClass
+	// pipeline is the result_pipeline of the config, empty when none is
+	// configured.
+	pipeline resultPipeline
+	// blockedImages are the blocked_image_domains of the config.
+	blockedImages imageBlocklist
+	// language is the language of searches not given one: auto, all or a
+	// language code.
+	language string
+	// redactor is set by -log-redact-queries.
+	redactor *queryRedactor
+}
+
+var state atomic.Pointer[serverState]
+
+// newServerState builds the state of cfg. The caller sets the instances,
+// the language and the redactor.
+func newServerState(cfg *Config) (*serverState, error) {
+	policies, err := newPolicySet(cfg.EnginePolicies)
+	if err != nil {
+		return nil, err
+	}
+	queryClasses, err := compileQueryClasses(cfg.QueryClasses)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := newResultPipeline(cfg.ResultPipeline)
+	if err != nil {
+		return nil, err
+	}
+	return &serverState{
+		config:        cfg,
+		denylist:      newDenylist(cfg.Deny),
+		fetchPolicy:   newFetchPolicy(cfg.Fetch),
+		policies:      policies,
+		queryClasses:  queryClasses,
+		pipeline:      pipeline,
+		blockedImages: newImageBlocklist(cfg.BlockedImageDomains),
+	}, nil
+}
+
+func init() {
+	state.Store(&serverState{config: &Config{}, fetchPolicy: newFetchPolicy(nil), language: autoLanguage})
+}
+
+// currentState returns the latest state, for code outside tool calls and
+// monitor runs, such as the HTTP endpoints.
+func currentState() *serverState {
+	return state.Load()
+}
+
+type stateKey struct{}
+
+// withState attaches a snapshot of the current state to ctx, unless ctx
+// already holds one.
+func withState(ctx context.Context) context.Context {
+	if ctx.Value(stateKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, stateKey{}, state.Load())
+}
+
+// stateOf returns the snapshot of ctx, or the current state for a ctx
+// without one.
+func stateOf(ctx context.Context) *serverState {
+	if s, ok := ctx.Value(stateKey{}).(*serverState); ok {
+		return s
+	}
+	return state.Load()
+}
+
+// snapshotState is the tool middleware taking the state snapshot of each
+// call. It also keeps dry runs to a cached /config of the instance.
+func snapshotState(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if dryRun, _ := isDryRun(request.GetArguments()); dryRun {
+			ctx = cachedCatalogOnly(ctx)
+		}
+		return next(withState(ctx), request)
+	}
+}
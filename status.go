@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+type instanceStatus struct {
+	URL              string `json:"url"`
+	Reachable        bool   `json:"reachable"`
+	LatencyMS        int64  `json:"latency_ms,omitempty"`
+	JSONFormat       bool   `json:"json_format"`
+	JSONFormatStatus int    `json:"json_format_status,omitempty"`
+	Version          string `json:"version,omitempty"`
+	// LegacyVersion is set for searx and early SearXNG releases, whose
+	// responses are read through the compatibility layer.
+	LegacyVersion  bool                `json:"legacy_version,omitempty"`
+	EnabledEngines int                 `json:"enabled_engines,omitempty"`
+	EngineErrors   map[string][]string `json:"engine_errors,omitempty"`
+	// SuspendedEngines are engines currently avoided after the instance
+	// reported them blocked.
+	SuspendedEngines []engineSuspension `json:"suspended_engines,omitempty"`
+	// SchemaIssues are the differences between the search responses seen
+	// so far and the expected schema.
+	SchemaIssues []schemaIssueCount `json:"schema_issues,omitempty"`
+	// Circuit is the state of the circuit breaker of the instance.
+	Circuit  *searxng.CircuitState `json:"circuit,omitempty"`
+	Problems []string              `json:"problems,omitempty"`
+}
+
+func searxngInstanceStatusHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var instances []*instanceStatus
+	for _, client := range allInstances(ctx) {
+		instances = append(instances, checkInstance(ctx, client))
+	}
+	return structuredResult(instanceStatusResponse{Instances: instances})
+}
+
+// checkInstance diagnoses the usual causes of empty search results: the
+// instance is down, the JSON format is disabled, or engines are failing.
+func checkInstance(ctx context.Context, client *searxng.Client) *instanceStatus {
+	status := &instanceStatus{
+		URL:              client.BaseURL,
+		SuspendedEngines: suspendedEngines.active(),
+		SchemaIssues:     schemaDrift.snapshot(),
+	}
+	if client.Breaker != nil {
+		state := client.Breaker.State()
+		status.Circuit = &state
+		if state.State != searxng.CircuitClosed {
+			status.Problems = append(status.Problems, fmt.Sprintf("circuit breaker %s after %d consecutive failures, requests fail fast until %s", state.State, state.ConsecutiveFailures, state.OpenUntil.Format(time.TimeOnly)))
+		}
+	}
+	for _, issue := range status.SchemaIssues {
+		if issue.Kind == searxng.SchemaTypeMismatch && !client.LenientParsing {
+			status.Problems = append(status.Problems, fmt.Sprintf("response field %s changed type (%s), searches fail to decode: upgrade this server or run it with -lenient-parsing", issue.Path, issue.Detail))
+		}
+	}
+
+	code, latency, err := client.Probe(ctx, "/", nil)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("instance unreachable: %v", err))
+		return status
+	}
+	status.Reachable = true
+	status.LatencyMS = latency.Milliseconds()
+	if code != http.StatusOK {
+		status.Problems = append(status.Problems, fmt.Sprintf("front page returned HTTP %d", code))
+	}
+
+	code, _, err = client.Probe(ctx, "/search", url.Values{"q": {"searxng"}, "format": {"json"}})
+	switch {
+	case err != nil:
+		status.Problems = append(status.Problems, fmt.Sprintf("JSON search failed: %v", err))
+	case code == http.StatusOK:
+		status.JSONFormat = true
+		status.JSONFormatStatus = code
+	case code == http.StatusForbidden && client.HTMLFallback:
+		status.JSONFormatStatus = code
+		status.Problems = append(status.Problems, "JSON search returned HTTP 403, searches fall back to parsing the HTML results page")
+	default:
+		status.JSONFormatStatus = code
+		status.Problems = append(status.Problems, fmt.Sprintf("JSON search returned HTTP %d, is \"json\" listed in search.formats of settings.yml?", code))
+	}
+
+	config, err := client.GetInstanceConfig(ctx)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("/config unavailable: %v", err))
+	} else {
+		status.Version = config.Version.Raw
+		status.LegacyVersion = config.Version.Legacy()
+		for _, engine := range config.Engines {
+			if engine.Enabled {
+				status.EnabledEngines++
+			}
+		}
+		if len(config.Engines) > 0 && status.EnabledEngines == 0 {
+			status.Problems = append(status.Problems, "no engines are enabled")
+		}
+	}
+
+	stats, err := client.GetStatsErrors(ctx)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("/stats/errors unavailable: %v", err))
+		return status
+	}
+	status.EngineErrors = summarizeEngineErrors(stats)
+
+	return status
+}
+
+// summarizeEngineErrors renders the primary errors of each engine as short
+// human readable lines, most frequent first.
+func summarizeEngineErrors(stats map[string][]searxng.EngineError) map[string][]string {
+	summary := make(map[string][]string)
+	for engine, errs := range stats {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Percentage > errs[j].Percentage })
+		for _, e := range errs {
+			if e.Secondary {
+				continue
+			}
+			line := e.ExceptionClassname
+			if line == "" {
+				line = e.LogMessage
+			} else if e.LogMessage != "" {
+				line += ": " + e.LogMessage
+			}
+			if len(e.LogParameters) > 0 {
+				line += " (" + strings.Join(e.LogParameters, ", ") + ")"
+			}
+			summary[engine] = append(summary[engine], fmt.Sprintf("%s, %.0f%% of requests", line, e.Percentage))
+		}
+	}
+	return summary
+}
+
+type engineHealth struct {
+	Engine       string   `json:"engine"`
+	Reliability  float64  `json:"reliability"`
+	ResponseTime float64  `json:"avg_response_time_s,omitempty"`
+	ResultCount  float64  `json:"result_count,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+	Healthy      bool     `json:"healthy"`
+}
+
+// healthyReliability is the reliability (percent) below which an engine is
+// not worth querying.
+const healthyReliability = 90
+
+func searxngEngineStatsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	stats, statsErr := activeInstance(ctx).GetStats(ctx)
+	engineErrors, errorsErr := activeInstance(ctx).GetStatsErrors(ctx)
+	if statsErr != nil && errorsErr != nil {
+		return upstreamErrorResult("getting engine statistics", statsErr), nil
+	}
+	errorSummary := summarizeEngineErrors(engineErrors)
+
+	var engines []engineHealth
+	listed := make(map[string]bool)
+	for _, stat := range stats {
+		listed[stat.Name] = true
+		engines = append(engines, engineHealth{
+			Engine:       stat.Name,
+			Reliability:  stat.Reliability,
+			ResponseTime: stat.ResponseTime,
+			ResultCount:  stat.ResultCount,
+			Errors:       errorSummary[stat.Name],
+			Healthy:      stat.Reliability >= healthyReliability,
+		})
+	}
+	// Engines can report errors without appearing on /stats, e.g. when
+	// every request failed.
+	for engine, errs := range errorSummary {
+		if !listed[engine] {
+			engines = append(engines, engineHealth{Engine: engine, Errors: errs})
+		}
+	}
+
+	if filter, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		wanted := make(map[string]bool)
+		for _, e := range filter {
+			wanted[e] = true
+		}
+		filtered := engines[:0]
+		for _, e := range engines {
+			if wanted[e.Engine] {
+				filtered = append(filtered, e)
+			}
+		}
+		engines = filtered
+	}
+
+	sort.Slice(engines, func(i, j int) bool {
+		if engines[i].Reliability != engines[j].Reliability {
+			return engines[i].Reliability > engines[j].Reliability
+		}
+		return engines[i].ResponseTime < engines[j].ResponseTime
+	})
+
+	response := engineStatsResponse{Engines: engines}
+	if statsErr != nil {
+		response.StatsError = statsErr.Error()
+	}
+	if errorsErr != nil {
+		response.ErrorsError = errorsErr.Error()
+	}
+
+	return structuredResult(response)
+}
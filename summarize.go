@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultSummaryBullets = 5
+	maxSummaryBullets     = 10
+	// maxSummarySources bounds the results put in the sampling prompt.
+	maxSummarySources = 20
+	// summarySnippetChars bounds the content of each result in the prompt.
+	summarySnippetChars = 600
+	summaryMaxTokens    = 1000
+)
+
+const summarySystemPrompt = "You summarize web search results for a research assistant. " +
+	"Use only the numbered sources given. Answer with bullet points, one per line, each starting with \"- \". " +
+	"End every bullet with the numbers of the sources it is based on, e.g. [1] or [2][5]. " +
+	"Do not add an introduction or a conclusion."
+
+// summarySource is a numbered source of a summary.
+type summarySource struct {
+	N          int    `json:"n"`
+	EvidenceID string `json:"evidence_id"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+}
+
+type resultSummary struct {
+	// Bullets cite Sources by their number, e.g. "... [2][3]".
+	Bullets []string        `json:"bullets"`
+	Sources []summarySource `json:"sources"`
+	// Model is the client model that wrote the summary.
+	Model    string   `json:"model,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+func summarizeResultsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var items []evidenceItem
+	ids, err := evidenceIDs(request.GetArguments())
+	if err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if len(ids) > 0 {
+		var missing []string
+		items, missing = evidence.get(ctx, ids)
+		if len(missing) > 0 {
+			return invalidArgumentsResult(fmt.Errorf("unknown evidence IDs: %s", strings.Join(missing, ", "))), nil
+		}
+	} else {
+		items = evidence.latest(ctx)
+	}
+	if len(items) == 0 {
+		return errorResult(toolError{Code: errorInvalidParams, Message: "No results to summarize: search first, or give evidence IDs"}), nil
+	}
+	if len(items) > maxSummarySources {
+		items = items[:maxSummarySources]
+	}
+
+	bullets := defaultSummaryBullets
+	if n, ok, err := intArgument(request.GetArguments(), "max_bullets"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok && n > 0 {
+		bullets = min(n, maxSummaryBullets)
+	}
+	focus, _ := request.GetArguments()["focus"].(string)
+
+	summary := resultSummary{Sources: make([]summarySource, len(items))}
+	for i, item := range items {
+		summary.Sources[i] = summarySource{N: i + 1, EvidenceID: item.ID, Title: item.Title, URL: item.URL}
+	}
+
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return errorResult(toolError{Code: errorInvalidParams, Message: "Summarizing needs an MCP session"}), nil
+	}
+	result, err := srv.RequestSampling(ctx, summaryRequest(items, focus, bullets))
+	if err != nil {
+		return errorResult(toolError{
+			Code:    errorInvalidParams,
+			Message: fmt.Sprintf("Cannot summarize: the client did not sample a summary (%v). summarize_results needs a client that supports MCP sampling, over the stdio transport", err),
+		}), nil
+	}
+	text, err := samplingText(result.Content)
+	if err != nil {
+		return errorResult(toolError{Code: errorUpstream, Message: fmt.Sprintf("Cannot summarize: %v", err)}), nil
+	}
+
+	summary.Model = result.Model
+	summary.Bullets = summaryBullets(text, bullets)
+	for i, bullet := range summary.Bullets {
+		for _, m := range citationPattern.FindAllStringSubmatch(bullet, -1) {
+			if n, _ := strconv.Atoi(m[1]); n < 1 || n > len(items) {
+				summary.Warnings = append(summary.Warnings, fmt.Sprintf("bullet %d cites [%d], which is not a source", i+1, n))
+			}
+		}
+		if !citationPattern.MatchString(bullet) {
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf("bullet %d cites no source", i+1))
+		}
+	}
+
+	return structuredResult(summary)
+}
+
+// summaryRequest asks the client model for a cited summary of items,
+// numbered from 1.
+func summaryRequest(items []evidenceItem, focus string, bullets int) mcp.CreateMessageRequest {
+	var b strings.Builder
+	if focus != "" {
+		fmt.Fprintf(&b, "Focus: %s\n\n", focus)
+	}
+	fmt.Fprintf(&b, "Summarize these search results in at most %d bullet points.\n\n", bullets)
+	for i, item := range items {
+		fmt.Fprintf(&b, "[%d] %s\n%s\n", i+1, collapse(item.Title), item.URL)
+		if content := cutText(collapse(item.Content), summarySnippetChars); content != "" {
+			fmt.Fprintf(&b, "%s\n", content)
+		}
+		b.WriteByte('\n')
+	}
+
+	var request mcp.CreateMessageRequest
+	request.Messages = []mcp.SamplingMessage{{
+		Role:    mcp.RoleUser,
+		Content: mcp.NewTextContent(b.String()),
+	}}
+	request.SystemPrompt = summarySystemPrompt
+	request.IncludeContext = "none"
+	request.Temperature = 0.2
+	request.MaxTokens = summaryMaxTokens
+	return request
+}
+
+// samplingText returns the text of a sampled message. Over the wire the
+// content arrives as a JSON object rather than an mcp.TextContent.
+func samplingText(content any) (string, error) {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text, nil
+	case *mcp.TextContent:
+		return c.Text, nil
+	case map[string]any:
+		if text, ok := c["text"].(string); ok && c["type"] == "text" {
+			return text, nil
+		}
+	}
+	return "", errors.New("the client model answered with no text")
+}
+
+// summaryBullets splits a sampled summary into at most n bullets. Lines
+// that continue a bullet are joined to it and a preamble before the first
+// bullet is dropped; text without bullet markers is one bullet per line.
+func summaryBullets(text string, n int) []string {
+	var lines []string
+	marked := false
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+			_, ok := cutBulletMarker(line)
+			marked = marked || ok
+		}
+	}
+
+	var bullets []string
+	for _, line := range lines {
+		rest, ok := cutBulletMarker(line)
+		switch {
+		case !marked || ok:
+			bullets = append(bullets, rest)
+		case len(bullets) > 0:
+			bullets[len(bullets)-1] += " " + line
+		}
+	}
+	if len(bullets) > n {
+		bullets = bullets[:n]
+	}
+	return bullets
+}
+
+var numberedBullet = regexp.MustCompile(`^\d+[.)]\s+`)
+
+func cutBulletMarker(line string) (string, bool) {
+	for _, marker := range []string{"- ", "* ", "• "} {
+		if rest, ok := strings.CutPrefix(line, marker); ok {
+			return strings.TrimSpace(rest), true
+		}
+	}
+	if loc := numberedBullet.FindStringIndex(line); loc != nil {
+		return line[loc[1]:], true
+	}
+	return line, false
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// samplingSession answers sampling requests with reply, or fails with err.
+type samplingSession struct {
+	testSession
+	reply    string
+	err      error
+	requests []mcp.CreateMessageRequest
+}
+
+func (s *samplingSession) RequestSampling(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	s.requests = append(s.requests, request)
+	if s.err != nil {
+		return nil, s.err
+	}
+	// Decoded from JSON, as the stdio transport does.
+	var result mcp.CreateMessageResult
+	data, _ := json.Marshal(map[string]interface{}{
+		"role":    "assistant",
+		"content": map[string]interface{}{"type": "text", "text": s.reply},
+		"model":   "test-model",
+	})
+	err := json.Unmarshal(data, &result)
+	return &result, err
+}
+
+func TestSummarizeResults(t *testing.T) {
+	useFakeInstance(t)
+	useEvidencePool(t)
+
+	srv := server.NewMCPServer("test", "1.0")
+	session := &samplingSession{reply: "Here is a summary:\n- The first result says one thing [1]\n  and more.\n- Both agree [1][2]\n- Unfounded claim\n- Wrong source [7]"}
+	ctx := srv.WithContext(context.Background(), session)
+
+	srv.AddTool(mcp.NewTool("searxng_search_v2"), searxngSearchV2Handler)
+	srv.AddTool(mcp.NewTool("summarize_results"), summarizeResultsHandler)
+	id := 0
+	call := func(tool string, arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		id++
+		message, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0", "id": id, "method": "tools/call",
+			"params": map[string]interface{}{"name": tool, "arguments": arguments},
+		})
+		response, ok := srv.HandleMessage(ctx, message).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("%s: response = %+v", tool, response)
+		}
+		result, ok := response.Result.(mcp.CallToolResult)
+		if !ok {
+			t.Fatalf("%s: result is %T", tool, response.Result)
+		}
+		return &result
+	}
+
+	if result := call("summarize_results", nil); errorCode(result) != errorInvalidParams {
+		t.Errorf("summary without a search = %+v, want an invalid_params error", result)
+	}
+
+	if result := call("searxng_search_v2", map[string]interface{}{"query": "golang"}); result.IsError {
+		t.Fatalf("search: %+v", result)
+	}
+
+	var summary resultSummary
+	decodeResult(t, call("summarize_results", map[string]interface{}{"focus": "release dates", "max_bullets": 4}), &summary)
+	want := []string{"The first result says one thing [1] and more.", "Both agree [1][2]", "Unfounded claim", "Wrong source [7]"}
+	if strings.Join(summary.Bullets, "|") != strings.Join(want, "|") {
+		t.Errorf("bullets = %q, want %q", summary.Bullets, want)
+	}
+	if len(summary.Sources) != 2 || summary.Sources[1] != (summarySource{N: 2, EvidenceID: "E2", Title: "Second result", URL: "https://example.org/second"}) {
+		t.Errorf("sources = %+v", summary.Sources)
+	}
+	if summary.Model != "test-model" || len(summary.Warnings) != 2 {
+		t.Errorf("model = %q, warnings = %q, want the uncited bullet and [7] reported", summary.Model, summary.Warnings)
+	}
+
+	prompt := session.requests[0].Messages[0].Content.(mcp.TextContent).Text
+	for _, part := range []string{"Focus: release dates", "at most 4 bullet points", "[2] Second result\nhttps://example.org/second\nSecond result content"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("prompt lacks %q:\n%s", part, prompt)
+		}
+	}
+
+	if result := call("summarize_results", map[string]interface{}{"ids": "E9"}); !result.IsError {
+		t.Error("unknown evidence ID accepted")
+	}
+
+	session.err = errors.New("Method not found")
+	if result := call("summarize_results", nil); errorCode(result) != errorInvalidParams || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "sampling") {
+		t.Errorf("result = %+v, want an error about sampling support", result)
+	}
+}
+
+func TestSummaryBullets(t *testing.T) {
+	got := summaryBullets("1. One [1]\n2) Two [2]\n* Three\n• Four\n- Five", 10)
+	if want := "One [1]|Two [2]|Three|Four|Five"; strings.Join(got, "|") != want {
+		t.Errorf("bullets = %q, want %q", got, want)
+	}
+	if got := summaryBullets("Plain line [1]\nAnother [2]", 1); len(got) != 1 || got[0] != "Plain line [1]" {
+		t.Errorf("bullets = %q, want the first line", got)
+	}
+}
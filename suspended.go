@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// engineSuspension is an engine the instance reported as blocked.
+type engineSuspension struct {
+	Engine string    `json:"engine"`
+	Reason string    `json:"reason"`
+	Until  time.Time `json:"until"`
+}
+
+// suspensionTracker remembers engines the instance reported as suspended
+// (CAPTCHA, rate limit, access denied) and keeps them out of searches until
+// the cooldown has passed, so a blocked Google does not cost every call an
+// engine slot and a timeout.
+type suspensionTracker struct {
+	mu       sync.Mutex
+	cooldown time.Duration
+	engines  map[string]engineSuspension
+	// unresponsive counts the unresponsive engine reports, for the metrics.
+	unresponsive map[unresponsiveKey]int64
+}
+
+// unresponsiveKey is an engine and the kind of its failure, see
+// unresponsiveReason.
+type unresponsiveKey struct {
+	Engine string
+	Reason string
+}
+
+var suspendedEngines = newSuspensionTracker(time.Hour)
+
+// newSuspensionTracker returns a tracker avoiding engines for cooldown. A
+// zero cooldown disables avoidance.
+func newSuspensionTracker(cooldown time.Duration) *suspensionTracker {
+	return &suspensionTracker{
+		cooldown:     cooldown,
+		engines:      make(map[string]engineSuspension),
+		unresponsive: make(map[unresponsiveKey]int64),
+	}
+}
+
+// record logs and counts the unresponsive engines of a search, starts the
+// cooldown of the suspended ones and returns their suspensions.
+func (t *suspensionTracker) record(unresponsive []searxng.UnresponsiveEngine) []engineSuspension {
+	var recorded []engineSuspension
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(unresponsive) > 0 {
+		log.Printf("Unresponsive engines: %s", describeUnresponsive(unresponsive))
+	}
+	until := time.Now().Add(t.cooldown)
+	for _, engine := range unresponsive {
+		t.unresponsive[unresponsiveKey{engine.Name, unresponsiveReason(engine.Reason)}]++
+		if !engine.Suspended() {
+			continue
+		}
+		suspension := engineSuspension{Engine: engine.Name, Reason: engine.Reason, Until: until}
+		if t.cooldown > 0 {
+			t.engines[engine.Name] = suspension
+		}
+		recorded = append(recorded, suspension)
+	}
+	return recorded
+}
+
+// avoid removes engines in cooldown from params.Engines and returns their
+// suspensions. When every requested engine is suspended the engines are
+// kept: searching a suspended engine beats silently switching to others.
+func (t *suspensionTracker) avoid(params *searxng.SearchParams) []engineSuspension {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var kept []string
+	var avoided []engineSuspension
+	for _, engine := range params.Engines {
+		suspension, ok := t.engines[engine]
+		if ok && now.After(suspension.Until) {
+			delete(t.engines, engine)
+			ok = false
+		}
+		if ok {
+			avoided = append(avoided, suspension)
+		} else {
+			kept = append(kept, engine)
+		}
+	}
+	if len(avoided) == 0 || len(kept) == 0 {
+		return nil
+	}
+	params.Engines = kept
+	return avoided
+}
+
+// active returns the engines currently in cooldown, sorted by name.
+func (t *suspensionTracker) active() []engineSuspension {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var active []engineSuspension
+	for name, suspension := range t.engines {
+		if now.After(suspension.Until) {
+			delete(t.engines, name)
+			continue
+		}
+		active = append(active, suspension)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Engine < active[j].Engine })
+	return active
+}
+
+// unresponsiveCounts returns the number of unresponsive reports per engine
+// and reason.
+func (t *suspensionTracker) unresponsiveCounts() map[unresponsiveKey]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := make(map[unresponsiveKey]int64, len(t.unresponsive))
+	for key, n := range t.unresponsive {
+		counts[key] = n
+	}
+	return counts
+}
+
+// unresponsiveReasons map the reasons SearXNG reports, e.g. "Suspended:
+// CAPTCHA" or "HTTP error", to a few metric labels.
+var unresponsiveReasons = []struct{ match, reason string }{
+	{"timeout", "timeout"},
+	{"captcha", "captcha"},
+	{"too many requests", "too_many_requests"},
+	{"access denied", "access_denied"},
+	{"parsing", "parsing_error"},
+	{"http", "http_error"},
+	{"ssl", "network_error"},
+	{"connection", "network_error"},
+}
+
+// unresponsiveReason reduces an unresponsive engine reason to a metric
+// label: the reasons carry free text such as the HTTP status.
+func unresponsiveReason(reason string) string {
+	reason = strings.ToLower(reason)
+	for _, r := range unresponsiveReasons {
+		if strings.Contains(reason, r.match) {
+			return r.reason
+		}
+	}
+	return "other"
+}
+
+// describeUnresponsive lists unresponsive engines as "google (timeout),
+// bing (Suspended: CAPTCHA)".
+func describeUnresponsive(unresponsive []searxng.UnresponsiveEngine) string {
+	parts := make([]string, len(unresponsive))
+	for i, engine := range unresponsive {
+		parts[i] = engine.Name
+		if engine.Reason != "" {
+			parts[i] = fmt.Sprintf("%s (%s)", engine.Name, engine.Reason)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
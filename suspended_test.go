@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+func TestSuspensionTrackerAvoid(t *testing.T) {
+	tracker := newSuspensionTracker(time.Hour)
+	recorded := tracker.record([]searxng.UnresponsiveEngine{
+		{Name: "google", Reason: "Suspended: CAPTCHA"},
+		{Name: "bing", Reason: "timeout"},
+	})
+	if len(recorded) != 1 || recorded[0].Engine != "google" {
+		t.Fatalf("recorded = %+v, want only google", recorded)
+	}
+
+	params := searxng.SearchParams{Engines: []string{"google", "bing", "duckduckgo"}}
+	avoided := tracker.avoid(&params)
+	if len(avoided) != 1 || avoided[0].Engine != "google" {
+		t.Errorf("avoided = %+v", avoided)
+	}
+	if want := []string{"bing", "duckduckgo"}; !reflect.DeepEqual(params.Engines, want) {
+		t.Errorf("engines = %q, want %q", params.Engines, want)
+	}
+
+	params = searxng.SearchParams{Engines: []string{"google"}}
+	if avoided := tracker.avoid(&params); avoided != nil || len(params.Engines) != 1 {
+		t.Errorf("a lone suspended engine must be kept, got %q, avoided %+v", params.Engines, avoided)
+	}
+
+	tracker.engines["google"] = engineSuspension{Engine: "google", Until: time.Now().Add(-time.Second)}
+	params = searxng.SearchParams{Engines: []string{"google", "bing"}}
+	if avoided := tracker.avoid(&params); avoided != nil {
+		t.Errorf("expired suspension still avoided: %+v", avoided)
+	}
+	if active := tracker.active(); len(active) != 0 {
+		t.Errorf("active = %+v, want none", active)
+	}
+}
+
+func TestSuspensionTrackerDisabled(t *testing.T) {
+	tracker := newSuspensionTracker(0)
+	tracker.record([]searxng.UnresponsiveEngine{{Name: "google", Reason: "too many requests"}})
+	params := searxng.SearchParams{Engines: []string{"google", "bing"}}
+	if avoided := tracker.avoid(&params); avoided != nil {
+		t.Errorf("avoided = %+v, want none with a zero cooldown", avoided)
+	}
+}
+
+func TestSearchV2ReportsSuspendedEngines(t *testing.T) {
+	fake := useFakeInstance(t)
+	previous := suspendedEngines
+	suspendedEngines = newSuspensionTracker(time.Hour)
+	t.Cleanup(func() { suspendedEngines = previous })
+
+	fake.SetSearchResponse(map[string]interface{}{
+		"query":                "golang",
+		"results":              []interface{}{},
+		"unresponsive_engines": [][]string{{"google", "Suspended: CAPTCHA"}},
+	})
+	arguments := map[string]interface{}{"query": "golang", "engines": "google,duckduckgo"}
+
+	var response searchV2Response
+	result, err := callTool(t, searxngSearchV2Handler, arguments)
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	decodeResult(t, result, &response)
+	if len(response.Meta.SuspendedEngines) != 1 || response.Meta.SuspendedEngines[0].Reason != "Suspended: CAPTCHA" {
+		t.Errorf("suspended_engines = %+v", response.Meta.SuspendedEngines)
+	}
+	if len(response.Meta.Warnings) != 1 || !strings.Contains(response.Meta.Warnings[0], "google (Suspended: CAPTCHA)") {
+		t.Errorf("warnings = %q, want the partial results noted", response.Meta.Warnings)
+	}
+
+	result, err = callTool(t, searxngSearchV2Handler, arguments)
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	response = searchV2Response{}
+	decodeResult(t, result, &response)
+	if len(response.Meta.AvoidedEngines) != 1 || response.Meta.AvoidedEngines[0].Engine != "google" {
+		t.Errorf("avoided_engines = %+v", response.Meta.AvoidedEngines)
+	}
+	req, _ := fake.LastRequest("/search")
+	if got := req.Query.Get("engines"); got != "duckduckgo" {
+		t.Errorf("engines = %q, want google left out", got)
+	}
+}
+
+func TestUnresponsiveCounts(t *testing.T) {
+	tracker := newSuspensionTracker(time.Hour)
+	tracker.record([]searxng.UnresponsiveEngine{
+		{Name: "google", Reason: "Suspended: CAPTCHA"},
+		{Name: "bing", Reason: "timeout"},
+	})
+	tracker.record([]searxng.UnresponsiveEngine{
+		{Name: "bing", Reason: "timeout"},
+		{Name: "brave", Reason: "HTTP error 502"},
+		{Name: "qwant", Reason: "unexpected crash"},
+	})
+
+	want := map[unresponsiveKey]int64{
+		{"google", "captcha"}:   1,
+		{"bing", "timeout"}:     2,
+		{"brave", "http_error"}: 1,
+		{"qwant", "other"}:      1,
+	}
+	if got := tracker.unresponsiveCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+}
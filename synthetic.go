@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// expandSyntheticEngines replaces synthetic engine names in params.Engines
+// by their real engines and appends their query operators to the query.
+// Several synthetic engines are combined with OR. It returns the names of
+// the expanded synthetic engines.
+func expandSyntheticEngines(ctx context.Context, params *searxng.SearchParams) []string {
+	var expanded, operators, engines []string
+	seen := make(map[string]bool)
+	addEngine := func(engine string) {
+		if !seen[engine] {
+			seen[engine] = true
+			engines = append(engines, engine)
+		}
+	}
+
+	for _, name := range params.Engines {
+		synthetic, ok := stateOf(ctx).config.SyntheticEngines[name]
+		if !ok {
+			addEngine(name)
+			continue
+		}
+		expanded = append(expanded, name)
+		if synthetic.Query != "" {
+			operators = append(operators, synthetic.Query)
+		}
+		for _, engine := range synthetic.Engines {
+			addEngine(engine)
+		}
+	}
+
+	if len(expanded) == 0 {
+		return nil
+	}
+	if len(operators) > 0 {
+		params.Query = params.Query + " " + strings.Join(operators, " OR ")
+	}
+	params.Engines = engines
+	return expanded
+}
+
+// syntheticEnginesHint lists the synthetic engines for tool descriptions.
+func syntheticEnginesHint() string {
+	names := currentState().config.syntheticEngineNames()
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(". Site presets usable as engine names: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+const (
+	// thumbnailSize is the largest width and height of proxied thumbnails.
+	thumbnailSize = 256
+	// thumbnailPath is where the sse server serves them.
+	thumbnailPath = "/thumb"
+	// maxThumbnailPixels bounds the images decoded to downsize them: a few
+	// compressed megabytes can hold a huge image.
+	maxThumbnailPixels = 16 << 20
+)
+
+// thumbnailProxy serves the thumbnails of image search results from the
+// server, downsized, so clients rendering previews contact the server
+// only instead of dozens of image hosts. Its URLs are signed: the proxy
+// fetches only the images the server linked to.
+type thumbnailProxy struct {
+	baseURL string
+	key     []byte
+}
+
+// thumbnails is nil when thumbnails are not proxied.
+var thumbnails *thumbnailProxy
+
+// newThumbnailProxy links thumbnails under baseURL, the public URL of the
+// sse server. Without a secret, a random key signs them: their URLs stop
+// working on restart, and replicas behind a load balancer need a shared
+// secret.
+func newThumbnailProxy(baseURL, secret string) *thumbnailProxy {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &thumbnailProxy{baseURL: strings.TrimSuffix(baseURL, "/"), key: key}
+}
+
+func (p *thumbnailProxy) sign(imageURL string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(imageURL))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// link returns the proxy URL of an image.
+func (p *thumbnailProxy) link(imageURL string) string {
+	return p.baseURL + thumbnailPath + "?" + url.Values{"url": {imageURL}, "sig": {p.sign(imageURL)}}.Encode()
+}
+
+// proxiedImageResults adds the proxy URL of their thumbnail, or of their
+// image when they have none, to image results.
+func proxiedImageResults(results []searxng.ImageResult) []imageResult {
+	proxied := make([]imageResult, len(results))
+	for i, r := range results {
+		proxied[i].ImageResult = r
+		source := r.ThumbnailSrc
+		if source == "" {
+			source = r.ImgSrc
+		}
+		if thumbnails != nil && isHTTPURL(source) {
+			proxied[i].ProxiedThumbnailSrc = thumbnails.link(source)
+		}
+	}
+	return proxied
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ServeHTTP serves the image of a signed proxy URL as a JPEG of at most
+// thumbnailSize pixels a side. Images Go cannot decode (WebP), or too
+// large to, are passed through as they are.
+func (p *thumbnailProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageURL := r.URL.Query().Get("url")
+	if !hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(p.sign(imageURL))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	data, mimeType, err := fetchImage(r.Context(), imageURL, maxImageBytes)
+	if err != nil {
+		log.Printf("Thumbnail of %s: %v", imageURL, err)
+		http.Error(w, "cannot fetch image", http.StatusBadGateway)
+		return
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && cfg.Width*cfg.Height <= maxThumbnailPixels {
+		if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, downscale(img, thumbnailSize), &jpeg.Options{Quality: 80}); err == nil {
+				data, mimeType = buf.Bytes(), "image/jpeg"
+			}
+		}
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
+
+// downscale fits img in a size×size box, averaging the pixels each output
+// pixel covers, on a white background for transparent images.
+func downscale(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/bounds.Dx())
+		} else {
+			width, height = max(1, width*size/bounds.Dy()), size
+		}
+	}
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return flat
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := bounds.Min.Y+y*bounds.Dy()/height, bounds.Min.Y+max((y+1)*bounds.Dy()/height, y*bounds.Dy()/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := bounds.Min.X+x*bounds.Dx()/width, bounds.Min.X+max((x+1)*bounds.Dx()/width, x*bounds.Dx()/width+1)
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := flat.RGBAAt(sx, sy)
+					r, g, b, n = r+uint32(c.R), g+uint32(c.G), b+uint32(c.B), n+1
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 0xff})
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestThumbnailProxy(t *testing.T) {
+	fake := useFakeInstance(t)
+
+	var large bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 300; x++ {
+		for y := 0; y < 300; y++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	if err := png.Encode(&large, img); err != nil {
+		t.Fatal(err)
+	}
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(large.Bytes())
+	}))
+	defer site.Close()
+
+	previous := thumbnails
+	thumbnails = newThumbnailProxy("https://mcp.example.com/", "secret")
+	t.Cleanup(func() { thumbnails = previous })
+	fake.SetSearchResponse(map[string]interface{}{
+		"query": "q",
+		"results": []interface{}{
+			map[string]interface{}{"url": "https://a.example/", "img_src": site.URL + "/full.png", "thumbnail_src": site.URL + "/thumb.png"},
+		},
+	})
+	result, err := callTool(t, searxngImageSearchHandler, map[string]interface{}{"query": "q"})
+	if err != nil || result.IsError {
+		t.Fatalf("image search: %+v, %v", result, err)
+	}
+	var response imageSearchResponse
+	decodeResult(t, result, &response)
+	if len(response.Results) != 1 || response.Results[0].ImgSrc != site.URL+"/full.png" {
+		t.Fatalf("results = %+v", response.Results)
+	}
+	link := response.Results[0].ProxiedThumbnailSrc
+	if !strings.HasPrefix(link, "https://mcp.example.com/thumb?") {
+		t.Fatalf("proxied thumbnail = %q", link)
+	}
+
+	u, _ := url.Parse(link)
+	rec := httptest.NewRecorder()
+	thumbnails.ServeHTTP(rec, httptest.NewRequest("GET", u.RequestURI(), nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("thumbnail: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	thumb, _, err := image.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 256 || b.Dy() != 128 {
+		t.Errorf("thumbnail is %dx%d, want 256x128", b.Dx(), b.Dy())
+	}
+	if r, g, _, _ := thumb.At(10, 10).RGBA(); r>>8 < 200 || g>>8 > 60 {
+		t.Errorf("left half is %v, want red", thumb.At(10, 10))
+	}
+	if r, g, b, _ := thumb.At(250, 10).RGBA(); r>>8 < 200 || g>>8 < 200 || b>>8 < 200 {
+		t.Errorf("transparent right half is %v, want white", thumb.At(250, 10))
+	}
+
+	query := u.Query()
+	query.Set("url", site.URL+"/other.png")
+	rec = httptest.NewRecorder()
+	thumbnails.ServeHTTP(rec, httptest.NewRequest("GET", "/thumb?"+query.Encode(), nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unsigned URL: %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the server.
+const tracerName = "go_mcp_server_searxng"
+
+// tracer creates the spans; it is a no-op until setupTracing installs an
+// exporter.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// tracingEnabled reports whether spans are exported: to the -otlp-endpoint
+// flag, or to the endpoint of the standard OTEL_EXPORTER_OTLP_ENDPOINT
+// variables.
+func tracingEnabled(endpoint string) bool {
+	return endpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// setupTracing exports spans over OTLP/HTTP to endpoint, e.g.
+// http://otel-collector:4318, or to the endpoint of the environment when
+// empty, and returns a function flushing them on shutdown. The
+// OTEL_EXPORTER_OTLP_* variables configure the exporter further, e.g.
+// headers.
+func setupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	var options []otlptracehttp.Option
+	if endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(tracerName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// traceContextFromRequest continues the trace of the gateway in front of
+// the server: the traceparent header of the HTTP request carrying a tool
+// call becomes the parent of its span.
+func traceContextFromRequest(ctx context.Context, r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
+// traceToolCalls wraps every tool call in a span.
+func traceToolCalls(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, span := tracer().Start(ctx, "tools/call "+request.Params.Name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("mcp.tool.name", request.Params.Name),
+				attribute.String("mcp.session.id", sessionID(ctx)),
+			),
+		)
+		defer span.End()
+
+		result, err := next(ctx, request)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result != nil && result.IsError:
+			code := errorCode(result)
+			span.SetAttributes(attribute.String("mcp.tool.error_code", code))
+			span.SetStatus(codes.Error, "tool returned an error result: "+code)
+		}
+		span.SetAttributes(attribute.Int("mcp.tool.response_bytes", responseSize(result)))
+		return result, err
+	}
+}
+
+// tracingTransport makes a client span of every request to a SearXNG
+// instance, with the engines, categories and page searched, and passes
+// the trace on to the instance.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func newTracingTransport(base http.RoundTripper) http.RoundTripper {
+	return tracingTransport{base: base}
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer().Start(req.Context(), "searxng "+req.Method+" "+req.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		),
+	)
+	defer span.End()
+	span.SetAttributes(searchAttributes(req)...)
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+// searchAttributes describes the search parameters of a request, sent in
+// the URL or, for POST searches, in the form body.
+func searchAttributes(req *http.Request) []attribute.KeyValue {
+	values := req.URL.Query()
+	if req.Method == http.MethodPost && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			if form, err := url.ParseQuery(string(data)); err == nil {
+				values = form
+			}
+		}
+	}
+	var attributes []attribute.KeyValue
+	for _, name := range []string{"engines", "categories", "language", "time_range"} {
+		if value := values.Get(name); value != "" {
+			attributes = append(attributes, attribute.String("searxng."+name, value))
+		}
+	}
+	if page, err := strconv.Atoi(values.Get("pageno")); err == nil {
+		attributes = append(attributes, attribute.Int("searxng.page", page))
+	}
+	return attributes
+}
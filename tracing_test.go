@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"go_mcp_server_searxng/pkg/searxng"
+)
+
+// useSpanRecorder records the spans of the test in memory.
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTraceToolCalls(t *testing.T) {
+	fake := useFakeInstance(t)
+	recorder := useSpanRecorder(t)
+	useState(t, func(s *serverState) {
+		s.client = searxng.New(fake.URL, searxng.WithSearchMethod("post"), searxng.WithRoundTripper(newTracingTransport))
+	})
+
+	var request mcp.CallToolRequest
+	request.Params.Name = "searxng_search_v2"
+	request.Params.Arguments = map[string]interface{}{"query": "q", "engines": []interface{}{"duckduckgo"}, "page": 2}
+	result, err := traceToolCalls(searxngSearchV2Handler)(context.Background(), request)
+	if err != nil || result.IsError {
+		t.Fatalf("search: %+v, %v", result, err)
+	}
+
+	// POST searches first fetch the session cookie of the instance.
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("%d spans, want the tool call and its two requests", len(spans))
+	}
+	upstream, call := spans[1], spans[2]
+	if call.Name() != "tools/call searxng_search_v2" || upstream.Name() != "searxng POST /search" || upstream.Parent().SpanID() != call.SpanContext().SpanID() {
+		t.Errorf("spans %q and %q, want the search request a child of the call", call.Name(), upstream.Name())
+	}
+	if got := spanAttribute(upstream, "searxng.engines").AsString(); got != "duckduckgo" {
+		t.Errorf("engines attribute = %q", got)
+	}
+	if got := spanAttribute(upstream, "searxng.page").AsInt64(); got != 2 {
+		t.Errorf("page attribute = %d", got)
+	}
+	last, _ := fake.LastRequest("/search")
+	if last.Header.Get("Traceparent") == "" {
+		t.Error("the trace was not passed on to the instance")
+	}
+
+	request.Params.Arguments = map[string]interface{}{}
+	traceToolCalls(searxngSearchV2Handler)(context.Background(), request)
+	failed := recorder.Ended()[3]
+	if failed.Status().Code != codes.Error || spanAttribute(failed, "mcp.tool.error_code").AsString() != errorInvalidParams {
+		t.Errorf("failed call status %+v, attributes %v", failed.Status(), failed.Attributes())
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"go_mcp_server_searxng/pkg/searxng"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// defaultWeatherEngines are the weather engines of SearXNG; they take the
+// query as the location.
+var defaultWeatherEngines = []string{"wttr.in", "open meteo"}
+
+// weatherConditions are the conditions at a time, each value with its
+// unit, e.g. "21 °C".
+type weatherConditions map[string]string
+
+type weatherReport struct {
+	Location  string              `json:"location"`
+	Current   weatherConditions   `json:"current,omitempty"`
+	Forecasts []weatherConditions `json:"forecasts,omitempty"`
+	// Text is the report of instances whose weather engine answers with
+	// an infobox rather than conditions.
+	Text   string `json:"text,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+func searxngWeatherHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	location, _ := request.GetArguments()["location"].(string)
+	if location = strings.TrimSpace(location); location == "" {
+		return invalidArgumentsResult(errors.New("location must be a place, e.g. Berlin or \"Paris, France\"")), nil
+	}
+
+	params := searxng.SearchParams{
+		Query:    location,
+		Engines:  append([]string(nil), defaultWeatherEngines...),
+		Language: stateOf(ctx).language,
+	}
+	if engines, ok, err := listArgument(request.GetArguments(), "engines"); err != nil {
+		return invalidArgumentsResult(err), nil
+	} else if ok {
+		params.Engines = engines
+	}
+	if err := checkEngineArgument(ctx, request); err != nil {
+		return invalidArgumentsResult(err), nil
+	}
+	if language, ok := request.GetArguments()["language"].(string); ok && language != "" {
+		params.Language = language
+	}
+
+	prepareSearch(ctx, &params)
+	result, cachedAt, err := cachedSearch(ctx, "search", params, func() (*searxng.SearchResponse, error) {
+		return activeInstance(ctx).Search(ctx, params)
+	})
+	if err != nil {
+		return upstreamErrorResult("weather search", err), nil
+	}
+	if cachedAt.IsZero() {
+		suspendedEngines.record(result.UnresponsiveEngines)
+	}
+
+	report, ok := weatherFromResponse(result)
+	if !ok {
+		return engineErrorResult(fmt.Sprintf("No weather for %q: check the spelling of the location, or enable the wttr.in or open meteo engine on the instance", location)), nil
+	}
+	if report.Location == "" {
+		report.Location = location
+	}
+	return structuredResult(report)
+}
+
+// weatherFromResponse reads the weather answer of recent instances, or
+// else the infobox older wttr.in engines answer with.
+func weatherFromResponse(result *searxng.SearchResponse) (weatherReport, bool) {
+	for _, answer := range result.Answers {
+		if answer.Current == nil {
+			continue
+		}
+		report := weatherReport{Current: newWeatherConditions(answer.Current), Source: answer.URL}
+		if report.Source == "" {
+			report.Source = answer.Service
+		}
+		report.Location = report.Current["location"]
+		delete(report.Current, "location")
+		for _, forecast := range answer.Forecasts {
+			conditions := newWeatherConditions(forecast)
+			delete(conditions, "location")
+			report.Forecasts = append(report.Forecasts, conditions)
+		}
+		return report, true
+	}
+
+	for _, raw := range result.Infoboxes {
+		box, ok := newInfobox(raw)
+		if !ok || box.Content == "" || !containsString(defaultWeatherEngines, strings.ToLower(box.Engine)) {
+			continue
+		}
+		return weatherReport{Location: box.Title, Text: htmlText(box.Content), Source: box.URL}, true
+	}
+	return weatherReport{}, false
+}
+
+// newWeatherConditions formats the conditions of a weather answer. Unit
+// values arrive as objects such as {"val": 21, "unit": "°C"}.
+func newWeatherConditions(raw map[string]interface{}) weatherConditions {
+	conditions := make(weatherConditions, len(raw))
+	for name, value := range raw {
+		if text := weatherValue(value); text != "" {
+			conditions[name] = text
+		}
+	}
+	return conditions
+}
+
+func weatherValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case map[string]interface{}:
+		for _, key := range []string{"val", "value"} {
+			if number, ok := v[key]; ok {
+				return strings.TrimSpace(weatherValue(number) + " " + weatherValue(v["unit"]))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if text := weatherValue(v[key]); text != "" {
+				parts = append(parts, key+": "+text)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// htmlText returns the text of an HTML fragment, one line per row or
+// block.
+func htmlText(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return collapse(fragment)
+	}
+	var lines []string
+	var line []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			if text := collapse(n.Data); text != "" {
+				line = append(line, text)
+			}
+		case n.Type == html.ElementNode:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+			switch n.Data {
+			case "tr", "p", "div", "h1", "h2", "h3", "h4", "li", "br":
+				if len(line) > 0 {
+					lines = append(lines, strings.Join(line, " "))
+					line = nil
+				}
+			}
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if len(line) > 0 {
+		lines = append(lines, strings.Join(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWeatherTool(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"answers": []interface{}{
+			map[string]interface{}{
+				"url":     "https://open-meteo.com/",
+				"engine":  "open meteo",
+				"service": "Open-meteo",
+				"current": map[string]interface{}{
+					"location":    "Berlin",
+					"condition":   "Partly cloudy",
+					"temperature": map[string]interface{}{"val": 21.5, "unit": "°C"},
+					"humidity":    map[string]interface{}{"value": 60, "unit": "%"},
+					"wind":        nil,
+				},
+				"forecasts": []interface{}{
+					map[string]interface{}{"time": "2026-10-17", "condition": "Rain", "temperature": map[string]interface{}{"val": 15, "unit": "°C"}},
+				},
+			},
+		},
+	})
+
+	result, err := callTool(t, searxngWeatherHandler, map[string]interface{}{"location": "Berlin"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	req, _ := fake.LastRequest("/search")
+	if req.Query.Get("engines") != "wttr.in,open meteo" {
+		t.Errorf("engines = %q", req.Query.Get("engines"))
+	}
+
+	var report weatherReport
+	decodeResult(t, result, &report)
+	want := weatherReport{
+		Location:  "Berlin",
+		Current:   weatherConditions{"condition": "Partly cloudy", "temperature": "21.5 °C", "humidity": "60 %"},
+		Forecasts: []weatherConditions{{"time": "2026-10-17", "condition": "Rain", "temperature": "15 °C"}},
+		Source:    "https://open-meteo.com/",
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v\nwant %+v", report, want)
+	}
+}
+
+func TestWeatherToolInfobox(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{
+		"infoboxes": []map[string]interface{}{{
+			"infobox": "Paris",
+			"id":      "https://wttr.in/Paris",
+			"engine":  "wttr.in",
+			"content": "<table><tr><td>Condition</td><td>Sunny</td></tr><tr><td>Temperature</td><td>18°C</td></tr></table>",
+		}},
+	})
+
+	result, err := callTool(t, searxngWeatherHandler, map[string]interface{}{"location": "Paris"})
+	if err != nil || result.IsError {
+		t.Fatalf("handler: %+v, %v", result, err)
+	}
+	var report weatherReport
+	decodeResult(t, result, &report)
+	if report.Location != "Paris" || report.Text != "Condition Sunny\nTemperature 18°C" || report.Source != "https://wttr.in/Paris" {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestWeatherToolNoWeather(t *testing.T) {
+	fake := useFakeInstance(t)
+	fake.SetSearchResponse(map[string]interface{}{"results": []interface{}{}})
+
+	result, err := callTool(t, searxngWeatherHandler, map[string]interface{}{"location": "Nowhere"})
+	if err != nil || !result.IsError {
+		t.Fatalf("handler: %+v, %v, want a tool error", result, err)
+	}
+}